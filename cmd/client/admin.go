@@ -0,0 +1,169 @@
+package main
+
+// admin命令调用管理端口上的AdminService，-target需要指定管理端口的地址，-token需要有admin角色
+//
+//	client -target 127.0.0.1:9528 admin stats
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// admin的子命令
+var adminCommands = []command{
+	{"stats", "print the number of cellphones, orders and uploaded covers", runAdminStats},
+	{"compact", "rewrite persistent stores and remove leftover files", runAdminCompact},
+	{"purge-covers", "remove cover files without a cellphone or replaced by a newer cover", runAdminPurgeCovers},
+	{"log-level", "print or change the log level of the server", runAdminLogLevel},
+	{"config", "print the effective config of the server with secrets redacted", runAdminConfig},
+}
+
+// admin <stats|compact|purge-covers|log-level|config> [flags]
+func runAdmin(e *env, args []string) error {
+	fs := e.flagSet("admin", "")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s admin <command> [flags]\n\ncommands:\n", e.usagePrefix)
+		for _, cmd := range adminCommands {
+			fmt.Fprintf(fs.Output(), "  %-14s %s\n", cmd.name, cmd.help)
+		}
+	}
+	if err := parseFlags(fs, args, 1, -1); err != nil {
+		return err
+	}
+	for _, cmd := range adminCommands {
+		if cmd.name == fs.Arg(0) {
+			sub := *e
+			sub.usagePrefix = strings.TrimSpace(e.usagePrefix + " admin")
+			return cmd.run(&sub, fs.Args()[1:])
+		}
+	}
+	return usageErrorf(fs, "unknown admin command %q", fs.Arg(0))
+}
+
+func (e *env) adminClient() pb.AdminServiceClient {
+	return pb.NewAdminServiceClient(e.connection())
+}
+
+// stats
+func runAdminStats(e *env, args []string) error {
+	fs := e.flagSet("stats", "")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	res, err := e.adminClient().GetStats(ctx, &pb.GetStatsRequest{})
+	if err != nil {
+		return &rpcError{"can not get stats", err}
+	}
+	return printOne(newPrinter(e, []string{"CELLPHONES", "ORDERS", "ORDER_AMOUNT", "COVERS_UPLOADED", "COVER_BYTES", "STARTED"},
+		func(s *pb.GetStatsResponse) []string {
+			return []string{
+				fmt.Sprint(s.Cellphones),
+				fmt.Sprint(s.Orders),
+				formatPrice(s.OrderAmount),
+				fmt.Sprint(s.CoversUploaded),
+				fmt.Sprint(s.CoverBytesUploaded),
+				formatTime(s.StartTime.AsTime()),
+			}
+		}), res)
+}
+
+// compact [-store NAME]...
+func runAdminCompact(e *env, args []string) error {
+	fs := e.flagSet("compact", "[-store NAME]...")
+	var stores stringsFlag
+	fs.Var(&stores, "store", "only this store, like catalog or webhooks, can be repeated or comma separated")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	res, err := e.adminClient().CompactStores(ctx, &pb.CompactStoresRequest{Stores: stores})
+	if err != nil {
+		return &rpcError{"can not compact stores", err}
+	}
+	if len(res.Stores) == 0 {
+		log.Println("the server has no persistent stores")
+	}
+	p := newPrinter(e, []string{"STORE", "DURATION_MS"},
+		func(s *pb.StoreCompaction) []string { return []string{s.Name, fmt.Sprint(s.DurationMs)} })
+	for _, store := range res.Stores {
+		if err := p.print(store); err != nil {
+			return err
+		}
+	}
+	return p.flush()
+}
+
+// purge-covers [-dry-run]
+func runAdminPurgeCovers(e *env, args []string) error {
+	fs := e.flagSet("purge-covers", "[-dry-run]")
+	dryRun := fs.Bool("dry-run", false, "only print the covers that would be removed")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	res, err := e.adminClient().PurgeOrphanCovers(ctx, &pb.PurgeOrphanCoversRequest{DryRun: *dryRun})
+	if err != nil {
+		return &rpcError{"can not purge covers", err}
+	}
+	p := newPrinter(e, []string{"FILE", "SIZE", "REASON"},
+		func(c *pb.OrphanCover) []string { return []string{c.File, fmt.Sprint(c.Size), c.Reason} })
+	for _, cover := range res.Covers {
+		if err := p.print(cover); err != nil {
+			return err
+		}
+	}
+	if err := p.flush(); err != nil {
+		return err
+	}
+	action := "removed"
+	if *dryRun {
+		action = "would be removed"
+	}
+	log.Printf("%d covers (%d bytes) %s\n", len(res.Covers), res.Bytes, action)
+	return nil
+}
+
+// log-level [LEVEL]
+func runAdminLogLevel(e *env, args []string) error {
+	fs := e.flagSet("log-level", "[debug|info|warn|error]")
+	if err := parseFlags(fs, args, 0, 1); err != nil {
+		return err
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	res, err := e.adminClient().SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: fs.Arg(0)})
+	if err != nil {
+		return &rpcError{"can not set log level", err}
+	}
+	return printOne(newPrinter(e, []string{"PREVIOUS", "LEVEL"},
+		func(r *pb.SetLogLevelResponse) []string { return []string{r.Previous, r.Level} }), res)
+}
+
+// config
+// 输出的就是json，不受-output影响
+func runAdminConfig(e *env, args []string) error {
+	fs := e.flagSet("config", "")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	res, err := e.adminClient().GetConfig(ctx, &pb.GetConfigRequest{})
+	if err != nil {
+		return &rpcError{"can not get config", err}
+	}
+	_, err = fmt.Fprintln(e.stdout, res.Json)
+	return err
+}
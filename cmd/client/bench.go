@@ -0,0 +1,225 @@
+package main
+
+// 压测服务端：按照权重混合调用CreateCellphone、SearchCellphone、UploadCellphoneCover和BuyCellphone，
+// 使用internal/sample生成的随机数据
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/bench"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+	"github.com/ryanreadbooks/go-grpc-example/pkg/cellphoneclient"
+)
+
+const defaultBenchMix = "create=2,search=5,upload=1,buy=2"
+
+// bench [-duration D] [-qps N | -concurrency N] [-mix create=2,search=5,upload=1,buy=2]
+func runBench(e *env, args []string) error {
+	fs := e.flagSet("bench", "[flags]")
+	duration := fs.Duration("duration", 10*time.Second, "how long to run")
+	qps := fs.Float64("qps", 0, "target requests per second, 0 means as fast as -concurrency allows")
+	concurrency := fs.Int("concurrency", 16, "maximum number of requests in flight")
+	mix := fs.String("mix", defaultBenchMix, "weights of create, search, upload and buy")
+	seed := fs.Int("seed-cellphones", 20, "cellphones to create before running, used by upload and buy")
+	coverFile := fs.String("cover-file", "", "cover image to upload, random bytes are uploaded by default")
+	coverSize := fs.Int("cover-size", 16*1024, "size of the random cover image in bytes")
+	reportFile := fs.String("report", "", "also write the json report with the latency distribution into this file")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+	weights, err := parseMix(*mix)
+	if err != nil {
+		return usageErrorf(fs, "%v", err)
+	}
+
+	cover := &benchCover{imageType: ".jpeg"}
+	if *coverFile != "" {
+		if cover.data, err = os.ReadFile(*coverFile); err != nil {
+			return err
+		}
+		cover.imageType = strings.ToLower(filepath.Ext(*coverFile))
+	} else {
+		cover.data = make([]byte, *coverSize)
+		rand.Read(cover.data)
+	}
+
+	b := &benchmark{client: e.sdk(), cover: cover}
+	if weights["upload"] > 0 || weights["buy"] > 0 {
+		if *seed <= 0 {
+			return usageErrorf(fs, "-seed-cellphones must be positive when upload or buy is in the mix")
+		}
+		ctx, cancel := e.context()
+		defer cancel()
+		for i := 0; i < *seed; i++ {
+			if err := b.create(ctx); err != nil {
+				return &rpcError{"can not create seed cellphones", err}
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(e.baseContext(), os.Interrupt)
+	defer stop()
+	report, err := bench.Run(ctx, bench.Config{
+		Ops: []bench.WeightedOp{
+			{Name: "create", Weight: weights["create"], Op: b.create},
+			{Name: "search", Weight: weights["search"], Op: b.search},
+			{Name: "upload", Weight: weights["upload"], Op: b.upload},
+			{Name: "buy", Weight: weights["buy"], Op: b.buy},
+		},
+		QPS:         *qps,
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Timeout:     e.timeout,
+		Seed:        time.Now().UnixNano(),
+	})
+	// 中断时仍然输出已经完成的请求的结果
+	if err != nil && ctx.Err() == nil {
+		return usageErrorf(fs, "%v", err)
+	}
+
+	if *reportFile != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*reportFile, append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	if e.output == outputJSON {
+		data, err := json.Marshal(report)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(e.stdout, "%s\n", data)
+		return err
+	}
+	return printBenchReport(e.stdout, report)
+}
+
+// 解析create=2,search=5这样的权重，没有出现的操作权重为0
+func parseMix(mix string) (map[string]int, error) {
+	weights := make(map[string]int)
+	for _, part := range strings.Split(mix, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix %q, expected name=weight", part)
+		}
+		switch name {
+		case "create", "search", "upload", "buy":
+		default:
+			return nil, fmt.Errorf("unknown operation %q in mix, expected create, search, upload or buy", name)
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q of %s", value, name)
+		}
+		weights[name] = weight
+	}
+	return weights, nil
+}
+
+type benchCover struct {
+	data      []byte
+	imageType string
+}
+
+// 压测中使用的操作，创建的手机用于上传封面和购买
+type benchmark struct {
+	client *cellphoneclient.Client
+	cover  *benchCover
+
+	mu  sync.Mutex
+	ids []string
+}
+
+func (b *benchmark) randomId() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ids[rand.Intn(len(b.ids))]
+}
+
+func (b *benchmark) create(ctx context.Context) error {
+	id, err := b.client.Create(ctx, sample.NewCellphone())
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.ids = append(b.ids, id)
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *benchmark) search(ctx context.Context) error {
+	_, err := b.client.SearchAll(ctx, &pb.FilterCondition{
+		MinCpuCore:         sample.RandomInt32(1, 6),
+		MinRamSize:         sample.RandomInt32(1, 8),
+		MinStorageSize:     sample.RandomInt32(100, 1024),
+		MinBatteryCapacity: sample.RandomInt32(2500, 8000),
+		Brands:             []string{"Apple", "Samsung", "Huawei", "Xiaomi", "OPPO", "VIVO", "Honor", "Pixel"},
+	})
+	return err
+}
+
+func (b *benchmark) upload(ctx context.Context) error {
+	_, err := b.client.UploadCoverWithType(ctx, b.randomId(), b.cover.imageType, bytes.NewReader(b.cover.data))
+	return err
+}
+
+func (b *benchmark) buy(ctx context.Context) error {
+	_, err := b.client.Buy(ctx, b.randomId(), sample.RandomFloat64(1000.0, 10000.0))
+	return err
+}
+
+func printBenchReport(w io.Writer, report *bench.Report) error {
+	total := report.Total
+	fmt.Fprintf(w, "elapsed %s, %d requests, %.1f req/s, %d errors\n\n",
+		report.Elapsed.Round(time.Millisecond), total.Requests, report.Throughput(total), total.Errors)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "OP\tREQUESTS\tERRORS\tREQ/S\tMEAN\tP50\tP90\tP99\tP99.9\tMAX")
+	rows := append([]*bench.OpReport(nil), report.Ops...)
+	for _, op := range append(rows, total) {
+		if op.Requests == 0 && op != total {
+			continue
+		}
+		h := op.Latency
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			op.Name, op.Requests, op.Errors, report.Throughput(op),
+			formatLatency(h.Mean()), formatLatency(h.Percentile(50)), formatLatency(h.Percentile(90)),
+			formatLatency(h.Percentile(99)), formatLatency(h.Percentile(99.9)), formatLatency(h.Max()))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if total.Errors == 0 {
+		return nil
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(tw, "OP\tCODE\tCOUNT")
+	for _, op := range report.Ops {
+		for _, code := range op.SortedCodes() {
+			fmt.Fprintf(tw, "%s\t%s\t%d\n", op.Name, code, op.ErrorsByCode[code])
+		}
+	}
+	return tw.Flush()
+}
+
+func formatLatency(d time.Duration) string {
+	return d.Round(10 * time.Microsecond).String()
+}
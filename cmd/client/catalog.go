@@ -0,0 +1,197 @@
+package main
+
+// 手机信息的导入导出子命令
+// 默认通过grpc调用服务端，指定-store时直接读写本地的手机信息文件，不需要启动服务端
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/catalog"
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/internal/validate"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 没有指定格式时根据扩展名判断，文件名为"-"表示标准输入输出，默认使用ndjson
+func catalogFormat(filename, name string) (catalog.Format, error) {
+	if name != "" {
+		return catalog.ParseFormat(name)
+	}
+	if filename == "-" {
+		return catalog.FormatNDJSON, nil
+	}
+	return catalog.FormatFromFilename(filename)
+}
+
+// import [-format F] [-atomic] [-store FILE] SRC
+func importCatalog(e *env, args []string) error {
+	fs := e.flagSet("import", "[flags] SRC (- means stdin)")
+	formatName := fs.String("format", "", "format of SRC: ndjson, csv or protobuf, detected from the extension by default")
+	atomic := fs.Bool("atomic", false, "import only if every cellphone is valid")
+	store := fs.String("store", "", "import into this catalog file directly instead of calling the server")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	src := fs.Arg(0)
+	format, err := catalogFormat(src, *formatName)
+	if err != nil {
+		return usageErrorf(fs, "%v", err)
+	}
+	in := os.Stdin
+	if src != "-" {
+		if in, err = os.Open(src); err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+	r, err := catalog.NewReader(in, format)
+	if err != nil {
+		return err
+	}
+
+	var summary *pb.BatchSummary
+	if *store != "" {
+		summary, err = importIntoStore(*store, r, *atomic)
+	} else {
+		// 边读边发送
+		summary, err = batchCreate(e, *atomic, func() (*pb.Cellphone, error) {
+			cellphone, err := r.Read()
+			if err != nil && err != io.EOF {
+				return nil, fmt.Errorf("can not read cellphones: %w", err)
+			}
+			return cellphone, err
+		})
+	}
+	if err != nil {
+		return err
+	}
+	return printSummary(e, summary)
+}
+
+// 直接导入到本地文件，和服务端一样先校验每一台手机，id为空时赋予新的id
+// 校验通过的手机最后一起保存，文件只写入一次
+func importIntoStore(filename string, r catalog.Reader, atomic bool) (*pb.BatchSummary, error) {
+	saver, err := service.NewFileCellphoneSaver(filename)
+	if err != nil {
+		return nil, err
+	}
+	ctx := rootCtx
+
+	var (
+		total, failed uint32
+		valid         []*pb.Cellphone
+		// valid中每一台手机在文件中的序号
+		indexes []uint32
+	)
+	for ; ; total++ {
+		cellphone, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can not read cellphones: %w", err)
+		}
+		if err := validate.Cellphone("cellphone", cellphone).Err(); err != nil {
+			failed++
+			log.Printf("cellphone #%d %s failed: %s\n", total, cellphone.Id, rpcerr.Decode(err))
+			continue
+		}
+		if cellphone.Id == "" {
+			cellphone.Id = uuid.NewString()
+		}
+		valid = append(valid, cellphone)
+		indexes = append(indexes, total)
+	}
+
+	if !atomic {
+		for i, err := range saver.SaveEach(ctx, valid) {
+			if err != nil {
+				failed++
+				log.Printf("cellphone #%d %s failed: %v\n", indexes[i], valid[i].Id, err)
+			}
+		}
+	}
+
+	committed := !atomic || failed == 0
+	if atomic && failed == 0 {
+		if err := saver.SaveAll(ctx, valid); err != nil {
+			return nil, fmt.Errorf("can not import cellphones: %w", err)
+		}
+	}
+	created := total - failed
+	if !committed {
+		created = 0
+	}
+	return &pb.BatchSummary{Total: total, Created: created, Failed: failed, Committed: committed}, nil
+}
+
+// export [-format F] [-store FILE] DST
+func exportCatalog(e *env, args []string) error {
+	fs := e.flagSet("export", "[flags] DST (- means stdout)")
+	formatName := fs.String("format", "", "format of DST: ndjson, csv or protobuf, detected from the extension by default")
+	store := fs.String("store", "", "export from this catalog file directly instead of calling the server")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	dst := fs.Arg(0)
+	format, err := catalogFormat(dst, *formatName)
+	if err != nil {
+		return usageErrorf(fs, "%v", err)
+	}
+
+	var cellphones []*pb.Cellphone
+	if *store != "" {
+		saver, err := service.NewFileCellphoneSaver(*store)
+		if err != nil {
+			return err
+		}
+		cellphones = saver.Search(nil)
+	} else if cellphones, err = searchAllCellphones(e); err != nil {
+		return err
+	}
+	// 按照id排序，多次导出的结果保持一致
+	sort.Slice(cellphones, func(i, j int) bool { return cellphones[i].Id < cellphones[j].Id })
+
+	out := os.Stdout
+	if dst != "-" {
+		if out, err = os.Create(dst); err != nil {
+			return err
+		}
+	}
+	w, err := catalog.NewWriter(out, format)
+	if err != nil {
+		return err
+	}
+	err = catalog.WriteAll(w, cellphones)
+	if dst != "-" {
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("can not export cellphones: %w", err)
+	}
+	log.Printf("exported %d cellphones\n", len(cellphones))
+	return nil
+}
+
+// 空的过滤条件匹配所有手机
+func searchAllCellphones(e *env) ([]*pb.Cellphone, error) {
+	ctx, cancel := e.context()
+	defer cancel()
+
+	cellphones, err := e.sdk().SearchAll(ctx, nil)
+	if err != nil {
+		return nil, &rpcError{"can not search cellphones", err}
+	}
+	return cellphones, nil
+}
@@ -0,0 +1,589 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/compression"
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+	"github.com/ryanreadbooks/go-grpc-example/pkg/cellphoneclient"
+)
+
+// create (-from-file FILE | -sample)
+func runCreate(e *env, args []string) error {
+	fs := e.flagSet("create", "(-from-file FILE | -sample)")
+	fromFile := fs.String("from-file", "", "json file of the cellphone, - means stdin")
+	useSample := fs.Bool("sample", false, "create a cellphone with random sample data")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if (*fromFile != "") == *useSample {
+		return usageErrorf(fs, "exactly one of -from-file and -sample is required")
+	}
+
+	cellphone := sample.NewCellphone()
+	if *fromFile != "" {
+		var err error
+		if cellphone, err = readCellphone(*fromFile); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	res, err := e.client().CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: cellphone})
+	if err != nil {
+		return &rpcError{"can not create cellphone", err}
+	}
+	return printOne(newPrinter(e, []string{"ID"},
+		func(r *pb.CreateCellphoneResponse) []string { return []string{r.Id} }), res)
+}
+
+// 读取json格式的手机信息，字段名可以是proto中的名字也可以是驼峰形式
+func readCellphone(filename string) (*pb.Cellphone, error) {
+	var data []byte
+	var err error
+	if filename == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cellphone pb.Cellphone
+	if err := protojson.Unmarshal(data, &cellphone); err != nil {
+		return nil, fmt.Errorf("can not parse cellphone from %s: %w", filename, err)
+	}
+	return &cellphone, nil
+}
+
+// get ID
+func runGet(e *env, args []string) error {
+	fs := e.flagSet("get", "ID")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	cellphone, err := e.client().GetCellphone(ctx, &pb.GetCellphoneRequest{Id: fs.Arg(0)})
+	if err != nil {
+		return &rpcError{"can not get cellphone", err}
+	}
+	return printOne(cellphonePrinter(e), cellphone)
+}
+
+// update -from-file FILE
+func runUpdate(e *env, args []string) error {
+	fs := e.flagSet("update", "-from-file FILE")
+	fromFile := fs.String("from-file", "", "json file of the cellphone including its id, - means stdin")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *fromFile == "" {
+		return usageErrorf(fs, "-from-file is required")
+	}
+	cellphone, err := readCellphone(*fromFile)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	updated, err := e.client().UpdateCellphone(ctx, &pb.UpdateCellphoneRequest{Cellphone: cellphone})
+	if err != nil {
+		return &rpcError{"can not update cellphone", err}
+	}
+	return printOne(cellphonePrinter(e), updated)
+}
+
+// delete ID
+func runDelete(e *env, args []string) error {
+	fs := e.flagSet("delete", "ID")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	if _, err := e.client().DeleteCellphone(ctx, &pb.DeleteCellphoneRequest{Id: fs.Arg(0)}); err != nil {
+		return &rpcError{"can not delete cellphone", err}
+	}
+	return nil
+}
+
+// 可以重复指定的参数，每次也可以用逗号分隔多个值
+type stringsFlag []string
+
+func (f *stringsFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *stringsFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*f = append(*f, v)
+		}
+	}
+	return nil
+}
+
+// 压缩算法的名字，设置时检查算法是否已经注册
+type compressorFlag string
+
+func (f *compressorFlag) String() string { return string(*f) }
+
+func (f *compressorFlag) Set(value string) error {
+	if err := compression.Validate(value); err != nil {
+		return err
+	}
+	*f = compressorFlag(value)
+	return nil
+}
+
+// 单次调用使用的压缩算法，覆盖全局的-compress
+func compressFlag(fs *flag.FlagSet) *compressorFlag {
+	var f compressorFlag
+	fs.Var(&f, "compress", "compressor of this call: identity, gzip or zstd, defaults to the global -compress")
+	return &f
+}
+
+// 以MB为单位的存储容量，比如8GiB、512MB、1TB，没有单位时为GB
+// 手机信息中的容量都是1024进制，所以GB和GiB的含义相同
+type sizeFlag int64
+
+func (f *sizeFlag) String() string { return fmt.Sprintf("%dMB", *f) }
+
+func (f *sizeFlag) Set(value string) error {
+	mb, err := parseSize(value)
+	if err != nil {
+		return err
+	}
+	*f = sizeFlag(mb)
+	return nil
+}
+
+func parseSize(value string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	number := strings.TrimRight(s, "BIGMT")
+	unit := strings.TrimSuffix(strings.TrimSuffix(s[len(number):], "B"), "I")
+
+	n, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	switch unit {
+	case "M":
+	case "", "G":
+		n *= 1024
+	case "T":
+		n *= 1024 * 1024
+	default:
+		return 0, fmt.Errorf("invalid size unit in %q, expected MB, GB or TB", value)
+	}
+	if n != math.Trunc(n) || n > math.MaxInt32*1024*1024 {
+		return 0, fmt.Errorf("size %q must be a whole number of MB", value)
+	}
+	return int64(n), nil
+}
+
+// 把手机信息中的存储容量换算成MB
+func megabytes(value int32, unit pb.Unit) int64 {
+	switch unit {
+	case pb.Unit_UnitGB:
+		return int64(value) * 1024
+	case pb.Unit_UnitTB:
+		return int64(value) * 1024 * 1024
+	default:
+		return int64(value)
+	}
+}
+
+// search [-brand B] [-min-ram SIZE] [-min-storage SIZE] [-min-cpu-cores N] [-min-battery MAH]
+func runSearch(e *env, args []string) error {
+	fs := e.flagSet("search", "[flags]")
+	var brands stringsFlag
+	var minRam, minStorage sizeFlag
+	fs.Var(&brands, "brand", "only cellphones of this brand, can be repeated or comma separated")
+	fs.Var(&minRam, "min-ram", "minimum ram size, like 8GiB")
+	fs.Var(&minStorage, "min-storage", "minimum storage size, like 256GB or 1TB")
+	minCpuCores := fs.Int("min-cpu-cores", 0, "minimum number of cpu cores")
+	minBattery := fs.Int("min-battery", 0, "minimum battery capacity in mAh")
+	compress := compressFlag(fs)
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	// 服务端只比较内存和硬盘大小的数值，不考虑单位，所以在客户端换算之后过滤
+	condition := &pb.FilterCondition{
+		MinCpuCore:         int32(*minCpuCores),
+		MinBatteryCapacity: int32(*minBattery),
+		Brands:             brands,
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	it := e.sdk(cellphoneclient.WithCompressor(string(*compress))).Search(ctx, condition)
+	defer it.Close()
+	p := cellphonePrinter(e)
+	for it.Next() {
+		cellphone := it.Cellphone()
+		if megabytes(cellphone.GetRam().GetValue(), cellphone.GetRam().GetUnit()) < int64(minRam) ||
+			megabytes(cellphone.GetStorage().GetValue(), cellphone.GetStorage().GetUnit()) < int64(minStorage) {
+			continue
+		}
+		if err := p.print(cellphone); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return &rpcError{"can not search cellphones", err}
+	}
+	return p.flush()
+}
+
+// upload-cover -id ID FILE
+func runUploadCover(e *env, args []string) error {
+	fs := e.flagSet("upload-cover", "-id ID FILE")
+	id := fs.String("id", "", "id of the cellphone")
+	compress := compressFlag(fs)
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	if *id == "" {
+		return usageErrorf(fs, "-id is required")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ctx, cancel := e.context()
+	defer cancel()
+	res, err := e.sdk(cellphoneclient.WithCompressor(string(*compress))).UploadCover(ctx, *id, f)
+	if err != nil {
+		return &rpcError{"can not upload cover", err}
+	}
+	return printOne(newPrinter(e, []string{"ID", "SIZE"},
+		func(r *pb.UploadCellphoneCoverResponse) []string { return []string{r.Id, fmt.Sprint(r.Size)} }), res)
+}
+
+// download-cover [-o PATH] ID
+func runDownloadCover(e *env, args []string) error {
+	fs := e.flagSet("download-cover", "[-o PATH] ID")
+	out := fs.String("o", "", "file or directory to save the cover into, - means stdout, defaults to ID with the image extension")
+	compress := compressFlag(fs)
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	id := fs.Arg(0)
+
+	ctx, cancel := e.context()
+	defer cancel()
+	stream, err := e.client().DownloadCellphoneCover(ctx, &pb.DownloadCellphoneCoverRequest{Id: id},
+		// 服务端用请求的压缩算法压缩封面数据
+		compression.CallOptions(string(*compress))...)
+	if err != nil {
+		return &rpcError{"can not download cover", err}
+	}
+	// 第一条响应为元数据
+	res, err := stream.Recv()
+	if err != nil {
+		return &rpcError{"can not download cover", err}
+	}
+	meta := res.GetMeta()
+	if meta == nil {
+		return errors.New("can not download cover: metadata is missing")
+	}
+
+	filename := *out
+	if filename == "" || isDir(filename) {
+		filename = filepath.Join(filename, id+meta.ImageType)
+	}
+	var w io.Writer = os.Stdout
+	if filename != "-" {
+		f, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	written, err := receiveCover(stream, w)
+	if err == nil && written != int64(meta.Size) {
+		err = fmt.Errorf("can not download cover: received %d of %d bytes", written, meta.Size)
+	}
+	if err != nil {
+		// 不保留不完整的文件
+		if filename != "-" {
+			os.Remove(filename)
+		}
+		return err
+	}
+	if filename == "-" {
+		return nil
+	}
+	log.Printf("cover of %s saved to %s\n", id, filename)
+	return printOne(newPrinter(e, []string{"ID", "TYPE", "SIZE"},
+		func(m *pb.CoverMetaInfo) []string { return []string{m.Id, m.ImageType, fmt.Sprint(m.Size)} }), meta)
+}
+
+func receiveCover(stream pb.CellphoneService_DownloadCellphoneCoverClient, w io.Writer) (int64, error) {
+	var written int64
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, &rpcError{"can not download cover", err}
+		}
+		n, err := w.Write(res.GetBlock())
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+func isDir(filename string) bool {
+	stat, err := os.Stat(filename)
+	return err == nil && stat.IsDir()
+}
+
+// buy -id ID -price PRICE [-idempotency-key KEY]
+func runBuy(e *env, args []string) error {
+	fs := e.flagSet("buy", "-id ID -price PRICE [-idempotency-key KEY]")
+	id := fs.String("id", "", "id of the cellphone")
+	price := fs.Float64("price", 0, "price paid for the cellphone")
+	// 重试时使用相同的幂等键，服务端不会重复下单
+	key := fs.String("idempotency-key", uuid.NewString(), "idempotency key of the order, random by default")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *id == "" {
+		return usageErrorf(fs, "-id is required")
+	}
+	if *price <= 0 {
+		return usageErrorf(fs, "-price must be positive")
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	res, err := e.sdk().BuyWithKey(ctx, *id, *price, *key)
+	if err != nil {
+		return &rpcError{"can not buy cellphone", err}
+	}
+	return printOne(newPrinter(e, []string{"ID", "AVG"},
+		func(r *pb.BuyCellphoneResponse) []string { return []string{r.Id, formatPrice(r.Avg)} }), res)
+}
+
+// orders [ID...]
+func runOrders(e *env, args []string) error {
+	fs := e.flagSet("orders", "[ID...]")
+	if err := parseFlags(fs, args, 0, -1); err != nil {
+		return err
+	}
+
+	ctx, cancel := e.context()
+	defer cancel()
+	res, err := e.client().ListOrders(ctx, &pb.ListOrdersRequest{Ids: fs.Args()})
+	if err != nil {
+		return &rpcError{"can not list orders", err}
+	}
+	p := ordersPrinter(e)
+	for _, order := range res.Orders {
+		if err := p.print(order); err != nil {
+			return err
+		}
+	}
+	return p.flush()
+}
+
+// watch [-since-revision N]
+// 因为处理太慢被服务端断开时，从最后收到的revision继续订阅
+func runWatch(e *env, args []string) error {
+	fs := e.flagSet("watch", "[-since-revision N]")
+	since := fs.Uint64("since-revision", 0, "resume watching after this revision, 0 means only new changes")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(e.baseContext(), os.Interrupt)
+	defer stop()
+
+	p := newPrinter(e, []string{"REVISION", "TYPE", "ID", "BRAND"},
+		func(event *pb.CellphoneEvent) []string {
+			return []string{fmt.Sprint(event.Revision), event.Type.String(), event.Cellphone.GetId(), event.Cellphone.GetBrand()}
+		})
+	p.stream = true
+
+	for {
+		stream, err := e.client().WatchCellphones(ctx, &pb.WatchRequest{SinceRevision: *since})
+		if err != nil {
+			return &rpcError{"can not watch cellphones", err}
+		}
+		if header, err := stream.Header(); err == nil {
+			log.Printf("watching from revision %s\n", strings.Join(header.Get(service.WatchRevisionMetadataKey), ","))
+		}
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				if rpcerr.ReasonOf(err) == pb.ErrorReason_WATCHER_TOO_SLOW {
+					log.Printf("watcher is too slow, resuming from revision %d\n", *since)
+					break
+				}
+				return &rpcError{"can not watch cellphones", err}
+			}
+			*since = event.Revision
+			if err := p.print(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watch-prices [ID...]
+func runWatchPrices(e *env, args []string) error {
+	fs := e.flagSet("watch-prices", "[ID...]")
+	if err := parseFlags(fs, args, 0, -1); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(e.baseContext(), os.Interrupt)
+	defer stop()
+
+	stream, err := e.client().WatchPrices(ctx, &pb.WatchPricesRequest{Ids: fs.Args()})
+	if err != nil {
+		return &rpcError{"can not watch prices", err}
+	}
+	p := newPrinter(e, []string{"ID", "COUNT", "AVG", "LAST", "COALESCED", "DROPPED"},
+		func(u *pb.PriceUpdate) []string {
+			return []string{u.Id, fmt.Sprint(u.Count), formatPrice(u.Avg), formatPrice(u.LastPrice),
+				fmt.Sprint(u.Coalesced), fmt.Sprint(u.Dropped)}
+		})
+	p.stream = true
+	for {
+		update, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return &rpcError{"can not watch prices", err}
+		}
+		if err := p.print(update); err != nil {
+			return err
+		}
+	}
+}
+
+// batch-create [-atomic] N
+func runBatchCreate(e *env, args []string) error {
+	fs := e.flagSet("batch-create", "[-atomic] N")
+	atomic := fs.Bool("atomic", false, "save the batch only if every cellphone is valid")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(fs.Arg(0))
+	if err != nil || n <= 0 {
+		return usageErrorf(fs, "N must be a positive number")
+	}
+
+	summary, err := batchCreate(e, *atomic, func() (*pb.Cellphone, error) {
+		if n == 0 {
+			return nil, io.EOF
+		}
+		n--
+		return sample.NewCellphone(), nil
+	})
+	if err != nil {
+		return err
+	}
+	return printSummary(e, summary)
+}
+
+// 通过BatchCreateCellphones保存next返回的所有手机，next返回io.EOF表示结束
+// 失败的手机打印到日志中
+// next返回其它错误时取消整个调用，atomic模式下不会保存任何手机
+func batchCreate(e *env, atomic bool, next func() (*pb.Cellphone, error)) (*pb.BatchSummary, error) {
+	ctx, cancel := context.WithCancel(e.baseContext())
+	defer cancel()
+
+	stream, err := e.client().BatchCreateCellphones(ctx)
+	if err != nil {
+		return nil, &rpcError{"can not batch create cellphones", err}
+	}
+
+	nextErr := make(chan error, 1)
+	go func() {
+		err := stream.Send(&pb.BatchCreateCellphonesRequest{
+			Data: &pb.BatchCreateCellphonesRequest_Options{Options: &pb.BatchOptions{Atomic: atomic}},
+		})
+		for err == nil {
+			var cellphone *pb.Cellphone
+			cellphone, err = next()
+			if err == io.EOF {
+				stream.CloseSend()
+				break
+			}
+			if err != nil {
+				nextErr <- err
+				cancel()
+				return
+			}
+			err = stream.Send(&pb.BatchCreateCellphonesRequest{
+				Data: &pb.BatchCreateCellphonesRequest_Cellphone{Cellphone: cellphone},
+			})
+		}
+		// Send的错误由Recv返回
+		nextErr <- nil
+	}()
+
+	for {
+		res, err := stream.Recv()
+		if err != nil {
+			if nerr := <-nextErr; nerr != nil {
+				return nil, nerr
+			}
+			return nil, &rpcError{"can not batch create cellphones", err}
+		}
+		if item := res.GetItem(); item != nil && item.Code != 0 {
+			log.Printf("cellphone #%d %s failed: %s (%s)\n", item.Index, item.Id, item.Message, item.Reason)
+		}
+		if summary := res.GetSummary(); summary != nil {
+			return summary, nil
+		}
+	}
+}
+
+// 打印批量操作的结果，有失败的手机时返回batchError
+func printSummary(e *env, summary *pb.BatchSummary) error {
+	if err := printOne(batchSummaryPrinter(e), summary); err != nil {
+		return err
+	}
+	if summary.Failed > 0 {
+		return &batchError{failed: summary.Failed, total: summary.Total}
+	}
+	return nil
+}
@@ -1,250 +1,376 @@
 package main
 
+// cellphone服务的命令行客户端
+//
+//	client [global flags] <command> [flags] [args]
+//
+// 命令的结果输出到标准输出，日志和错误输出到标准错误
+
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"io"
 	"log"
 	"os"
-	"path/filepath"
-	"runtime"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
-	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/compression"
+	"github.com/ryanreadbooks/go-grpc-example/internal/connopts"
+	_ "github.com/ryanreadbooks/go-grpc-example/internal/loadbalance"
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
+	"github.com/ryanreadbooks/go-grpc-example/internal/serviceconfig"
+	"github.com/ryanreadbooks/go-grpc-example/internal/tracing"
 	"github.com/ryanreadbooks/go-grpc-example/pb"
+	"github.com/ryanreadbooks/go-grpc-example/pkg/cellphoneclient"
+)
+
+// 进程的退出码
+const (
+	exitOK = 0
+	// 其它错误，比如读写本地文件失败
+	exitFailure = 1
+	// 命令行参数错误
+	exitUsage = 2
+	// 手机或者封面不存在
+	exitNotFound = 3
+	// 请求被服务端拒绝，比如参数不合法、手机已经存在或者批量操作中有失败的手机
+	exitRejected = 4
+	// 没有通过认证或者没有权限
+	exitPermission = 5
+	// 服务端不可用或者超时
+	exitUnavailable = 6
 )
 
 // 创建客户端
-func Dial(target string) *grpc.ClientConn {
+func Dial(target string, opts ...grpc.DialOption) *grpc.ClientConn {
 	// insecure
-	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	opts = append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, opts...)
+	conn, err := grpc.Dial(target, opts...)
 	if err != nil {
 		log.Fatalf("can not dial to %s, failure connection: %v\n", target, err)
 	}
 	return conn
 }
 
-func InitCellphoneServiceClient(target string) (pb.CellphoneServiceClient, *grpc.ClientConn) {
-	conn := Dial(target)
-	return pb.NewCellphoneServiceClient(conn), conn
+// 所有rpc调用的context都从这里派生
+var rootCtx = context.Background()
+
+// 一个子命令
+type command struct {
+	name string
+	help string
+	run  func(e *env, args []string) error
 }
 
-func main() {
-	// parse flag options
-	target := flag.String("target", "", "the target of the grpc server")
-	targetService := flag.String("service", "cellphone", "the target service of the server")
+var commands = []command{
+	{"create", "create a cellphone from a json file or random sample data", runCreate},
+	{"get", "print a cellphone", runGet},
+	{"update", "replace a cellphone with the content of a json file", runUpdate},
+	{"delete", "delete a cellphone", runDelete},
+	{"search", "search cellphones matching the given conditions", runSearch},
+	{"upload-cover", "upload the cover image of a cellphone", runUploadCover},
+	{"download-cover", "download the cover image of a cellphone", runDownloadCover},
+	{"buy", "buy a cellphone", runBuy},
+	{"orders", "list orders of cellphones", runOrders},
+	{"watch", "print cellphone changes until interrupted", runWatch},
+	{"watch-prices", "print price updates until interrupted", runWatchPrices},
+	{"batch-create", "create random sample cellphones in one batch", runBatchCreate},
+	{"import", "import cellphones from a ndjson, csv or protobuf file", importCatalog},
+	{"export", "export cellphones into a ndjson, csv or protobuf file", exportCatalog},
+	{"bench", "drive a mix of rpcs at a target qps or concurrency and report latencies", runBench},
+	{"admin", "run operational tasks through the admin service, -target should be the admin address", runAdmin},
+}
 
-	invokeCreateCellphone := flag.Bool("create-cellphone", true, "invoke CreateCellphone method")
-	invokeSearchCellphone := flag.Bool("search-cellphone", false, "invoke SearchCellphone method")
-	invokeUploadCellphoneCover := flag.Bool("upload-cellphone-cover", false, "invoke UploadCellphoneCover method")
-	uploadedCoverImgFilename := flag.String("cover-filename", "", "uploaded cover image filename")
-	invokeBuyCellphone := flag.Bool("buy-cellphone", false, "invoke BuyCellphone method")
+// 子命令运行时的环境
+type env struct {
+	target   string
+	dialOpts []grpc.DialOption
+	timeout  time.Duration
+	output   string
+	stdout   io.Writer
+	// 打印用法时命令名之前的部分
+	usagePrefix string
+	// rpc调用的context从ctx派生，并携带md中的metadata
+	ctx context.Context
+	md  metadata.MD
 
-	flag.Parse()
+	conn *grpc.ClientConn
+}
 
-	log.Printf("dialing to %s\n", *target)
+// 第一次调用时才连接服务端，离线的命令不需要服务端
+func (e *env) connection() *grpc.ClientConn {
+	if e.conn == nil {
+		e.conn = Dial(e.target, e.dialOpts...)
+	}
+	return e.conn
+}
 
-	if *targetService == "cellphone" {
-		var client pb.CellphoneServiceClient
-		client, conn := InitCellphoneServiceClient(*target)
-		defer conn.Close()
-		if *invokeCreateCellphone {
-			createCellphone(client)
-		}
-		if *invokeSearchCellphone {
-			searchCellphone(client)
-		}
-		if *invokeUploadCellphoneCover {
-			if *uploadedCoverImgFilename == "" {
-				log.Fatal("no cover image filename is specified")
-			}
-			uploadCellphoneCover(client, *uploadedCoverImgFilename)
-		}
-		if *invokeBuyCellphone {
-			buyCellphone(client)
+func (e *env) client() pb.CellphoneServiceClient {
+	return pb.NewCellphoneServiceClient(e.connection())
+}
+
+// 封装了流式调用的客户端，超时时间由e.context()控制
+func (e *env) sdk(opts ...cellphoneclient.Option) *cellphoneclient.Client {
+	return cellphoneclient.New(e.connection(), opts...)
+}
+
+// 没有超时时间的context，用于持续到被中断的流
+func (e *env) baseContext() context.Context {
+	if e.ctx == nil {
+		return e.outgoing(rootCtx)
+	}
+	return e.outgoing(e.ctx)
+}
+
+// 附加上md中的metadata
+func (e *env) outgoing(ctx context.Context) context.Context {
+	if len(e.md) == 0 {
+		return ctx
+	}
+	kv := make([]string, 0, 2*e.md.Len())
+	for k, values := range e.md {
+		for _, v := range values {
+			kv = append(kv, k, v)
 		}
-	} else {
-		log.Fatalf("target service '%s' not supported\n", *targetService)
 	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
 }
 
-// 调用rpc的创建cellphone方法
-func createCellphone(client pb.CellphoneServiceClient) (res *pb.CreateCellphoneResponse) {
-	req := &pb.CreateCellphoneRequest{
-		Cellphone: sample.NewCellphone(),
+// unary rpc和有限的流使用的context，timeout为0时不限制时间
+func (e *env) context() (context.Context, context.CancelFunc) {
+	if e.timeout <= 0 {
+		return context.WithCancel(e.baseContext())
 	}
+	return context.WithTimeout(e.baseContext(), e.timeout)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
+func (e *env) close() {
+	if e.conn != nil {
+		e.conn.Close()
+	}
+}
 
-	res, err := client.CreateCellphone(ctx, req)
-	if err != nil {
-		log.Fatalf("can not create cellphone: %v\n", err)
+// 子命令的参数解析出错时返回usageError
+func (e *env) flagSet(name, synopsis string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage:", strings.TrimSpace(strings.Join([]string{e.usagePrefix, name, synopsis}, " ")))
+		fs.PrintDefaults()
 	}
+	return fs
+}
 
-	log.Printf("cellphone: %s created\n", res.Id)
-	return
+// 命令行参数错误
+type usageError struct {
+	err error
 }
 
-// 调用rpc的查询cellphone方法
-func searchCellphone(client pb.CellphoneServiceClient) {
-	// 创建查询cellphone的条件
-	condition := pb.FilterCondition{
-		MinCpuCore:         sample.RandomInt32(1, 6),
-		MinRamSize:         sample.RandomInt32(1, 8),
-		MinStorageSize:     sample.RandomInt32(100, 1024),
-		MinBatteryCapacity: sample.RandomInt32(2500, 8000),
-		Brands:             []string{"Apple", "Samsung", "Huawei", "Xiaomi", "OPPO", "VIVO", "Honor", "Pixel"},
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+// 解析子命令的参数，位置参数的数量必须在[min, max]之间，max小于0表示不限制
+func parseFlags(fs *flag.FlagSet, args []string, min, max int) error {
+	if err := fs.Parse(args); err != nil {
+		return &usageError{err}
 	}
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		return usageErrorf(fs, "unexpected number of arguments: %d", fs.NArg())
+	}
+	return nil
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
+// 和flag包一样，先打印错误再打印用法
+func usageErrorf(fs *flag.FlagSet, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	fmt.Fprintln(fs.Output(), err)
+	fs.Usage()
+	return &usageError{err}
+}
 
-	stream, err := client.SearchCellphone(ctx, &condition)
-	if err != nil {
-		log.Fatalf("can not search cellphone: %v\n", err)
+// rpc调用失败，退出码由错误码决定
+type rpcError struct {
+	what string
+	err  error
+}
+
+func (e *rpcError) Error() string { return fmt.Sprintf("%s: %s", e.what, rpcerr.Decode(e.err)) }
+func (e *rpcError) Unwrap() error { return e.err }
+
+// 批量操作中有失败的手机
+type batchError struct {
+	failed, total uint32
+}
+
+func (e *batchError) Error() string {
+	return fmt.Sprintf("%d of %d cellphones failed", e.failed, e.total)
+}
+
+func exitCode(err error) int {
+	if err == nil || errors.Is(err, flag.ErrHelp) {
+		return exitOK
 	}
-	var cellphones []*pb.Cellphone
-	for {
-		cellphone, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			log.Fatalf("can not recv from stream: %v\n", err)
+	var usage *usageError
+	var rpc *rpcError
+	var batch *batchError
+	switch {
+	case errors.As(err, &usage):
+		return exitUsage
+	case errors.As(err, &batch):
+		return exitRejected
+	case errors.As(err, &rpc):
+		switch status.Code(rpc.err) {
+		case codes.NotFound:
+			return exitNotFound
+		case codes.InvalidArgument, codes.AlreadyExists, codes.FailedPrecondition, codes.OutOfRange, codes.Aborted:
+			return exitRejected
+		case codes.Unauthenticated, codes.PermissionDenied:
+			return exitPermission
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+			return exitUnavailable
 		}
-		cellphones = append(cellphones, cellphone)
-	}
-	if len(cellphones) == 0 {
-		log.Println("required cellphone not found")
-		return
-	}
-	for _, c := range cellphones {
-		log.Printf("cellphone id: %s\n", c.Id)
 	}
+	return exitFailure
 }
 
-// 调用rpc的上传图片的方法
-func uploadCellphoneCover(client pb.CellphoneServiceClient, imgFile string) {
-	// 先创建一条手机信息
-	res := createCellphone(client)
-	imageType := filepath.Ext(imgFile)
-	f, err := os.Open(imgFile)
-	if err != nil {
-		log.Fatalf("can not open file %s: %v\n", imgFile, err)
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
 	}
-	defer f.Close()
+	return nil
+}
 
-	stat, err := f.Stat()
-	if err != nil {
-		log.Fatalf("can not acquire file(%s) stat: %v\n", imgFile, err)
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "usage: client [global flags] <command> [flags] [args]")
+	fmt.Fprintln(out, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "  %-16s %s\n", cmd.name, cmd.help)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
+	fmt.Fprintln(out, "\nrun 'client <command> -h' for the flags of a command")
+	fmt.Fprintln(out, "\nglobal flags:")
+	flag.PrintDefaults()
+	fmt.Fprintf(out, "\nexit codes:\n"+
+		"  %d  success\n  %d  failure\n  %d  invalid command line\n  %d  cellphone or cover not found\n"+
+		"  %d  request rejected by the server\n  %d  unauthenticated or permission denied\n  %d  server unavailable or timed out\n",
+		exitOK, exitFailure, exitUsage, exitNotFound, exitRejected, exitPermission, exitUnavailable)
+}
 
-	stream, err := client.UploadCellphoneCover(ctx)
+// 加载service config，none表示不使用任何策略
+func loadServiceConfig(filename string) (*serviceconfig.Config, error) {
+	switch filename {
+	case "":
+		return serviceconfig.Default(), nil
+	case "none":
+		return serviceconfig.Parse([]byte("{}"))
+	}
+	cfg, err := serviceconfig.Load(filename)
 	if err != nil {
-		log.Fatalf("can not invoke upload cellphone cover method: %v\n", err)
+		return nil, fmt.Errorf("can not load service config %q: %w", filename, err)
 	}
+	return cfg, nil
+}
 
-	// 先发文件信息过去
-	metaInfoReq := pb.UploadCellphoneCoverRequest{
-		Data: &pb.UploadCellphoneCoverRequest_Meta{
-			Meta: &pb.CoverMetaInfo{
-				Id:        res.Id,
-				ImageType: imageType,
-				Size:      uint32(stat.Size()),
-			},
-		},
+func main() {
+	// parse flag options
+	target := flag.String("target", "127.0.0.1:9527", "the target of the grpc server, like static:///host1:9527,host2:9527 or dns:///host:9527 for several servers")
+	token := flag.String("token", "", "bearer token (api key or jwt) sent in the authorization metadata")
+	trace := flag.Bool("trace", false, "print client spans to stderr")
+	traceparent := flag.String("traceparent", "", "w3c traceparent of the caller, rpcs will join this trace")
+	timeout := flag.Duration("timeout", 10*time.Second, "timeout of each rpc, streams that run until interrupted are not limited")
+	output := flag.String("output", outputTable, "output format: table or json")
+	maxMsgSize := flag.Int("max-msg-size", connopts.DefaultClient().MaxRecvMsgBytes, "maximum size in bytes of a message sent or received")
+	keepalive := flag.Duration("keepalive", connopts.DefaultClient().KeepaliveTime, "ping the server after the connection is idle this long during rpcs, 0 to disable")
+	lb := flag.String("lb", "", "load balancing policy when the target has several addresses: round_robin or least_request")
+	var compress compressorFlag
+	flag.Var(&compress, "compress", "compressor of all rpcs: gzip or zstd, the server compresses responses with the same one")
+	serviceConfig := flag.String("service-config", "", "json service config with retry and hedging policies, empty for the built-in one, none to disable")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(exitUsage)
+	}
+	if *output != outputTable && *output != outputJSON {
+		log.Printf("unknown output format %q\n", *output)
+		os.Exit(exitUsage)
 	}
 
-	// 发送meta info
-	err = stream.Send(&metaInfoReq)
+	conn := connopts.DefaultClient()
+	conn.MaxRecvMsgBytes, conn.MaxSendMsgBytes = *maxMsgSize, *maxMsgSize
+	conn.KeepaliveTime = *keepalive
+	dialOpts := conn.DialOptions()
+	cfg, err := loadServiceConfig(*serviceConfig)
+	if err == nil && *lb != "" {
+		// 健康检查不通过的后端不参与负载均衡
+		cfg, err = cfg.WithLoadBalancing(*lb, true)
+	}
 	if err != nil {
-		log.Fatalf("can not send file meta info: %v\n", err)
+		log.Println(err)
+		os.Exit(exitUsage)
 	}
-
-	// 开始不断发送block
-	var buf []byte = make([]byte, 4096)
-
-	for {
-		n, err := f.Read(buf)
-		if err == io.EOF {
-			// 读完了文件的所有内容
-			uploadRes, err := stream.CloseAndRecv()
+	dialOpts = append(dialOpts, cfg.DialOptions()...)
+	if compress != "" {
+		dialOpts = append(dialOpts, grpc.WithDefaultCallOptions(compression.CallOptions(string(compress))...))
+	}
+	if *token != "" {
+		// 每次调用都会携带token
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(auth.NewTokenCredentials(*token)))
+	}
+	if *trace || *traceparent != "" {
+		var exporter tracing.Exporter
+		if *trace {
+			// 标准输出留给命令的结果
+			exporter = tracing.NewStdoutExporter(os.Stderr)
+		}
+		if *traceparent != "" {
+			parent, err := tracing.ParseTraceparent(*traceparent)
 			if err != nil {
-				log.Fatalf("can not close and recv: %v\n", err)
+				log.Printf("can not parse traceparent %q: %v\n", *traceparent, err)
+				os.Exit(exitUsage)
 			}
-			log.Printf("successfully uploaded %d bytes\n", uploadRes.Size)
-			break
-		}
-		if err != nil {
-			log.Fatalf("can not read bytes from file\n")
-		}
-
-		blockReq := pb.UploadCellphoneCoverRequest{
-			Data: &pb.UploadCellphoneCoverRequest_Block{
-				Block: buf[:n],
-			},
-		}
-		err = stream.Send(&blockReq)
-		if err != nil {
-			log.Fatalf("can not send block request to server: %v\n", err)
+			rootCtx = tracing.ContextWithRemoteSpanContext(rootCtx, parent)
 		}
+		tracer := tracing.NewTracer(exporter)
+		dialOpts = append(dialOpts,
+			grpc.WithChainUnaryInterceptor(tracing.UnaryClientInterceptor(tracer)),
+			grpc.WithChainStreamInterceptor(tracing.StreamClientInterceptor(tracer)))
 	}
-}
 
-// 调用rpc的BuyCellphone的方法
-func buyCellphone(client pb.CellphoneServiceClient) {
-	var createdCellphoneIds []string = make([]string, 0, 5)
-	// 先创建一些手机信息
-	for i := 0; i < 5; i++ {
-		createRes := createCellphone(client)
-		createdCellphoneIds = append(createdCellphoneIds, createRes.Id)
+	name := flag.Arg(0)
+	cmd := findCommand(name)
+	if cmd == nil {
+		log.Printf("unknown command %q, run 'client -h' for the list of commands\n", name)
+		os.Exit(exitUsage)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
+	e := &env{
+		target:   *target,
+		dialOpts: dialOpts,
+		timeout:  *timeout,
+		output:   *output,
+		stdout:   os.Stdout,
 
-	// 调用方法
-	stream, err := client.BuyCellphone(ctx)
-	if err != nil {
-		log.Fatalf("can not invoke BuyCellphone method: %v\n", err)
+		usagePrefix: "client [global flags]",
 	}
-
-	// 开另外一个goroutine来接收
-	waitc := make(chan struct{})
-	go func() {
-		for {
-			response, err := stream.Recv()
-			if err == io.EOF {
-				close(waitc)
-				break
-			}
-			if err != nil {
-				log.Printf("err when receiving buy cellphone response: %v\n", err)
-				runtime.Goexit()
-			}
-			log.Printf("%s: %.3f\n", response.Id, response.Avg)
-		}
-	}()
-
-	for i := 0; i < 2; i++ {
-		for _, id := range createdCellphoneIds {
-			// 发送请求
-			req := pb.BuyCellphoneRequest{
-				Id:    id,
-				Price: sample.RandomFloat64(1000.0, 10000.0),
-			}
-			err := stream.Send(&req)
-			if err != nil {
-				log.Fatalf("can not send buy request: %v\n", err)
-			}
-		}
+	err = cmd.run(e, flag.Args()[1:])
+	e.close()
+	var ue *usageError
+	// 参数错误时已经打印过错误和用法
+	if err != nil && !errors.Is(err, flag.ErrHelp) && !errors.As(err, &ue) {
+		log.Printf("%s: %v\n", name, err)
 	}
-	stream.CloseSend()
-	<-waitc
+	os.Exit(exitCode(err))
 }
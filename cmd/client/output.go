@@ -0,0 +1,141 @@
+package main
+
+// 命令的输出格式
+// table：带表头的对齐文本，适合直接阅读
+// json：每条结果一行protojson，适合交给jq等工具处理
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// 和网关以及导出的json格式保持一致
+var jsonOptions = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+// 按照output格式打印同一种类型的结果
+type printer[T proto.Message] struct {
+	w       io.Writer
+	output  string
+	columns []string
+	row     func(T) []string
+	// 流式的结果每一行都立刻输出
+	stream bool
+
+	tw          *tabwriter.Writer
+	wroteHeader bool
+}
+
+func newPrinter[T proto.Message](e *env, columns []string, row func(T) []string) *printer[T] {
+	return &printer[T]{
+		w:       e.stdout,
+		output:  e.output,
+		columns: columns,
+		row:     row,
+		tw:      tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0),
+	}
+}
+
+func (p *printer[T]) print(m T) error {
+	if p.output == outputJSON {
+		data, err := jsonOptions.Marshal(m)
+		if err != nil {
+			return err
+		}
+		// protojson的输出不稳定，可能包含多余的空格，压缩成一行
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, data); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err = p.w.Write(buf.Bytes())
+		return err
+	}
+
+	if !p.wroteHeader {
+		p.wroteHeader = true
+		fmt.Fprintln(p.tw, strings.Join(p.columns, "\t"))
+	}
+	fmt.Fprintln(p.tw, strings.Join(p.row(m), "\t"))
+	if p.stream {
+		return p.tw.Flush()
+	}
+	return nil
+}
+
+func (p *printer[T]) flush() error {
+	if p.output == outputJSON {
+		return nil
+	}
+	return p.tw.Flush()
+}
+
+// 打印一条结果
+func printOne[T proto.Message](p *printer[T], m T) error {
+	if err := p.print(m); err != nil {
+		return err
+	}
+	return p.flush()
+}
+
+func cellphonePrinter(e *env) *printer[*pb.Cellphone] {
+	return newPrinter(e, []string{"ID", "BRAND", "CPU", "RAM", "STORAGE", "BATTERY", "OS", "SCREEN", "CREATED"},
+		func(c *pb.Cellphone) []string {
+			return []string{
+				c.Id,
+				c.Brand,
+				fmt.Sprintf("%d cores", c.GetCpu().GetCores()),
+				formatSize(c.GetRam().GetValue(), c.GetRam().GetUnit()),
+				fmt.Sprintf("%s %s", formatSize(c.GetStorage().GetValue(), c.GetStorage().GetUnit()),
+					c.GetStorage().GetStorageType()),
+				fmt.Sprintf("%dmAh", c.GetBattery().GetCapacity()),
+				strings.TrimSpace(c.GetOperatingSystem().GetName() + " " + c.GetOperatingSystem().GetVersion()),
+				fmt.Sprintf("%.1fin %s", c.GetScreen().GetSize(), c.GetScreen().GetResolution()),
+				formatTime(c.GetCreatedAt().AsTime()),
+			}
+		})
+}
+
+func ordersPrinter(e *env) *printer[*pb.Order] {
+	return newPrinter(e, []string{"ID", "COUNT", "TOTAL", "AVG"},
+		func(o *pb.Order) []string {
+			return []string{o.Id, fmt.Sprint(o.Count), formatPrice(o.Total), formatPrice(o.Avg)}
+		})
+}
+
+func batchSummaryPrinter(e *env) *printer[*pb.BatchSummary] {
+	return newPrinter(e, []string{"TOTAL", "CREATED", "FAILED", "COMMITTED"},
+		func(s *pb.BatchSummary) []string {
+			return []string{fmt.Sprint(s.Total), fmt.Sprint(s.Created), fmt.Sprint(s.Failed), fmt.Sprint(s.Committed)}
+		})
+}
+
+// 比如8GB，单位的名字去掉Unit前缀
+func formatSize(value int32, unit pb.Unit) string {
+	return fmt.Sprintf("%d%s", value, strings.TrimPrefix(unit.String(), "Unit"))
+}
+
+func formatPrice(price float64) string {
+	return fmt.Sprintf("%.2f", price)
+}
+
+func formatTime(t time.Time) string {
+	if t.Unix() == 0 {
+		return ""
+	}
+	return t.Local().Format(time.RFC3339)
+}
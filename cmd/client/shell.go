@@ -0,0 +1,626 @@
+package main
+
+// 交互式的shell，逐行读取并执行命令：
+// 除了client的所有命令之外，还可以打开BuyCellphone双向流逐行发送购买请求，响应会异步地打印出来，
+// 设置每次调用携带的metadata和超时时间，以及打印每次调用收到的header和trailer
+// 标准输入不是终端时不打印提示符，可以用来执行脚本
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const shellPrompt = "> "
+
+// shell中特有的命令
+type builtin struct {
+	name     string
+	synopsis string
+	help     string
+	run      func(s *shell, args []string) error
+}
+
+var builtins []builtin
+
+func init() {
+	// 在init中赋值，避免shell中执行的命令和commands以及builtins之间的初始化循环
+	commands = append(commands,
+		command{"shell", "run commands interactively, with bidi streams, metadata and deadlines", runShell})
+	builtins = []builtin{
+		{"help", "", "print this help", (*shell).help},
+		{"exit", "", "leave the shell, quit and Ctrl-D also work", nil},
+		{"meta", "[set|add KEY VALUE... | del KEY... | clear]", "print or change the metadata sent with every rpc", (*shell).meta},
+		{"deadline", "[DURATION|off]", "print or change the timeout of each rpc", (*shell).deadline},
+		{"headers", "[on|off]", "print the header and trailer received by every rpc", (*shell).headers},
+		{"output", "[table|json]", "print or change the output format", (*shell).output},
+		{"stream", "open|close|cancel", "open or finish the BuyCellphone bidi stream", (*shell).stream},
+		{"send", "ID PRICE", "send a buy request on the open stream, responses are printed as they arrive", (*shell).send},
+		{"metadata-test", "[ID]", "call CustomService.MetadataCarryTest and print the echoed metadata", (*shell).metadataTest},
+	}
+}
+
+// 多个goroutine共用的输出
+type syncWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+type shell struct {
+	e           *env
+	ctx         context.Context
+	interactive bool
+	stderr      io.Writer
+	showHeaders atomic.Bool
+
+	// 正在执行的命令，被中断时取消
+	mu      sync.Mutex
+	running context.CancelFunc
+
+	buy *buyStream
+}
+
+// 打开的BuyCellphone双向流，响应由另一个goroutine接收
+type buyStream struct {
+	stream pb.CellphoneService_BuyCellphoneClient
+	cancel context.CancelFunc
+	// 接收的goroutine结束时关闭
+	done chan struct{}
+}
+
+func (b *buyStream) finished() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// shell
+func runShell(e *env, args []string) error {
+	fs := e.flagSet("shell", "")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	s := &shell{
+		e:      e,
+		ctx:    e.baseContext(),
+		stderr: &syncWriter{mu: &mu, w: os.Stderr},
+	}
+	if stat, err := os.Stdin.Stat(); err == nil && stat.Mode()&os.ModeCharDevice != 0 {
+		s.interactive = true
+	}
+	e.stdout = &syncWriter{mu: &mu, w: e.stdout}
+	e.usagePrefix = ""
+	if e.md == nil {
+		e.md = metadata.MD{}
+	}
+	// 在第一次连接之前加上，所有的rpc都会经过
+	e.dialOpts = append(e.dialOpts,
+		grpc.WithChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(s.streamInterceptor))
+
+	logOutput := log.Writer()
+	log.SetOutput(s.stderr)
+	defer log.SetOutput(logOutput)
+
+	// Ctrl-C只取消正在执行的命令，不退出shell
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer func() {
+		signal.Stop(interrupts)
+		close(interrupts)
+	}()
+	go func() {
+		for range interrupts {
+			s.interrupt()
+		}
+	}()
+
+	if s.interactive {
+		fmt.Fprintf(s.stderr, "connected to %s, type help for the list of commands\n", e.target)
+	}
+	in := bufio.NewScanner(os.Stdin)
+	for {
+		s.prompt()
+		if !in.Scan() {
+			break
+		}
+		args, err := splitLine(in.Text())
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		if args[0] == "exit" || args[0] == "quit" {
+			break
+		}
+		s.execute(args)
+	}
+	if s.interactive {
+		fmt.Fprintln(s.stderr)
+	}
+	if s.buy != nil {
+		s.buy.cancel()
+		<-s.buy.done
+	}
+	return in.Err()
+}
+
+// 执行一行命令，错误打印到日志中
+func (s *shell) execute(args []string) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.mu.Lock()
+	s.running = cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.running = nil
+		s.mu.Unlock()
+		cancel()
+	}()
+	s.e.ctx = ctx
+
+	name := args[0]
+	var err error
+	if b := findBuiltin(name); b != nil {
+		err = b.run(s, args[1:])
+	} else if cmd := findCommand(name); cmd != nil && cmd.name != "shell" {
+		err = cmd.run(s.e, args[1:])
+	} else {
+		err = fmt.Errorf("unknown command, type help for the list of commands")
+	}
+	var ue *usageError
+	// 参数错误时已经打印过错误和用法
+	if err != nil && !errors.Is(err, flag.ErrHelp) && !errors.As(err, &ue) {
+		log.Printf("%s: %v\n", name, err)
+	}
+}
+
+func findBuiltin(name string) *builtin {
+	for i := range builtins {
+		if builtins[i].name == name && builtins[i].run != nil {
+			return &builtins[i]
+		}
+	}
+	return nil
+}
+
+func (s *shell) interrupt() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running != nil {
+		s.running()
+		return
+	}
+	fmt.Fprint(s.stderr, "\n(type exit or press Ctrl-D to leave the shell)\n"+shellPrompt)
+}
+
+func (s *shell) prompt() {
+	if s.interactive {
+		fmt.Fprint(s.stderr, shellPrompt)
+	}
+}
+
+// 在命令之外异步地输出，之后重新打印提示符
+func (s *shell) async(f func()) {
+	s.mu.Lock()
+	idle := s.running == nil
+	s.mu.Unlock()
+	if s.interactive && idle {
+		fmt.Fprint(s.stderr, "\r")
+	}
+	f()
+	if idle {
+		s.prompt()
+	}
+}
+
+// 按照空白分割一行命令，支持单引号、双引号和反斜杠转义，#之后为注释
+func splitLine(line string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+loop:
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == '#' && !inArg:
+			break loop
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if escaped {
+		return nil, errors.New("unterminated escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// 打开headers之后每次调用都获取header和trailer
+func (s *shell) unaryInterceptor(ctx context.Context, method string, req, reply any,
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if !s.showHeaders.Load() {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	var header, trailer metadata.MD
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Header(&header), grpc.Trailer(&trailer))...)
+	s.printMetadata(method, "header", header)
+	s.printMetadata(method, "trailer", trailer)
+	return err
+}
+
+func (s *shell) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil || !s.showHeaders.Load() {
+		return stream, err
+	}
+	return &headerPrintingStream{ClientStream: stream, s: s, method: method}, nil
+}
+
+// 收到第一条响应时打印header，流结束时打印trailer
+type headerPrintingStream struct {
+	grpc.ClientStream
+	s      *shell
+	method string
+
+	headerOnce, trailerOnce sync.Once
+}
+
+func (h *headerPrintingStream) RecvMsg(m any) error {
+	err := h.ClientStream.RecvMsg(m)
+	h.headerOnce.Do(func() {
+		if header, err := h.Header(); err == nil {
+			h.s.printMetadata(h.method, "header", header)
+		}
+	})
+	if err != nil {
+		h.trailerOnce.Do(func() {
+			h.s.printMetadata(h.method, "trailer", h.Trailer())
+		})
+	}
+	return err
+}
+
+// 按照key的顺序打印，比如BuyCellphone trailer num-metadata-recv-trailer: 3
+func (s *shell) printMetadata(method, kind string, md metadata.MD) {
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s %s %s: %s\n", path.Base(method), kind, k, strings.Join(md[k], ", "))
+	}
+	io.WriteString(s.stderr, b.String())
+}
+
+// 把usage写到stderr的flag.FlagSet
+func (s *shell) flagSet(name, synopsis string) *flag.FlagSet {
+	fs := s.e.flagSet(name, synopsis)
+	fs.SetOutput(s.stderr)
+	return fs
+}
+
+// help
+func (s *shell) help(args []string) error {
+	fs := s.flagSet("help", "")
+	if err := parseFlags(fs, args, 0, 0); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(s.e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "shell commands:")
+	for _, b := range builtins {
+		fmt.Fprintf(tw, "  %s\t%s\n", strings.TrimSpace(b.name+" "+b.synopsis), b.help)
+	}
+	fmt.Fprintln(tw, "\nclient commands, run '<command> -h' for their flags:")
+	for _, cmd := range commands {
+		if cmd.name != "shell" {
+			fmt.Fprintf(tw, "  %s\t%s\n", cmd.name, cmd.help)
+		}
+	}
+	return tw.Flush()
+}
+
+// meta [set|add KEY VALUE... | del KEY... | clear]
+func (s *shell) meta(args []string) error {
+	fs := s.flagSet("meta", "[set|add KEY VALUE... | del KEY... | clear]")
+	if err := parseFlags(fs, args, 0, -1); err != nil {
+		return err
+	}
+	md := s.e.md
+	switch op := fs.Arg(0); op {
+	case "":
+		keys := make([]string, 0, len(md))
+		for k := range md {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(s.e.stdout, "%s: %s\n", k, strings.Join(md[k], ", "))
+		}
+	case "set", "add":
+		if fs.NArg() < 3 {
+			return usageErrorf(fs, "%s requires a key and at least one value", op)
+		}
+		if op == "set" {
+			md.Set(fs.Arg(1), fs.Args()[2:]...)
+		} else {
+			md.Append(fs.Arg(1), fs.Args()[2:]...)
+		}
+	case "del":
+		for _, k := range fs.Args()[1:] {
+			md.Delete(k)
+		}
+	case "clear":
+		for k := range md {
+			delete(md, k)
+		}
+	default:
+		return usageErrorf(fs, "unknown operation %q", op)
+	}
+	return nil
+}
+
+// deadline [DURATION|off]
+func (s *shell) deadline(args []string) error {
+	fs := s.flagSet("deadline", "[DURATION|off]")
+	if err := parseFlags(fs, args, 0, 1); err != nil {
+		return err
+	}
+	switch value := fs.Arg(0); value {
+	case "":
+		if s.e.timeout <= 0 {
+			fmt.Fprintln(s.e.stdout, "off")
+		} else {
+			fmt.Fprintln(s.e.stdout, s.e.timeout)
+		}
+	case "off":
+		s.e.timeout = 0
+	default:
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return usageErrorf(fs, "invalid deadline %q, expected a positive duration like 500ms or off", value)
+		}
+		s.e.timeout = timeout
+	}
+	return nil
+}
+
+// headers [on|off]
+func (s *shell) headers(args []string) error {
+	fs := s.flagSet("headers", "[on|off]")
+	if err := parseFlags(fs, args, 0, 1); err != nil {
+		return err
+	}
+	switch value := fs.Arg(0); value {
+	case "":
+		fmt.Fprintln(s.e.stdout, map[bool]string{true: "on", false: "off"}[s.showHeaders.Load()])
+	case "on", "off":
+		s.showHeaders.Store(value == "on")
+	default:
+		return usageErrorf(fs, "expected on or off")
+	}
+	return nil
+}
+
+// output [table|json]
+func (s *shell) output(args []string) error {
+	fs := s.flagSet("output", "[table|json]")
+	if err := parseFlags(fs, args, 0, 1); err != nil {
+		return err
+	}
+	switch value := fs.Arg(0); value {
+	case "":
+		fmt.Fprintln(s.e.stdout, s.e.output)
+	case outputTable, outputJSON:
+		s.e.output = value
+	default:
+		return usageErrorf(fs, "unknown output format %q", value)
+	}
+	return nil
+}
+
+// stream open|close|cancel
+// 流不受deadline的限制，一直持续到close或者cancel，metadata在open时确定
+func (s *shell) stream(args []string) error {
+	fs := s.flagSet("stream", "open|close|cancel")
+	if err := parseFlags(fs, args, 1, 1); err != nil {
+		return err
+	}
+	switch op := fs.Arg(0); op {
+	case "open":
+		if s.buy != nil && !s.buy.finished() {
+			return errors.New("the stream is already open")
+		}
+		return s.openBuyStream()
+	case "close", "cancel":
+		if s.buy == nil {
+			return errors.New("no stream is open")
+		}
+		if op == "close" {
+			// 服务端处理完已经发送的请求之后结束流
+			s.buy.stream.CloseSend()
+		} else {
+			s.buy.cancel()
+		}
+		select {
+		case <-s.buy.done:
+		case <-s.e.ctx.Done():
+			// 不再等待服务端，直接取消
+			s.buy.cancel()
+			<-s.buy.done
+		}
+		s.buy = nil
+	default:
+		return usageErrorf(fs, "unknown operation %q", op)
+	}
+	return nil
+}
+
+func (s *shell) openBuyStream() error {
+	ctx, cancel := context.WithCancel(s.e.outgoing(s.ctx))
+	stream, err := s.e.client().BuyCellphone(ctx)
+	if err != nil {
+		cancel()
+		return &rpcError{"can not open stream", err}
+	}
+	b := &buyStream{stream: stream, cancel: cancel, done: make(chan struct{})}
+	s.buy = b
+
+	p := newPrinter(s.e, []string{"ID", "AVG"},
+		func(r *pb.BuyCellphoneResponse) []string { return []string{r.Id, formatPrice(r.Avg)} })
+	p.stream = true
+	go func() {
+		defer close(b.done)
+		defer cancel()
+		for {
+			res, err := stream.Recv()
+			if err != nil {
+				s.async(func() {
+					switch {
+					case err == io.EOF:
+						log.Println("stream closed by the server")
+					case ctx.Err() != nil:
+						log.Println("stream canceled")
+					default:
+						log.Println(&rpcError{"stream failed", err})
+					}
+				})
+				return
+			}
+			s.async(func() {
+				if err := p.print(res); err != nil {
+					log.Println(err)
+				}
+			})
+		}
+	}()
+	log.Println("stream opened, send requests with: send ID PRICE")
+	return nil
+}
+
+// send ID PRICE
+func (s *shell) send(args []string) error {
+	fs := s.flagSet("send", "ID PRICE")
+	if err := parseFlags(fs, args, 2, 2); err != nil {
+		return err
+	}
+	price, err := strconv.ParseFloat(fs.Arg(1), 64)
+	if err != nil || price <= 0 {
+		return usageErrorf(fs, "PRICE must be a positive number")
+	}
+	if s.buy == nil || s.buy.finished() {
+		return errors.New("no stream is open, open one with: stream open")
+	}
+	if err := s.buy.stream.Send(&pb.BuyCellphoneRequest{Id: fs.Arg(0), Price: price, IdempotencyKey: uuid.NewString()}); err != nil {
+		// 具体的错误由接收的goroutine打印
+		return errors.New("the stream is broken")
+	}
+	return nil
+}
+
+// metadata-test [ID]
+// header和trailer总是打印，打开headers时由拦截器打印
+func (s *shell) metadataTest(args []string) error {
+	fs := s.flagSet("metadata-test", "[ID]")
+	if err := parseFlags(fs, args, 0, 1); err != nil {
+		return err
+	}
+	id := fs.Arg(0)
+	if id == "" {
+		id = "shell"
+	}
+
+	ctx, cancel := s.e.context()
+	defer cancel()
+	var header, trailer metadata.MD
+	const method = "/pb.CustomService/MetadataCarryTest"
+	res, err := pb.NewCustomServiceClient(s.e.connection()).MetadataCarryTest(ctx, &pb.CustomRequest{Id: id},
+		grpc.Header(&header), grpc.Trailer(&trailer))
+	if !s.showHeaders.Load() {
+		s.printMetadata(method, "header", header)
+		s.printMetadata(method, "trailer", trailer)
+	}
+	if err != nil {
+		return &rpcError{"can not call MetadataCarryTest", err}
+	}
+
+	if s.e.output == outputJSON {
+		return printOne(newPrinter(s.e, nil, func(*pb.CustomResponse) []string { return nil }), res)
+	}
+	keys := make([]string, 0, len(res.Metadata))
+	for k := range res.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tw := tabwriter.NewWriter(s.e.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\t%s\n\nKEY\tVALUES\n", res.Id)
+	for _, k := range keys {
+		fmt.Fprintf(tw, "%s\t%s\n", k, strings.Join(res.Metadata[k].GetValues(), ", "))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+	"github.com/ryanreadbooks/go-grpc-example/internal/debug"
+	"github.com/ryanreadbooks/go-grpc-example/internal/logging"
+	"github.com/ryanreadbooks/go-grpc-example/internal/metrics"
+	"github.com/ryanreadbooks/go-grpc-example/internal/ratelimit"
+	"github.com/ryanreadbooks/go-grpc-example/internal/tracing"
+)
+
+// 健康检查不需要认证，也不受限流的限制
+var healthMethods = []string{healthpb.Health_Check_FullMethodName, healthpb.Health_Watch_FullMethodName}
+
+// 按照配置组装拦截器，越靠前的拦截器越先执行
+func buildInterceptors(cfg *config.Config,
+	logger *slog.Logger,
+	serverMetrics *metrics.ServerMetrics,
+	authn auth.Authenticator,
+	tracker *debug.Tracker) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor, error) {
+
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+
+	// 追踪放在最前面，被拒绝的请求也能记录下来
+	if cfg.Tracing.Enabled {
+		exporter, err := newSpanExporter(cfg.Tracing)
+		if err != nil {
+			return nil, nil, err
+		}
+		tracer := tracing.NewTracer(exporter)
+		unary = append(unary, tracing.UnaryServerInterceptor(tracer))
+		stream = append(stream, tracing.StreamServerInterceptor(tracer))
+	}
+
+	logOpts := logging.Options{Payloads: cfg.Log.Payloads, Redact: cfg.Log.Redact}
+	unary = append(unary,
+		logging.UnaryServerInterceptor(logger, logOpts),
+		serverMetrics.UnaryServerInterceptor(),
+	)
+	stream = append(stream,
+		logging.StreamServerInterceptor(logger, logOpts),
+		serverMetrics.StreamServerInterceptor(),
+	)
+
+	// 没有开启认证时authn为nil
+	if authn != nil {
+		policy := auth.Policy(cfg.Auth.Policy)
+		if len(policy) == 0 {
+			policy = auth.DefaultPolicy()
+		}
+		if _, ok := policy[auth.AdminServicePattern]; !ok {
+			policy = maps.Clone(policy)
+			policy[auth.AdminServicePattern] = []string{"admin"}
+		}
+		publicMethods := append(slices.Clone(healthMethods), cfg.Auth.PublicMethods...)
+		authOpts := auth.Options{PublicMethods: publicMethods}
+		unary = append(unary, auth.UnaryServerInterceptor(authn, policy, authOpts))
+		stream = append(stream, auth.StreamServerInterceptor(authn, policy, authOpts))
+	}
+
+	// 限流放在认证之后，这样可以按照principal进行限流
+	if cfg.RateLimit.Enabled {
+		limiter := ratelimit.New(cfg.RateLimit)
+		limitOpts := ratelimit.Options{ExemptMethods: healthMethods}
+		unary = append(unary, ratelimit.UnaryServerInterceptor(limiter, limitOpts))
+		stream = append(stream, ratelimit.StreamServerInterceptor(limiter, limitOpts))
+	}
+
+	// 示例用的流拦截器，在debug日志中打印流中收发的每一条消息
+	stream = append(stream, installServerStreamInterceptor())
+
+	// 放在最后，只记录真正开始处理的流
+	stream = append(stream, tracker.StreamServerInterceptor())
+
+	return unary, stream, nil
+}
+
+// 安装一个stream interceptor
+type myWrappedStream struct {
+	grpc.ServerStream
+}
+
+// 实现SendMsg方法
+func (s *myWrappedStream) SendMsg(data interface{}) error {
+	// TODO 就是在这里实现自定义流拦截器的发送逻辑
+	// 这里简单打印日志
+	// data是需要往流中发送的数据，我们可以在这里对这个准备发送的数据进行自定义操作
+	slog.DebugContext(s.Context(), "myWrappedStream send a message", "type", fmt.Sprintf("%T", data), "message", data)
+	return s.ServerStream.SendMsg(data)
+}
+
+// 实现RecvMsg方法
+func (s *myWrappedStream) RecvMsg(data interface{}) error {
+	// TODO 就是在这里实现自定义流拦截器的接收逻辑
+	// 这里简单打印日志
+	err := s.ServerStream.RecvMsg(data) // 从流中接收数据
+	if err != nil {
+		return err
+	}
+	// 调用了RecvMsg从流中接收了数据之后，就可通过data访问到接收的数据内容
+	// data的类型是在proto文件中定义的流数据的类型
+	// 就可以按照自己的需求进一步处理
+	slog.DebugContext(s.Context(), "myWrappedStream receive a message", "type", fmt.Sprintf("%T", data), "message", data)
+
+	return nil
+}
+
+func newMyServerStream(s grpc.ServerStream) grpc.ServerStream {
+	return &myWrappedStream{s}
+}
+
+func installServerStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		slog.DebugContext(ss.Context(), "actual grpc.ServerStream type", "type", fmt.Sprintf("%T", ss)) // 前面的拦截器也会包装流，所以不一定是*grpc.serverStream
+		return handler(srv, newMyServerStream(ss))
+	}
+}
+
+// api keys和jwt可以同时开启
+func newAuthenticator(cfg config.AuthConfig) (auth.Authenticator, error) {
+	var chain auth.Chain
+	if cfg.APIKeysFile != "" {
+		keys, err := auth.LoadStaticKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, keys)
+	}
+	if cfg.JWTSecret != "" {
+		chain = append(chain, auth.NewJWTAuthenticator([]byte(cfg.JWTSecret)))
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("auth is enabled but neither api_keys_file nor jwt_secret is configured")
+	}
+	return chain, nil
+}
+
+func newSpanExporter(cfg config.TracingConfig) (tracing.Exporter, error) {
+	switch cfg.Exporter {
+	case "stdout":
+		return tracing.NewStdoutExporter(os.Stdout), nil
+	case "none", "":
+		return tracing.NopExporter{}, nil
+	}
+	return nil, fmt.Errorf("unknown span exporter %q", cfg.Exporter)
+}
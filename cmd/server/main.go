@@ -1,94 +1,334 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
-	"net"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
+	channelzservice "google.golang.org/grpc/channelz/service"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
 
+	"github.com/ryanreadbooks/go-grpc-example/internal/admin"
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	// 注册gzip和zstd，响应使用和请求相同的压缩算法
+	_ "github.com/ryanreadbooks/go-grpc-example/internal/compression"
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+	"github.com/ryanreadbooks/go-grpc-example/internal/connopts"
 	"github.com/ryanreadbooks/go-grpc-example/internal/custom"
+	"github.com/ryanreadbooks/go-grpc-example/internal/debug"
+	"github.com/ryanreadbooks/go-grpc-example/internal/gateway"
+	"github.com/ryanreadbooks/go-grpc-example/internal/grpcweb"
+	"github.com/ryanreadbooks/go-grpc-example/internal/logging"
+	"github.com/ryanreadbooks/go-grpc-example/internal/metrics"
+	"github.com/ryanreadbooks/go-grpc-example/internal/serve"
 	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/internal/webhook"
 	"github.com/ryanreadbooks/go-grpc-example/pb"
 )
 
-// 安装一个stream interceptor
-type myWrappedStream struct {
-	grpc.ServerStream
-}
+func main() {
+	configFile := flag.String("config", "", "path of the json config file")
+	cellphoneServiceOn := flag.Bool("cellphone", true, "turn on cellphone service")
+	customServiceOn := flag.Bool("custom", false, "turn on custom service")
+	logLevel := flag.String("log-level", "", "log level: debug, info, warn or error")
+	addr := flag.String("addr", "", "address of the grpc server, host:port or unix:///path/to.sock")
+	unixSocket := flag.String("unix-socket", "", "path of an extra unix socket the grpc server listens on")
+	adminAddr := flag.String("admin-addr", "", "address of the admin server, which serves /metrics, /debug, grpc health, reflection and channelz")
+	gatewayAddr := flag.String("gateway-addr", "", "address of the http/json gateway of cellphone service")
+	grpcWebAddr := flag.String("grpcweb-addr", "", "address of the grpc-web server")
+	catalogFile := flag.String("catalog-file", "", "file to persist cellphones in (.ndjson, .csv or .binpb)")
 
-// 实现SendMsg方法
-func (s *myWrappedStream) SendMsg(data interface{}) error {
-	// TODO 就是在这里实现自定义流拦截器的发送逻辑
-	// 这里简单打印日志
-	// data是需要往流中发送的数据，我们可以在这里对这个准备发送的数据进行自定义操作
-	log.Printf("myWrappedStream send a message(%T): %v\n", data, data)
-	return s.ServerStream.SendMsg(data)
-}
+	flag.Parse()
 
-// 实现RecvMsg方法
-func (s *myWrappedStream) RecvMsg(data interface{}) error {
-	// TODO 就是在这里实现自定义流拦截器的接收逻辑
-	// 这里简单打印日志
-	err := s.ServerStream.RecvMsg(data) // 从流中接收数据
+	cfg, err := config.Load(*configFile)
 	if err != nil {
-		return err
+		log.Fatal(err)
 	}
-	// 调用了RecvMsg从流中接收了数据之后，就可通过data访问到接收的数据内容
-	// data的类型是在proto文件中定义的流数据的类型
-	// 就可以按照自己的需求进一步处理
-	log.Printf("myWrappedStream receive a message(%T): %v\n", data, data)
+	// 命令行中显式指定的参数覆盖配置文件
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "addr":
+			cfg.Addr = *addr
+		case "unix-socket":
+			cfg.GRPCAddrs = append(cfg.GRPCAddrs, "unix://"+*unixSocket)
+		case "cellphone":
+			cfg.CellphoneService = *cellphoneServiceOn
+		case "custom":
+			cfg.CustomService = *customServiceOn
+		case "log-level":
+			cfg.Log.Level = *logLevel
+		case "admin-addr":
+			cfg.AdminAddr = *adminAddr
+		case "gateway-addr":
+			cfg.GatewayAddr = *gatewayAddr
+		case "grpcweb-addr":
+			cfg.GRPCWeb.Addr = *grpcWebAddr
+		case "catalog-file":
+			cfg.CatalogFile = *catalogFile
+		}
+	})
 
-	return nil
-}
-
-func newMyServerStream(s grpc.ServerStream) grpc.ServerStream {
-	return &myWrappedStream{s}
-}
+	logger, level, err := logging.New(os.Stderr, cfg.Log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
 
-func installServerStreamInterceptor() grpc.StreamServerInterceptor {
-	return func(srv interface{},
-		ss grpc.ServerStream,
-		info *grpc.StreamServerInfo,
-		handler grpc.StreamHandler) error {
+	// 收到退出信号时关闭所有的服务
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	group := &serve.Group{ShutdownTimeout: time.Duration(cfg.ShutdownTimeoutSeconds) * time.Second}
 
-		log.Printf("actual grpc.ServerStream type: %T\n", ss) // *grpc.serverStream
-		return handler(srv, newMyServerStream(ss))
-	}
-}
+	registry := metrics.NewRegistry()
+	serverMetrics := metrics.NewServerMetrics(registry)
 
-func main() {
-	cellphoneServiceOn := flag.Bool("cellphone", true, "turn on cellphone service")
-	customServiceOn := flag.Bool("custom", false, "turn on custom service")
+	// 记录进行中的购买和上传封面的流，通过管理端口的/debug/streams查看
+	tracker := debug.NewTracker("/pb.CellphoneService/BuyCellphone", "/pb.CellphoneService/UploadCellphoneCover")
 
-	flag.Parse()
+	// 拦截器和管理端口上的HTTP接口使用同一个authenticator
+	var authn auth.Authenticator
+	if cfg.Auth.Enabled {
+		authn, err = newAuthenticator(cfg.Auth)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	listener, err := net.Listen("tcp", "127.0.0.1:9527") // tcp监听
+	unaryInterceptors, streamInterceptors, err := buildInterceptors(cfg, logger, serverMetrics, authn, tracker)
 	if err != nil {
 		log.Fatal(err)
 	}
 	// 创建服务器
-	// 并且添加流拦截器
-	server := grpc.NewServer(
-		grpc.StreamInterceptor(installServerStreamInterceptor()),
-	)
-	defer server.GracefulStop()
-	defer listener.Close()
-
-	if *cellphoneServiceOn {
-		serverImpl := service.NewCellphoneServiceServer("image/server")
+	// 并且添加拦截器和连接相关的选项
+	server := grpc.NewServer(append(connopts.ServerOptions(cfg.Connection),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)...)
+
+	// 客户端负载均衡时通过健康检查判断后端是否可用，停止之前先标记为不可用
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	group.RegisterOnShutdown(healthServer.Shutdown)
+
+	grpcServer := serve.GRPC(server)
+	for _, addr := range append([]string{cfg.Addr}, cfg.GRPCAddrs...) {
+		l, err := serve.Listen(addr)
+		if err != nil {
+			group.Close()
+			log.Fatalf("can not listen grpc on %s: %v\n", addr, err)
+		}
+		group.Add("grpc", connopts.LimitListener(l, cfg.Connection), grpcServer)
+	}
+
+	var webhooks *webhook.Dispatcher
+	if cfg.Webhook.Enabled {
+		webhooks, err = newWebhookDispatcher(cfg.Webhook)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go webhooks.Run(ctx)
+	}
+
+	// admin服务需要访问cellphone服务的存储，所以先创建存储
+	adminOpts := []admin.Option{admin.WithConfig(cfg), admin.WithLogLevel(level)}
+	if webhooks != nil {
+		adminOpts = append(adminOpts, admin.WithStore("webhooks", webhooks.Store()))
+	}
+	var saver service.CellphoneSaver
+	orders := service.NewInMemoryOrderSaver()
+	if cfg.CellphoneService {
+		saver = service.NewInMemoryCellphoneSaver()
+		if cfg.CatalogFile != "" {
+			fileSaver, err := service.NewFileCellphoneSaver(cfg.CatalogFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+			saver = fileSaver
+			adminOpts = append(adminOpts, admin.WithStore("catalog", fileSaver))
+		}
+		adminOpts = append(adminOpts,
+			admin.WithCellphoneSaver(saver),
+			admin.WithOrderSaver(orders),
+			admin.WithCoverPath(cfg.CoverPath))
+	}
+	if !cfg.Auth.Enabled && cfg.Admin.AllowInsecure {
+		adminOpts = append(adminOpts, admin.WithAllowInsecure())
+	}
+	adminServerImpl := admin.NewServer(adminOpts...)
+
+	if cfg.CellphoneService {
+		serviceOpts := []service.Option{
+			service.WithCoverPath(cfg.CoverPath),
+			service.WithCellphoneSaver(saver),
+			service.WithOrderSaver(orders),
+			service.WithObserver(metrics.NewCellphoneMetrics(registry, saver)),
+			service.WithObserver(adminServerImpl),
+		}
+		if webhooks != nil {
+			serviceOpts = append(serviceOpts, service.WithObserver(webhooks))
+		}
+		serverImpl := service.NewCellphoneServiceServer(serviceOpts...)
 		pb.RegisterCellphoneServiceServer(server, serverImpl)
+
+		if cfg.GatewayAddr != "" {
+			gw := gateway.New(serverImpl,
+				gateway.WithUnaryInterceptors(unaryInterceptors...),
+				gateway.WithStreamInterceptors(streamInterceptors...))
+			if err := group.Listen("gateway", cfg.GatewayAddr, serve.HTTP(&http.Server{Handler: gw})); err != nil {
+				log.Fatal(err)
+			}
+		}
 	}
-	if *customServiceOn {
+	if cfg.CustomService {
 		customServerImpl := custom.NewCustomServiceServer()
 		pb.RegisterCustomServiceServer(server, customServerImpl)
 	}
 
-	log.Printf("server is listening on %s\n", listener.Addr().String())
-	log.Printf("cellphone service: %v\n", *cellphoneServiceOn)
-	log.Printf("custom service: %v\n", *customServiceOn)
-	err = server.Serve(listener)
+	if cfg.AdminAddr != "" {
+		// AdminService可以删除文件、修改日志等级，/webhooks可以让服务端请求任意的url，
+		// /debug可以读取内存和流中的数据，所以都只有admin角色可以访问，没有开启认证时默认不提供
+		var adminServiceServer *grpc.Server
+		var webhooksHTTP, debugHTTP http.Handler
+		switch {
+		case cfg.Auth.Enabled || cfg.Admin.AllowInsecure:
+			if !cfg.Auth.Enabled {
+				logger.Warn("admin service, webhook and debug api are not protected because auth is disabled and admin.allow_insecure is set",
+					"addr", cfg.AdminAddr)
+			}
+			adminServiceServer = grpc.NewServer(
+				grpc.ChainUnaryInterceptor(unaryInterceptors...),
+				grpc.ChainStreamInterceptor(streamInterceptors...))
+			pb.RegisterAdminServiceServer(adminServiceServer, adminServerImpl)
+			if webhooks != nil {
+				webhooksHTTP = adminOnly(authn, webhooks.Handler())
+			}
+			debugHTTP = adminOnly(authn, debug.Handler(tracker))
+		default:
+			logger.Warn("admin service, webhook and debug api are disabled because auth is disabled, set admin.allow_insecure to serve them anyway",
+				"addr", cfg.AdminAddr)
+		}
+		adminHTTP := newAdminServer(server, adminServiceServer, healthServer, registry, webhooksHTTP, debugHTTP)
+		if err := group.Listen("admin", cfg.AdminAddr, serve.HTTP(adminHTTP)); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if cfg.GRPCWeb.Addr != "" {
+		if err := group.Listen("grpc-web", cfg.GRPCWeb.Addr, serve.HTTP(newGRPCWebServer(cfg.GRPCWeb, server))); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	logger.Info("server is starting",
+		"cellphone_service", cfg.CellphoneService,
+		"custom_service", cfg.CustomService)
+	if err := group.Run(ctx); err != nil {
+		log.Fatal(err)
+	}
+	logger.Info("server is stopped")
+}
+
+// 开启认证时只有admin角色可以访问管理端口上的HTTP接口，authn为nil时不做检查
+func adminOnly(authn auth.Authenticator, next http.Handler) http.Handler {
+	if authn == nil {
+		return next
+	}
+	return auth.HTTPHandler(authn, []string{"admin"}, next)
+}
+
+// 管理端口，HTTP提供/metrics接口，webhooks不为nil时还可以通过/webhooks管理webhook，
+// debugHTTP不为nil时在/debug下提供pprof等调试接口；
+// 同一个端口上通过h2c提供gRPC的健康检查、反射和channelz，反射列出的是对外的gRPC服务和AdminService
+// AdminService由adminService提供，和对外的服务一样经过认证等拦截器，为nil时不提供AdminService
+func newAdminServer(public, adminService *grpc.Server, healthServer *health.Server,
+	registry *metrics.Registry, webhooks, debugHTTP http.Handler) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry.Handler())
+	if debugHTTP != nil {
+		mux.Handle("/debug/", debugHTTP)
+	}
+	if webhooks != nil {
+		mux.Handle("/webhooks", webhooks)
+		mux.Handle("/webhooks/", webhooks)
+	}
+
+	adminGRPC := grpc.NewServer()
+	healthpb.RegisterHealthServer(adminGRPC, healthServer)
+	// channelz记录的是整个进程中的服务器、连接和socket
+	channelzservice.RegisterChannelzServiceToServer(adminGRPC)
+	services := serviceInfos{public}
+	if adminService != nil {
+		services = append(services, adminService)
+	}
+	reflectionpb.RegisterServerReflectionServer(adminGRPC, reflection.NewServer(reflection.ServerOptions{
+		Services: services,
+	}))
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			if adminService != nil && strings.HasPrefix(r.URL.Path, "/"+pb.AdminService_ServiceDesc.ServiceName+"/") {
+				adminService.ServeHTTP(w, r)
+				return
+			}
+			adminGRPC.ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+	s := &http.Server{Handler: h2c.NewHandler(handler, &http2.Server{})}
+	// h2c的连接不受http.Server.Shutdown管理，健康检查的Watch也不会自己结束
+	s.RegisterOnShutdown(adminGRPC.Stop)
+	if adminService != nil {
+		s.RegisterOnShutdown(adminService.Stop)
+	}
+	return s
+}
+
+// 合并多个gRPC服务器上的服务，用于反射
+type serviceInfos []reflection.ServiceInfoProvider
+
+func (s serviceInfos) GetServiceInfo() map[string]grpc.ServiceInfo {
+	res := make(map[string]grpc.ServiceInfo)
+	for _, provider := range s {
+		for name, info := range provider.GetServiceInfo() {
+			res[name] = info
+		}
+	}
+	return res
+}
+
+// grpc-web服务，浏览器通过HTTP/1.1或者h2c访问
+func newGRPCWebServer(cfg config.GRPCWebConfig, server *grpc.Server) *http.Server {
+	handler := grpcweb.New(server, grpcweb.Options{
+		AllowedOrigins: cfg.AllowedOrigins,
+		AllowedHeaders: cfg.AllowedHeaders,
+		MaxAge:         time.Duration(cfg.MaxAgeSeconds) * time.Second,
+	})
+	return &http.Server{Handler: h2c.NewHandler(handler, &http2.Server{})}
+}
+
+func newWebhookDispatcher(cfg config.WebhookConfig) (*webhook.Dispatcher, error) {
+	store, err := webhook.NewStore(cfg.QueueDir, cfg.Retention)
 	if err != nil {
-		log.Fatalf("can not serve: %v\n", err)
+		return nil, err
 	}
+	return webhook.NewDispatcher(store, webhook.Options{
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.InitialBackoffSeconds) * time.Second,
+		MaxBackoff:     time.Duration(cfg.MaxBackoffSeconds) * time.Second,
+		Timeout:        time.Duration(cfg.TimeoutSeconds) * time.Second,
+		Workers:        cfg.Workers,
+	}), nil
 }
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/debug"
+	"github.com/ryanreadbooks/go-grpc-example/internal/metrics"
+)
+
+func TestAdminServerDebug(t *testing.T) {
+	t.Parallel()
+
+	authn := auth.NewStaticKeys([]auth.APIKey{
+		{Key: "admin-key", Subject: "alice", Roles: []string{"admin"}},
+		{Key: "buyer-key", Subject: "bob", Roles: []string{"buyer"}},
+	})
+
+	testCases := []struct {
+		Name      string
+		DebugHTTP http.Handler
+		Token     string
+		Code      int
+	}{
+		{Name: "no token", DebugHTTP: adminOnly(authn, debug.Handler(debug.NewTracker())), Code: http.StatusUnauthorized},
+		{Name: "not admin", DebugHTTP: adminOnly(authn, debug.Handler(debug.NewTracker())), Token: "buyer-key", Code: http.StatusForbidden},
+		{Name: "admin", DebugHTTP: adminOnly(authn, debug.Handler(debug.NewTracker())), Token: "admin-key", Code: http.StatusOK},
+		// 没有开启认证也没有设置allow_insecure时不提供
+		{Name: "disabled", DebugHTTP: nil, Code: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			server := grpc.NewServer()
+			defer server.Stop()
+			admin := newAdminServer(server, nil, health.NewServer(), metrics.NewRegistry(), nil, tc.DebugHTTP)
+			ts := httptest.NewServer(admin.Handler)
+			defer ts.Close()
+
+			req, err := http.NewRequest(http.MethodGet, ts.URL+"/debug/pprof/", nil)
+			require.Nil(t, err)
+			if tc.Token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.Token)
+			}
+			res, err := http.DefaultClient.Do(req)
+			require.Nil(t, err)
+			defer res.Body.Close()
+			require.Equal(t, tc.Code, res.StatusCode)
+
+			// /metrics不需要认证
+			res, err = http.Get(ts.URL + "/metrics")
+			require.Nil(t, err)
+			defer res.Body.Close()
+			require.Equal(t, http.StatusOK, res.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/admin"
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 在同一个服务端上运行cellphone服务和admin服务
+func runTestServer(t *testing.T, saver service.CellphoneSaver, coverPath string, opts ...admin.Option) *grpc.ClientConn {
+	orders := service.NewInMemoryOrderSaver()
+	opts = append([]admin.Option{
+		admin.WithAllowInsecure(),
+		admin.WithCellphoneSaver(saver),
+		admin.WithOrderSaver(orders),
+		admin.WithCoverPath(coverPath),
+	}, opts...)
+	adminServer := admin.NewServer(opts...)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口监听
+	require.Nil(t, err)
+	server := grpc.NewServer()
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(
+		service.WithCoverPath(coverPath),
+		service.WithCellphoneSaver(saver),
+		service.WithOrderSaver(orders),
+		service.WithObserver(adminServer)))
+	pb.RegisterAdminServiceServer(server, adminServer)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGetStats(t *testing.T) {
+	t.Parallel()
+
+	conn := runTestServer(t, service.NewInMemoryCellphoneSaver(), t.TempDir())
+	client := pb.NewCellphoneServiceClient(conn)
+	adminClient := pb.NewAdminServiceClient(conn)
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		res, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+		require.Nil(t, err)
+		ids = append(ids, res.Id)
+	}
+
+	// 两台手机各下一单
+	stream, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	for _, id := range ids[:2] {
+		require.Nil(t, stream.Send(&pb.BuyCellphoneRequest{Id: id, Price: 1000, IdempotencyKey: uuid.NewString()}))
+		_, err := stream.Recv()
+		require.Nil(t, err)
+	}
+	require.Nil(t, stream.CloseSend())
+
+	upload, err := client.UploadCellphoneCover(ctx)
+	require.Nil(t, err)
+	require.Nil(t, upload.Send(&pb.UploadCellphoneCoverRequest{
+		Data: &pb.UploadCellphoneCoverRequest_Meta{Meta: &pb.CoverMetaInfo{Id: ids[0], ImageType: ".jpeg", Size: 100}}}))
+	require.Nil(t, upload.Send(&pb.UploadCellphoneCoverRequest{
+		Data: &pb.UploadCellphoneCoverRequest_Block{Block: make([]byte, 100)}}))
+	_, err = upload.CloseAndRecv()
+	require.Nil(t, err)
+
+	stats, err := adminClient.GetStats(ctx, &pb.GetStatsRequest{})
+	require.Nil(t, err)
+	require.Equal(t, int32(3), stats.Cellphones)
+	require.Equal(t, uint64(2), stats.Orders)
+	require.Equal(t, 2000.0, stats.OrderAmount)
+	require.Equal(t, uint64(1), stats.CoversUploaded)
+	require.Equal(t, uint64(100), stats.CoverBytesUploaded)
+	require.WithinDuration(t, time.Now(), stats.StartTime.AsTime(), time.Minute)
+}
+
+func TestCompactStores(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	catalogFile := filepath.Join(dir, "catalog.ndjson")
+	saver, err := service.NewFileCellphoneSaver(catalogFile)
+	require.Nil(t, err)
+	require.Nil(t, saver.Save(context.Background(), sample.NewCellphone()))
+	// 写文件时崩溃留下的临时文件，以及被误删的数据文件
+	staleFile := catalogFile + ".123.tmp"
+	require.Nil(t, os.WriteFile(staleFile, []byte("{"), 0o600))
+	require.Nil(t, os.Remove(catalogFile))
+
+	client := pb.NewAdminServiceClient(runTestServer(t, saver, dir, admin.WithStore("catalog", saver)))
+
+	_, err = client.CompactStores(context.Background(), &pb.CompactStoresRequest{Stores: []string{"catalog", "orders"}})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	res, err := client.CompactStores(context.Background(), &pb.CompactStoresRequest{})
+	require.Nil(t, err)
+	require.Len(t, res.Stores, 1)
+	require.Equal(t, "catalog", res.Stores[0].Name)
+
+	_, err = os.Stat(staleFile)
+	require.True(t, os.IsNotExist(err))
+	reloaded, err := service.NewFileCellphoneSaver(catalogFile)
+	require.Nil(t, err)
+	require.Equal(t, int32(1), reloaded.Size())
+}
+
+func TestPurgeOrphanCovers(t *testing.T) {
+	t.Parallel()
+
+	coverPath := t.TempDir()
+	saver := service.NewInMemoryCellphoneSaver()
+	cellphone := sample.NewCellphone()
+	require.Nil(t, saver.Save(context.Background(), cellphone))
+
+	writeFile := func(name string, size int, modTime time.Time) string {
+		filename := filepath.Join(coverPath, name)
+		require.Nil(t, os.WriteFile(filename, make([]byte, size), 0o644))
+		require.Nil(t, os.Chtimes(filename, modTime, modTime))
+		return filename
+	}
+	now := time.Now()
+	current := writeFile(cellphone.Id+".png", 10, now)
+	superseded := writeFile(cellphone.Id+".jpeg", 20, now.Add(-time.Hour))
+	orphan := writeFile(uuid.NewString()+".jpeg", 30, now)
+	// 文件名不是uuid的文件不是封面
+	readme := writeFile("README.md", 40, now)
+
+	client := pb.NewAdminServiceClient(runTestServer(t, saver, coverPath))
+
+	testCases := []struct {
+		Name   string
+		DryRun bool
+	}{
+		{Name: "dry run", DryRun: true},
+		{Name: "purge", DryRun: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			res, err := client.PurgeOrphanCovers(context.Background(), &pb.PurgeOrphanCoversRequest{DryRun: tc.DryRun})
+			require.Nil(t, err)
+			require.Equal(t, uint64(50), res.Bytes)
+			reasons := make(map[string]string)
+			for _, cover := range res.Covers {
+				reasons[cover.File] = cover.Reason
+			}
+			require.Equal(t, map[string]string{
+				superseded: service.OrphanSuperseded,
+				orphan:     service.OrphanNoCellphone,
+			}, reasons)
+
+			for _, file := range []string{superseded, orphan} {
+				_, err := os.Stat(file)
+				require.Equal(t, tc.DryRun, err == nil)
+			}
+			for _, file := range []string{current, readme} {
+				_, err := os.Stat(file)
+				require.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	t.Parallel()
+
+	level := new(slog.LevelVar)
+	client := pb.NewAdminServiceClient(runTestServer(t, service.NewInMemoryCellphoneSaver(), t.TempDir(),
+		admin.WithLogLevel(level)))
+
+	testCases := []struct {
+		Name     string
+		Level    string
+		Previous string
+		Current  string
+		Code     codes.Code
+	}{
+		{Name: "query", Level: "", Previous: "INFO", Current: "INFO"},
+		{Name: "debug", Level: "debug", Previous: "INFO", Current: "DEBUG"},
+		{Name: "upper case", Level: "WARN", Previous: "DEBUG", Current: "WARN"},
+		{Name: "invalid", Level: "verbose", Code: codes.InvalidArgument},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			res, err := client.SetLogLevel(context.Background(), &pb.SetLogLevelRequest{Level: tc.Level})
+			require.Equal(t, tc.Code, status.Code(err))
+			if tc.Code != codes.OK {
+				return
+			}
+			require.Equal(t, tc.Previous, res.Previous)
+			require.Equal(t, tc.Current, res.Level)
+		})
+	}
+	require.Equal(t, slog.LevelWarn, level.Level())
+}
+
+func TestGetConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = "top-secret"
+	client := pb.NewAdminServiceClient(runTestServer(t, service.NewInMemoryCellphoneSaver(), t.TempDir(),
+		admin.WithConfig(cfg)))
+
+	res, err := client.GetConfig(context.Background(), &pb.GetConfigRequest{})
+	require.Nil(t, err)
+	require.NotContains(t, res.Json, "top-secret")
+	var dumped config.Config
+	require.Nil(t, json.Unmarshal([]byte(res.Json), &dumped))
+	require.Equal(t, cfg.Addr, dumped.Addr)
+	require.Equal(t, cfg.Connection, dumped.Connection)
+	// 导出时不会修改正在使用的配置
+	require.Equal(t, "top-secret", cfg.Auth.JWTSecret)
+}
+
+// 没有配置的功能返回FailedPrecondition
+func TestNotConfigured(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := grpc.NewServer()
+	pb.RegisterAdminServiceServer(server, admin.NewServer(admin.WithAllowInsecure()))
+	go server.Serve(listener)
+	defer server.Stop()
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+	client := pb.NewAdminServiceClient(conn)
+
+	_, err = client.PurgeOrphanCovers(context.Background(), &pb.PurgeOrphanCoversRequest{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.SetLogLevel(context.Background(), &pb.SetLogLevelRequest{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = client.GetConfig(context.Background(), &pb.GetConfigRequest{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	res, err := client.CompactStores(context.Background(), &pb.CompactStoresRequest{})
+	require.Nil(t, err)
+	require.Empty(t, res.Stores)
+}
+
+// 默认策略下只有admin角色可以调用
+func TestAuth(t *testing.T) {
+	t.Parallel()
+
+	authn := auth.NewStaticKeys([]auth.APIKey{
+		{Key: "admin-key", Subject: "admin", Roles: []string{"admin"}},
+		{Key: "buyer-key", Subject: "buyer", Roles: []string{"buyer"}},
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := grpc.NewServer(grpc.UnaryInterceptor(auth.UnaryServerInterceptor(authn, auth.DefaultPolicy(), auth.Options{})))
+	pb.RegisterAdminServiceServer(server, admin.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+
+	testCases := []struct {
+		Name  string
+		Token string
+		Code  codes.Code
+	}{
+		{Name: "no token", Token: "", Code: codes.Unauthenticated},
+		{Name: "buyer", Token: "buyer-key", Code: codes.PermissionDenied},
+		{Name: "admin", Token: "admin-key", Code: codes.OK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+			if tc.Token != "" {
+				opts = append(opts, grpc.WithPerRPCCredentials(auth.NewTokenCredentials(tc.Token)))
+			}
+			conn, err := grpc.Dial(listener.Addr().String(), opts...)
+			require.Nil(t, err)
+			defer conn.Close()
+			_, err = pb.NewAdminServiceClient(conn).GetStats(context.Background(), &pb.GetStatsRequest{})
+			require.Equal(t, tc.Code, status.Code(err))
+		})
+	}
+}
+
+// 没有认证拦截器时，所有的调用都会被拒绝，不会执行任何操作
+func TestRequireAuth(t *testing.T) {
+	t.Parallel()
+
+	coverPath := t.TempDir()
+	orphan := filepath.Join(coverPath, uuid.NewString()+".jpeg")
+	require.Nil(t, os.WriteFile(orphan, []byte("cover"), 0o600))
+	level := new(slog.LevelVar)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := grpc.NewServer()
+	pb.RegisterAdminServiceServer(server, admin.NewServer(
+		admin.WithCellphoneSaver(service.NewInMemoryCellphoneSaver()),
+		admin.WithCoverPath(coverPath),
+		admin.WithLogLevel(level),
+		admin.WithConfig(config.Default())))
+	go server.Serve(listener)
+	defer server.Stop()
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+	client := pb.NewAdminServiceClient(conn)
+	ctx := context.Background()
+
+	testCases := []struct {
+		Name string
+		Call func() error
+	}{
+		{Name: "GetStats", Call: func() error {
+			_, err := client.GetStats(ctx, &pb.GetStatsRequest{})
+			return err
+		}},
+		{Name: "CompactStores", Call: func() error {
+			_, err := client.CompactStores(ctx, &pb.CompactStoresRequest{})
+			return err
+		}},
+		{Name: "PurgeOrphanCovers", Call: func() error {
+			_, err := client.PurgeOrphanCovers(ctx, &pb.PurgeOrphanCoversRequest{})
+			return err
+		}},
+		{Name: "SetLogLevel", Call: func() error {
+			_, err := client.SetLogLevel(ctx, &pb.SetLogLevelRequest{Level: "debug"})
+			return err
+		}},
+		{Name: "GetConfig", Call: func() error {
+			_, err := client.GetConfig(ctx, &pb.GetConfigRequest{})
+			return err
+		}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			require.Equal(t, codes.Unauthenticated, status.Code(tc.Call()))
+		})
+	}
+
+	_, err = os.Stat(orphan)
+	require.Nil(t, err)
+	require.Equal(t, slog.LevelInfo, level.Level())
+}
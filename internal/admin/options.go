@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"log/slog"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+)
+
+// 创建admin服务时的可选项
+type Option func(*Server)
+
+// 统计手机数量，以及判断封面是否还有对应的手机
+func WithCellphoneSaver(saver service.CellphoneSaver) Option {
+	return func(s *Server) {
+		s.saver = saver
+	}
+}
+
+// 统计订单数量和金额
+func WithOrderSaver(orders service.OrderSaver) Option {
+	return func(s *Server) {
+		s.orders = orders
+	}
+}
+
+// 存放封面图片的目录，和cellphone服务使用的相同
+func WithCoverPath(coverPath string) Option {
+	return func(s *Server) {
+		s.coverPath = coverPath
+	}
+}
+
+// 运行时可以修改的日志等级
+func WithLogLevel(level *slog.LevelVar) Option {
+	return func(s *Server) {
+		s.level = level
+	}
+}
+
+// 通过GetConfig导出的配置
+func WithConfig(cfg *config.Config) Option {
+	return func(s *Server) {
+		s.cfg = cfg
+	}
+}
+
+// 添加一个可以通过CompactStores处理的存储，可以添加多个
+func WithStore(name string, c Compactor) Option {
+	return func(s *Server) {
+		s.stores = append(s.stores, store{name: name, compactor: c})
+	}
+}
+
+// 允许没有通过认证的调用，只应该在没有开启认证的本地调试中使用
+func WithAllowInsecure() Option {
+	return func(s *Server) {
+		s.allowInsecure = true
+	}
+}
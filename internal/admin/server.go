@@ -0,0 +1,214 @@
+package admin
+
+// 运维使用的AdminService，通过管理端口提供
+// 只有admin角色可以调用，由认证拦截器和auth.AdminServicePattern保证
+// 没有经过认证拦截器的调用会被拒绝，除非使用了WithAllowInsecure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+	"github.com/ryanreadbooks/go-grpc-example/internal/logging"
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 可以手动重写的持久化存储
+type Compactor interface {
+	Compact() error
+}
+
+type store struct {
+	name      string
+	compactor Compactor
+}
+
+// 同时实现了service.Observer接口，通过service.WithObserver安装之后统计封面上传
+type Server struct {
+	pb.UnimplementedAdminServiceServer
+	service.NopObserver
+
+	saver     service.CellphoneSaver
+	orders    service.OrderSaver
+	coverPath string
+	level     *slog.LevelVar
+	cfg       *config.Config
+	stores    []store
+	startTime time.Time
+
+	allowInsecure bool
+
+	coversUploaded     atomic.Uint64
+	coverBytesUploaded atomic.Uint64
+}
+
+func NewServer(opts ...Option) *Server {
+	s := &Server{startTime: time.Now()}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Server) CoverUploaded(_ context.Context, _ string, size uint32) {
+	s.coversUploaded.Add(1)
+	s.coverBytesUploaded.Add(uint64(size))
+}
+
+// 防止在没有开启认证时把AdminService暴露出去，角色由认证拦截器按照策略检查
+func (s *Server) authorize(ctx context.Context) error {
+	if s.allowInsecure {
+		return nil
+	}
+	if _, ok := auth.FromContext(ctx); !ok {
+		return status.Error(codes.Unauthenticated, "admin service requires authentication")
+	}
+	return nil
+}
+
+// 没有配置某个功能需要的选项，比如没有开启cellphone服务
+func notConfigured(what string) error {
+	return status.Errorf(codes.FailedPrecondition, "%s is not configured on this server", what)
+}
+
+func (s *Server) GetStats(ctx context.Context, _ *pb.GetStatsRequest) (*pb.GetStatsResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	res := &pb.GetStatsResponse{
+		CoversUploaded:     s.coversUploaded.Load(),
+		CoverBytesUploaded: s.coverBytesUploaded.Load(),
+		StartTime:          timestamppb.New(s.startTime),
+	}
+	if s.saver != nil {
+		res.Cellphones = s.saver.Size()
+	}
+	if s.orders != nil {
+		for _, o := range s.orders.List() {
+			res.Orders += uint64(o.Count)
+			res.OrderAmount += o.Total
+		}
+	}
+	return res, nil
+}
+
+func (s *Server) CompactStores(ctx context.Context, req *pb.CompactStoresRequest) (*pb.CompactStoresResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	stores := s.stores
+	if len(req.Stores) > 0 {
+		stores = nil
+		for i, name := range req.Stores {
+			st, ok := s.findStore(name)
+			if !ok {
+				return nil, rpcerr.InvalidArgument(fmt.Sprintf("unknown store %q", name),
+					[]*errdetails.BadRequest_FieldViolation{
+						{Field: fmt.Sprintf("stores[%d]", i), Description: "must be one of the configured stores"},
+					})
+			}
+			stores = append(stores, st)
+		}
+	}
+
+	res := &pb.CompactStoresResponse{}
+	for _, st := range stores {
+		start := time.Now()
+		if err := st.compactor.Compact(); err != nil {
+			slog.ErrorContext(ctx, "can not compact store", "store", st.name, "error", err)
+			return nil, rpcerr.StorageFailure(fmt.Errorf("can not compact %s: %w", st.name, err))
+		}
+		elapsed := time.Since(start)
+		slog.InfoContext(ctx, "store compacted", "store", st.name, "duration", elapsed)
+		res.Stores = append(res.Stores, &pb.StoreCompaction{Name: st.name, DurationMs: elapsed.Milliseconds()})
+	}
+	return res, nil
+}
+
+func (s *Server) findStore(name string) (store, bool) {
+	for _, st := range s.stores {
+		if st.name == name {
+			return st, true
+		}
+	}
+	return store{}, false
+}
+
+func (s *Server) PurgeOrphanCovers(ctx context.Context, req *pb.PurgeOrphanCoversRequest) (*pb.PurgeOrphanCoversResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if s.saver == nil || s.coverPath == "" {
+		return nil, notConfigured("cellphone service")
+	}
+	orphans, err := service.OrphanCovers(s.coverPath, s.saver)
+	if err != nil {
+		return nil, rpcerr.StorageFailure(err)
+	}
+
+	res := &pb.PurgeOrphanCoversResponse{}
+	for _, orphan := range orphans {
+		if !req.DryRun {
+			// 已经被删除的文件不算失败
+			if err := os.Remove(orphan.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				slog.ErrorContext(ctx, "can not remove orphan cover", "file", orphan.Path, "error", err)
+				return nil, rpcerr.StorageFailure(err)
+			}
+		}
+		res.Covers = append(res.Covers, &pb.OrphanCover{File: orphan.Path, Size: uint64(orphan.Size), Reason: orphan.Reason})
+		res.Bytes += uint64(orphan.Size)
+	}
+	if !req.DryRun {
+		slog.InfoContext(ctx, "orphan covers purged", "count", len(res.Covers), "bytes", res.Bytes)
+	}
+	return res, nil
+}
+
+func (s *Server) SetLogLevel(ctx context.Context, req *pb.SetLogLevelRequest) (*pb.SetLogLevelResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if s.level == nil {
+		return nil, notConfigured("log level")
+	}
+	previous := s.level.Level()
+	if req.Level != "" {
+		if err := logging.SetLevel(s.level, req.Level); err != nil {
+			return nil, rpcerr.InvalidArgument(err.Error(), []*errdetails.BadRequest_FieldViolation{
+				{Field: "level", Description: "must be one of debug, info, warn and error"},
+			})
+		}
+		// 调高等级之后这条日志可能不会输出，所以在两个等级中选较高的一个
+		slog.Log(ctx, max(previous, s.level.Level()), "log level changed", "previous", previous, "level", s.level.Level())
+	}
+	return &pb.SetLogLevelResponse{Previous: previous.String(), Level: s.level.Level().String()}, nil
+}
+
+func (s *Server) GetConfig(ctx context.Context, _ *pb.GetConfigRequest) (*pb.GetConfigResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	if s.cfg == nil {
+		return nil, notConfigured("config")
+	}
+	data, err := json.MarshalIndent(s.cfg.Redacted(), "", "  ")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can not encode config: %v", err)
+	}
+	return &pb.GetConfigResponse{Json: string(data)}, nil
+}
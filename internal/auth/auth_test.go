@@ -0,0 +1,233 @@
+package auth_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+var testSecret = []byte("test-secret")
+
+func TestJWT(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	valid, err := auth.SignJWT(testSecret, &auth.Claims{Subject: "alice", Roles: []string{"buyer"}, ExpiresAt: now.Add(time.Hour).Unix()})
+	require.Nil(t, err)
+	expired, err := auth.SignJWT(testSecret, &auth.Claims{Subject: "alice", ExpiresAt: now.Add(-time.Minute).Unix()})
+	require.Nil(t, err)
+	notYet, err := auth.SignJWT(testSecret, &auth.Claims{Subject: "alice", NotBefore: now.Add(time.Hour).Unix()})
+	require.Nil(t, err)
+	otherSecret, err := auth.SignJWT([]byte("other"), &auth.Claims{Subject: "alice"})
+	require.Nil(t, err)
+
+	testCases := []struct {
+		Name  string
+		Token string
+		Valid bool
+	}{
+		{"valid", valid, true},
+		{"expired", expired, false},
+		{"not-before", notYet, false},
+		{"wrong-secret", otherSecret, false},
+		{"alg-none", "eyJhbGciOiJub25lIn0.eyJzdWIiOiJhbGljZSJ9.", false},
+		{"malformed", "not-a-jwt", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			claims, err := auth.ParseJWT(testSecret, tc.Token, now)
+			if tc.Valid {
+				require.Nil(t, err)
+				require.Equal(t, "alice", claims.Subject)
+				require.Equal(t, []string{"buyer"}, claims.Roles)
+			} else {
+				require.ErrorIs(t, err, auth.ErrInvalidToken)
+			}
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := auth.Policy{
+		"/pb.CellphoneService/CreateCellphone": {"admin"},
+		"/pb.AdminService/*":                   {"admin"},
+	}
+	admin := &auth.Principal{Subject: "root", Roles: []string{"admin"}}
+	buyer := &auth.Principal{Subject: "bob", Roles: []string{"buyer"}}
+
+	require.True(t, policy.Allowed("/pb.CellphoneService/CreateCellphone", admin))
+	require.False(t, policy.Allowed("/pb.CellphoneService/CreateCellphone", buyer))
+	require.True(t, policy.Allowed("/pb.AdminService/GetStats", admin))
+	require.False(t, policy.Allowed("/pb.AdminService/GetStats", buyer))
+	// 没有出现在策略中的方法只需要通过认证
+	require.True(t, policy.Allowed("/pb.CellphoneService/SearchCellphone", buyer))
+}
+
+func TestLoadStaticKeys(t *testing.T) {
+	t.Parallel()
+
+	filename := filepath.Join(t.TempDir(), "keys.json")
+	require.Nil(t, os.WriteFile(filename, []byte(`[{"key": "k1", "subject": "admin-key", "roles": ["admin"]}]`), 0600))
+
+	keys, err := auth.LoadStaticKeys(filename)
+	require.Nil(t, err)
+	p, err := keys.Authenticate(context.Background(), "k1")
+	require.Nil(t, err)
+	require.Equal(t, "admin-key", p.Subject)
+	require.True(t, p.HasRole("admin"))
+
+	_, err = keys.Authenticate(context.Background(), "k2")
+	require.ErrorIs(t, err, auth.ErrInvalidToken)
+
+	_, err = auth.LoadStaticKeys(filepath.Join(t.TempDir(), "not-exist.json"))
+	require.NotNil(t, err)
+}
+
+// 测试中运行带有认证拦截器的cellphone服务
+func runTestAuthServer(t *testing.T) (*grpc.Server, net.Listener) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口监听
+	require.Nil(t, err)
+
+	authn := auth.Chain{
+		auth.NewStaticKeys([]auth.APIKey{
+			{Key: "admin-key", Subject: "admin", Roles: []string{"admin"}},
+			{Key: "buyer-key", Subject: "buyer", Roles: []string{"buyer"}},
+		}),
+		auth.NewJWTAuthenticator(testSecret),
+	}
+	opts := auth.Options{}
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(auth.UnaryServerInterceptor(authn, auth.DefaultPolicy(), opts)),
+		grpc.StreamInterceptor(auth.StreamServerInterceptor(authn, auth.DefaultPolicy(), opts)),
+	)
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer())
+	go server.Serve(listener)
+
+	return server, listener
+}
+
+func makeTestClient(t *testing.T, addr, token string) (pb.CellphoneServiceClient, *grpc.ClientConn) {
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(auth.NewTokenCredentials(token)))
+	}
+	conn, err := grpc.Dial(addr, opts...)
+	require.Nil(t, err)
+	return pb.NewCellphoneServiceClient(conn), conn
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	t.Parallel()
+
+	server, listener := runTestAuthServer(t)
+	defer server.GracefulStop()
+
+	buyerJWT, err := auth.SignJWT(testSecret, &auth.Claims{Subject: "carol", Roles: []string{"buyer"}})
+	require.Nil(t, err)
+	adminJWT, err := auth.SignJWT(testSecret, &auth.Claims{Subject: "dave", Roles: []string{"admin"}})
+	require.Nil(t, err)
+
+	testCases := []struct {
+		Name       string
+		Token      string
+		CreateCode codes.Code
+		BuyCode    codes.Code
+	}{
+		{"no-token", "", codes.Unauthenticated, codes.Unauthenticated},
+		{"invalid-token", "who-am-i", codes.Unauthenticated, codes.Unauthenticated},
+		{"admin-key", "admin-key", codes.OK, codes.OK},
+		{"buyer-key", "buyer-key", codes.PermissionDenied, codes.OK},
+		{"buyer-jwt", buyerJWT, codes.PermissionDenied, codes.OK},
+		{"admin-jwt", adminJWT, codes.OK, codes.OK},
+	}
+
+	// 先用admin创建一台手机，用于测试购买
+	adminClient, adminConn := makeTestClient(t, listener.Addr().String(), "admin-key")
+	defer adminConn.Close()
+	created, err := adminClient.CreateCellphone(context.Background(), &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			client, conn := makeTestClient(t, listener.Addr().String(), tc.Token)
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			_, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+			require.Equal(t, tc.CreateCode, status.Code(err))
+
+			stream, err := client.BuyCellphone(ctx)
+			require.Nil(t, err)
+			// 服务端可能已经关闭了流，此时Send返回io.EOF，具体的错误要通过Recv获得
+			if err := stream.Send(&pb.BuyCellphoneRequest{Id: created.Id, Price: 1999}); err != nil {
+				require.Equal(t, io.EOF, err)
+			}
+			_, err = stream.Recv()
+			require.Equal(t, tc.BuyCode, status.Code(err))
+		})
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	t.Parallel()
+
+	authn := auth.NewStaticKeys([]auth.APIKey{
+		{Key: "admin-key", Subject: "alice", Roles: []string{"admin"}},
+		{Key: "buyer-key", Subject: "bob", Roles: []string{"buyer"}},
+	})
+	handler := auth.HTTPHandler(authn, []string{"admin"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := auth.FromContext(r.Context())
+		require.True(t, ok)
+		io.WriteString(w, p.Subject)
+	}))
+
+	testCases := []struct {
+		Name          string
+		Authorization string
+		Code          int
+	}{
+		{Name: "no token", Authorization: "", Code: http.StatusUnauthorized},
+		{Name: "not bearer", Authorization: "Basic admin-key", Code: http.StatusUnauthorized},
+		{Name: "invalid token", Authorization: "Bearer wrong-key", Code: http.StatusUnauthorized},
+		{Name: "missing role", Authorization: "Bearer buyer-key", Code: http.StatusForbidden},
+		{Name: "admin", Authorization: "Bearer admin-key", Code: http.StatusOK},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			req := httptest.NewRequest(http.MethodGet, "/webhooks", nil)
+			if tc.Authorization != "" {
+				req.Header.Set("Authorization", tc.Authorization)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			require.Equal(t, tc.Code, rec.Code)
+			if tc.Code == http.StatusOK {
+				require.Equal(t, "alice", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+)
+
+// 根据token认证调用方
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+// 依次尝试多个Authenticator，只要有一个认证成功即可
+type Chain []Authenticator
+
+func (c Chain) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	lastErr := ErrInvalidToken
+	for _, a := range c {
+		p, err := a.Authenticate(ctx, token)
+		if err == nil {
+			return p, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// api keys文件中的一条记录
+type APIKey struct {
+	Key     string   `json:"key"`
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+}
+
+// 使用静态api key进行认证
+type StaticKeys struct {
+	keys []APIKey
+}
+
+func NewStaticKeys(keys []APIKey) *StaticKeys {
+	return &StaticKeys{keys: keys}
+}
+
+// 从json文件中加载api keys，文件内容为APIKey数组
+func LoadStaticKeys(filename string) (*StaticKeys, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can not read api keys file %s: %w", filename, err)
+	}
+	var keys []APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("can not parse api keys file %s: %w", filename, err)
+	}
+	for i, k := range keys {
+		if k.Key == "" {
+			return nil, fmt.Errorf("api key #%d in %s is empty", i, filename)
+		}
+	}
+	return NewStaticKeys(keys), nil
+}
+
+func (s *StaticKeys) Authenticate(_ context.Context, token string) (*Principal, error) {
+	for _, k := range s.keys {
+		// 使用常数时间的比较，避免时序攻击
+		if subtle.ConstantTimeCompare([]byte(k.Key), []byte(token)) == 1 {
+			return &Principal{Subject: k.Subject, Roles: append([]string(nil), k.Roles...)}, nil
+		}
+	}
+	return nil, ErrInvalidToken
+}
@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// 客户端使用的凭证，在每次调用时携带"authorization: Bearer <token>"
+type TokenCredentials struct {
+	token string
+}
+
+var _ credentials.PerRPCCredentials = (*TokenCredentials)(nil)
+
+func NewTokenCredentials(token string) *TokenCredentials {
+	return &TokenCredentials{token: token}
+}
+
+func (t *TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{AuthorizationMetadataKey: "Bearer " + t.token}, nil
+}
+
+// 示例中使用的是insecure连接，所以不要求安全的传输通道
+// 生产环境中应该配合TLS使用，避免token泄漏
+func (t *TokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
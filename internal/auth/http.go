@@ -0,0 +1,32 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// 保护管理端口上的HTTP接口，请求需要携带"Authorization: Bearer <token>"，
+// 并且调用方拥有roles中的任意一个角色，通过后可以用FromContext取出principal
+func HTTPHandler(authn Authenticator, roles []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "bearer") || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "authorization must be a bearer token", http.StatusUnauthorized)
+			return
+		}
+		principal, err := authn.Authenticate(r.Context(), token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "authentication failed: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+		for _, role := range roles {
+			if principal.HasRole(role) {
+				next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), principal)))
+				return
+			}
+		}
+		http.Error(w, principal.Subject+" is not allowed to access "+r.URL.Path, http.StatusForbidden)
+	})
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 携带token的metadata key
+const AuthorizationMetadataKey = "authorization"
+
+// 认证和鉴权拦截器的选项
+type Options struct {
+	// 不需要认证就可以访问的方法
+	PublicMethods []string
+}
+
+type checker struct {
+	authn  Authenticator
+	policy Policy
+	public map[string]struct{}
+}
+
+func newChecker(authn Authenticator, policy Policy, opts Options) *checker {
+	public := make(map[string]struct{}, len(opts.PublicMethods))
+	for _, m := range opts.PublicMethods {
+		public[m] = struct{}{}
+	}
+	return &checker{authn: authn, policy: policy, public: public}
+}
+
+// 认证调用方并检查是否有权限调用方法，通过后返回带有principal的context
+func (c *checker) check(ctx context.Context, fullMethod string) (context.Context, error) {
+	if _, ok := c.public[fullMethod]; ok {
+		return ctx, nil
+	}
+
+	token, err := bearerToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	principal, err := c.authn.Authenticate(ctx, token)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "authentication failed: %v", err)
+	}
+	if !c.policy.Allowed(fullMethod, principal) {
+		return nil, status.Errorf(codes.PermissionDenied, "%s is not allowed to call %s", principal.Subject, fullMethod)
+	}
+	return NewContext(ctx, principal), nil
+}
+
+// 从metadata中取出"authorization: Bearer <token>"
+func bearerToken(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "missing metadata")
+	}
+	values := md.Get(AuthorizationMetadataKey)
+	if len(values) == 0 {
+		return "", status.Error(codes.Unauthenticated, "missing authorization token")
+	}
+	scheme, token, found := strings.Cut(values[0], " ")
+	if !found || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return "", status.Error(codes.Unauthenticated, "authorization must be a bearer token")
+	}
+	return token, nil
+}
+
+// unary rpc的认证鉴权拦截器
+func UnaryServerInterceptor(authn Authenticator, policy Policy, opts Options) grpc.UnaryServerInterceptor {
+	c := newChecker(authn, policy, opts)
+
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		ctx, err := c.check(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streaming rpc的认证鉴权拦截器
+func StreamServerInterceptor(authn Authenticator, policy Policy, opts Options) grpc.StreamServerInterceptor {
+	c := newChecker(authn, policy, opts)
+
+	return func(srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		ctx, err := c.check(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// 替换stream中的context，使handler能取到principal
+type authenticatedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedServerStream) Context() context.Context {
+	return s.ctx
+}
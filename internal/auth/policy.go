@@ -0,0 +1,53 @@
+package auth
+
+import "strings"
+
+// 每个方法允许访问的角色
+// key为方法全名，比如"/pb.CellphoneService/CreateCellphone"，
+// 也可以用"/pb.CellphoneService/*"表示服务中的所有方法
+// 没有出现在策略中的方法只要通过认证即可访问
+type Policy map[string][]string
+
+// AdminService中的所有方法
+const AdminServicePattern = "/pb.AdminService/*"
+
+// 默认的访问策略
+func DefaultPolicy() Policy {
+	return Policy{
+		"/pb.CellphoneService/CreateCellphone":       {"admin"},
+		"/pb.CellphoneService/UpdateCellphone":       {"admin"},
+		"/pb.CellphoneService/DeleteCellphone":       {"admin"},
+		"/pb.CellphoneService/BatchCreateCellphones": {"admin"},
+		"/pb.CellphoneService/UploadCellphoneCover":  {"admin"},
+		"/pb.CellphoneService/BuyCellphone":          {"buyer", "admin"},
+		"/pb.CellphoneService/ListOrders":            {"buyer", "admin"},
+		AdminServicePattern:                          {"admin"},
+	}
+}
+
+// 找出方法对应的角色，精确匹配优先
+func (p Policy) rolesFor(fullMethod string) ([]string, bool) {
+	if roles, ok := p[fullMethod]; ok {
+		return roles, true
+	}
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		if roles, ok := p[fullMethod[:i]+"/*"]; ok {
+			return roles, true
+		}
+	}
+	return nil, false
+}
+
+// principal是否可以调用该方法
+func (p Policy) Allowed(fullMethod string, principal *Principal) bool {
+	roles, ok := p.rolesFor(fullMethod)
+	if !ok || len(roles) == 0 {
+		return true
+	}
+	for _, role := range roles {
+		if principal.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package auth
+
+import "context"
+
+// 通过认证的调用方
+type Principal struct {
+	// 调用方的标识，比如api key的名字或者jwt中的sub
+	Subject string `json:"subject"`
+	// 调用方拥有的角色
+	Roles []string `json:"roles"`
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// 把principal放进context中
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// 从context中取出principal，没有通过认证时返回false
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
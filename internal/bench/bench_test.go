@@ -0,0 +1,191 @@
+package bench_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/bench"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	t.Parallel()
+
+	h := bench.NewHistogram(time.Minute, 3)
+	// 1ms到10s均匀分布
+	for i := 1; i <= 10000; i++ {
+		h.Record(time.Duration(i) * time.Millisecond)
+	}
+	require.Equal(t, int64(10000), h.Count())
+	require.Equal(t, time.Millisecond, h.Min())
+	require.Equal(t, 10*time.Second, h.Max())
+
+	testCases := []struct {
+		Name       string
+		Percentile float64
+		Want       time.Duration
+	}{
+		{Name: "p0", Percentile: 0, Want: time.Millisecond},
+		{Name: "p50", Percentile: 50, Want: 5 * time.Second},
+		{Name: "p90", Percentile: 90, Want: 9 * time.Second},
+		{Name: "p99.9", Percentile: 99.9, Want: 9990 * time.Millisecond},
+		{Name: "p100", Percentile: 100, Want: 10 * time.Second},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(tt *testing.T) {
+			got := h.Percentile(tc.Percentile)
+			// 3位有效数字，相对误差不超过0.1%
+			require.InDelta(tt, float64(tc.Want), float64(got), float64(tc.Want)/1000)
+			require.GreaterOrEqual(tt, got, tc.Want)
+		})
+	}
+
+	// 超过上限的值按照上限记录
+	h.Record(time.Hour)
+	require.Equal(t, time.Minute, h.Max())
+}
+
+func TestHistogramMergeDistribution(t *testing.T) {
+	t.Parallel()
+
+	a := bench.NewHistogram(time.Minute, 2)
+	b := bench.NewHistogram(time.Minute, 2)
+	a.RecordN(time.Millisecond, 99)
+	b.Record(time.Second)
+	a.Merge(b)
+	require.Equal(t, int64(100), a.Count())
+	require.Equal(t, time.Second, a.Max())
+	require.InDelta(t, float64(time.Millisecond), float64(a.Percentile(99)), float64(time.Millisecond)/100)
+
+	points := a.Distribution(5)
+	require.NotEmpty(t, points)
+	// 百分位和数值都是递增的，最后一个点为100%
+	for i := 1; i < len(points); i++ {
+		require.Greater(t, points[i].Percentile, points[i-1].Percentile)
+		require.GreaterOrEqual(t, points[i].ValueMicros, points[i-1].ValueMicros)
+	}
+	last := points[len(points)-1]
+	require.Equal(t, float64(100), last.Percentile)
+	require.Equal(t, int64(100), last.Count)
+	require.Equal(t, int64(time.Second/time.Microsecond), last.ValueMicros)
+
+	require.Nil(t, bench.NewHistogram(time.Minute, 2).Distribution(5))
+}
+
+func TestRunClosedLoop(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int64
+	ok := func(ctx context.Context) error {
+		calls.Add(1)
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	fail := func(ctx context.Context) error {
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+
+	report, err := bench.Run(context.Background(), bench.Config{
+		Ops: []bench.WeightedOp{
+			{Name: "ok", Weight: 3, Op: ok},
+			{Name: "fail", Weight: 1, Op: fail},
+			{Name: "never", Weight: 0, Op: fail},
+		},
+		Concurrency: 4,
+		Duration:    200 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.Len(t, report.Ops, 3)
+
+	okReport, failReport, neverReport := report.Ops[0], report.Ops[1], report.Ops[2]
+	require.Equal(t, calls.Load(), okReport.Requests)
+	require.Zero(t, okReport.Errors)
+	require.Equal(t, okReport.Requests, okReport.Latency.Count())
+	require.GreaterOrEqual(t, okReport.Latency.Min(), time.Millisecond)
+
+	require.Positive(t, failReport.Requests)
+	require.Equal(t, failReport.Requests, failReport.Errors)
+	require.Equal(t, map[codes.Code]int64{codes.Unavailable: failReport.Errors}, failReport.ErrorsByCode)
+
+	require.Zero(t, neverReport.Requests)
+	require.Equal(t, okReport.Requests+failReport.Requests, report.Total.Requests)
+	require.Equal(t, failReport.Errors, report.Total.Errors)
+	require.Positive(t, report.Throughput(report.Total))
+
+	// json中包含延迟分布
+	data, err := json.Marshal(report)
+	require.NoError(t, err)
+	var decoded struct {
+		Total struct {
+			Requests     int64            `json:"requests"`
+			ErrorsByCode map[string]int64 `json:"errors_by_code"`
+			Latency      struct {
+				Distribution []bench.Point `json:"distribution"`
+			} `json:"latency"`
+		} `json:"total"`
+	}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Equal(t, report.Total.Requests, decoded.Total.Requests)
+	require.Equal(t, failReport.Errors, decoded.Total.ErrorsByCode["Unavailable"])
+	require.NotEmpty(t, decoded.Total.Latency.Distribution)
+}
+
+func TestRunOpenLoop(t *testing.T) {
+	t.Parallel()
+
+	report, err := bench.Run(context.Background(), bench.Config{
+		Ops:         []bench.WeightedOp{{Name: "ok", Weight: 1, Op: func(ctx context.Context) error { return nil }}},
+		QPS:         200,
+		Concurrency: 4,
+		Duration:    500 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	// 按照固定的速率发起请求
+	require.InDelta(t, 100, report.Total.Requests, 15)
+}
+
+func TestRunOpenLoopQueueing(t *testing.T) {
+	t.Parallel()
+
+	// 每个请求耗时20ms，但是每10ms就要发起一个请求，排队的时间也算进延迟
+	report, err := bench.Run(context.Background(), bench.Config{
+		Ops: []bench.WeightedOp{{Name: "slow", Weight: 1, Op: func(ctx context.Context) error {
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}}},
+		QPS:         100,
+		Concurrency: 1,
+		Duration:    300 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	require.Greater(t, report.Total.Latency.Max(), 60*time.Millisecond)
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	op := []bench.WeightedOp{{Name: "ok", Weight: 1, Op: func(ctx context.Context) error { return nil }}}
+	testCases := []struct {
+		Name   string
+		Config bench.Config
+	}{
+		{Name: "no-ops", Config: bench.Config{Concurrency: 1, Duration: time.Second}},
+		{Name: "zero-weight", Config: bench.Config{
+			Ops: []bench.WeightedOp{{Name: "ok", Op: op[0].Op}}, Concurrency: 1, Duration: time.Second}},
+		{Name: "no-concurrency", Config: bench.Config{Ops: op, Duration: time.Second}},
+		{Name: "no-duration", Config: bench.Config{Ops: op, Concurrency: 1}},
+		{Name: "negative-qps", Config: bench.Config{Ops: op, Concurrency: 1, Duration: time.Second, QPS: -1}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(tt *testing.T) {
+			_, err := bench.Run(context.Background(), tc.Config)
+			require.Error(tt, err)
+		})
+	}
+}
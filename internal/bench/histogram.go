@@ -0,0 +1,213 @@
+package bench
+
+// 参考HdrHistogram实现的延迟直方图
+// 数值按照2的幂分成多个bucket，每个bucket再线性地分成多个sub bucket，
+// 所以任何数值的相对误差都不超过10^-significantFigures，而占用的内存是固定的
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// 记录的最小单位
+const histogramUnit = time.Microsecond
+
+type Histogram struct {
+	highest int64
+
+	subBucketHalfCountMagnitude uint
+	subBucketCount              int64
+	subBucketHalfCount          int64
+	subBucketMask               int64
+
+	counts []int64
+	total  int64
+	min    int64
+	max    int64
+	sum    float64
+}
+
+// highest为可以准确记录的最大值，超过它的数值按照highest记录
+// significantFigures为有效数字的位数，范围为1到5
+func NewHistogram(highest time.Duration, significantFigures int) *Histogram {
+	significantFigures = max(1, min(5, significantFigures))
+	h := &Histogram{
+		highest: max(2, int64(highest/histogramUnit)),
+		min:     math.MaxInt64,
+	}
+
+	// 需要用单位精度表示的最大值
+	largestSingleUnit := 2 * int64(math.Pow10(significantFigures))
+	subBucketCountMagnitude := uint(math.Ceil(math.Log2(float64(largestSingleUnit))))
+	h.subBucketHalfCountMagnitude = subBucketCountMagnitude - 1
+	h.subBucketCount = 1 << subBucketCountMagnitude
+	h.subBucketHalfCount = h.subBucketCount / 2
+	h.subBucketMask = h.subBucketCount - 1
+
+	// 第一个bucket可以表示[0, subBucketCount)，之后每个bucket的范围翻倍
+	buckets := 1
+	for smallestUntrackable := h.subBucketCount; smallestUntrackable <= h.highest; smallestUntrackable <<= 1 {
+		buckets++
+	}
+	h.counts = make([]int64, (buckets+1)*int(h.subBucketHalfCount))
+	return h
+}
+
+// 记录一个数值，负数按照0记录
+func (h *Histogram) Record(d time.Duration) {
+	h.RecordN(d, 1)
+}
+
+// 记录n个相同的数值
+func (h *Histogram) RecordN(d time.Duration, n int64) {
+	if n <= 0 {
+		return
+	}
+	v := max(0, min(h.highest, int64(d/histogramUnit)))
+	h.counts[h.countsIndex(v)] += n
+	h.total += n
+	h.sum += float64(v) * float64(n)
+	h.min = min(h.min, v)
+	h.max = max(h.max, v)
+}
+
+// 把other中的数值合并进来，两个直方图的参数必须相同
+func (h *Histogram) Merge(other *Histogram) {
+	if other.total == 0 {
+		return
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.total += other.total
+	h.sum += other.sum
+	h.min = min(h.min, other.min)
+	h.max = max(h.max, other.max)
+}
+
+func (h *Histogram) Count() int64 {
+	return h.total
+}
+
+func (h *Histogram) Min() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.min) * histogramUnit
+}
+
+func (h *Histogram) Max() time.Duration {
+	return time.Duration(h.max) * histogramUnit
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return time.Duration(h.sum/float64(h.total)) * histogramUnit
+}
+
+// 返回不小于percentile%的数值的最小值，percentile的范围为0到100
+// 返回值为所在sub bucket能表示的最大值，不会超过记录过的最大值
+func (h *Histogram) Percentile(percentile float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	v, _ := h.valueAtPercentile(percentile)
+	return time.Duration(v) * histogramUnit
+}
+
+// 返回数值和小于等于它的数量
+func (h *Histogram) valueAtPercentile(percentile float64) (int64, int64) {
+	percentile = max(0, min(100, percentile))
+	target := max(1, int64(math.Ceil(percentile/100*float64(h.total))))
+	var cumulative int64
+	for i, c := range h.counts {
+		cumulative += c
+		if cumulative >= target {
+			return min(h.max, h.highestEquivalentValue(h.valueFromIndex(i))), cumulative
+		}
+	}
+	return h.max, h.total
+}
+
+// 直方图分布中的一个点
+type Point struct {
+	// 百分位，范围为0到100
+	Percentile float64 `json:"percentile"`
+	// 该百分位上的数值，单位为微秒
+	ValueMicros int64 `json:"value_us"`
+	// 小于等于该数值的数量
+	Count int64 `json:"count"`
+}
+
+// 和HdrHistogram的percentile distribution输出一样，百分位越接近100取的点越密：
+// 每当剩余的比例减半，就再取ticksPerHalfDistance个点，最后一个点为100%
+func (h *Histogram) Distribution(ticksPerHalfDistance int) []Point {
+	if h.total == 0 {
+		return nil
+	}
+	ticksPerHalfDistance = max(1, ticksPerHalfDistance)
+	var points []Point
+	add := func(percentile float64) {
+		v, count := h.valueAtPercentile(percentile)
+		points = append(points, Point{Percentile: percentile, ValueMicros: v, Count: count})
+	}
+
+	for half := 0; ; half++ {
+		// 这一段的范围为[100-100/2^half, 100-100/2^(half+1))
+		low := 100 - 100/math.Pow(2, float64(half))
+		high := 100 - 100/math.Pow(2, float64(half+1))
+		step := (high - low) / float64(ticksPerHalfDistance)
+		for i := 0; i < ticksPerHalfDistance; i++ {
+			add(low + float64(i)*step)
+			if points[len(points)-1].Count == h.total {
+				// 剩下的百分位对应的都是最大值
+				points[len(points)-1].Percentile = 100
+				return points
+			}
+		}
+		// 精度已经超过数量能区分的范围
+		if 100/math.Pow(2, float64(half+1)) < 100/float64(h.total) {
+			break
+		}
+	}
+	add(100)
+	return points
+}
+
+func (h *Histogram) bucketIndex(v int64) int {
+	// v的最高位决定在哪个bucket，小于subBucketCount的都在第0个bucket
+	pow2Ceiling := 64 - bits.LeadingZeros64(uint64(v|h.subBucketMask))
+	return pow2Ceiling - int(h.subBucketHalfCountMagnitude) - 1
+}
+
+func (h *Histogram) countsIndex(v int64) int {
+	bucket := h.bucketIndex(v)
+	subBucket := v >> uint(bucket)
+	// 除了第0个bucket，每个bucket的前半部分都和上一个bucket重叠，只使用后半部分
+	return (bucket+1)<<h.subBucketHalfCountMagnitude + int(subBucket-h.subBucketHalfCount)
+}
+
+func (h *Histogram) valueFromIndex(index int) int64 {
+	bucket := index>>h.subBucketHalfCountMagnitude - 1
+	subBucket := int64(index)&(h.subBucketHalfCount-1) + h.subBucketHalfCount
+	if bucket < 0 {
+		subBucket -= h.subBucketHalfCount
+		bucket = 0
+	}
+	return subBucket << uint(bucket)
+}
+
+// 和v在同一个sub bucket中的最大值
+func (h *Histogram) highestEquivalentValue(v int64) int64 {
+	bucket := h.bucketIndex(v)
+	subBucket := v >> uint(bucket)
+	size := int64(1) << uint(bucket)
+	if subBucket >= h.subBucketCount {
+		size <<= 1
+	}
+	lowest := subBucket << uint(bucket)
+	return lowest + size - 1
+}
@@ -0,0 +1,78 @@
+package bench
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 延迟分布中每当剩余的比例减半时取的点数，和HdrHistogram的默认值相同
+const distributionTicksPerHalfDistance = 5
+
+// json中的延迟统计，单位为微秒
+type latencyJSON struct {
+	MinMicros    int64   `json:"min_us"`
+	MeanMicros   int64   `json:"mean_us"`
+	MaxMicros    int64   `json:"max_us"`
+	P50Micros    int64   `json:"p50_us"`
+	P90Micros    int64   `json:"p90_us"`
+	P99Micros    int64   `json:"p99_us"`
+	P999Micros   int64   `json:"p999_us"`
+	Distribution []Point `json:"distribution"`
+}
+
+type opReportJSON struct {
+	Name         string           `json:"name"`
+	Requests     int64            `json:"requests"`
+	Errors       int64            `json:"errors"`
+	Throughput   float64          `json:"throughput"`
+	ErrorsByCode map[string]int64 `json:"errors_by_code"`
+	Latency      latencyJSON      `json:"latency"`
+}
+
+type reportJSON struct {
+	ElapsedSeconds float64        `json:"elapsed_seconds"`
+	Total          opReportJSON   `json:"total"`
+	Ops            []opReportJSON `json:"ops"`
+}
+
+func micros(d time.Duration) int64 {
+	return int64(d / time.Microsecond)
+}
+
+func (r *Report) opJSON(o *OpReport) opReportJSON {
+	errorsByCode := make(map[string]int64, len(o.ErrorsByCode))
+	for code, n := range o.ErrorsByCode {
+		errorsByCode[code.String()] = n
+	}
+	h := o.Latency
+	return opReportJSON{
+		Name:         o.Name,
+		Requests:     o.Requests,
+		Errors:       o.Errors,
+		Throughput:   r.Throughput(o),
+		ErrorsByCode: errorsByCode,
+		Latency: latencyJSON{
+			MinMicros:    micros(h.Min()),
+			MeanMicros:   micros(h.Mean()),
+			MaxMicros:    micros(h.Max()),
+			P50Micros:    micros(h.Percentile(50)),
+			P90Micros:    micros(h.Percentile(90)),
+			P99Micros:    micros(h.Percentile(99)),
+			P999Micros:   micros(h.Percentile(99.9)),
+			Distribution: h.Distribution(distributionTicksPerHalfDistance),
+		},
+	}
+}
+
+// 包括每个操作的延迟分布
+func (r *Report) MarshalJSON() ([]byte, error) {
+	res := reportJSON{
+		ElapsedSeconds: r.Elapsed.Seconds(),
+		Total:          r.opJSON(r.Total),
+		Ops:            make([]opReportJSON, 0, len(r.Ops)),
+	}
+	for _, o := range r.Ops {
+		res.Ops = append(res.Ops, r.opJSON(o))
+	}
+	return json.Marshal(res)
+}
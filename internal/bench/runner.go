@@ -0,0 +1,271 @@
+package bench
+
+// 按照权重混合调用多个操作，统计吞吐量、错误和延迟
+// 指定了QPS时为开环模式：按照固定的速率发起请求，延迟从计划发起的时间开始计算，
+// 服务端变慢导致请求排队的时间也会算进延迟（避免coordinated omission）
+// 否则为闭环模式：Concurrency个worker不断地发起请求
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// 延迟直方图可以准确记录的最大值
+	DefaultHighestLatency = time.Minute
+	// 延迟直方图的有效数字位数
+	DefaultSignificantFigures = 3
+)
+
+// 一个被压测的操作，返回的错误按照gRPC错误码分类
+type Op func(ctx context.Context) error
+
+type WeightedOp struct {
+	Name   string
+	Weight int
+	Op     Op
+}
+
+type Config struct {
+	Ops []WeightedOp
+	// 每秒发起的请求数量，0表示闭环模式
+	QPS float64
+	// 同时进行的请求数量上限
+	Concurrency int
+	// 压测持续的时间
+	Duration time.Duration
+	// 单个请求的超时时间，0表示不限制
+	Timeout time.Duration
+	// 随机选择操作时使用的种子
+	Seed int64
+}
+
+// 一个操作的统计结果
+type OpReport struct {
+	Name     string
+	Requests int64
+	Errors   int64
+	// 错误码 -> 数量
+	ErrorsByCode map[codes.Code]int64
+	Latency      *Histogram
+}
+
+type Report struct {
+	// 实际持续的时间，包括等待最后一批请求完成的时间
+	Elapsed time.Duration
+	// 按照Config.Ops中的顺序
+	Ops []*OpReport
+	// 所有操作合并之后的结果
+	Total *OpReport
+}
+
+// 每秒完成的请求数量
+func (r *Report) Throughput(op *OpReport) float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(op.Requests) / r.Elapsed.Seconds()
+}
+
+// 按照错误码排序的错误
+func (o *OpReport) SortedCodes() []codes.Code {
+	res := make([]codes.Code, 0, len(o.ErrorsByCode))
+	for code := range o.ErrorsByCode {
+		res = append(res, code)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res
+}
+
+func newOpReport(name string) *OpReport {
+	return &OpReport{
+		Name:         name,
+		ErrorsByCode: make(map[codes.Code]int64),
+		Latency:      NewHistogram(DefaultHighestLatency, DefaultSignificantFigures),
+	}
+}
+
+func (o *OpReport) merge(other *OpReport) {
+	o.Requests += other.Requests
+	o.Errors += other.Errors
+	for code, n := range other.ErrorsByCode {
+		o.ErrorsByCode[code] += n
+	}
+	o.Latency.Merge(other.Latency)
+}
+
+func (c *Config) validate() error {
+	if len(c.Ops) == 0 {
+		return errors.New("no operation to run")
+	}
+	total := 0
+	for _, op := range c.Ops {
+		if op.Weight < 0 {
+			return fmt.Errorf("weight of %s must not be negative", op.Name)
+		}
+		total += op.Weight
+	}
+	if total == 0 {
+		return errors.New("at least one operation must have a positive weight")
+	}
+	if c.Concurrency <= 0 {
+		return errors.New("concurrency must be positive")
+	}
+	if c.QPS < 0 {
+		return errors.New("qps must not be negative")
+	}
+	if c.Duration <= 0 {
+		return errors.New("duration must be positive")
+	}
+	return nil
+}
+
+// 运行压测，直到Duration结束或者ctx被取消
+// 结束时不再发起新的请求，并等待正在进行的请求完成
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	// 每个worker单独统计，最后再合并，避免加锁
+	workers := make([][]*OpReport, cfg.Concurrency)
+	for i := range workers {
+		workers[i] = make([]*OpReport, len(cfg.Ops))
+		for j, op := range cfg.Ops {
+			workers[i][j] = newOpReport(op.Name)
+		}
+	}
+
+	start := time.Now()
+	runCtx, cancel := context.WithDeadline(ctx, start.Add(cfg.Duration))
+	defer cancel()
+
+	// 开环模式下传递请求计划发起的时间
+	var schedule chan time.Time
+	if cfg.QPS > 0 {
+		schedule = make(chan time.Time, cfg.Concurrency)
+		go pace(runCtx, start, cfg.QPS, schedule)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			w := &worker{
+				cfg:     &cfg,
+				reports: workers[i],
+				rand:    rand.New(rand.NewSource(cfg.Seed + int64(i))),
+			}
+			w.run(ctx, runCtx, schedule)
+		}(i)
+	}
+	wg.Wait()
+
+	report := &Report{Elapsed: time.Since(start), Total: newOpReport("total")}
+	for j, op := range cfg.Ops {
+		merged := newOpReport(op.Name)
+		for i := range workers {
+			merged.merge(workers[i][j])
+		}
+		report.Ops = append(report.Ops, merged)
+		report.Total.merge(merged)
+	}
+	return report, ctx.Err()
+}
+
+// 按照qps发送计划时间，ctx结束时关闭schedule
+// worker都在忙时会阻塞，之后的计划时间不变，所以排队的时间会算进延迟
+func pace(ctx context.Context, start time.Time, qps float64, schedule chan<- time.Time) {
+	defer close(schedule)
+	interval := time.Duration(float64(time.Second) / qps)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for i := int64(0); ; i++ {
+		next := start.Add(time.Duration(i) * interval)
+		timer.Reset(time.Until(next))
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case schedule <- next:
+		}
+	}
+}
+
+type worker struct {
+	cfg     *Config
+	reports []*OpReport
+	rand    *rand.Rand
+}
+
+// parent被取消时正在进行的请求也会被取消，runCtx结束时只是不再发起新的请求
+func (w *worker) run(parent, runCtx context.Context, schedule <-chan time.Time) {
+	for {
+		var scheduled time.Time
+		if schedule != nil {
+			var ok bool
+			if scheduled, ok = <-schedule; !ok {
+				return
+			}
+		} else {
+			if runCtx.Err() != nil {
+				return
+			}
+			scheduled = time.Now()
+		}
+		w.call(parent, scheduled)
+	}
+}
+
+func (w *worker) call(parent context.Context, scheduled time.Time) {
+	i := w.pick()
+	ctx := parent
+	if w.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, w.cfg.Timeout)
+		defer cancel()
+	}
+	err := w.cfg.Ops[i].Op(ctx)
+	latency := time.Since(scheduled)
+	// 中断压测导致的失败不计入结果
+	if parent.Err() != nil {
+		return
+	}
+
+	report := w.reports[i]
+	report.Requests++
+	report.Latency.Record(latency)
+	if err != nil {
+		report.Errors++
+		report.ErrorsByCode[status.Code(err)]++
+	}
+}
+
+// 按照权重随机选择一个操作
+func (w *worker) pick() int {
+	total := 0
+	for _, op := range w.cfg.Ops {
+		total += op.Weight
+	}
+	n := w.rand.Intn(total)
+	for i, op := range w.cfg.Ops {
+		if n < op.Weight {
+			return i
+		}
+		n -= op.Weight
+	}
+	return len(w.cfg.Ops) - 1
+}
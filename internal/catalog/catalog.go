@@ -0,0 +1,202 @@
+package catalog
+
+// 手机信息的导入导出格式
+// ndjson：每行一台手机，内容为protojson
+// csv：第一行为表头，嵌套的字段展开成cpu.cores这样的列
+// protobuf：每台手机为一条带长度前缀的protobuf消息
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+type Format string
+
+const (
+	FormatNDJSON   Format = "ndjson"
+	FormatCSV      Format = "csv"
+	FormatProtobuf Format = "protobuf"
+)
+
+// ndjson中一行的最大长度
+const maxLineBytes = 1 << 20
+
+// 解析格式的名字
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatNDJSON, FormatCSV, FormatProtobuf:
+		return f, nil
+	case "jsonl":
+		return FormatNDJSON, nil
+	case "pb", "binpb":
+		return FormatProtobuf, nil
+	default:
+		return "", fmt.Errorf("unknown catalog format %q", name)
+	}
+}
+
+// 根据文件的扩展名判断格式
+func FormatFromFilename(filename string) (Format, error) {
+	ext := strings.TrimPrefix(filepath.Ext(filename), ".")
+	if ext == "" {
+		return "", fmt.Errorf("can not detect catalog format of %q", filename)
+	}
+	return ParseFormat(ext)
+}
+
+// 按顺序读取手机信息，读完之后返回io.EOF
+type Reader interface {
+	Read() (*pb.Cellphone, error)
+}
+
+// 按顺序写入手机信息，写完之后需要调用Flush
+type Writer interface {
+	Write(*pb.Cellphone) error
+	Flush() error
+}
+
+func NewReader(r io.Reader, f Format) (Reader, error) {
+	switch f {
+	case FormatNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+		return &ndjsonReader{scanner: scanner}, nil
+	case FormatCSV:
+		return newCSVReader(csv.NewReader(r)), nil
+	case FormatProtobuf:
+		return &protobufReader{r: bufio.NewReader(r)}, nil
+	default:
+		return nil, fmt.Errorf("unknown catalog format %q", f)
+	}
+}
+
+func NewWriter(w io.Writer, f Format) (Writer, error) {
+	switch f {
+	case FormatNDJSON:
+		return &ndjsonWriter{w: bufio.NewWriter(w)}, nil
+	case FormatCSV:
+		return newCSVWriter(csv.NewWriter(w)), nil
+	case FormatProtobuf:
+		return &protobufWriter{w: bufio.NewWriter(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown catalog format %q", f)
+	}
+}
+
+// 读取所有的手机信息
+func ReadAll(r Reader) ([]*pb.Cellphone, error) {
+	var cellphones []*pb.Cellphone
+	for {
+		cellphone, err := r.Read()
+		if err == io.EOF {
+			return cellphones, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		cellphones = append(cellphones, cellphone)
+	}
+}
+
+// 写入所有的手机信息并Flush
+func WriteAll(w Writer, cellphones []*pb.Cellphone) error {
+	for _, cellphone := range cellphones {
+		if err := w.Write(cellphone); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonReader) Read() (*pb.Cellphone, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := bytes.TrimSpace(r.scanner.Bytes())
+		// 跳过空行
+		if len(line) == 0 {
+			continue
+		}
+		var cellphone pb.Cellphone
+		if err := protojson.Unmarshal(line, &cellphone); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return &cellphone, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+type ndjsonWriter struct {
+	w *bufio.Writer
+}
+
+// 和网关的json格式保持一致
+var marshalOptions = protojson.MarshalOptions{UseProtoNames: true}
+
+func (w *ndjsonWriter) Write(cellphone *pb.Cellphone) error {
+	data, err := marshalOptions.Marshal(cellphone)
+	if err != nil {
+		return err
+	}
+	// protojson的输出不稳定，可能包含多余的空格，压缩成一行
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err = w.w.Write(buf.Bytes())
+	return err
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.w.Flush()
+}
+
+type protobufReader struct {
+	r     *bufio.Reader
+	index int
+}
+
+func (r *protobufReader) Read() (*pb.Cellphone, error) {
+	var cellphone pb.Cellphone
+	err := protodelim.UnmarshalFrom(r.r, &cellphone)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, fmt.Errorf("message %d: %w", r.index, err)
+	}
+	r.index++
+	return &cellphone, nil
+}
+
+type protobufWriter struct {
+	w *bufio.Writer
+}
+
+func (w *protobufWriter) Write(cellphone *pb.Cellphone) error {
+	_, err := protodelim.MarshalTo(w.w, cellphone)
+	return err
+}
+
+func (w *protobufWriter) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,142 @@
+package catalog_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/catalog"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	cellphones := []*pb.Cellphone{sample.NewCellphone(), sample.NewCellphone(), sample.NewCellphone()}
+	// 缺少嵌套字段的手机
+	cellphones = append(cellphones, &pb.Cellphone{Id: sample.NewCellphone().Id, Brand: "Brand, with \"quotes\""})
+
+	for _, format := range []catalog.Format{catalog.FormatNDJSON, catalog.FormatCSV, catalog.FormatProtobuf} {
+		format := format
+		t.Run(string(format), func(it *testing.T) {
+			it.Parallel()
+
+			var buf bytes.Buffer
+			w, err := catalog.NewWriter(&buf, format)
+			require.Nil(it, err)
+			require.Nil(it, catalog.WriteAll(w, cellphones))
+
+			r, err := catalog.NewReader(&buf, format)
+			require.Nil(it, err)
+			got, err := catalog.ReadAll(r)
+			require.Nil(it, err)
+			require.Len(it, got, len(cellphones))
+			for i := range cellphones {
+				require.True(it, proto.Equal(cellphones[i], got[i]), "%v\n%v", cellphones[i], got[i])
+			}
+		})
+	}
+}
+
+func TestCSV(t *testing.T) {
+	t.Parallel()
+
+	header := catalog.CSVHeader()
+	require.Equal(t, "id", header[0])
+	require.Contains(t, header, "cpu.cores")
+	require.Contains(t, header, "ram.unit")
+	require.Contains(t, header, "operating_system.version")
+	require.Contains(t, header, "created_at")
+
+	// 没有手机时也有表头
+	var buf bytes.Buffer
+	w, err := catalog.NewWriter(&buf, catalog.FormatCSV)
+	require.Nil(t, err)
+	require.Nil(t, w.Flush())
+	require.Equal(t, strings.Join(header, ",")+"\n", buf.String())
+
+	var testCases = []struct {
+		Name  string
+		Input string
+		Want  *pb.Cellphone
+		Err   string
+	}{
+		{
+			Name:  "partial-columns",
+			Input: "brand,ram.unit,ram.value,cpu.cores\nApple,UnitGB,8,6\n",
+			Want: &pb.Cellphone{
+				Brand: "Apple",
+				Ram:   &pb.RAM{Value: 8, Unit: pb.Unit_UnitGB},
+				Cpu:   &pb.CPU{Cores: 6},
+			},
+		},
+		{
+			Name:  "enum-number",
+			Input: "storage.storage_type\n2\n",
+			Want:  &pb.Cellphone{Storage: &pb.Storage{StorageType: pb.StorageType_HDD}},
+		},
+		{Name: "unknown-column", Input: "brand,color\nApple,red\n", Err: "unknown column"},
+		{Name: "bad-number", Input: "cpu.cores\nsix\n", Err: "line 2: column cpu.cores"},
+		{Name: "bad-enum", Input: "ram.unit\nUnitPB\n", Err: "unknown Unit value"},
+		{Name: "bad-timestamp", Input: "created_at\nyesterday\n", Err: "column created_at"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(it *testing.T) {
+			r, err := catalog.NewReader(strings.NewReader(tc.Input), catalog.FormatCSV)
+			require.Nil(it, err)
+			got, err := catalog.ReadAll(r)
+			if tc.Err != "" {
+				require.ErrorContains(it, err, tc.Err)
+				return
+			}
+			require.Nil(it, err)
+			require.Len(it, got, 1)
+			require.True(it, proto.Equal(tc.Want, got[0]), got[0].String())
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+
+	var testCases = []struct {
+		Name     string
+		Filename string
+		Format   catalog.Format
+		Err      bool
+	}{
+		{Name: "ndjson", Filename: "catalog.ndjson", Format: catalog.FormatNDJSON},
+		{Name: "jsonl", Filename: "dir/catalog.JSONL", Format: catalog.FormatNDJSON},
+		{Name: "csv", Filename: "catalog.csv", Format: catalog.FormatCSV},
+		{Name: "binpb", Filename: "catalog.binpb", Format: catalog.FormatProtobuf},
+		{Name: "no-ext", Filename: "catalog", Err: true},
+		{Name: "unknown", Filename: "catalog.xml", Err: true},
+	}
+
+	for _, tc := range testCases {
+		format, err := catalog.FormatFromFilename(tc.Filename)
+		if tc.Err {
+			require.Error(t, err, tc.Name)
+			continue
+		}
+		require.Nil(t, err, tc.Name)
+		require.Equal(t, tc.Format, format, tc.Name)
+	}
+
+	// 错误的ndjson和protobuf
+	r, err := catalog.NewReader(strings.NewReader("{}\n\n{\"brand\":1}\n"), catalog.FormatNDJSON)
+	require.Nil(t, err)
+	_, err = catalog.ReadAll(r)
+	require.ErrorContains(t, err, "line 3")
+
+	r, err = catalog.NewReader(bytes.NewReader([]byte{0x05, 0x0a}), catalog.FormatProtobuf)
+	require.Nil(t, err)
+	_, err = catalog.ReadAll(r)
+	require.Error(t, err)
+}
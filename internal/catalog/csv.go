@@ -0,0 +1,255 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// csv中的一列，对应Cellphone中的一个标量字段
+// 比如cpu.cores对应的path为[cpu, cores]
+type column struct {
+	name string
+	path []protoreflect.FieldDescriptor
+}
+
+var (
+	timestampName = (&timestamppb.Timestamp{}).ProtoReflect().Descriptor().FullName()
+	// 按照字段在proto中定义的顺序展开所有的列
+	columns = flatten(nil, (&pb.Cellphone{}).ProtoReflect().Descriptor())
+)
+
+// 把嵌套的message展开成列，Timestamp作为一列，格式为RFC3339
+func flatten(prefix []protoreflect.FieldDescriptor, md protoreflect.MessageDescriptor) []column {
+	var res []column
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := append(append([]protoreflect.FieldDescriptor(nil), prefix...), fd)
+		if fd.Kind() == protoreflect.MessageKind && fd.Message().FullName() != timestampName {
+			res = append(res, flatten(path, fd.Message())...)
+			continue
+		}
+		names := make([]string, len(path))
+		for j, p := range path {
+			names[j] = string(p.Name())
+		}
+		res = append(res, column{name: strings.Join(names, "."), path: path})
+	}
+	return res
+}
+
+// csv的表头
+func CSVHeader() []string {
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	return header
+}
+
+type csvWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func newCSVWriter(w *csv.Writer) *csvWriter {
+	return &csvWriter{w: w}
+}
+
+func (w *csvWriter) Write(cellphone *pb.Cellphone) error {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if err := w.w.Write(CSVHeader()); err != nil {
+			return err
+		}
+	}
+	record := make([]string, len(columns))
+	m := cellphone.ProtoReflect()
+	for i, c := range columns {
+		record[i] = formatValue(m, c.path)
+	}
+	return w.w.Write(record)
+}
+
+// 没有任何手机时也写出表头
+func (w *csvWriter) Flush() error {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if err := w.w.Write(CSVHeader()); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// 嵌套的message不存在时为空字符串
+func formatValue(m protoreflect.Message, path []protoreflect.FieldDescriptor) string {
+	for _, fd := range path[:len(path)-1] {
+		if !m.Has(fd) {
+			return ""
+		}
+		m = m.Get(fd).Message()
+	}
+	fd := path[len(path)-1]
+	v := m.Get(fd)
+	switch fd.Kind() {
+	case protoreflect.MessageKind:
+		if !m.Has(fd) {
+			return ""
+		}
+		ts := v.Message().Interface().(*timestamppb.Timestamp)
+		return ts.AsTime().Format(time.RFC3339Nano)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	case protoreflect.DoubleKind, protoreflect.FloatKind:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	default:
+		return v.String()
+	}
+}
+
+type csvReader struct {
+	r *csv.Reader
+	// 每一列对应的字段，为nil表示还没有读取表头
+	fields []*column
+	line   int
+}
+
+func newCSVReader(r *csv.Reader) *csvReader {
+	r.ReuseRecord = true
+	return &csvReader{r: r}
+}
+
+func (r *csvReader) readHeader() error {
+	header, err := r.r.Read()
+	if err != nil {
+		return err
+	}
+	r.line++
+	byName := make(map[string]*column, len(columns))
+	for i := range columns {
+		byName[columns[i].name] = &columns[i]
+	}
+	// 表头中的列可以是任意顺序，也可以只包含部分列
+	r.fields = make([]*column, len(header))
+	for i, name := range header {
+		c, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return fmt.Errorf("line %d: unknown column %q", r.line, name)
+		}
+		r.fields[i] = c
+	}
+	return nil
+}
+
+func (r *csvReader) Read() (*pb.Cellphone, error) {
+	if r.fields == nil {
+		if err := r.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+	record, err := r.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	r.line++
+
+	var cellphone pb.Cellphone
+	m := cellphone.ProtoReflect()
+	for i, value := range record {
+		// 空的单元格表示字段没有设置
+		if value == "" {
+			continue
+		}
+		if err := setValue(m, r.fields[i].path, value); err != nil {
+			return nil, fmt.Errorf("line %d: column %s: %w", r.line, r.fields[i].name, err)
+		}
+	}
+	return &cellphone, nil
+}
+
+func setValue(m protoreflect.Message, path []protoreflect.FieldDescriptor, value string) error {
+	for _, fd := range path[:len(path)-1] {
+		m = m.Mutable(fd).Message()
+	}
+	fd := path[len(path)-1]
+
+	var v protoreflect.Value
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v = protoreflect.ValueOfString(value)
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v = protoreflect.ValueOfBool(b)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return err
+		}
+		v = protoreflect.ValueOfInt32(int32(n))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v = protoreflect.ValueOfInt64(n)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return err
+		}
+		v = protoreflect.ValueOfUint32(uint32(n))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		v = protoreflect.ValueOfUint64(n)
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		v = protoreflect.ValueOfFloat64(f)
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return err
+		}
+		v = protoreflect.ValueOfFloat32(float32(f))
+	case protoreflect.EnumKind:
+		// 枚举可以是名字也可以是数字
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(value)); ev != nil {
+			v = protoreflect.ValueOfEnum(ev.Number())
+		} else if n, err := strconv.Atoi(value); err == nil && fd.Enum().Values().ByNumber(protoreflect.EnumNumber(n)) != nil {
+			v = protoreflect.ValueOfEnum(protoreflect.EnumNumber(n))
+		} else {
+			return fmt.Errorf("unknown %s value %q", fd.Enum().Name(), value)
+		}
+	case protoreflect.MessageKind:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return err
+		}
+		v = protoreflect.ValueOfMessage(timestamppb.New(t).ProtoReflect())
+	default:
+		return fmt.Errorf("unsupported field kind %s", fd.Kind())
+	}
+	m.Set(fd, v)
+	return nil
+}
@@ -0,0 +1,42 @@
+package compression
+
+// gRPC消息的压缩，gzip使用gRPC自带的实现，zstd使用klauspost/compress
+// 导入这个包就注册了两种压缩算法；服务端注册之后，会用请求使用的算法压缩响应
+// gRPC对每条消息单独压缩，只有一台手机的消息压缩之后反而会变大，封面数据块这样的大消息才能明显变小
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+const (
+	// 不压缩，可以用来在单次调用中取消连接上默认的压缩
+	Identity = encoding.Identity
+	Gzip     = gzip.Name
+	Zstd     = "zstd"
+)
+
+// 所有可以使用的压缩算法
+func Names() []string {
+	return []string{Identity, Gzip, Zstd}
+}
+
+// 检查压缩算法是否已经注册，空字符串表示不指定
+func Validate(name string) error {
+	if name == "" || name == Identity || encoding.GetCompressor(name) != nil {
+		return nil
+	}
+	return fmt.Errorf("unknown compressor %q, should be one of %s", name, strings.Join(Names(), ", "))
+}
+
+// 使用name压缩请求的调用选项，name为空时返回nil
+func CallOptions(name string) []grpc.CallOption {
+	if name == "" {
+		return nil
+	}
+	return []grpc.CallOption{grpc.UseCompressor(name)}
+}
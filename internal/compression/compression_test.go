@@ -0,0 +1,236 @@
+package compression_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/compression"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+	"github.com/ryanreadbooks/go-grpc-example/pkg/cellphoneclient"
+)
+
+// 运行保存了n台手机的服务端，返回监听的地址
+func runTestServer(tb testing.TB, n int) string {
+	cellphones := make([]*pb.Cellphone, n)
+	for i := range cellphones {
+		cellphones[i] = sample.NewCellphone()
+	}
+	saver := service.NewInMemoryCellphoneSaver()
+	require.Nil(tb, saver.SaveAll(context.Background(), cellphones))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口监听
+	require.Nil(tb, err)
+	server := grpc.NewServer()
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(
+		service.WithCoverPath(tb.TempDir()), service.WithCellphoneSaver(saver)))
+	go server.Serve(listener)
+	tb.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+// 统计客户端收到的消息的大小和响应使用的压缩算法
+type payloadStats struct {
+	mu               sync.Mutex
+	length           int
+	compressedLength int
+	wireLength       int
+	compression      string
+}
+
+func (s *payloadStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context { return ctx }
+
+func (s *payloadStats) HandleRPC(_ context.Context, rs stats.RPCStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch rs := rs.(type) {
+	case *stats.InHeader:
+		s.compression = rs.Compression
+	case *stats.InPayload:
+		s.length += rs.Length
+		s.compressedLength += rs.CompressedLength
+		s.wireLength += rs.WireLength
+	}
+}
+
+func (s *payloadStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+
+func (s *payloadStats) HandleConn(context.Context, stats.ConnStats) {}
+
+func (s *payloadStats) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.length, s.compressedLength, s.wireLength, s.compression = 0, 0, 0, ""
+}
+
+func newTestClient(tb testing.TB, addr string, s *payloadStats, opts ...cellphoneclient.Option) *cellphoneclient.Client {
+	opts = append(opts, cellphoneclient.WithDialOptions(grpc.WithStatsHandler(s)))
+	client, err := cellphoneclient.Dial(addr, opts...)
+	require.Nil(tb, err)
+	tb.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range append(compression.Names(), "") {
+		require.Nil(t, compression.Validate(name))
+	}
+	require.NotNil(t, compression.Validate("snappy"))
+
+	_, err := cellphoneclient.Dial("127.0.0.1:9527", cellphoneclient.WithCompressor("snappy"))
+	require.NotNil(t, err)
+}
+
+// 服务端用请求的压缩算法压缩响应
+func TestSearchCellphone(t *testing.T) {
+	t.Parallel()
+
+	const n = 100
+	addr := runTestServer(t, n)
+
+	testCases := []struct {
+		Name       string
+		Compressor string
+		// 响应头中的grpc-encoding
+		Encoding   string
+		Compressed bool
+	}{
+		{Name: "default", Compressor: "", Encoding: "", Compressed: false},
+		{Name: "identity", Compressor: compression.Identity, Encoding: "", Compressed: false},
+		{Name: "gzip", Compressor: compression.Gzip, Encoding: compression.Gzip, Compressed: true},
+		{Name: "zstd", Compressor: compression.Zstd, Encoding: compression.Zstd, Compressed: true},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &payloadStats{}
+			client := newTestClient(t, addr, s, cellphoneclient.WithCompressor(tc.Compressor))
+			cellphones, err := client.SearchAll(context.Background(), &pb.FilterCondition{})
+			require.Nil(t, err)
+			require.Len(t, cellphones, n)
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			require.Equal(t, tc.Encoding, s.compression)
+			// 每条消息单独压缩，一台手机的数据太少，压缩之后不一定变小
+			require.Equal(t, tc.Compressed, s.compressedLength != s.length)
+			// 每条消息有5字节的gRPC头
+			require.Equal(t, s.compressedLength+5*n, s.wireLength)
+		})
+	}
+}
+
+// 压缩后的封面上传和下载之后保持不变
+func TestCover(t *testing.T) {
+	t.Parallel()
+
+	addr := runTestServer(t, 0)
+	for _, name := range []string{compression.Gzip, compression.Zstd} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			s := &payloadStats{}
+			client := newTestClient(t, addr, s, cellphoneclient.WithCompressor(name))
+			id, err := client.Create(context.Background(), sample.NewCellphone())
+			require.Nil(t, err)
+
+			// 比一个block大的可压缩数据
+			data := append([]byte("\xff\xd8\xff"), bytes.Repeat([]byte(name), 10000)...)
+			_, err = client.UploadCoverWithType(context.Background(), id, ".jpeg", bytes.NewReader(data))
+			require.Nil(t, err)
+			s.reset()
+			var buf bytes.Buffer
+			_, err = client.DownloadCover(context.Background(), id, &buf)
+			require.Nil(t, err)
+			require.Equal(t, data, buf.Bytes())
+
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			require.Equal(t, name, s.compression)
+			require.Less(t, s.wireLength, s.length/2)
+		})
+	}
+}
+
+// 比较搜索结果流在不同压缩算法下的大小，payload-B/op为没有压缩的大小，wire-B/op为实际传输的大小
+//
+//	go test -bench SearchCellphone -run ^$ ./internal/compression
+func BenchmarkSearchCellphone(b *testing.B) {
+	for _, n := range []int{10, 1000} {
+		addr := runTestServer(b, n)
+		for _, name := range compression.Names() {
+			b.Run(name+"/"+strconv.Itoa(n), func(b *testing.B) {
+				s := &payloadStats{}
+				client := newTestClient(b, addr, s, cellphoneclient.WithCompressor(name))
+				s.reset()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					it := client.Search(context.Background(), &pb.FilterCondition{})
+					for it.Next() {
+					}
+					if err := it.Err(); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.StopTimer()
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				b.ReportMetric(float64(s.length)/float64(b.N), "payload-B/op")
+				b.ReportMetric(float64(s.wireLength)/float64(b.N), "wire-B/op")
+			})
+		}
+	}
+}
+
+// 单独比较压缩算法的速度和压缩率，数据为序列化之后的搜索结果
+func BenchmarkCompress(b *testing.B) {
+	var data []byte
+	for i := 0; i < 100; i++ {
+		cellphone, err := proto.Marshal(sample.NewCellphone())
+		require.Nil(b, err)
+		data = append(data, cellphone...)
+	}
+	for _, name := range []string{compression.Gzip, compression.Zstd} {
+		compressor := encoding.GetCompressor(name)
+		b.Run(name, func(b *testing.B) {
+			var compressed int
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				var buf bytes.Buffer
+				w, err := compressor.Compress(&buf)
+				if err != nil {
+					b.Fatal(err)
+				}
+				w.Write(data)
+				w.Close()
+				compressed = buf.Len()
+
+				r, err := compressor.Decompress(&buf)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := io.Copy(io.Discard, r); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(compressed)/float64(len(data)), "ratio")
+		})
+	}
+}
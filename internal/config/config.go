@@ -0,0 +1,245 @@
+package config
+
+// 服务端的配置，可以从json文件中加载
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// 日志相关的配置
+type LogConfig struct {
+	// 日志等级：debug, info, warn, error
+	Level string `json:"level"`
+	// 日志输出格式：text或者json
+	Format string `json:"format"`
+	// 是否在debug等级下打印请求和响应的内容
+	Payloads bool `json:"payloads"`
+	// 打印内容时需要隐藏的字段名，比如"price"或者"pb.BuyCellphoneRequest.price"
+	Redact []string `json:"redact"`
+}
+
+// 认证和鉴权相关的配置
+type AuthConfig struct {
+	// 是否开启认证
+	Enabled bool `json:"enabled"`
+	// 静态api keys文件的路径
+	APIKeysFile string `json:"api_keys_file"`
+	// 用来校验HS256 jwt签名的密钥
+	JWTSecret string `json:"jwt_secret"`
+	// 方法全名 -> 允许访问的角色，为空时使用默认策略
+	// 没有配置AdminService时，AdminService只有admin角色可以调用
+	Policy map[string][]string `json:"policy"`
+	// 不需要认证的方法
+	PublicMethods []string `json:"public_methods"`
+}
+
+// 管理端口相关的配置
+type AdminConfig struct {
+	// 没有开启认证时也提供AdminService、管理webhook的/webhooks接口和/debug下的调试接口，任何能访问管理端口的人都可以调用，
+	// 只应该在本地调试时使用；默认没有开启认证时不提供这些接口，开启认证后只有admin角色可以访问
+	AllowInsecure bool `json:"allow_insecure"`
+}
+
+// 限流规则
+type RateLimitRule struct {
+	// 每秒补充的令牌数量，0表示不限制
+	Rate float64 `json:"rate"`
+	// 令牌桶的容量，也就是允许的突发请求数量
+	Burst int `json:"burst"`
+	// 对于客户端流，是否每接收一条消息就消耗一个令牌
+	PerMessage bool `json:"per_message"`
+}
+
+// 限流相关的配置
+// 限流按照调用方区分：通过认证的使用principal，否则使用对端的ip
+type RateLimitConfig struct {
+	Enabled bool `json:"enabled"`
+	// 没有单独配置的方法使用的默认规则
+	Default RateLimitRule `json:"default"`
+	// 方法全名 -> 限流规则
+	Methods map[string]RateLimitRule `json:"methods"`
+	// 每个调用方同时打开的流的数量上限，0表示不限制
+	MaxConcurrentStreams int `json:"max_concurrent_streams"`
+}
+
+// 链路追踪相关的配置
+type TracingConfig struct {
+	Enabled bool `json:"enabled"`
+	// span的导出方式：stdout或者none
+	// none时仍然会传递traceparent，但不导出span
+	Exporter string `json:"exporter"`
+}
+
+// grpc-web相关的配置
+type GRPCWebConfig struct {
+	// 监听的地址，同时支持HTTP/1.1和h2c，为空则不开启
+	Addr string `json:"addr"`
+	// 允许跨域访问的origin，"*"表示允许所有
+	AllowedOrigins []string `json:"allowed_origins"`
+	// 除了默认的请求头以外，浏览器还可以携带的请求头
+	AllowedHeaders []string `json:"allowed_headers"`
+	// 预检请求的缓存时间
+	MaxAgeSeconds int `json:"max_age_seconds"`
+}
+
+// webhook相关的配置，订阅通过管理端口的/webhooks接口注册
+type WebhookConfig struct {
+	Enabled bool `json:"enabled"`
+	// 保存订阅和推送队列的目录，为空时只保存在内存中
+	QueueDir string `json:"queue_dir"`
+	// 最多尝试多少次
+	MaxAttempts int `json:"max_attempts"`
+	// 第一次重试之前等待的时间，之后每次翻倍
+	InitialBackoffSeconds int `json:"initial_backoff_seconds"`
+	// 重试等待时间的上限
+	MaxBackoffSeconds int `json:"max_backoff_seconds"`
+	// 单次推送的超时时间
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// 同时进行的推送数量
+	Workers int `json:"workers"`
+	// 最多保留多少条已经结束的推送记录
+	Retention int `json:"retention"`
+}
+
+// 连接相关的配置
+type ConnectionConfig struct {
+	// 接收和发送的单条消息的大小上限，上传封面时每个block是一条消息
+	MaxRecvMsgBytes int `json:"max_recv_msg_bytes"`
+	MaxSendMsgBytes int `json:"max_send_msg_bytes"`
+	// 每个连接上同时进行的调用数量上限，超过的调用等待之前的调用结束
+	MaxConcurrentStreams uint32 `json:"max_concurrent_streams"`
+	// 每个gRPC监听地址上的连接数量上限，超过的连接等待之前的连接关闭，0表示不限制
+	MaxConnections int `json:"max_connections"`
+	// 连接空闲多久之后发送ping检查客户端是否还在
+	KeepaliveTimeSeconds int `json:"keepalive_time_seconds"`
+	// 发送ping之后多久没有回应就关闭连接
+	KeepaliveTimeoutSeconds int `json:"keepalive_timeout_seconds"`
+	// 没有调用的连接空闲多久之后关闭，0表示不关闭
+	MaxConnectionIdleSeconds int `json:"max_connection_idle_seconds"`
+	// 连接最长使用多久，到期之后客户端重新连接，负载均衡时新的后端可以分到连接，0表示不限制
+	MaxConnectionAgeSeconds int `json:"max_connection_age_seconds"`
+	// 连接到期之后等待已有调用结束的时间
+	MaxConnectionAgeGraceSeconds int `json:"max_connection_age_grace_seconds"`
+	// 客户端发送ping的最小间隔，更频繁的ping会导致连接被关闭
+	MinPingIntervalSeconds int `json:"min_ping_interval_seconds"`
+	// 是否允许客户端在没有调用时发送ping
+	PermitPingWithoutStream bool `json:"permit_ping_without_stream"`
+}
+
+// 服务端配置
+type Config struct {
+	// gRPC服务监听的地址
+	// 所有的监听地址都可以是host:port，也可以是unix socket，比如unix:///run/cellphone.sock
+	Addr string `json:"addr"`
+	// gRPC服务额外监听的地址，比如给同一台机器上的sidecar使用的unix socket
+	GRPCAddrs []string `json:"grpc_addrs"`
+	// 管理端口监听的地址，提供/metrics和/debug等HTTP接口，以及gRPC的健康检查、反射和channelz，为空则不开启
+	AdminAddr string `json:"admin_addr"`
+	// HTTP/JSON网关监听的地址，为空则不开启，只有开启了cellphone服务才有效
+	GatewayAddr string `json:"gateway_addr"`
+	// 收到退出信号后等待已有请求结束的最长时间，超过后强制关闭，0表示一直等待
+	ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds"`
+	// 存放封面图片的目录
+	CoverPath string `json:"cover_path"`
+	// 保存手机信息的文件，格式由扩展名决定（.ndjson, .csv, .binpb），为空时只保存在内存中
+	CatalogFile string `json:"catalog_file"`
+	// 是否开启cellphone服务
+	CellphoneService bool `json:"cellphone_service"`
+	// 是否开启custom服务
+	CustomService bool `json:"custom_service"`
+
+	Log       LogConfig       `json:"log"`
+	Auth      AuthConfig      `json:"auth"`
+	Admin     AdminConfig     `json:"admin"`
+	RateLimit RateLimitConfig `json:"rate_limit"`
+	Tracing   TracingConfig   `json:"tracing"`
+	GRPCWeb   GRPCWebConfig   `json:"grpc_web"`
+	Webhook   WebhookConfig   `json:"webhook"`
+
+	Connection ConnectionConfig `json:"connection"`
+}
+
+// 默认配置
+func Default() *Config {
+	return &Config{
+		Addr:             "127.0.0.1:9527",
+		AdminAddr:        "127.0.0.1:9528",
+		GatewayAddr:      "127.0.0.1:9529",
+		CoverPath:        "image/server",
+		CellphoneService: true,
+		CustomService:    false,
+
+		ShutdownTimeoutSeconds: 10,
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+			Redact: []string{"block"},
+		},
+		RateLimit: RateLimitConfig{
+			Default: RateLimitRule{Rate: 50, Burst: 100},
+			Methods: map[string]RateLimitRule{
+				"/pb.CellphoneService/BuyCellphone":         {Rate: 10, Burst: 20, PerMessage: true},
+				"/pb.CellphoneService/UploadCellphoneCover": {Rate: 1, Burst: 5},
+			},
+			MaxConcurrentStreams: 8,
+		},
+		Tracing: TracingConfig{
+			Exporter: "stdout",
+		},
+		GRPCWeb: GRPCWebConfig{
+			AllowedOrigins: []string{"*"},
+			MaxAgeSeconds:  600,
+		},
+		Webhook: WebhookConfig{
+			QueueDir:              "data/webhooks",
+			MaxAttempts:           8,
+			InitialBackoffSeconds: 1,
+			MaxBackoffSeconds:     300,
+			TimeoutSeconds:        10,
+			Workers:               4,
+			Retention:             1000,
+		},
+		Connection: ConnectionConfig{
+			MaxRecvMsgBytes:              4 * 1024 * 1024,
+			MaxSendMsgBytes:              4 * 1024 * 1024,
+			MaxConcurrentStreams:         100,
+			MaxConnections:               1000,
+			KeepaliveTimeSeconds:         2 * 60 * 60,
+			KeepaliveTimeoutSeconds:      20,
+			MaxConnectionAgeSeconds:      30 * 60,
+			MaxConnectionAgeGraceSeconds: 30,
+			MinPingIntervalSeconds:       10,
+			PermitPingWithoutStream:      true,
+		},
+	}
+}
+
+// 从json文件中加载配置，文件中没有出现的字段保持默认值
+func Load(filename string) (*Config, error) {
+	cfg := Default()
+	if filename == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("can not read config file %s: %w", filename, err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("can not parse config file %s: %w", filename, err)
+	}
+	return cfg, nil
+}
+
+// 替换敏感字段的值
+const redacted = "REDACTED"
+
+// 隐藏了密钥的配置，用于打印或者导出
+func (c *Config) Redacted() *Config {
+	copied := *c
+	if copied.Auth.JWTSecret != "" {
+		copied.Auth.JWTSecret = redacted
+	}
+	return &copied
+}
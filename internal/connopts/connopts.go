@@ -0,0 +1,96 @@
+package connopts
+
+// 服务端和客户端连接相关的选项：keepalive、消息大小和连接数量的限制
+// 客户端的keepalive间隔不能小于服务端允许的最小ping间隔，否则连接会被服务端以too_many_pings关闭
+
+import (
+	"net"
+	"time"
+
+	"golang.org/x/net/netutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+)
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
+
+// 按照配置创建服务端的选项，值为0的配置使用gRPC的默认值
+func ServerOptions(cfg config.ConnectionConfig) []grpc.ServerOption {
+	var opts []grpc.ServerOption
+	if cfg.MaxRecvMsgBytes > 0 {
+		opts = append(opts, grpc.MaxRecvMsgSize(cfg.MaxRecvMsgBytes))
+	}
+	if cfg.MaxSendMsgBytes > 0 {
+		opts = append(opts, grpc.MaxSendMsgSize(cfg.MaxSendMsgBytes))
+	}
+	if cfg.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(cfg.MaxConcurrentStreams))
+	}
+	// keepalive.ServerParameters中为0的字段使用默认值
+	opts = append(opts,
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:                  seconds(cfg.KeepaliveTimeSeconds),
+			Timeout:               seconds(cfg.KeepaliveTimeoutSeconds),
+			MaxConnectionIdle:     seconds(cfg.MaxConnectionIdleSeconds),
+			MaxConnectionAge:      seconds(cfg.MaxConnectionAgeSeconds),
+			MaxConnectionAgeGrace: seconds(cfg.MaxConnectionAgeGraceSeconds),
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             seconds(cfg.MinPingIntervalSeconds),
+			PermitWithoutStream: cfg.PermitPingWithoutStream,
+		}),
+	)
+	return opts
+}
+
+// 限制listener上同时存在的连接数量，超过的连接在之前的连接关闭之后才会被接受
+func LimitListener(l net.Listener, cfg config.ConnectionConfig) net.Listener {
+	if cfg.MaxConnections <= 0 {
+		return l
+	}
+	return netutil.LimitListener(l, cfg.MaxConnections)
+}
+
+// 客户端连接相关的选项
+type Client struct {
+	// 单条消息的大小上限
+	MaxRecvMsgBytes int
+	MaxSendMsgBytes int
+	// 连接空闲多久之后发送ping，0表示不发送；gRPC不允许小于10秒
+	KeepaliveTime time.Duration
+	// 发送ping之后多久没有回应就认为连接已经断开
+	KeepaliveTimeout time.Duration
+}
+
+// 和服务端的默认配置对应
+func DefaultClient() Client {
+	return Client{
+		MaxRecvMsgBytes:  4 * 1024 * 1024,
+		MaxSendMsgBytes:  4 * 1024 * 1024,
+		KeepaliveTime:    time.Minute,
+		KeepaliveTimeout: 20 * time.Second,
+	}
+}
+
+func (c Client) DialOptions() []grpc.DialOption {
+	var callOpts []grpc.CallOption
+	if c.MaxRecvMsgBytes > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(c.MaxRecvMsgBytes))
+	}
+	if c.MaxSendMsgBytes > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(c.MaxSendMsgBytes))
+	}
+	opts := []grpc.DialOption{grpc.WithDefaultCallOptions(callOpts...)}
+	if c.KeepaliveTime > 0 {
+		// 只在有调用时发送ping，空闲的连接不需要保持
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    c.KeepaliveTime,
+			Timeout: c.KeepaliveTimeout,
+		}))
+	}
+	return opts
+}
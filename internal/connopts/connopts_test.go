@@ -0,0 +1,163 @@
+package connopts_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+	"github.com/ryanreadbooks/go-grpc-example/internal/connopts"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 按照连接配置运行服务端，返回监听的地址
+func runTestServer(t *testing.T, cfg config.ConnectionConfig) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口监听
+	require.Nil(t, err)
+	server := grpc.NewServer(connopts.ServerOptions(cfg)...)
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(service.WithCoverPath(t.TempDir())))
+	go server.Serve(connopts.LimitListener(listener, cfg))
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func newTestClient(t *testing.T, addr string, c connopts.Client) pb.CellphoneServiceClient {
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, c.DialOptions()...)
+	conn, err := grpc.Dial(addr, opts...)
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewCellphoneServiceClient(conn)
+}
+
+// 上传一个size字节的block
+func uploadBlock(client pb.CellphoneServiceClient, id string, size int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	stream, err := client.UploadCellphoneCover(ctx)
+	if err != nil {
+		return err
+	}
+	// 服务端返回错误时Send返回io.EOF，具体的错误由CloseAndRecv返回；
+	// 客户端检查出的错误直接由Send返回
+	for _, req := range []*pb.UploadCellphoneCoverRequest{
+		{Data: &pb.UploadCellphoneCoverRequest_Meta{Meta: &pb.CoverMetaInfo{Id: id, ImageType: ".jpeg", Size: uint32(size)}}},
+		{Data: &pb.UploadCellphoneCoverRequest_Block{Block: make([]byte, size)}},
+	} {
+		if err := stream.Send(req); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+func TestMessageSize(t *testing.T) {
+	t.Parallel()
+
+	serverCfg := config.Default().Connection
+	serverCfg.MaxRecvMsgBytes = 8 * 1024
+	serverCfg.MaxSendMsgBytes = 64 * 1024
+	addr := runTestServer(t, serverCfg)
+	client := newTestClient(t, addr, connopts.DefaultClient())
+
+	res, err := client.CreateCellphone(context.Background(), &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+
+	testCases := []struct {
+		Name   string
+		Client pb.CellphoneServiceClient
+		Size   int
+		Code   codes.Code
+	}{
+		{Name: "within limit", Client: client, Size: 4096, Code: codes.OK},
+		// 服务端拒绝太大的消息
+		{Name: "server recv limit", Client: client, Size: 16 * 1024, Code: codes.ResourceExhausted},
+		// 客户端在发送之前拒绝太大的消息
+		{Name: "client send limit", Client: newTestClient(t, addr, connopts.Client{MaxSendMsgBytes: 1024}),
+			Size: 4096, Code: codes.ResourceExhausted},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := uploadBlock(tc.Client, res.Id, tc.Size)
+			require.Equal(t, tc.Code, status.Code(err), "%v", err)
+		})
+	}
+
+	// 客户端拒绝太大的响应
+	small := newTestClient(t, addr, connopts.Client{MaxRecvMsgBytes: 64})
+	_, err = small.GetCellphone(context.Background(), &pb.GetCellphoneRequest{Id: res.Id})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+// 服务端不发送超过限制的响应
+func TestServerSendLimit(t *testing.T) {
+	t.Parallel()
+
+	serverCfg := config.Default().Connection
+	serverCfg.MaxSendMsgBytes = 64
+	client := newTestClient(t, runTestServer(t, serverCfg), connopts.DefaultClient())
+
+	res, err := client.CreateCellphone(context.Background(), &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+	_, err = client.GetCellphone(context.Background(), &pb.GetCellphoneRequest{Id: res.Id})
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+// 超过并发数量的调用等待之前的调用结束
+func TestMaxConcurrentStreams(t *testing.T) {
+	t.Parallel()
+
+	serverCfg := config.Default().Connection
+	serverCfg.MaxConcurrentStreams = 1
+	client := newTestClient(t, runTestServer(t, serverCfg), connopts.DefaultClient())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watch, err := client.WatchCellphones(ctx, &pb.WatchRequest{})
+	require.Nil(t, err)
+	_, err = watch.Header()
+	require.Nil(t, err)
+
+	timeout, cancelTimeout := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelTimeout()
+	_, err = client.ListOrders(timeout, &pb.ListOrdersRequest{})
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	// 流结束之后可以继续调用
+	cancel()
+	_, err = client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+	require.Nil(t, err)
+}
+
+// 超过数量上限的连接等待之前的连接关闭
+func TestMaxConnections(t *testing.T) {
+	t.Parallel()
+
+	serverCfg := config.Default().Connection
+	serverCfg.MaxConnections = 1
+	addr := runTestServer(t, serverCfg)
+
+	first := newTestClient(t, addr, connopts.DefaultClient())
+	_, err := first.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+	require.Nil(t, err)
+
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+	second := pb.NewCellphoneServiceClient(conn)
+	timeout, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = second.ListOrders(timeout, &pb.ListOrdersRequest{}, grpc.WaitForReady(true))
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
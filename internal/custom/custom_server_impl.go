@@ -2,16 +2,16 @@ package custom
 
 import (
 	"context"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"strconv"
 	"time"
-	"fmt"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata" // 用来获取grpc传输的metadata信息
+	"google.golang.org/grpc/status"
 
 	"github.com/ryanreadbooks/go-grpc-example/internal/service"
 	"github.com/ryanreadbooks/go-grpc-example/pb"
@@ -39,7 +39,7 @@ func (c *customServiceServer) MetadataCarryTest(ctx context.Context, req *pb.Cus
 		// md里面包含了metadata中的键值对
 		// md内的key都是小写的
 		for k, v := range md {
-			log.Printf("server side: %s: %s\n", k, v)
+			slog.DebugContext(ctx, "metadata received", "key", k, "values", v)
 			if _, ok := res[k]; !ok {
 				res[k] = &pb.StringList{}
 				res[k].Values = make([]string, 0)
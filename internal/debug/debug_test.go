@@ -0,0 +1,119 @@
+package debug_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/debug"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+func runTestServer(t *testing.T, tracker *debug.Tracker) pb.CellphoneServiceClient {
+	listener, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口监听
+	require.Nil(t, err)
+	server := grpc.NewServer(grpc.StreamInterceptor(tracker.StreamServerInterceptor()))
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(service.WithCoverPath(t.TempDir())))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewCellphoneServiceClient(conn)
+}
+
+// 通过/debug/streams查询进行中的流
+func getStreams(t *testing.T, handler http.Handler) []debug.StreamInfo {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/streams", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var streams []debug.StreamInfo
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), &streams))
+	return streams
+}
+
+func TestStreams(t *testing.T) {
+	t.Parallel()
+
+	tracker := debug.NewTracker("/pb.CellphoneService/BuyCellphone")
+	handler := debug.Handler(tracker)
+	client := runTestServer(t, tracker)
+	ctx := context.Background()
+
+	res, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+	require.Empty(t, getStreams(t, handler))
+
+	start := time.Now()
+	buy, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		require.Nil(t, buy.Send(&pb.BuyCellphoneRequest{Id: res.Id, Price: 1000, IdempotencyKey: uuid.NewString()}))
+		_, err := buy.Recv()
+		require.Nil(t, err)
+	}
+	// 没有在追踪列表中的方法不会被记录
+	upload, err := client.UploadCellphoneCover(ctx)
+	require.Nil(t, err)
+	require.Nil(t, upload.Send(&pb.UploadCellphoneCoverRequest{
+		Data: &pb.UploadCellphoneCoverRequest_Meta{Meta: &pb.CoverMetaInfo{Id: res.Id, ImageType: ".jpeg", Size: 100}}}))
+
+	streams := getStreams(t, handler)
+	require.Len(t, streams, 1)
+	require.Equal(t, "/pb.CellphoneService/BuyCellphone", streams[0].Method)
+	require.NotEqual(t, "unknown", streams[0].Peer)
+	require.WithinDuration(t, start, streams[0].StartTime, time.Second)
+	require.Equal(t, uint64(2), streams[0].Received)
+	require.Equal(t, uint64(2), streams[0].Sent)
+
+	// 流结束之后不再列出
+	require.Nil(t, buy.CloseSend())
+	_, err = buy.Recv()
+	require.Equal(t, io.EOF, err)
+	require.Eventually(t, func() bool { return len(tracker.Streams()) == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+
+	handler := debug.Handler(debug.NewTracker())
+	testCases := []struct {
+		Name     string
+		Method   string
+		Path     string
+		Code     int
+		Contains string
+	}{
+		{Name: "goroutines", Method: http.MethodGet, Path: "/debug/goroutines", Code: http.StatusOK, Contains: "goroutine "},
+		{Name: "goroutines aggregated", Method: http.MethodGet, Path: "/debug/goroutines?debug=1", Code: http.StatusOK, Contains: "goroutine profile: total"},
+		{Name: "goroutines bad level", Method: http.MethodGet, Path: "/debug/goroutines?debug=3", Code: http.StatusBadRequest},
+		{Name: "pprof index", Method: http.MethodGet, Path: "/debug/pprof/", Code: http.StatusOK, Contains: "heap"},
+		{Name: "pprof heap", Method: http.MethodGet, Path: "/debug/pprof/heap?debug=1", Code: http.StatusOK, Contains: "heap profile"},
+		{Name: "no streams", Method: http.MethodGet, Path: "/debug/streams", Code: http.StatusOK, Contains: "[]"},
+		{Name: "post streams", Method: http.MethodPost, Path: "/debug/streams", Code: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tc.Method, tc.Path, nil))
+			require.Equal(t, tc.Code, rec.Code)
+			require.Contains(t, rec.Body.String(), tc.Contains)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package debug
+
+// 运行时的调试接口，挂载在管理端口的/debug下
+//
+//	GET /debug/pprof/             pprof，可以用go tool pprof采集
+//	GET /debug/goroutines         所有goroutine的调用栈，debug=1时按调用栈合并
+//	GET /debug/streams            进行中的流，包括对端地址、开始时间和收发的消息数量
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/pprof"
+	runtimepprof "runtime/pprof"
+	"strconv"
+)
+
+func Handler(tracker *Tracker) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/goroutines", serveGoroutines)
+	mux.HandleFunc("/debug/streams", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tracker.Streams())
+	})
+	return mux
+}
+
+func serveGoroutines(w http.ResponseWriter, r *http.Request) {
+	level := 2
+	if v := r.URL.Query().Get("debug"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 2 {
+			http.Error(w, "debug must be 1 or 2", http.StatusBadRequest)
+			return
+		}
+		level = n
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	runtimepprof.Lookup("goroutine").WriteTo(w, level)
+}
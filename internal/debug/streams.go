@@ -0,0 +1,131 @@
+package debug
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/logging"
+)
+
+// 记录正在进行中的流，用于排查卡住的流
+type Tracker struct {
+	methods map[string]bool
+
+	mu      sync.Mutex
+	nextId  uint64
+	streams map[uint64]*trackedStream
+}
+
+// 只记录methods中的方法，比如/pb.CellphoneService/BuyCellphone，为空时记录所有的流
+func NewTracker(methods ...string) *Tracker {
+	t := &Tracker{streams: make(map[uint64]*trackedStream)}
+	if len(methods) > 0 {
+		t.methods = make(map[string]bool, len(methods))
+		for _, m := range methods {
+			t.methods[m] = true
+		}
+	}
+	return t
+}
+
+// 正在进行中的一个流
+type StreamInfo struct {
+	Id        uint64    `json:"id"`
+	Method    string    `json:"method"`
+	Peer      string    `json:"peer"`
+	Principal string    `json:"principal,omitempty"`
+	RequestId string    `json:"request_id,omitempty"`
+	StartTime time.Time `json:"start_time"`
+	Duration  string    `json:"duration"`
+	Received  uint64    `json:"received"`
+	Sent      uint64    `json:"sent"`
+}
+
+type trackedStream struct {
+	grpc.ServerStream
+	info     StreamInfo
+	received atomic.Uint64
+	sent     atomic.Uint64
+}
+
+func (s *trackedStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Add(1)
+	}
+	return err
+}
+
+func (s *trackedStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received.Add(1)
+	}
+	return err
+}
+
+// 放在认证拦截器之后才能记录调用方
+func (t *Tracker) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		if t.methods != nil && !t.methods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+
+		ctx := ss.Context()
+		stream := &trackedStream{
+			ServerStream: ss,
+			info: StreamInfo{
+				Method:    info.FullMethod,
+				Peer:      "unknown",
+				RequestId: logging.RequestID(ctx),
+				StartTime: time.Now(),
+			},
+		}
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			stream.info.Peer = p.Addr.String()
+		}
+		if p, ok := auth.FromContext(ctx); ok {
+			stream.info.Principal = p.Subject
+		}
+
+		t.mu.Lock()
+		t.nextId++
+		stream.info.Id = t.nextId
+		t.streams[stream.info.Id] = stream
+		t.mu.Unlock()
+
+		defer func() {
+			t.mu.Lock()
+			delete(t.streams, stream.info.Id)
+			t.mu.Unlock()
+		}()
+		return handler(srv, stream)
+	}
+}
+
+// 当前进行中的流，按照开始时间排序
+func (t *Tracker) Streams() []StreamInfo {
+	now := time.Now()
+	t.mu.Lock()
+	res := make([]StreamInfo, 0, len(t.streams))
+	for _, s := range t.streams {
+		info := s.info
+		info.Duration = now.Sub(info.StartTime).Round(time.Millisecond).String()
+		info.Received = s.received.Load()
+		info.Sent = s.sent.Load()
+		res = append(res, info)
+	}
+	t.mu.Unlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res
+}
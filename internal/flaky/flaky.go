@@ -0,0 +1,130 @@
+package flaky
+
+// 不稳定的服务端，用来测试客户端的重试、对冲和超时
+// 拦截器按照规则让每个方法的前几次调用失败、变慢，或者执行之后丢弃响应
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// 一个方法的故障规则，调用按照到达的顺序从0开始编号
+type Rule struct {
+	// 方法全名，比如/pb.CellphoneService/GetCellphone
+	Method string
+	// 前FailFirst次调用不执行，直接返回Code
+	FailFirst int
+	Code      codes.Code
+	// 前SlowFirst次调用先等待Delay再执行
+	SlowFirst int
+	Delay     time.Duration
+	// 前DropFirst次调用正常执行，但是丢弃所有响应并返回Code
+	// 模拟服务端已经处理完成但是响应丢失的情况
+	DropFirst int
+}
+
+type Injector struct {
+	mu    sync.Mutex
+	rules map[string]Rule
+	calls map[string]int
+}
+
+func New(rules ...Rule) *Injector {
+	i := &Injector{
+		rules: make(map[string]Rule, len(rules)),
+		calls: make(map[string]int),
+	}
+	for _, rule := range rules {
+		i.rules[rule.Method] = rule
+	}
+	return i
+}
+
+// 方法被调用的次数，包括失败的调用
+func (i *Injector) Calls(method string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.calls[method]
+}
+
+// 记录一次调用，返回这次调用的编号和适用的规则
+func (i *Injector) begin(method string) (int, Rule, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	n := i.calls[method]
+	i.calls[method]++
+	rule, ok := i.rules[method]
+	return n, rule, ok
+}
+
+// 在执行之前等待或者失败
+func (r Rule) before(ctx context.Context, n int) error {
+	if n < r.FailFirst {
+		return status.Errorf(r.Code, "flaky: call #%d of %s failed", n, r.Method)
+	}
+	if n < r.SlowFirst {
+		timer := time.NewTimer(r.Delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+func (r Rule) dropped(n int) error {
+	return status.Errorf(r.Code, "flaky: response of call #%d of %s dropped", n, r.Method)
+}
+
+func (i *Injector) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		n, rule, ok := i.begin(info.FullMethod)
+		if !ok {
+			return handler(ctx, req)
+		}
+		if err := rule.before(ctx, n); err != nil {
+			return nil, err
+		}
+		res, err := handler(ctx, req)
+		if err == nil && n < rule.DropFirst {
+			return nil, rule.dropped(n)
+		}
+		return res, err
+	}
+}
+
+func (i *Injector) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		n, rule, ok := i.begin(info.FullMethod)
+		if !ok {
+			return handler(srv, ss)
+		}
+		if err := rule.before(ss.Context(), n); err != nil {
+			return err
+		}
+		if n >= rule.DropFirst {
+			return handler(srv, ss)
+		}
+		if err := handler(srv, &droppingStream{ss}); err != nil {
+			return err
+		}
+		return rule.dropped(n)
+	}
+}
+
+// 不发送任何响应，也不发送header，这样客户端收到的是只有trailer的响应
+type droppingStream struct {
+	grpc.ServerStream
+}
+
+func (s *droppingStream) SendMsg(m any) error          { return nil }
+func (s *droppingStream) SetHeader(metadata.MD) error  { return nil }
+func (s *droppingStream) SendHeader(metadata.MD) error { return nil }
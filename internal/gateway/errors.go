@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"net/http"
+
+	// 错误详情的类型需要注册之后才能编码成json
+	_ "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC状态码对应的http状态码
+// 参考：https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	// Unknown, Internal, DataLoss
+	return http.StatusInternalServerError
+}
+
+// 错误响应的body是google.rpc.Status的json，包含错误详情
+func (g *Gateway) writeError(w http.ResponseWriter, ts *transportStream, err error) {
+	st := status.Convert(err)
+	if ts != nil {
+		writeMetadata(w, ts.metadata())
+	}
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(HTTPStatusFromCode(st.Code()))
+	g.writeMessage(w, st.Proto())
+}
@@ -0,0 +1,420 @@
+package gateway
+
+// HTTP/JSON网关：把REST风格的http请求转换成对pb.CellphoneServiceServer的调用
+//
+//	POST /v1/cellphones              body为pb.Cellphone，返回pb.CreateCellphoneResponse
+//	GET  /v1/cellphones:search       查询参数为pb.FilterCondition的字段，以NDJSON的形式逐行返回pb.Cellphone
+//	POST /v1/cellphones/{id}/cover   body为图片的原始内容，Content-Type为图片类型，返回pb.UploadCellphoneCoverResponse
+//	POST /v1/orders                  body为pb.BuyCellphoneRequest，返回pb.BuyCellphoneResponse
+//
+// 请求直接交给生成的handler处理，所以gRPC服务端的拦截器（认证、限流、日志等）同样适用于网关
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/validate"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const (
+	contentTypeJSON   = "application/json"
+	contentTypeNDJSON = "application/x-ndjson"
+
+	// 以这个前缀开头的http头会去掉前缀后放进metadata中
+	MetadataHeaderPrefix = "Grpc-Metadata-"
+
+	// json请求body的大小上限
+	maxBodyBytes = 1 << 20
+	// 上传封面时每次交给handler的数据块大小
+	coverBlockSize = 4096
+)
+
+// 会被原样转发到metadata中的http头
+var forwardedHeaders = []string{"Authorization", "X-Request-Id", "Traceparent", "Tracestate"}
+
+// 支持的封面图片类型
+var coverImageTypes = map[string]string{
+	"image/jpeg": ".jpeg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type Gateway struct {
+	srv pb.CellphoneServiceServer
+
+	unaryInterceptors  []grpc.UnaryServerInterceptor
+	streamInterceptors []grpc.StreamServerInterceptor
+	unary              grpc.UnaryServerInterceptor
+	stream             grpc.StreamServerInterceptor
+
+	methods map[string]grpc.MethodDesc
+	streams map[string]grpc.StreamDesc
+	marshal protojson.MarshalOptions
+}
+
+var _ http.Handler = (*Gateway)(nil)
+
+type Option func(g *Gateway)
+
+// 调用unary rpc时经过的拦截器，越靠前的越先执行
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(g *Gateway) {
+		g.unaryInterceptors = append(g.unaryInterceptors, interceptors...)
+	}
+}
+
+// 调用streaming rpc时经过的拦截器，越靠前的越先执行
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(g *Gateway) {
+		g.streamInterceptors = append(g.streamInterceptors, interceptors...)
+	}
+}
+
+func New(srv pb.CellphoneServiceServer, opts ...Option) *Gateway {
+	g := &Gateway{
+		srv:     srv,
+		methods: make(map[string]grpc.MethodDesc),
+		streams: make(map[string]grpc.StreamDesc),
+		marshal: protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true},
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	g.unary = chainUnary(g.unaryInterceptors)
+	g.stream = chainStream(g.streamInterceptors)
+
+	for _, m := range pb.CellphoneService_ServiceDesc.Methods {
+		g.methods[m.MethodName] = m
+	}
+	for _, s := range pb.CellphoneService_ServiceDesc.Streams {
+		g.streams[s.StreamName] = s
+	}
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case path == "/v1/cellphones":
+		g.route(w, r, http.MethodPost, g.createCellphone)
+	case path == "/v1/cellphones:search":
+		g.route(w, r, http.MethodGet, g.searchCellphone)
+	case path == "/v1/orders":
+		g.route(w, r, http.MethodPost, g.buyCellphone)
+	case strings.HasPrefix(path, "/v1/cellphones/") && strings.HasSuffix(path, "/cover"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/v1/cellphones/"), "/cover")
+		if id == "" || strings.Contains(id, "/") {
+			g.notFound(w, r)
+			return
+		}
+		g.route(w, r, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			g.uploadCellphoneCover(w, r, id)
+		})
+	default:
+		g.notFound(w, r)
+	}
+}
+
+func (g *Gateway) route(w http.ResponseWriter, r *http.Request, method string, handle http.HandlerFunc) {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		g.writeMessage(w, status.Newf(codes.Unimplemented, "method %s is not allowed", r.Method).Proto())
+		return
+	}
+	handle(w, r)
+}
+
+func (g *Gateway) notFound(w http.ResponseWriter, r *http.Request) {
+	g.writeError(w, nil, status.Errorf(codes.NotFound, "no route for %s %s", r.Method, r.URL.Path))
+}
+
+func (g *Gateway) createCellphone(w http.ResponseWriter, r *http.Request) {
+	ctx, ts := g.newContext(r, "CreateCellphone")
+	cellphone := &pb.Cellphone{}
+	if err := readBody(r, cellphone); err != nil {
+		g.writeError(w, ts, err)
+		return
+	}
+
+	res, err := g.invokeUnary(ctx, "CreateCellphone", &pb.CreateCellphoneRequest{Cellphone: cellphone})
+	if err != nil {
+		g.writeError(w, ts, err)
+		return
+	}
+	g.writeResponse(w, ts, res.(proto.Message))
+}
+
+// 每找到一台手机就写一行json并立即flush
+// 开始返回结果之后再发生错误，只能在最后一行以{"error": status}的形式返回
+func (g *Gateway) searchCellphone(w http.ResponseWriter, r *http.Request) {
+	ctx, ts := g.newContext(r, "SearchCellphone")
+	condition := &pb.FilterCondition{}
+	if err := populateQuery(condition, r.URL.Query()); err != nil {
+		g.writeError(w, ts, status.Error(codes.InvalidArgument, err.Error()))
+		return
+	}
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	start := func() {
+		started = true
+		writeMetadata(w, ts.metadata())
+		w.Header().Set("Content-Type", contentTypeNDJSON)
+		w.WriteHeader(http.StatusOK)
+	}
+	stream := &serverStream{
+		ctx:  ctx,
+		ts:   ts,
+		recv: recvOnce(condition),
+		send: func(m interface{}) error {
+			if !started {
+				start()
+			}
+			if err := g.writeMessage(w, m.(proto.Message)); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return nil
+		},
+	}
+
+	err := g.invokeStream(stream, "SearchCellphone")
+	if err != nil && !started {
+		g.writeError(w, ts, err)
+		return
+	}
+	if !started {
+		// 没有符合条件的手机
+		start()
+	}
+	if err != nil {
+		g.writeErrorLine(w, err)
+	}
+}
+
+// 图片内容直接作为body上传，服务端按照块的形式交给handler
+func (g *Gateway) uploadCellphoneCover(w http.ResponseWriter, r *http.Request, id string) {
+	ctx, ts := g.newContext(r, "UploadCellphoneCover")
+	imageType, ok := coverImageTypes[r.Header.Get("Content-Type")]
+	if !ok {
+		g.writeError(w, ts, status.Errorf(codes.InvalidArgument,
+			"unsupported cover image content type %q", r.Header.Get("Content-Type")))
+		return
+	}
+
+	var body io.Reader = r.Body
+	size := r.ContentLength
+	if size < 0 {
+		// 不知道body的大小时先全部读出来，多读一个字节用来判断是否超过大小限制
+		data, err := io.ReadAll(io.LimitReader(r.Body, int64(validate.MaxCoverImageBytes)+1))
+		if err != nil {
+			g.writeError(w, ts, status.Errorf(codes.InvalidArgument, "can not read request body: %v", err))
+			return
+		}
+		size = int64(len(data))
+		body = bytes.NewReader(data)
+	}
+	if size > math.MaxUint32 {
+		size = math.MaxUint32
+	}
+
+	meta := &pb.CoverMetaInfo{Id: id, Size: uint32(size), ImageType: imageType}
+	metaSent := false
+	buf := make([]byte, coverBlockSize)
+	var response proto.Message
+	stream := &serverStream{
+		ctx: ctx,
+		ts:  ts,
+		recv: func(m interface{}) error {
+			req := m.(*pb.UploadCellphoneCoverRequest)
+			// 第一条消息是meta info
+			if !metaSent {
+				metaSent = true
+				req.Data = &pb.UploadCellphoneCoverRequest_Meta{Meta: meta}
+				return nil
+			}
+			n, err := io.ReadFull(body, buf)
+			if n > 0 {
+				req.Data = &pb.UploadCellphoneCoverRequest_Block{Block: append([]byte(nil), buf[:n]...)}
+				return nil
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return io.EOF
+			}
+			return status.Errorf(codes.InvalidArgument, "can not read request body: %v", err)
+		},
+		send: func(m interface{}) error {
+			response = m.(proto.Message)
+			return nil
+		},
+	}
+
+	if err := g.invokeStream(stream, "UploadCellphoneCover"); err != nil {
+		g.writeError(w, ts, err)
+		return
+	}
+	g.writeResponse(w, ts, response)
+}
+
+// 一次http请求对应一个订单
+func (g *Gateway) buyCellphone(w http.ResponseWriter, r *http.Request) {
+	ctx, ts := g.newContext(r, "BuyCellphone")
+	order := &pb.BuyCellphoneRequest{}
+	if err := readBody(r, order); err != nil {
+		g.writeError(w, ts, err)
+		return
+	}
+
+	var response proto.Message
+	stream := &serverStream{
+		ctx:  ctx,
+		ts:   ts,
+		recv: recvOnce(order),
+		send: func(m interface{}) error {
+			response = m.(proto.Message)
+			return nil
+		},
+	}
+	if err := g.invokeStream(stream, "BuyCellphone"); err != nil {
+		g.writeError(w, ts, err)
+		return
+	}
+	g.writeResponse(w, ts, response)
+}
+
+func (g *Gateway) invokeUnary(ctx context.Context, method string, req proto.Message) (interface{}, error) {
+	dec := func(in interface{}) error {
+		proto.Merge(in.(proto.Message), req)
+		return nil
+	}
+	return g.methods[method].Handler(g.srv, ctx, dec, g.unary)
+}
+
+func (g *Gateway) invokeStream(stream *serverStream, method string) error {
+	desc := g.streams[method]
+	if g.stream == nil {
+		return desc.Handler(g.srv, stream)
+	}
+	info := &grpc.StreamServerInfo{
+		FullMethod:     fullMethod(method),
+		IsClientStream: desc.ClientStreams,
+		IsServerStream: desc.ServerStreams,
+	}
+	return g.stream(g.srv, stream, info, desc.Handler)
+}
+
+// 把http头转换成incoming metadata，并记录对端的地址
+func (g *Gateway) newContext(r *http.Request, method string) (context.Context, *transportStream) {
+	md := metadata.MD{}
+	for _, h := range forwardedHeaders {
+		if values := r.Header.Values(h); len(values) != 0 {
+			md.Append(strings.ToLower(h), values...)
+		}
+	}
+	for key, values := range r.Header {
+		if strings.HasPrefix(key, MetadataHeaderPrefix) {
+			md.Append(strings.ToLower(strings.TrimPrefix(key, MetadataHeaderPrefix)), values...)
+		}
+	}
+
+	ctx := metadata.NewIncomingContext(r.Context(), md)
+	if addr, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{Addr: net.TCPAddrFromAddrPort(addr)})
+	}
+	// grpc.SetHeader和grpc.SetTrailer需要context中有transport stream
+	ts := &transportStream{method: fullMethod(method)}
+	return grpc.NewContextWithServerTransportStream(ctx, ts), ts
+}
+
+func fullMethod(method string) string {
+	return "/" + pb.CellphoneService_ServiceDesc.ServiceName + "/" + method
+}
+
+// 第一次调用时返回msg，之后返回io.EOF
+func recvOnce(msg proto.Message) func(m interface{}) error {
+	received := false
+	return func(m interface{}) error {
+		if received {
+			return io.EOF
+		}
+		received = true
+		proto.Merge(m.(proto.Message), msg)
+		return nil
+	}
+}
+
+func readBody(r *http.Request, msg proto.Message) error {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "can not read request body: %v", err)
+	}
+	if len(data) > maxBodyBytes {
+		return status.Errorf(codes.InvalidArgument, "request body is larger than %d bytes", maxBodyBytes)
+	}
+	if err := protojson.Unmarshal(data, msg); err != nil {
+		return status.Errorf(codes.InvalidArgument, "can not parse request body: %v", err)
+	}
+	return nil
+}
+
+func (g *Gateway) writeResponse(w http.ResponseWriter, ts *transportStream, msg proto.Message) {
+	if msg == nil {
+		g.writeError(w, ts, status.Error(codes.Internal, "no response from server"))
+		return
+	}
+	writeMetadata(w, ts.metadata())
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(http.StatusOK)
+	g.writeMessage(w, msg)
+}
+
+// 每条消息占一行
+func (g *Gateway) writeMessage(w io.Writer, msg proto.Message) error {
+	data, err := g.marshal.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+func (g *Gateway) writeErrorLine(w io.Writer, err error) {
+	data, merr := g.marshal.Marshal(status.Convert(err).Proto())
+	if merr != nil {
+		return
+	}
+	fmt.Fprintf(w, "{\"error\":%s}\n", data)
+}
+
+// header和trailer中的metadata都作为http头返回，比如x-request-id和retry-after
+func writeMetadata(w http.ResponseWriter, md metadata.MD) {
+	for key, values := range md {
+		if strings.HasPrefix(key, "grpc-") || strings.HasSuffix(key, "-bin") {
+			continue
+		}
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+}
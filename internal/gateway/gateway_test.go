@@ -0,0 +1,331 @@
+package gateway_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/gateway"
+	"github.com/ryanreadbooks/go-grpc-example/internal/logging"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+func runTestGateway(t *testing.T, opts ...gateway.Option) *httptest.Server {
+	srv := service.NewCellphoneServiceServer(service.WithCoverPath(t.TempDir()))
+	ts := httptest.NewServer(gateway.New(srv, opts...))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func post(t *testing.T, url, contentType string, body []byte, header ...string) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.Nil(t, err)
+	req.Header.Set("Content-Type", contentType)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	res, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func createCellphone(t *testing.T, url string, c *pb.Cellphone, header ...string) *http.Response {
+	data, err := protojson.Marshal(c)
+	require.Nil(t, err)
+	return post(t, url+"/v1/cellphones", "application/json", data, header...)
+}
+
+func decode(t *testing.T, res *http.Response, msg proto.Message) {
+	data, err := io.ReadAll(res.Body)
+	require.Nil(t, err)
+	require.Nil(t, protojson.Unmarshal(data, msg), string(data))
+}
+
+// 错误响应的body为google.rpc.Status
+func decodeStatus(t *testing.T, res *http.Response) *spb.Status {
+	st := &spb.Status{}
+	decode(t, res, st)
+	return st
+}
+
+func reasonOf(t *testing.T, st *spb.Status) string {
+	for _, detail := range st.Details {
+		info := &errdetails.ErrorInfo{}
+		if detail.MessageIs(info) {
+			require.Nil(t, detail.UnmarshalTo(info))
+			return info.Reason
+		}
+	}
+	return ""
+}
+
+func TestGatewayCreateCellphone(t *testing.T) {
+	t.Parallel()
+
+	ts := runTestGateway(t)
+	cellphone := sample.NewCellphone()
+
+	res := createCellphone(t, ts.URL, cellphone)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "application/json", res.Header.Get("Content-Type"))
+	created := &pb.CreateCellphoneResponse{}
+	decode(t, res, created)
+	require.Equal(t, cellphone.Id, created.Id)
+
+	// 重复添加
+	res = createCellphone(t, ts.URL, cellphone)
+	require.Equal(t, http.StatusConflict, res.StatusCode)
+	st := decodeStatus(t, res)
+	require.EqualValues(t, codes.AlreadyExists, st.Code)
+	require.Equal(t, pb.ErrorReason_CELLPHONE_ALREADY_EXISTS.String(), reasonOf(t, st))
+
+	// 不合法的字段
+	invalid := sample.NewCellphone()
+	invalid.Cpu = nil
+	res = createCellphone(t, ts.URL, invalid)
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// body不是json
+	res = post(t, ts.URL+"/v1/cellphones", "application/json", []byte("not json"))
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+	require.EqualValues(t, codes.InvalidArgument, decodeStatus(t, res).Code)
+
+	// 方法不对
+	res, err := http.Get(ts.URL + "/v1/cellphones")
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+	require.Equal(t, http.MethodPost, res.Header.Get("Allow"))
+
+	// 路由不存在
+	res, err = http.Get(ts.URL + "/v2/cellphones")
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestGatewaySearchCellphone(t *testing.T) {
+	t.Parallel()
+
+	ts := runTestGateway(t)
+	for i := 0; i < 5; i++ {
+		c := sample.NewCellphone()
+		c.Brand = "Apple"
+		require.Equal(t, http.StatusOK, createCellphone(t, ts.URL, c).StatusCode)
+	}
+	c := sample.NewCellphone()
+	c.Brand = "Pixel"
+	require.Equal(t, http.StatusOK, createCellphone(t, ts.URL, c).StatusCode)
+
+	testCases := []struct {
+		Name   string
+		Query  string
+		Status int
+		Num    int
+	}{
+		{"all", "", http.StatusOK, 6},
+		{"by-brand", "?brands=Apple", http.StatusOK, 5},
+		{"comma-separated", "?brands=Apple,Pixel", http.StatusOK, 6},
+		{"json-name", "?brands=Pixel&minCpuCore=1", http.StatusOK, 1},
+		{"no-result", "?brands=Nokia", http.StatusOK, 0},
+		{"unknown-parameter", "?color=red", http.StatusBadRequest, 0},
+		{"invalid-number", "?min_cpu_core=many", http.StatusBadRequest, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			res, err := http.Get(ts.URL + "/v1/cellphones:search" + tc.Query)
+			require.Nil(t, err)
+			defer res.Body.Close()
+			require.Equal(t, tc.Status, res.StatusCode)
+			if tc.Status != http.StatusOK {
+				return
+			}
+			require.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+
+			// 每一行是一台手机
+			num := 0
+			scanner := bufio.NewScanner(res.Body)
+			for scanner.Scan() {
+				cellphone := &pb.Cellphone{}
+				require.Nil(t, protojson.Unmarshal(scanner.Bytes(), cellphone))
+				require.NotEmpty(t, cellphone.Id)
+				num++
+			}
+			require.Equal(t, tc.Num, num)
+		})
+	}
+}
+
+func TestGatewayUploadCellphoneCover(t *testing.T) {
+	t.Parallel()
+
+	ts := runTestGateway(t)
+	res := createCellphone(t, ts.URL, sample.NewCellphone())
+	created := &pb.CreateCellphoneResponse{}
+	decode(t, res, created)
+
+	small, err := os.ReadFile("../../image/client/apple.jpeg")
+	require.Nil(t, err)
+	large, err := os.ReadFile("../../image/client/huawei.jpeg")
+	require.Nil(t, err)
+
+	testCases := []struct {
+		Name        string
+		Id          string
+		ContentType string
+		Image       []byte
+		Status      int
+		Reason      string
+	}{
+		{"success", created.Id, "image/jpeg", small, http.StatusOK, ""},
+		{"too-large", created.Id, "image/jpeg", large, http.StatusBadRequest, pb.ErrorReason_COVER_TOO_LARGE.String()},
+		{"not-found", "dce5fc07-d7d1-49fe-aaef-183aa779fce2", "image/jpeg", small, http.StatusNotFound, pb.ErrorReason_CELLPHONE_NOT_FOUND.String()},
+		{"invalid-uuid", "invalid-uuid", "image/jpeg", small, http.StatusBadRequest, pb.ErrorReason_INVALID_UUID.String()},
+		{"unsupported-type", created.Id, "text/plain", small, http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			res := post(t, ts.URL+"/v1/cellphones/"+tc.Id+"/cover", tc.ContentType, tc.Image)
+			require.Equal(t, tc.Status, res.StatusCode)
+			if tc.Status != http.StatusOK {
+				require.Equal(t, tc.Reason, reasonOf(t, decodeStatus(t, res)))
+				return
+			}
+			uploaded := &pb.UploadCellphoneCoverResponse{}
+			decode(t, res, uploaded)
+			require.Equal(t, tc.Id, uploaded.Id)
+			require.EqualValues(t, len(tc.Image), uploaded.Size)
+		})
+	}
+}
+
+func TestGatewayBuyCellphone(t *testing.T) {
+	t.Parallel()
+
+	ts := runTestGateway(t)
+	res := createCellphone(t, ts.URL, sample.NewCellphone())
+	created := &pb.CreateCellphoneResponse{}
+	decode(t, res, created)
+
+	order := func(id string, price float64) *http.Response {
+		data, err := protojson.Marshal(&pb.BuyCellphoneRequest{Id: id, Price: price})
+		require.Nil(t, err)
+		return post(t, ts.URL+"/v1/orders", "application/json", data)
+	}
+
+	require.Equal(t, http.StatusOK, order(created.Id, 1000).StatusCode)
+	res = order(created.Id, 2000)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	bought := &pb.BuyCellphoneResponse{}
+	decode(t, res, bought)
+	require.Equal(t, created.Id, bought.Id)
+	require.Equal(t, 1500.0, bought.Avg)
+
+	res = order("dce5fc07-d7d1-49fe-aaef-183aa779fce2", 1000)
+	require.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestGatewayInterceptors(t *testing.T) {
+	t.Parallel()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	keys := auth.NewStaticKeys([]auth.APIKey{{Key: "admin-key", Subject: "alice", Roles: []string{"admin"}}})
+	ts := runTestGateway(t,
+		gateway.WithUnaryInterceptors(
+			logging.UnaryServerInterceptor(logger, logging.Options{}),
+			auth.UnaryServerInterceptor(keys, auth.DefaultPolicy(), auth.Options{}),
+		),
+		gateway.WithStreamInterceptors(
+			logging.StreamServerInterceptor(logger, logging.Options{}),
+			auth.StreamServerInterceptor(keys, auth.DefaultPolicy(), auth.Options{}),
+		),
+	)
+
+	// 没有token
+	res := createCellphone(t, ts.URL, sample.NewCellphone())
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	// 拦截器设置的header会作为http头返回
+	require.NotEmpty(t, res.Header.Get(logging.RequestIDMetadataKey))
+
+	// authorization头会转发到metadata中，x-request-id会被沿用
+	res = createCellphone(t, ts.URL, sample.NewCellphone(),
+		"Authorization", "Bearer admin-key",
+		"X-Request-Id", "gateway-request")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Equal(t, "gateway-request", res.Header.Get(logging.RequestIDMetadataKey))
+
+	// stream拦截器同样生效
+	res, err := http.Get(ts.URL + "/v1/cellphones:search")
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	require.NotEmpty(t, res.Header.Get(logging.RequestIDMetadataKey))
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/cellphones:search", nil)
+	require.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer admin-key")
+	res, err = http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = post(t, ts.URL+"/v1/orders", "application/json", []byte(`{"id": "x", "price": 1}`))
+	require.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestHTTPStatusFromCode(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[codes.Code]int{
+		codes.OK:                http.StatusOK,
+		codes.InvalidArgument:   http.StatusBadRequest,
+		codes.NotFound:          http.StatusNotFound,
+		codes.AlreadyExists:     http.StatusConflict,
+		codes.PermissionDenied:  http.StatusForbidden,
+		codes.Unauthenticated:   http.StatusUnauthorized,
+		codes.ResourceExhausted: http.StatusTooManyRequests,
+		codes.OutOfRange:        http.StatusBadRequest,
+		codes.Unavailable:       http.StatusServiceUnavailable,
+		codes.DeadlineExceeded:  http.StatusGatewayTimeout,
+		codes.Internal:          http.StatusInternalServerError,
+	}
+	for code, want := range testCases {
+		require.Equal(t, want, gateway.HTTPStatusFromCode(code), code.String())
+	}
+}
+
+// 错误详情编码成json之后依然可读
+func TestGatewayErrorBody(t *testing.T) {
+	t.Parallel()
+
+	ts := runTestGateway(t)
+	res := post(t, ts.URL+"/v1/orders", "application/json", []byte(`{"id": "bad", "price": 1}`))
+	require.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	var body map[string]interface{}
+	require.Nil(t, json.NewDecoder(res.Body).Decode(&body))
+	require.EqualValues(t, codes.InvalidArgument, body["code"])
+	details := body["details"].([]interface{})
+	require.NotEmpty(t, details)
+	require.True(t, strings.HasSuffix(details[0].(map[string]interface{})["@type"].(string), "google.rpc.ErrorInfo"))
+}
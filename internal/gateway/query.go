@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// 把url中的查询参数填充到消息的字段中
+// 参数名可以是proto中的字段名或者json名，比如min_cpu_core和minCpuCore
+// repeated字段可以出现多次，也可以用逗号分隔
+func populateQuery(msg proto.Message, query url.Values) error {
+	m := msg.ProtoReflect()
+	fields := m.Descriptor().Fields()
+	for key, values := range query {
+		fd := fields.ByName(protoreflect.Name(key))
+		if fd == nil {
+			fd = fields.ByJSONName(key)
+		}
+		if fd == nil {
+			return fmt.Errorf("unknown query parameter %q", key)
+		}
+		if fd.Kind() == protoreflect.MessageKind || fd.Kind() == protoreflect.GroupKind || fd.IsMap() {
+			return fmt.Errorf("query parameter %q is not a scalar field", key)
+		}
+
+		if fd.IsList() {
+			list := m.Mutable(fd).List()
+			for _, value := range values {
+				for _, v := range strings.Split(value, ",") {
+					pv, err := parseScalar(fd, v)
+					if err != nil {
+						return fmt.Errorf("invalid value %q for query parameter %q: %w", v, key, err)
+					}
+					list.Append(pv)
+				}
+			}
+			continue
+		}
+		if len(values) != 1 {
+			return fmt.Errorf("query parameter %q must appear only once", key)
+		}
+		pv, err := parseScalar(fd, values[0])
+		if err != nil {
+			return fmt.Errorf("invalid value %q for query parameter %q: %w", values[0], key, err)
+		}
+		m.Set(fd, pv)
+	}
+	return nil
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(s)), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(s, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", fd.Kind())
+}
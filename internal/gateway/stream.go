@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// 收集handler和拦截器设置的header和trailer，最后写到http响应头中
+type transportStream struct {
+	method string
+
+	mu      sync.Mutex
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *transportStream) Method() string {
+	return s.method
+}
+
+func (s *transportStream) SetHeader(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *transportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *transportStream) SetTrailer(md metadata.MD) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+// header和trailer合在一起返回
+func (s *transportStream) metadata() metadata.MD {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return metadata.Join(s.header, s.trailer)
+}
+
+// 把一次http请求伪装成grpc.ServerStream，交给生成的stream handler处理
+type serverStream struct {
+	ctx context.Context
+	ts  *transportStream
+	// 每次调用返回一条请求消息，没有更多消息时返回io.EOF
+	recv func(m interface{}) error
+	// 处理一条响应消息
+	send func(m interface{}) error
+}
+
+var _ grpc.ServerStream = (*serverStream)(nil)
+
+func (s *serverStream) SetHeader(md metadata.MD) error {
+	return s.ts.SetHeader(md)
+}
+
+func (s *serverStream) SendHeader(md metadata.MD) error {
+	return s.ts.SendHeader(md)
+}
+
+func (s *serverStream) SetTrailer(md metadata.MD) {
+	s.ts.SetTrailer(md)
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	return s.send(m)
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	return s.recv(m)
+}
+
+// 和grpc.ChainUnaryInterceptor一样，越靠前的拦截器越先执行
+func chainUnary(interceptors []grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	if len(interceptors) == 0 {
+		return nil
+	}
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		return interceptors[0](ctx, req, info, unaryHandler(interceptors, 0, info, handler))
+	}
+}
+
+func unaryHandler(interceptors []grpc.UnaryServerInterceptor, cur int,
+	info *grpc.UnaryServerInfo, final grpc.UnaryHandler) grpc.UnaryHandler {
+
+	if cur == len(interceptors)-1 {
+		return final
+	}
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return interceptors[cur+1](ctx, req, info, unaryHandler(interceptors, cur+1, info, final))
+	}
+}
+
+func chainStream(interceptors []grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	if len(interceptors) == 0 {
+		return nil
+	}
+	return func(srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		return interceptors[0](srv, ss, info, streamHandler(interceptors, 0, info, handler))
+	}
+}
+
+func streamHandler(interceptors []grpc.StreamServerInterceptor, cur int,
+	info *grpc.StreamServerInfo, final grpc.StreamHandler) grpc.StreamHandler {
+
+	if cur == len(interceptors)-1 {
+		return final
+	}
+	return func(srv interface{}, ss grpc.ServerStream) error {
+		return interceptors[cur+1](srv, ss, info, streamHandler(interceptors, cur+1, info, final))
+	}
+}
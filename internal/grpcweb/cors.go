@@ -0,0 +1,86 @@
+package grpcweb
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 浏览器默认可以携带的请求头
+var defaultAllowedHeaders = []string{
+	"Content-Type",
+	"X-Grpc-Web",
+	"X-User-Agent",
+	"Grpc-Timeout",
+	"Authorization",
+	"X-Request-Id",
+	"Traceparent",
+	"Tracestate",
+}
+
+// 浏览器可以读取的响应头，trailers-only响应会把状态放在响应头中
+var exposedHeaders = []string{
+	"Grpc-Status",
+	"Grpc-Message",
+	"Grpc-Status-Details-Bin",
+	"X-Request-Id",
+	"Retry-After",
+}
+
+type cors struct {
+	allowAll       bool
+	origins        map[string]bool
+	allowedHeaders string
+	maxAge         string
+}
+
+func newCORS(opts Options) *cors {
+	c := &cors{origins: make(map[string]bool)}
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			c.allowAll = true
+		}
+		c.origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	headers := append(append([]string(nil), defaultAllowedHeaders...), opts.AllowedHeaders...)
+	c.allowedHeaders = strings.Join(headers, ", ")
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return c
+}
+
+// 没有Origin头的请求不是跨域请求，总是允许
+func (c *cors) allowed(origin string) bool {
+	return origin == "" || c.allowAll || c.origins[origin]
+}
+
+// 预检请求
+func (c *cors) preflight(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	w.Header().Add("Vary", "Origin")
+	if !c.allowed(origin) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	h := w.Header()
+	h.Set("Access-Control-Allow-Origin", origin)
+	h.Set("Access-Control-Allow-Methods", http.MethodPost)
+	h.Set("Access-Control-Allow-Headers", c.allowedHeaders)
+	if c.maxAge != "" {
+		h.Set("Access-Control-Max-Age", c.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// 实际请求的响应头
+func (c *cors) setHeaders(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return
+	}
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Set("Access-Control-Allow-Origin", origin)
+	h.Set("Access-Control-Expose-Headers", strings.Join(exposedHeaders, ", "))
+}
@@ -0,0 +1,164 @@
+package grpcweb
+
+// gRPC-Web协议：https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md
+// 把浏览器发来的grpc-web请求转换成HTTP/2的gRPC请求交给grpc.Server处理，
+// 再把响应中的trailer编码到body中返回
+// 浏览器不支持客户端流，所以只支持unary和server streaming rpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	ContentTypeGRPCWeb     = "application/grpc-web"
+	ContentTypeGRPCWebText = "application/grpc-web-text"
+
+	contentTypeGRPC = "application/grpc"
+	// text模式下请求body的大小上限，和gRPC默认的接收消息大小一致
+	maxTextRequestBytes = 4 << 20
+)
+
+// 跨域相关的配置
+type Options struct {
+	// 允许跨域访问的origin，"*"表示允许所有
+	AllowedOrigins []string
+	// 除了默认的请求头以外，浏览器还可以携带的请求头
+	AllowedHeaders []string
+	// 预检请求的结果可以缓存多久
+	MaxAge time.Duration
+}
+
+// 同时可以处理grpc-web请求和HTTP/2上的原生gRPC请求
+type Handler struct {
+	server *grpc.Server
+	cors   *cors
+
+	once sync.Once
+	// 方法全名 -> 是否为客户端流
+	clientStreams map[string]bool
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+func New(server *grpc.Server, opts Options) *Handler {
+	return &Handler{server: server, cors: newCORS(opts)}
+}
+
+// 判断是不是grpc-web请求
+func IsGRPCWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), ContentTypeGRPCWeb)
+}
+
+// 判断是不是grpc-web的预检请求
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case isPreflight(r):
+		h.cors.preflight(w, r)
+	case IsGRPCWebRequest(r):
+		h.serveGRPCWeb(w, r)
+	case r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeGRPC):
+		h.server.ServeHTTP(w, r)
+	default:
+		http.Error(w, "not a grpc-web request", http.StatusUnsupportedMediaType)
+	}
+}
+
+func (h *Handler) serveGRPCWeb(w http.ResponseWriter, r *http.Request) {
+	if !h.cors.allowed(r.Header.Get("Origin")) {
+		http.Error(w, "origin is not allowed", http.StatusForbidden)
+		return
+	}
+	h.cors.setHeaders(w, r)
+
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, ContentTypeGRPCWebText)
+	responseType := ContentTypeGRPCWeb + "+proto"
+	if text {
+		responseType = ContentTypeGRPCWebText + "+proto"
+	}
+	rw := newResponseWriter(w, responseType, text)
+
+	if h.isClientStream(r.URL.Path) {
+		rw.header.Set("Grpc-Status", strconv.Itoa(int(codes.Unimplemented)))
+		rw.header.Set("Grpc-Message", "client streaming is not supported by grpc-web")
+		rw.finish()
+		return
+	}
+
+	body := r.Body
+	if text {
+		decoded, err := decodeText(r.Body)
+		if err != nil {
+			rw.header.Set("Grpc-Status", strconv.Itoa(int(codes.InvalidArgument)))
+			rw.header.Set("Grpc-Message", err.Error())
+			rw.finish()
+			return
+		}
+		body = io.NopCloser(bytes.NewReader(decoded))
+	}
+
+	// 伪装成HTTP/2的gRPC请求
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+	req.Header.Set("Content-Type", contentTypeGRPC+"+proto")
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	req.Body = body
+
+	h.server.ServeHTTP(rw, req)
+	rw.finish()
+}
+
+// 客户端流和双向流不能通过grpc-web调用
+func (h *Handler) isClientStream(fullMethod string) bool {
+	h.once.Do(func() {
+		h.clientStreams = make(map[string]bool)
+		for service, info := range h.server.GetServiceInfo() {
+			for _, m := range info.Methods {
+				h.clientStreams["/"+service+"/"+m.Name] = m.IsClientStream
+			}
+		}
+	})
+	return h.clientStreams[fullMethod]
+}
+
+// text模式的body可能由多段带有padding的base64拼接而成，所以每4个字符单独解码
+func decodeText(r io.Reader) ([]byte, error) {
+	limit := base64.StdEncoding.EncodedLen(maxTextRequestBytes)
+	data, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > limit {
+		return nil, fmt.Errorf("request body is larger than %d bytes", limit)
+	}
+	data = bytes.Join(bytes.Fields(data), nil)
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid base64 request body of %d bytes", len(data))
+	}
+	res := make([]byte, 0, base64.StdEncoding.DecodedLen(len(data)))
+	var buf [3]byte
+	for i := 0; i < len(data); i += 4 {
+		n, err := base64.StdEncoding.Decode(buf[:], data[i:i+4])
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 request body: %w", err)
+		}
+		res = append(res, buf[:n]...)
+	}
+	return res, nil
+}
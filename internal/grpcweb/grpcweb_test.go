@@ -0,0 +1,314 @@
+package grpcweb_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/grpcweb"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const (
+	createMethod = "/pb.CellphoneService/CreateCellphone"
+	searchMethod = "/pb.CellphoneService/SearchCellphone"
+	uploadMethod = "/pb.CellphoneService/UploadCellphoneCover"
+	testOrigin   = "http://shop.example.com"
+)
+
+func runTestServer(t *testing.T) *httptest.Server {
+	server := grpc.NewServer()
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(service.WithCoverPath(t.TempDir())))
+	handler := grpcweb.New(server, grpcweb.Options{
+		AllowedOrigins: []string{testOrigin},
+		AllowedHeaders: []string{"X-Custom"},
+		MaxAge:         time.Minute,
+	})
+	ts := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// 手动构造grpc-web的消息帧
+func frame(t *testing.T, msg proto.Message) []byte {
+	data, err := proto.Marshal(msg)
+	require.Nil(t, err)
+	buf := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(buf[1:], uint32(len(data)))
+	return append(buf, data...)
+}
+
+type response struct {
+	header   http.Header
+	messages [][]byte
+	trailer  map[string]string
+}
+
+// 手动解析响应中的数据帧和trailer帧
+func parseFrames(t *testing.T, body []byte) ([][]byte, map[string]string) {
+	var messages [][]byte
+	var trailer map[string]string
+	for len(body) > 0 {
+		require.GreaterOrEqual(t, len(body), 5)
+		flag := body[0]
+		length := binary.BigEndian.Uint32(body[1:5])
+		payload := body[5 : 5+length]
+		body = body[5+length:]
+
+		if flag&0x80 == 0 {
+			messages = append(messages, payload)
+			continue
+		}
+		// trailer帧必须是最后一帧
+		require.Empty(t, body)
+		trailer = make(map[string]string)
+		scanner := bufio.NewScanner(bytes.NewReader(payload))
+		for scanner.Scan() {
+			key, value, ok := strings.Cut(scanner.Text(), ": ")
+			require.True(t, ok, scanner.Text())
+			require.Equal(t, strings.ToLower(key), key)
+			trailer[key] = value
+		}
+	}
+	return messages, trailer
+}
+
+// 每4个字符解码，响应可能由多段带有padding的base64组成
+func decodeChunkedBase64(t *testing.T, data []byte) []byte {
+	require.Zero(t, len(data)%4)
+	var res []byte
+	var buf [3]byte
+	for i := 0; i < len(data); i += 4 {
+		n, err := base64.StdEncoding.Decode(buf[:], data[i:i+4])
+		require.Nil(t, err)
+		res = append(res, buf[:n]...)
+	}
+	return res
+}
+
+func call(t *testing.T, client *http.Client, url, method, contentType string, body []byte) *response {
+	req, err := http.NewRequest(http.MethodPost, url+method, bytes.NewReader(body))
+	require.Nil(t, err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Grpc-Web", "1")
+	req.Header.Set("Origin", testOrigin)
+	res, err := client.Do(req)
+	require.Nil(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	data, err := io.ReadAll(res.Body)
+	require.Nil(t, err)
+	if strings.HasPrefix(contentType, grpcweb.ContentTypeGRPCWebText) {
+		data = decodeChunkedBase64(t, data)
+	}
+	messages, trailer := parseFrames(t, data)
+	require.NotNil(t, trailer, "missing trailer frame")
+	return &response{header: res.Header, messages: messages, trailer: trailer}
+}
+
+// 不加密的HTTP/2客户端
+func h2cClient() *http.Client {
+	return &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+}
+
+func TestUnary(t *testing.T) {
+	t.Parallel()
+
+	ts := runTestServer(t)
+	clients := map[string]*http.Client{"http1": http.DefaultClient, "h2c": h2cClient()}
+	contentTypes := []string{
+		grpcweb.ContentTypeGRPCWeb,
+		grpcweb.ContentTypeGRPCWeb + "+proto",
+		grpcweb.ContentTypeGRPCWebText,
+		grpcweb.ContentTypeGRPCWebText + "+proto",
+	}
+
+	for name, client := range clients {
+		for _, contentType := range contentTypes {
+			t.Run(name+"-"+contentType, func(t *testing.T) {
+				cellphone := sample.NewCellphone()
+				body := frame(t, &pb.CreateCellphoneRequest{Cellphone: cellphone})
+				text := strings.HasPrefix(contentType, grpcweb.ContentTypeGRPCWebText)
+				if text {
+					body = []byte(base64.StdEncoding.EncodeToString(body))
+				}
+
+				res := call(t, client, ts.URL, createMethod, contentType, body)
+				if text {
+					require.Equal(t, grpcweb.ContentTypeGRPCWebText+"+proto", res.header.Get("Content-Type"))
+				} else {
+					require.Equal(t, grpcweb.ContentTypeGRPCWeb+"+proto", res.header.Get("Content-Type"))
+				}
+				require.Equal(t, "0", res.trailer["grpc-status"])
+				// trailer不会出现在响应头中
+				require.Empty(t, res.header.Get("Grpc-Status"))
+				require.Empty(t, res.header.Get("Trailer"))
+
+				require.Len(t, res.messages, 1)
+				created := &pb.CreateCellphoneResponse{}
+				require.Nil(t, proto.Unmarshal(res.messages[0], created))
+				require.Equal(t, cellphone.Id, created.Id)
+
+				// 重复添加返回错误，错误详情也在trailer中
+				res = call(t, client, ts.URL, createMethod, contentType, body)
+				require.Empty(t, res.messages)
+				require.Equal(t, "6", res.trailer["grpc-status"]) // AlreadyExists
+				require.NotEmpty(t, res.trailer["grpc-message"])
+				require.NotEmpty(t, res.trailer["grpc-status-details-bin"])
+			})
+		}
+	}
+}
+
+func TestServerStreaming(t *testing.T) {
+	t.Parallel()
+
+	ts := runTestServer(t)
+	for i := 0; i < 3; i++ {
+		c := sample.NewCellphone()
+		c.Brand = "Pixel"
+		res := call(t, http.DefaultClient, ts.URL, createMethod, grpcweb.ContentTypeGRPCWeb,
+			frame(t, &pb.CreateCellphoneRequest{Cellphone: c}))
+		require.Equal(t, "0", res.trailer["grpc-status"])
+	}
+
+	condition := frame(t, &pb.FilterCondition{Brands: []string{"Pixel"}})
+	for _, contentType := range []string{grpcweb.ContentTypeGRPCWeb, grpcweb.ContentTypeGRPCWebText} {
+		t.Run(contentType, func(t *testing.T) {
+			body := condition
+			if contentType == grpcweb.ContentTypeGRPCWebText {
+				body = []byte(base64.StdEncoding.EncodeToString(body))
+			}
+			res := call(t, h2cClient(), ts.URL, searchMethod, contentType, body)
+			require.Equal(t, "0", res.trailer["grpc-status"])
+			require.Len(t, res.messages, 3)
+			for _, m := range res.messages {
+				cellphone := &pb.Cellphone{}
+				require.Nil(t, proto.Unmarshal(m, cellphone))
+				require.Equal(t, "Pixel", cellphone.Brand)
+			}
+		})
+	}
+}
+
+func TestUnsupportedRequests(t *testing.T) {
+	t.Parallel()
+
+	ts := runTestServer(t)
+
+	// 客户端流不支持
+	res := call(t, http.DefaultClient, ts.URL, uploadMethod, grpcweb.ContentTypeGRPCWeb, nil)
+	require.Equal(t, "12", res.trailer["grpc-status"]) // Unimplemented
+
+	// 不存在的方法
+	res = call(t, http.DefaultClient, ts.URL, "/pb.CellphoneService/Unknown", grpcweb.ContentTypeGRPCWeb, nil)
+	require.Equal(t, "12", res.trailer["grpc-status"])
+
+	// 不合法的base64
+	res = call(t, http.DefaultClient, ts.URL, createMethod, grpcweb.ContentTypeGRPCWebText, []byte("!!"))
+	require.Equal(t, "3", res.trailer["grpc-status"]) // InvalidArgument
+
+	// HTTP/1.1上的原生gRPC请求
+	req, err := http.NewRequest(http.MethodPost, ts.URL+createMethod, nil)
+	require.Nil(t, err)
+	req.Header.Set("Content-Type", "application/grpc")
+	httpRes, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer httpRes.Body.Close()
+	require.Equal(t, http.StatusUnsupportedMediaType, httpRes.StatusCode)
+}
+
+func TestCORS(t *testing.T) {
+	t.Parallel()
+
+	ts := runTestServer(t)
+
+	preflight := func(origin string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, ts.URL+createMethod, nil)
+		require.Nil(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	res := preflight(testOrigin)
+	require.Equal(t, http.StatusNoContent, res.StatusCode)
+	require.Equal(t, testOrigin, res.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, http.MethodPost, res.Header.Get("Access-Control-Allow-Methods"))
+	require.Contains(t, res.Header.Get("Access-Control-Allow-Headers"), "X-Grpc-Web")
+	require.Contains(t, res.Header.Get("Access-Control-Allow-Headers"), "X-Custom")
+	require.Equal(t, "60", res.Header.Get("Access-Control-Max-Age"))
+
+	res = preflight("http://evil.example.com")
+	require.Equal(t, http.StatusForbidden, res.StatusCode)
+	require.Empty(t, res.Header.Get("Access-Control-Allow-Origin"))
+
+	// 实际请求的响应中可以读取grpc-status等响应头
+	cellphone := sample.NewCellphone()
+	r := call(t, http.DefaultClient, ts.URL, createMethod, grpcweb.ContentTypeGRPCWeb,
+		frame(t, &pb.CreateCellphoneRequest{Cellphone: cellphone}))
+	require.Equal(t, testOrigin, r.header.Get("Access-Control-Allow-Origin"))
+	require.Contains(t, r.header.Get("Access-Control-Expose-Headers"), "Grpc-Status")
+
+	// 不允许的origin
+	req, err := http.NewRequest(http.MethodPost, ts.URL+createMethod, bytes.NewReader(nil))
+	require.Nil(t, err)
+	req.Header.Set("Content-Type", grpcweb.ContentTypeGRPCWeb)
+	req.Header.Set("Origin", "http://evil.example.com")
+	httpRes, err := http.DefaultClient.Do(req)
+	require.Nil(t, err)
+	defer httpRes.Body.Close()
+	require.Equal(t, http.StatusForbidden, httpRes.StatusCode)
+}
+
+// 同一个端口上也可以通过h2c处理原生的gRPC请求
+func TestNativeGRPCOverH2C(t *testing.T) {
+	t.Parallel()
+
+	ts := runTestServer(t)
+	conn, err := grpc.Dial(strings.TrimPrefix(ts.URL, "http://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := pb.NewCellphoneServiceClient(conn)
+	res, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+	require.NotEmpty(t, res.Id)
+
+	_, err = client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,137 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	dataFrame    byte = 0x00
+	trailerFrame byte = 0x80
+)
+
+// gRPC放在http trailer中的字段，grpc-web需要把它们放到body最后的trailer帧中
+var grpcTrailers = map[string]bool{
+	"Grpc-Status":             true,
+	"Grpc-Message":            true,
+	"Grpc-Status-Details-Bin": true,
+}
+
+// 把grpc.Server写出的HTTP/2响应转换成grpc-web响应
+// 数据帧原样写出，trailer在最后以trailer帧的形式写到body中
+// text模式下body需要base64编码，每次Flush编码一次
+type responseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+
+	wroteHeader bool
+	// text模式下还没有编码的数据
+	pending bytes.Buffer
+}
+
+func newResponseWriter(w http.ResponseWriter, contentType string, text bool) *responseWriter {
+	return &responseWriter{
+		w:           w,
+		header:      make(http.Header),
+		contentType: contentType,
+		text:        text,
+	}
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+
+	h := rw.w.Header()
+	for key, values := range rw.header {
+		// http trailer的声明以及trailer本身都不作为响应头
+		if key == "Trailer" || grpcTrailers[key] || strings.HasPrefix(key, http2.TrailerPrefix) {
+			continue
+		}
+		h[key] = values
+	}
+	h.Set("Content-Type", rw.contentType)
+	h.Del("Content-Length")
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.text {
+		return rw.pending.Write(p)
+	}
+	return rw.w.Write(p)
+}
+
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if rw.text && rw.pending.Len() != 0 {
+		encoded := make([]byte, base64.StdEncoding.EncodedLen(rw.pending.Len()))
+		base64.StdEncoding.Encode(encoded, rw.pending.Bytes())
+		rw.pending.Reset()
+		rw.w.Write(encoded)
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// 处理结束后写出trailer帧
+func (rw *responseWriter) finish() {
+	trailer := make(http.Header)
+	for key, values := range rw.header {
+		if grpcTrailers[key] {
+			trailer[key] = values
+		} else if strings.HasPrefix(key, http2.TrailerPrefix) {
+			trailer[http.CanonicalHeaderKey(strings.TrimPrefix(key, http2.TrailerPrefix))] = values
+		}
+	}
+	rw.Write(encodeTrailer(trailer))
+	rw.Flush()
+}
+
+// trailer帧的内容和HTTP/1的header格式一样，key为小写
+func encodeTrailer(trailer http.Header) []byte {
+	keys := make([]string, 0, len(trailer))
+	for key := range trailer {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var payload bytes.Buffer
+	for _, key := range keys {
+		for _, v := range trailer[key] {
+			payload.WriteString(strings.ToLower(key))
+			payload.WriteString(": ")
+			payload.WriteString(v)
+			payload.WriteString("\r\n")
+		}
+	}
+	return frame(trailerFrame, payload.Bytes())
+}
+
+func frame(flag byte, payload []byte) []byte {
+	buf := make([]byte, 5+len(payload))
+	buf[0] = flag
+	binary.BigEndian.PutUint32(buf[1:5], uint32(len(payload)))
+	copy(buf[5:], payload)
+	return buf
+}
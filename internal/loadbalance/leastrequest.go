@@ -0,0 +1,96 @@
+package loadbalance
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/balancer/roundrobin"
+	// 注册客户端的健康检查，service config中配置了healthCheckConfig时生效
+	_ "google.golang.org/grpc/health"
+)
+
+const (
+	// 轮流使用每个可用的后端
+	RoundRobin = roundrobin.Name
+	// 使用未完成请求最少的后端
+	LeastRequest = "least_request"
+)
+
+func init() {
+	balancer.Register(leastRequestBuilder{})
+}
+
+// 和round_robin一样只使用健康检查通过的后端
+var leastRequestConfig = base.Config{HealthCheck: true}
+
+type leastRequestBuilder struct{}
+
+// 每个连接使用自己的计数
+func (leastRequestBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pb := &leastRequestPickerBuilder{outstanding: make(map[balancer.SubConn]*atomic.Int64)}
+	return base.NewBalancerBuilder(LeastRequest, pb, leastRequestConfig).Build(cc, opts)
+}
+
+func (leastRequestBuilder) Name() string {
+	return LeastRequest
+}
+
+type leastRequestPickerBuilder struct {
+	mu sync.Mutex
+	// 每个后端未完成的请求数，后端的状态变化时保留
+	outstanding map[balancer.SubConn]*atomic.Int64
+}
+
+func (b *leastRequestPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// 不可用的后端的计数已经没有意义了
+	for sc := range b.outstanding {
+		if _, ok := info.ReadySCs[sc]; !ok {
+			delete(b.outstanding, sc)
+		}
+	}
+	p := &leastRequestPicker{}
+	for sc := range info.ReadySCs {
+		n, ok := b.outstanding[sc]
+		if !ok {
+			n = new(atomic.Int64)
+			b.outstanding[sc] = n
+		}
+		p.backends = append(p.backends, backend{sc: sc, outstanding: n})
+	}
+	return p
+}
+
+type backend struct {
+	sc          balancer.SubConn
+	outstanding *atomic.Int64
+}
+
+type leastRequestPicker struct {
+	backends []backend
+	// 每次从不同的后端开始比较，未完成请求数相同时轮流使用
+	next atomic.Uint32
+}
+
+func (p *leastRequestPicker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	start := p.next.Add(1)
+	var picked *backend
+	for i := range p.backends {
+		// 用uint32取模，计数器溢出后在32位平台上转换成int会得到负数
+		b := &p.backends[(start+uint32(i))%uint32(len(p.backends))]
+		if picked == nil || b.outstanding.Load() < picked.outstanding.Load() {
+			picked = b
+		}
+	}
+	picked.outstanding.Add(1)
+	return balancer.PickResult{
+		SubConn: picked.sc,
+		Done:    func(balancer.DoneInfo) { picked.outstanding.Add(-1) },
+	}, nil
+}
@@ -0,0 +1,204 @@
+package loadbalance_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/flaky"
+	"github.com/ryanreadbooks/go-grpc-example/internal/loadbalance"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/internal/serviceconfig"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const listOrders = "/pb.CellphoneService/ListOrders"
+
+// 一个后端，记录收到的调用
+type testBackend struct {
+	addr     string
+	injector *flaky.Injector
+	health   *health.Server
+}
+
+func runBackend(t *testing.T, rules ...flaky.Rule) *testBackend {
+	listener, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口监听
+	require.Nil(t, err)
+
+	b := &testBackend{addr: listener.Addr().String(), injector: flaky.New(rules...), health: health.NewServer()}
+	server := grpc.NewServer(grpc.UnaryInterceptor(b.injector.UnaryServerInterceptor()))
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(service.WithCoverPath(t.TempDir())))
+	healthpb.RegisterHealthServer(server, b.health)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return b
+}
+
+func (b *testBackend) calls() int {
+	return b.injector.Calls(listOrders)
+}
+
+func dialBackends(t *testing.T, policy string, backends ...*testBackend) pb.CellphoneServiceClient {
+	var addrs []string
+	for _, b := range backends {
+		addrs = append(addrs, b.addr)
+	}
+	cfg, err := serviceconfig.Parse([]byte("{}"))
+	require.Nil(t, err)
+	cfg, err = cfg.WithLoadBalancing(policy, true)
+	require.Nil(t, err)
+
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, cfg.DialOptions()...)
+	conn, err := grpc.Dial(loadbalance.Target(addrs...), opts...)
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewCellphoneServiceClient(conn)
+
+	// 等待所有的后端都可以使用，慢的后端收到调用即可
+	require.Eventually(t, func() bool {
+		used := make(map[*testBackend]int)
+		for _, b := range backends {
+			used[b] = b.calls()
+		}
+		for i := 0; i < 2*len(backends); i++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			client.ListOrders(ctx, &pb.ListOrdersRequest{})
+			cancel()
+		}
+		for _, b := range backends {
+			if b.calls() == used[b] {
+				return false
+			}
+		}
+		return true
+	}, 3*time.Second, 10*time.Millisecond)
+	return client
+}
+
+func TestParseAddrs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Name     string
+		Endpoint string
+		Addrs    []string
+		Invalid  bool
+	}{
+		{Name: "single", Endpoint: "127.0.0.1:9527", Addrs: []string{"127.0.0.1:9527"}},
+		{Name: "multiple", Endpoint: "host1:9527, host2:9527,", Addrs: []string{"host1:9527", "host2:9527"}},
+		{Name: "duplicate", Endpoint: "host1:9527,host1:9527", Addrs: []string{"host1:9527"}},
+		{Name: "ipv6", Endpoint: "[::1]:9527", Addrs: []string{"[::1]:9527"}},
+		{Name: "missing port", Endpoint: "host1", Invalid: true},
+		{Name: "empty", Endpoint: " , ", Invalid: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			addrs, err := loadbalance.ParseAddrs(tc.Endpoint)
+			if tc.Invalid {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.Addrs, addrs)
+		})
+	}
+}
+
+func TestUnknownPolicy(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := serviceconfig.Parse([]byte("{}"))
+	require.Nil(t, err)
+	_, err = cfg.WithLoadBalancing("random", true)
+	require.NotNil(t, err)
+}
+
+// 请求平均分配到每个后端
+func TestRoundRobin(t *testing.T) {
+	t.Parallel()
+
+	backends := []*testBackend{runBackend(t), runBackend(t), runBackend(t)}
+	client := dialBackends(t, loadbalance.RoundRobin, backends...)
+
+	before := make([]int, len(backends))
+	for i, b := range backends {
+		before[i] = b.calls()
+	}
+	for i := 0; i < 30; i++ {
+		_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+		require.Nil(t, err)
+	}
+	for i, b := range backends {
+		require.Equal(t, 10, b.calls()-before[i])
+	}
+}
+
+// 慢的后端上有未完成的请求时，新的请求都发给其它后端
+func TestLeastRequest(t *testing.T) {
+	t.Parallel()
+
+	slow := runBackend(t, flaky.Rule{Method: listOrders, SlowFirst: 1000, Delay: time.Minute})
+	fast := runBackend(t)
+	client := dialBackends(t, loadbalance.LeastRequest, fast, slow)
+
+	// 在慢的后端上留下一个未完成的请求
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for before := slow.calls(); slow.calls() == before; {
+		go client.ListOrders(ctx, &pb.ListOrdersRequest{})
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	before := fast.calls()
+	for i := 0; i < 20; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		_, err := client.ListOrders(ctx, &pb.ListOrdersRequest{})
+		cancel()
+		require.Nil(t, err)
+	}
+	require.Equal(t, 20, fast.calls()-before)
+}
+
+// 健康检查不通过的后端不参与负载均衡，恢复之后重新参与
+func TestHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []string{loadbalance.RoundRobin, loadbalance.LeastRequest} {
+		policy := policy
+		t.Run(policy, func(t *testing.T) {
+			t.Parallel()
+
+			healthy, sick := runBackend(t), runBackend(t)
+			client := dialBackends(t, policy, healthy, sick)
+
+			sick.health.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+			require.Eventually(t, func() bool {
+				before := sick.calls()
+				for i := 0; i < 10; i++ {
+					if _, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{}); err != nil {
+						return false
+					}
+				}
+				return sick.calls() == before
+			}, 3*time.Second, 10*time.Millisecond)
+
+			sick.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+			require.Eventually(t, func() bool {
+				before := sick.calls()
+				for i := 0; i < 10; i++ {
+					if _, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{}); err != nil {
+						return false
+					}
+				}
+				return sick.calls() > before
+			}, 3*time.Second, 10*time.Millisecond)
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package loadbalance
+
+// 客户端负载均衡：静态地址列表的resolver和最少未完成请求的balancer
+// 导入这个包之后，可以使用static:///host1:9527,host2:9527作为target，
+// 也可以使用grpc内置的dns:///host:9527解析出多个地址
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc/resolver"
+)
+
+// 静态地址列表的scheme
+const Scheme = "static"
+
+func init() {
+	resolver.Register(staticBuilder{})
+}
+
+type staticBuilder struct{}
+
+func (staticBuilder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	addrs, err := ParseAddrs(target.Endpoint())
+	if err != nil {
+		return nil, err
+	}
+	state := resolver.State{}
+	for _, addr := range addrs {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: addr})
+	}
+	if err := cc.UpdateState(state); err != nil {
+		return nil, err
+	}
+	return staticResolver{}, nil
+}
+
+func (staticBuilder) Scheme() string {
+	return Scheme
+}
+
+// 地址列表不会变化，不需要重新解析
+type staticResolver struct{}
+
+func (staticResolver) ResolveNow(resolver.ResolveNowOptions) {}
+func (staticResolver) Close()                                {}
+
+// 解析逗号分隔的host:port列表，忽略重复的地址
+func ParseAddrs(endpoint string) ([]string, error) {
+	var addrs []string
+	seen := make(map[string]bool)
+	for _, addr := range strings.Split(endpoint, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, err
+		}
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, errors.New("static target has no address")
+	}
+	return addrs, nil
+}
+
+// 多个地址组成的target
+func Target(addrs ...string) string {
+	return Scheme + ":///" + strings.Join(addrs, ",")
+}
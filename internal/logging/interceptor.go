@@ -0,0 +1,185 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// 日志中使用的key
+const (
+	KeyRequestID = "request_id"
+	KeyMethod    = "method"
+	KeyPeer      = "peer"
+	KeyCode      = "code"
+	KeyLatency   = "latency"
+	KeySent      = "msgs_sent"
+	KeyReceived  = "msgs_received"
+	KeyPayload   = "payload"
+)
+
+// 拦截器的选项
+type Options struct {
+	// 是否在debug等级下打印每个请求和响应的内容
+	Payloads bool
+	// 打印内容时需要隐藏的字段
+	Redact []string
+}
+
+// 为每个unary rpc打印一条结构化日志
+func UnaryServerInterceptor(logger *slog.Logger, opts Options) grpc.UnaryServerInterceptor {
+	r := newRedactor(opts.Redact)
+
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		start := time.Now()
+		requestID := incomingRequestID(ctx)
+		ctx = WithRequestID(ctx, requestID)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID))
+
+		if opts.Payloads {
+			logger.DebugContext(ctx, "request received",
+				KeyMethod, info.FullMethod, KeyPayload, r.payload(req))
+		}
+
+		res, err := handler(ctx, req)
+
+		code := status.Code(err)
+		attrs := []slog.Attr{
+			slog.String(KeyMethod, info.FullMethod),
+			slog.String(KeyPeer, peerAddr(ctx)),
+			slog.String(KeyCode, code.String()),
+			slog.Duration(KeyLatency, time.Since(start)),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+		} else if opts.Payloads {
+			attrs = append(attrs, slog.Any(KeyPayload, r.payload(res)))
+		}
+		logger.LogAttrs(ctx, levelFor(code), "finished unary call", attrs...)
+
+		return res, err
+	}
+}
+
+// 为每个streaming rpc打印一条结构化日志，并且统计收发的消息数量
+func StreamServerInterceptor(logger *slog.Logger, opts Options) grpc.StreamServerInterceptor {
+	r := newRedactor(opts.Redact)
+
+	return func(srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		start := time.Now()
+		requestID := incomingRequestID(ss.Context())
+		ss.SetHeader(metadata.Pairs(RequestIDMetadataKey, requestID))
+
+		ws := &loggingServerStream{
+			ServerStream: ss,
+			ctx:          WithRequestID(ss.Context(), requestID),
+			logger:       logger,
+			method:       info.FullMethod,
+			payloads:     opts.Payloads,
+			redactor:     r,
+		}
+		logger.DebugContext(ws.ctx, "stream started",
+			KeyMethod, info.FullMethod,
+			KeyPeer, peerAddr(ws.ctx),
+			"client_stream", info.IsClientStream,
+			"server_stream", info.IsServerStream)
+
+		err := handler(srv, ws)
+
+		code := status.Code(err)
+		attrs := []slog.Attr{
+			slog.String(KeyMethod, info.FullMethod),
+			slog.String(KeyPeer, peerAddr(ws.ctx)),
+			slog.String(KeyCode, code.String()),
+			slog.Duration(KeyLatency, time.Since(start)),
+			slog.Int64(KeySent, ws.sent.Load()),
+			slog.Int64(KeyReceived, ws.received.Load()),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+		}
+		logger.LogAttrs(ws.ctx, levelFor(code), "finished streaming call", attrs...)
+
+		return err
+	}
+}
+
+// 包装grpc.ServerStream，拦截流中的每一条消息
+type loggingServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	logger   *slog.Logger
+	method   string
+	payloads bool
+	redactor redactor
+	// 收发可能在不同的goroutine中进行
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+func (s *loggingServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *loggingServerStream) SendMsg(data interface{}) error {
+	err := s.ServerStream.SendMsg(data)
+	if err != nil {
+		return err
+	}
+	seq := s.sent.Add(1)
+	if s.payloads {
+		s.logger.DebugContext(s.ctx, "stream message sent",
+			KeyMethod, s.method, "seq", seq, KeyPayload, s.redactor.payload(data))
+	}
+	return nil
+}
+
+func (s *loggingServerStream) RecvMsg(data interface{}) error {
+	err := s.ServerStream.RecvMsg(data)
+	if err != nil {
+		return err
+	}
+	seq := s.received.Add(1)
+	if s.payloads {
+		s.logger.DebugContext(s.ctx, "stream message received",
+			KeyMethod, s.method, "seq", seq, KeyPayload, s.redactor.payload(data))
+	}
+	return nil
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// 根据状态码决定日志等级
+// 客户端造成的错误用warn，服务端的错误用error
+func levelFor(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.OutOfRange, codes.DeadlineExceeded:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
@@ -0,0 +1,191 @@
+package logging_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+	"github.com/ryanreadbooks/go-grpc-example/internal/custom"
+	"github.com/ryanreadbooks/go-grpc-example/internal/logging"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 并发安全的日志输出
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// 把输出的每一行json日志解析出来
+func (b *syncBuffer) records(t *testing.T) []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		record := make(map[string]interface{})
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func findRecord(records []map[string]interface{}, msg string) map[string]interface{} {
+	for _, r := range records {
+		if r["msg"] == msg {
+			return r
+		}
+	}
+	return nil
+}
+
+// 测试中运行带有日志拦截器的server
+func runTestLoggingServer(t *testing.T, out io.Writer, cfg config.LogConfig) (*grpc.Server, net.Listener) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口监听
+	require.Nil(t, err)
+
+	logger, _, err := logging.New(out, cfg)
+	require.Nil(t, err)
+
+	opts := logging.Options{Payloads: cfg.Payloads, Redact: cfg.Redact}
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(logging.UnaryServerInterceptor(logger, opts)),
+		grpc.StreamInterceptor(logging.StreamServerInterceptor(logger, opts)),
+	)
+	pb.RegisterCustomServiceServer(server, custom.NewCustomServiceServer())
+	go server.Serve(listener)
+
+	return server, listener
+}
+
+func makeTestCustomServiceClient(t *testing.T, addr string) (pb.CustomServiceClient, *grpc.ClientConn) {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	return pb.NewCustomServiceClient(conn), conn
+}
+
+func TestLoggingUnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	out := &syncBuffer{}
+	server, listener := runTestLoggingServer(t, out, config.LogConfig{
+		Level:    "debug",
+		Format:   "json",
+		Payloads: true,
+		Redact:   []string{"pb.SimpleRequest.id"},
+	})
+	defer server.GracefulStop()
+
+	client, conn := makeTestCustomServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, logging.RequestIDMetadataKey, "req-123")
+
+	var header metadata.MD
+	_, err := client.CallWithUnaryInterceptor(ctx, &pb.SimpleRequest{Id: "secret-id"}, grpc.Header(&header))
+	require.Nil(t, err)
+	// request id会通过header返回给客户端
+	require.Equal(t, []string{"req-123"}, header.Get(logging.RequestIDMetadataKey))
+
+	records := out.records(t)
+	finished := findRecord(records, "finished unary call")
+	require.NotNil(t, finished)
+	require.Equal(t, "INFO", finished["level"])
+	require.Equal(t, "/pb.CustomService/CallWithUnaryInterceptor", finished[logging.KeyMethod])
+	require.Equal(t, "OK", finished[logging.KeyCode])
+	require.Equal(t, "req-123", finished[logging.KeyRequestID])
+	require.NotEmpty(t, finished[logging.KeyPeer])
+	require.Contains(t, finished, logging.KeyLatency)
+
+	received := findRecord(records, "request received")
+	require.NotNil(t, received)
+	require.NotContains(t, received[logging.KeyPayload], "secret-id")
+	require.Contains(t, received[logging.KeyPayload], "[REDACTED]")
+}
+
+func TestLoggingStreamInterceptor(t *testing.T) {
+	t.Parallel()
+
+	out := &syncBuffer{}
+	server, listener := runTestLoggingServer(t, out, config.LogConfig{Level: "info", Format: "json"})
+	defer server.GracefulStop()
+
+	client, conn := makeTestCustomServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	stream, err := client.CallWithStreamInterceptor(ctx)
+	require.Nil(t, err)
+
+	const num = 5
+	for i := 0; i < num; i++ {
+		require.Nil(t, stream.Send(&pb.SimpleRequest{Id: "id"}))
+		_, err := stream.Recv()
+		require.Nil(t, err)
+	}
+	require.Nil(t, stream.CloseSend())
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+
+	// 没有传request id时服务端会生成一个
+	header, err := stream.Header()
+	require.Nil(t, err)
+	require.Len(t, header.Get(logging.RequestIDMetadataKey), 1)
+
+	// 等待服务端的拦截器打印完日志
+	var finished map[string]interface{}
+	require.Eventually(t, func() bool {
+		finished = findRecord(out.records(t), "finished streaming call")
+		return finished != nil
+	}, time.Second, 10*time.Millisecond)
+
+	require.Equal(t, "/pb.CustomService/CallWithStreamInterceptor", finished[logging.KeyMethod])
+	require.Equal(t, "OK", finished[logging.KeyCode])
+	require.EqualValues(t, num, finished[logging.KeySent])
+	require.EqualValues(t, num, finished[logging.KeyReceived])
+	require.Equal(t, header.Get(logging.RequestIDMetadataKey)[0], finished[logging.KeyRequestID])
+
+	// info等级下不会打印debug日志
+	require.Nil(t, findRecord(out.records(t), "stream started"))
+}
+
+func TestLoggingNewWithInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Name string
+		Cfg  config.LogConfig
+	}{
+		{"invalid-level", config.LogConfig{Level: "verbose"}},
+		{"invalid-format", config.LogConfig{Format: "xml"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, _, err := logging.New(io.Discard, tc.Cfg)
+			require.NotNil(t, err)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package logging
+
+// 基于log/slog的结构化日志
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+)
+
+// 根据配置创建logger
+// 返回的LevelVar可以在运行时修改日志等级
+func New(w io.Writer, cfg config.LogConfig) (*slog.Logger, *slog.LevelVar, error) {
+	level := new(slog.LevelVar)
+	if err := SetLevel(level, cfg.Level); err != nil {
+		return nil, nil, err
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(cfg.Format) {
+	case "json":
+		handler = slog.NewJSONHandler(w, handlerOpts)
+	case "", "text":
+		handler = slog.NewTextHandler(w, handlerOpts)
+	default:
+		return nil, nil, fmt.Errorf("unsupported log format: %s", cfg.Format)
+	}
+
+	return slog.New(&contextHandler{handler}), level, nil
+}
+
+// 解析并设置日志等级，level为空时使用info
+func SetLevel(v *slog.LevelVar, level string) error {
+	if level == "" {
+		v.Set(slog.LevelInfo)
+		return nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	v.Set(l)
+	return nil
+}
+
+// 自动把context中的request id带到每条日志中
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String(KeyRequestID, id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
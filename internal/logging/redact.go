@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const redactedText = "[REDACTED]"
+
+// 负责隐藏消息中的敏感字段
+// 字段可以用短名字（price）或者全名（pb.BuyCellphoneRequest.price）指定
+type redactor map[string]struct{}
+
+func newRedactor(fields []string) redactor {
+	r := make(redactor, len(fields))
+	for _, f := range fields {
+		r[f] = struct{}{}
+	}
+	return r
+}
+
+func (r redactor) match(fd protoreflect.FieldDescriptor) bool {
+	if _, ok := r[string(fd.Name())]; ok {
+		return true
+	}
+	_, ok := r[string(fd.FullName())]
+	return ok
+}
+
+// 返回一个可以延迟格式化的日志值，只有真正输出日志时才会进行拷贝和序列化
+func (r redactor) payload(data interface{}) slog.LogValuer {
+	return payloadValue{r: r, data: data}
+}
+
+type payloadValue struct {
+	r    redactor
+	data interface{}
+}
+
+func (p payloadValue) LogValue() slog.Value {
+	m, ok := p.data.(proto.Message)
+	if !ok {
+		return slog.StringValue(fmt.Sprintf("%v", p.data))
+	}
+	if len(p.r) != 0 {
+		m = proto.Clone(m)
+		p.r.redact(m.ProtoReflect())
+	}
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return slog.StringValue(fmt.Sprintf("<can not marshal %T: %v>", m, err))
+	}
+	return slog.StringValue(string(b))
+}
+
+// 递归地处理消息中的每一个字段
+// 字符串字段替换成[REDACTED]，其它类型的字段直接清空
+func (r redactor) redact(m protoreflect.Message) {
+	var matched []protoreflect.FieldDescriptor
+	var nested []protoreflect.Value
+	var nestedFields []protoreflect.FieldDescriptor
+
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if r.match(fd) {
+			matched = append(matched, fd)
+		} else if fd.Message() != nil {
+			nested = append(nested, v)
+			nestedFields = append(nestedFields, fd)
+		}
+		return true
+	})
+
+	for _, fd := range matched {
+		if fd.Kind() == protoreflect.StringKind && fd.Cardinality() != protoreflect.Repeated {
+			m.Set(fd, protoreflect.ValueOfString(redactedText))
+		} else {
+			m.Clear(fd)
+		}
+	}
+
+	for i, v := range nested {
+		fd := nestedFields[i]
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				continue
+			}
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				r.redact(mv.Message())
+				return true
+			})
+		case fd.IsList():
+			list := v.List()
+			for j := 0; j < list.Len(); j++ {
+				r.redact(list.Get(j).Message())
+			}
+		default:
+			r.redact(v.Message())
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/metadata"
+)
+
+// 携带request id的metadata key
+const RequestIDMetadataKey = "x-request-id"
+
+type requestIDKey struct{}
+
+// 从context中取出request id
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// 把request id放进context中
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// 优先使用客户端在metadata中传过来的request id，没有的话就生成一个新的
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDMetadataKey); len(ids) != 0 && ids[0] != "" {
+			return ids[0]
+		}
+	}
+	return uuid.NewString()
+}
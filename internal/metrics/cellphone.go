@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// cellphone服务的业务指标
+// 实现了service.Observer接口，通过service.WithObserver安装
+type CellphoneMetrics struct {
+	created         *Counter
+	ordersPlaced    *Counter
+	orderAmount     *Counter
+	coversUploaded  *Counter
+	coverBytes      *Counter
+	coverRejections *CounterVec
+}
+
+// saver用来统计当前保存的手机数量
+func NewCellphoneMetrics(reg *Registry, saver service.CellphoneSaver) *CellphoneMetrics {
+	reg.NewGaugeFunc("cellphone_stored",
+		"Number of cellphones currently stored.",
+		func() float64 { return float64(saver.Size()) })
+
+	return &CellphoneMetrics{
+		created: reg.NewCounter("cellphone_created_total",
+			"Total number of cellphones created."),
+		ordersPlaced: reg.NewCounter("cellphone_orders_placed_total",
+			"Total number of cellphone orders placed."),
+		orderAmount: reg.NewCounter("cellphone_orders_amount_total",
+			"Sum of the prices of all placed orders."),
+		coversUploaded: reg.NewCounter("cellphone_covers_uploaded_total",
+			"Total number of cover images uploaded successfully."),
+		coverBytes: reg.NewCounter("cellphone_cover_uploaded_bytes_total",
+			"Total bytes of cover images uploaded successfully."),
+		coverRejections: reg.NewCounterVec("cellphone_cover_rejections_total",
+			"Total number of rejected cover uploads.",
+			"grpc_code"),
+	}
+}
+
+func (m *CellphoneMetrics) CellphoneCreated(context.Context, *pb.Cellphone) {
+	m.created.Inc()
+}
+
+func (m *CellphoneMetrics) CoverUploaded(_ context.Context, _ string, size uint32) {
+	m.coversUploaded.Inc()
+	m.coverBytes.Add(float64(size))
+}
+
+func (m *CellphoneMetrics) CoverRejected(_ context.Context, _ string, err error) {
+	m.coverRejections.With(status.Code(err).String()).Inc()
+}
+
+func (m *CellphoneMetrics) OrderPlaced(_ context.Context, _ string, price float64, _ *service.Orders) {
+	m.ordersPlaced.Inc()
+	if price > 0 {
+		m.orderAmount.Add(price)
+	}
+}
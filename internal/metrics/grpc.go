@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// rpc的类型
+const (
+	Unary        = "unary"
+	ClientStream = "client_stream"
+	ServerStream = "server_stream"
+	BidiStream   = "bidi_stream"
+)
+
+// gRPC服务端的指标
+type ServerMetrics struct {
+	started  *CounterVec
+	handled  *CounterVec
+	latency  *HistogramVec
+	inFlight *GaugeVec
+	msgRecv  *CounterVec
+	msgSent  *CounterVec
+}
+
+func NewServerMetrics(reg *Registry) *ServerMetrics {
+	return &ServerMetrics{
+		started: reg.NewCounterVec("grpc_server_started_total",
+			"Total number of RPCs started on the server.",
+			"grpc_type", "grpc_service", "grpc_method"),
+		handled: reg.NewCounterVec("grpc_server_handled_total",
+			"Total number of RPCs completed on the server, regardless of success or failure.",
+			"grpc_type", "grpc_service", "grpc_method", "grpc_code"),
+		latency: reg.NewHistogramVec("grpc_server_handling_seconds",
+			"Histogram of response latency (seconds) of gRPC that had been application-level handled by the server.",
+			DefBuckets, "grpc_type", "grpc_service", "grpc_method"),
+		inFlight: reg.NewGaugeVec("grpc_server_streams_in_flight",
+			"Number of streaming RPCs currently being handled by the server.",
+			"grpc_type", "grpc_service", "grpc_method"),
+		msgRecv: reg.NewCounterVec("grpc_server_msg_received_total",
+			"Total number of stream messages received from the client.",
+			"grpc_type", "grpc_service", "grpc_method"),
+		msgSent: reg.NewCounterVec("grpc_server_msg_sent_total",
+			"Total number of stream messages sent by the server.",
+			"grpc_type", "grpc_service", "grpc_method"),
+	}
+}
+
+// 统计unary rpc的调用次数和延迟
+func (m *ServerMetrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		service, method := splitMethodName(info.FullMethod)
+		m.started.With(Unary, service, method).Inc()
+		start := time.Now()
+
+		res, err := handler(ctx, req)
+
+		m.latency.With(Unary, service, method).Observe(time.Since(start).Seconds())
+		m.handled.With(Unary, service, method, status.Code(err).String()).Inc()
+		return res, err
+	}
+}
+
+// 统计streaming rpc的调用次数、延迟、正在进行的流的数量以及收发的消息数量
+func (m *ServerMetrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		typ := streamType(info)
+		service, method := splitMethodName(info.FullMethod)
+		m.started.With(typ, service, method).Inc()
+		inFlight := m.inFlight.With(typ, service, method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+		start := time.Now()
+
+		err := handler(srv, &monitoredServerStream{
+			ServerStream: ss,
+			recv:         m.msgRecv.With(typ, service, method),
+			sent:         m.msgSent.With(typ, service, method),
+		})
+
+		m.latency.With(typ, service, method).Observe(time.Since(start).Seconds())
+		m.handled.With(typ, service, method, status.Code(err).String()).Inc()
+		return err
+	}
+}
+
+// 统计流中收发的每一条消息
+type monitoredServerStream struct {
+	grpc.ServerStream
+	recv *Counter
+	sent *Counter
+}
+
+func (s *monitoredServerStream) SendMsg(data interface{}) error {
+	err := s.ServerStream.SendMsg(data)
+	if err == nil {
+		s.sent.Inc()
+	}
+	return err
+}
+
+func (s *monitoredServerStream) RecvMsg(data interface{}) error {
+	err := s.ServerStream.RecvMsg(data)
+	if err == nil {
+		s.recv.Inc()
+	}
+	return err
+}
+
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return BidiStream
+	case info.IsClientStream:
+		return ClientStream
+	case info.IsServerStream:
+		return ServerStream
+	}
+	return Unary
+}
+
+// "/pb.CellphoneService/CreateCellphone" -> "pb.CellphoneService", "CreateCellphone"
+func splitMethodName(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
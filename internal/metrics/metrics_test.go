@@ -0,0 +1,156 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/metrics"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/internal/validate"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+func scrape(t *testing.T, reg *metrics.Registry) string {
+	recorder := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Contains(t, recorder.Header().Get("Content-Type"), "text/plain")
+	return recorder.Body.String()
+}
+
+func TestRegistryExposition(t *testing.T) {
+	t.Parallel()
+
+	reg := metrics.NewRegistry()
+	counter := reg.NewCounterVec("requests_total", "Total requests.", "method")
+	counter.With("b").Inc()
+	counter.With("a").Add(2)
+	gauge := reg.NewGauge("temperature", "Current temperature.")
+	gauge.Set(3.5)
+	gauge.Dec()
+	reg.NewGaugeFunc("answer", "The answer.", func() float64 { return 42 })
+	hist := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "path")
+	hist.With(`/a"b`).Observe(0.05)
+	hist.With(`/a"b`).Observe(0.5)
+	hist.With(`/a"b`).Observe(5)
+
+	expected := `# HELP requests_total Total requests.
+# TYPE requests_total counter
+requests_total{method="a"} 2
+requests_total{method="b"} 1
+# HELP temperature Current temperature.
+# TYPE temperature gauge
+temperature 2.5
+# HELP answer The answer.
+# TYPE answer gauge
+answer 42
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{path="/a\"b",le="0.1"} 1
+latency_seconds_bucket{path="/a\"b",le="1"} 2
+latency_seconds_bucket{path="/a\"b",le="+Inf"} 3
+latency_seconds_sum{path="/a\"b"} 5.55
+latency_seconds_count{path="/a\"b"} 3
+`
+	require.Equal(t, expected, scrape(t, reg))
+
+	// 同名的指标不能重复注册
+	require.Panics(t, func() { reg.NewCounter("requests_total", "dup") })
+	// counter不能减少
+	require.Panics(t, func() { counter.With("a").Add(-1) })
+}
+
+// 测试通过拦截器和Observer统计cellphone服务的指标
+func TestCellphoneServiceMetrics(t *testing.T) {
+	t.Parallel()
+
+	reg := metrics.NewRegistry()
+	serverMetrics := metrics.NewServerMetrics(reg)
+	saver := service.NewInMemoryCellphoneSaver()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(serverMetrics.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(serverMetrics.StreamServerInterceptor()),
+	)
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(
+		service.WithCellphoneSaver(saver),
+		service.WithObserver(metrics.NewCellphoneMetrics(reg, saver)),
+	))
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+	client := pb.NewCellphoneServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+	_, err = client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: &pb.Cellphone{Id: "invalid-uuid"}})
+	require.NotNil(t, err)
+
+	// 上传一张过大的图片，会被拒绝
+	upload, err := client.UploadCellphoneCover(ctx)
+	require.Nil(t, err)
+	require.Nil(t, upload.Send(&pb.UploadCellphoneCoverRequest{
+		Data: &pb.UploadCellphoneCoverRequest_Meta{
+			Meta: &pb.CoverMetaInfo{Id: res.Id, Size: validate.MaxCoverImageBytes + 1, ImageType: ".jpeg"},
+		},
+	}))
+	_, err = upload.CloseAndRecv()
+	require.NotNil(t, err)
+
+	buy, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	for _, price := range []float64{1000, 2000, 3000} {
+		require.Nil(t, buy.Send(&pb.BuyCellphoneRequest{Id: res.Id, Price: price}))
+		_, err := buy.Recv()
+		require.Nil(t, err)
+	}
+	require.Nil(t, buy.CloseSend())
+	_, err = buy.Recv()
+	require.Equal(t, io.EOF, err)
+
+	expectedLines := []string{
+		`grpc_server_handled_total{grpc_type="unary",grpc_service="pb.CellphoneService",grpc_method="CreateCellphone",grpc_code="OK"} 1`,
+		`grpc_server_handled_total{grpc_type="unary",grpc_service="pb.CellphoneService",grpc_method="CreateCellphone",grpc_code="InvalidArgument"} 1`,
+		`grpc_server_handled_total{grpc_type="client_stream",grpc_service="pb.CellphoneService",grpc_method="UploadCellphoneCover",grpc_code="OutOfRange"} 1`,
+		`grpc_server_handling_seconds_count{grpc_type="unary",grpc_service="pb.CellphoneService",grpc_method="CreateCellphone"} 2`,
+		`grpc_server_msg_received_total{grpc_type="bidi_stream",grpc_service="pb.CellphoneService",grpc_method="BuyCellphone"} 3`,
+		`grpc_server_msg_sent_total{grpc_type="bidi_stream",grpc_service="pb.CellphoneService",grpc_method="BuyCellphone"} 3`,
+		`cellphone_stored 1`,
+		`cellphone_created_total 1`,
+		`cellphone_orders_placed_total 3`,
+		`cellphone_orders_amount_total 6000`,
+		`cellphone_cover_rejections_total{grpc_code="OutOfRange"} 1`,
+	}
+
+	// 流式rpc的handler返回之后才会更新指标
+	require.Eventually(t, func() bool {
+		body := scrape(t, reg)
+		return strings.Contains(body, `grpc_server_handled_total{grpc_type="bidi_stream",grpc_service="pb.CellphoneService",grpc_method="BuyCellphone",grpc_code="OK"} 1`)
+	}, time.Second, 10*time.Millisecond)
+
+	body := scrape(t, reg)
+	for _, line := range expectedLines {
+		require.Contains(t, body, line+"\n")
+	}
+	require.Contains(t, body,
+		`grpc_server_streams_in_flight{grpc_type="bidi_stream",grpc_service="pb.CellphoneService",grpc_method="BuyCellphone"} 0`)
+}
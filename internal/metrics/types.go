@@ -0,0 +1,193 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// 只增不减的计数器
+type Counter struct {
+	v atomicFloat
+}
+
+func (c *Counter) Inc() {
+	c.v.add(1)
+}
+
+// v不能为负数
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter can not decrease")
+	}
+	c.v.add(v)
+}
+
+func (c *Counter) Value() float64 {
+	return c.v.load()
+}
+
+// 带有label的一组计数器
+type CounterVec struct {
+	*family[Counter]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	v := &CounterVec{newFamily(name, help, "counter", labels, func() *Counter { return &Counter{} })}
+	r.register(name, v)
+	return v
+}
+
+// 没有label的计数器
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).With()
+}
+
+// 按照label的取值获取计数器，label的取值个数要和定义时的一致
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.with(values...)
+}
+
+func (v *CounterVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	for _, s := range v.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, s.values), formatValue(s.metric.Value()))
+	}
+}
+
+// 可增可减的仪表盘
+type Gauge struct {
+	v atomicFloat
+}
+
+func (g *Gauge) Set(v float64) {
+	g.v.set(v)
+}
+
+func (g *Gauge) Inc() {
+	g.v.add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.add(-1)
+}
+
+func (g *Gauge) Add(v float64) {
+	g.v.add(v)
+}
+
+func (g *Gauge) Value() float64 {
+	return g.v.load()
+}
+
+// 带有label的一组仪表盘
+type GaugeVec struct {
+	*family[Gauge]
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	v := &GaugeVec{newFamily(name, help, "gauge", labels, func() *Gauge { return &Gauge{} })}
+	r.register(name, v)
+	return v
+}
+
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.NewGaugeVec(name, help).With()
+}
+
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.with(values...)
+}
+
+func (v *GaugeVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	for _, s := range v.sorted() {
+		fmt.Fprintf(w, "%s%s %s\n", v.name, formatLabels(v.labels, s.values), formatValue(s.metric.Value()))
+	}
+}
+
+// 在输出时才调用函数取值的仪表盘
+type gaugeFunc struct {
+	desc
+	fn func() float64
+}
+
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &gaugeFunc{desc: desc{name: name, help: help, typ: "gauge"}, fn: fn})
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	g.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", g.name, formatValue(g.fn()))
+}
+
+// 直方图，统计落在各个分桶中的观测值的数量
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+func (h *Histogram) Observe(v float64) {
+	// 找到第一个上界不小于v的分桶
+	idx := sort.SearchFloat64s(h.buckets, v)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if idx < len(h.counts) {
+		h.counts[idx]++
+	}
+	h.count++
+	h.sum += v
+}
+
+// 返回观测值的总数和总和
+func (h *Histogram) Snapshot() (count uint64, sum float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.count, h.sum
+}
+
+// 带有label的一组直方图
+type HistogramVec struct {
+	*family[Histogram]
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	v := &HistogramVec{newFamily(name, help, "histogram", labels, func() *Histogram {
+		return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+	})}
+	r.register(name, v)
+	return v
+}
+
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.with(values...)
+}
+
+func (v *HistogramVec) write(w *bufio.Writer) {
+	v.writeHeader(w)
+	for _, s := range v.sorted() {
+		h := s.metric
+		h.mu.Lock()
+		// 分桶是累积的，每个桶包含所有小于等于上界的观测值
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", v.name,
+				formatLabels(v.labels, s.values, "le", formatValue(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", v.name, formatLabels(v.labels, s.values, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", v.name, formatLabels(v.labels, s.values), formatValue(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", v.name, formatLabels(v.labels, s.values), h.count)
+		h.mu.Unlock()
+	}
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// 令牌桶
+// 每秒补充rate个令牌，最多存放burst个令牌，每次请求消耗一个令牌
+// 不是并发安全的，由Limiter加锁保护
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int, now time.Time) *bucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+// 补充从上次到now这段时间内产生的令牌
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// 尝试取出一个令牌，取不到时返回需要等待的时间
+func (b *bucket) take(now time.Time) (bool, time.Duration) {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := (1 - b.tokens) / b.rate
+	return false, time.Duration(math.Ceil(wait * float64(time.Second)))
+}
+
+// 令牌桶已经装满，和新建的桶没有区别，可以回收
+func (b *bucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
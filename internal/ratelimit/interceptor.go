@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// 限流拦截器的选项
+type Options struct {
+	// 不受限流和流数量上限限制的方法，比如健康检查
+	ExemptMethods []string
+}
+
+func (o Options) exempt() map[string]struct{} {
+	exempt := make(map[string]struct{}, len(o.ExemptMethods))
+	for _, m := range o.ExemptMethods {
+		exempt[m] = struct{}{}
+	}
+	return exempt
+}
+
+// 被限流时在trailer中告诉客户端多少秒之后重试
+const RetryAfterMetadataKey = "retry-after"
+
+// 流的数量超过上限时建议的重试间隔，无法预测已有的流什么时候结束，所以给一个较短的固定值
+const streamRetryAfter = time.Second
+
+// 返回ResourceExhausted错误，并附带RetryInfo
+func exhausted(msg string, retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, msg)
+	if retryAfter > 0 {
+		if withDetails, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)}); err == nil {
+			st = withDetails
+		}
+	}
+	return st.Err()
+}
+
+// 向上取整到秒，至少为1秒
+func retryAfterTrailer(retryAfter time.Duration) metadata.MD {
+	seconds := int64(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return metadata.Pairs(RetryAfterMetadataKey, strconv.FormatInt(seconds, 10))
+}
+
+// unary rpc的限流拦截器，需要安装在认证拦截器之后才能按照principal限流
+func UnaryServerInterceptor(l *Limiter, opts Options) grpc.UnaryServerInterceptor {
+	exempt := opts.exempt()
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		if _, ok := exempt[info.FullMethod]; ok {
+			return handler(ctx, req)
+		}
+		if ok, retryAfter := l.Allow(ClientKey(ctx), info.FullMethod); !ok {
+			grpc.SetTrailer(ctx, retryAfterTrailer(retryAfter))
+			return nil, exhausted("rate limit exceeded for "+info.FullMethod, retryAfter)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streaming rpc的限流拦截器
+// 限制同时打开的流的数量，打开流时消耗一个令牌，
+// 规则中开启了PerMessage时，流中每接收一条消息也会消耗一个令牌
+func StreamServerInterceptor(l *Limiter, opts Options) grpc.StreamServerInterceptor {
+	exempt := opts.exempt()
+	return func(srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		if _, ok := exempt[info.FullMethod]; ok {
+			return handler(srv, ss)
+		}
+		client := ClientKey(ss.Context())
+		release, ok := l.AcquireStream(client)
+		if !ok {
+			ss.SetTrailer(retryAfterTrailer(streamRetryAfter))
+			return exhausted("too many concurrent streams", streamRetryAfter)
+		}
+		defer release()
+
+		if ok, retryAfter := l.Allow(client, info.FullMethod); !ok {
+			ss.SetTrailer(retryAfterTrailer(retryAfter))
+			return exhausted("rate limit exceeded for "+info.FullMethod, retryAfter)
+		}
+
+		if info.IsClientStream && l.rule(info.FullMethod).PerMessage {
+			ss = &limitedServerStream{ServerStream: ss, limiter: l, client: client, method: info.FullMethod}
+		}
+		return handler(srv, ss)
+	}
+}
+
+// 每接收一条消息消耗一个令牌
+type limitedServerStream struct {
+	grpc.ServerStream
+	limiter *Limiter
+	client  string
+	method  string
+}
+
+func (s *limitedServerStream) RecvMsg(data interface{}) error {
+	if err := s.ServerStream.RecvMsg(data); err != nil {
+		return err
+	}
+	if ok, retryAfter := s.limiter.Allow(s.client, s.method); !ok {
+		s.SetTrailer(retryAfterTrailer(retryAfter))
+		return exhausted("rate limit exceeded for messages of "+s.method, retryAfter)
+	}
+	return nil
+}
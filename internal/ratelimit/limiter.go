@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/peer"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+)
+
+// 多久清理一次空闲的令牌桶
+const sweepInterval = time.Minute
+
+// 按照调用方和方法进行限流，并且限制每个调用方同时打开的流的数量
+type Limiter struct {
+	cfg config.RateLimitConfig
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	streams   map[string]int
+	lastSweep time.Time
+}
+
+type bucketKey struct {
+	client string
+	method string
+}
+
+func New(cfg config.RateLimitConfig) *Limiter {
+	return &Limiter{
+		cfg:       cfg,
+		buckets:   make(map[bucketKey]*bucket),
+		streams:   make(map[string]int),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *Limiter) rule(fullMethod string) config.RateLimitRule {
+	if r, ok := l.cfg.Methods[fullMethod]; ok {
+		return r
+	}
+	return l.cfg.Default
+}
+
+// 调用方消耗一个令牌，被限流时返回需要等待的时间
+func (l *Limiter) Allow(client, fullMethod string) (bool, time.Duration) {
+	r := l.rule(fullMethod)
+	if r.Rate <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+	key := bucketKey{client: client, method: fullMethod}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = newBucket(r.Rate, r.Burst, now)
+		l.buckets[key] = b
+	}
+	return b.take(now)
+}
+
+// 清理已经装满的令牌桶，避免map无限增长
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for k, b := range l.buckets {
+		if b.full(now) {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// 调用方打开一条流，超过上限时返回false
+// 成功时返回的release函数必须在流结束时调用
+func (l *Limiter) AcquireStream(client string) (release func(), ok bool) {
+	if l.cfg.MaxConcurrentStreams <= 0 {
+		return func() {}, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.streams[client] >= l.cfg.MaxConcurrentStreams {
+		return nil, false
+	}
+	l.streams[client]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.streams[client]--; l.streams[client] <= 0 {
+				delete(l.streams, client)
+			}
+		})
+	}, true
+}
+
+// 限流时区分调用方的key
+// 通过认证的调用方使用principal，否则使用对端的ip
+func ClientKey(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return "principal:" + p.Subject
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr := p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			addr = host
+		}
+		return "peer:" + addr
+	}
+	return "unknown"
+}
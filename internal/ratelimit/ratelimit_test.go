@@ -0,0 +1,249 @@
+package ratelimit_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/config"
+	"github.com/ryanreadbooks/go-grpc-example/internal/custom"
+	"github.com/ryanreadbooks/go-grpc-example/internal/ratelimit"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const (
+	unaryMethod = "/pb.CustomService/CallWithUnaryInterceptor"
+	buyMethod   = "/pb.CellphoneService/BuyCellphone"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	t.Parallel()
+
+	l := ratelimit.New(config.RateLimitConfig{
+		Default: config.RateLimitRule{Rate: 20, Burst: 2},
+		Methods: map[string]config.RateLimitRule{
+			"/unlimited": {Rate: 0},
+		},
+	})
+
+	// 桶里一开始有burst个令牌
+	for i := 0; i < 2; i++ {
+		ok, _ := l.Allow("client-a", "/limited")
+		require.True(t, ok)
+	}
+	ok, retryAfter := l.Allow("client-a", "/limited")
+	require.False(t, ok)
+	require.Greater(t, retryAfter, time.Duration(0))
+	require.LessOrEqual(t, retryAfter, 50*time.Millisecond)
+
+	// 不同的调用方互不影响
+	ok, _ = l.Allow("client-b", "/limited")
+	require.True(t, ok)
+
+	// 不限流的方法
+	for i := 0; i < 100; i++ {
+		ok, _ := l.Allow("client-a", "/unlimited")
+		require.True(t, ok)
+	}
+
+	// 等待令牌补充
+	time.Sleep(retryAfter)
+	ok, _ = l.Allow("client-a", "/limited")
+	require.True(t, ok)
+}
+
+func TestLimiterAcquireStream(t *testing.T) {
+	t.Parallel()
+
+	l := ratelimit.New(config.RateLimitConfig{MaxConcurrentStreams: 2})
+
+	release1, ok := l.AcquireStream("client")
+	require.True(t, ok)
+	release2, ok := l.AcquireStream("client")
+	require.True(t, ok)
+	_, ok = l.AcquireStream("client")
+	require.False(t, ok)
+
+	release1()
+	release1() // 重复调用不会多释放
+	release3, ok := l.AcquireStream("client")
+	require.True(t, ok)
+	_, ok = l.AcquireStream("client")
+	require.False(t, ok)
+
+	release2()
+	release3()
+}
+
+// 测试中运行带有限流拦截器的server
+func runTestRateLimitServer(t *testing.T, cfg config.RateLimitConfig) (*grpc.Server, net.Listener) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口监听
+	require.Nil(t, err)
+
+	limiter := ratelimit.New(cfg)
+	opts := ratelimit.Options{ExemptMethods: []string{healthpb.Health_Check_FullMethodName, healthpb.Health_Watch_FullMethodName}}
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(ratelimit.UnaryServerInterceptor(limiter, opts)),
+		grpc.StreamInterceptor(ratelimit.StreamServerInterceptor(limiter, opts)),
+	)
+	pb.RegisterCustomServiceServer(server, custom.NewCustomServiceServer())
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer())
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+
+	return server, listener
+}
+
+func makeTestCustomServiceClient(t *testing.T, addr string) (pb.CustomServiceClient, *grpc.ClientConn) {
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	return pb.NewCustomServiceClient(conn), conn
+}
+
+func TestRateLimitUnaryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	server, listener := runTestRateLimitServer(t, config.RateLimitConfig{
+		Methods: map[string]config.RateLimitRule{
+			unaryMethod: {Rate: 0.1, Burst: 2},
+		},
+	})
+	defer server.GracefulStop()
+
+	client, conn := makeTestCustomServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	for i := 0; i < 2; i++ {
+		_, err := client.CallWithUnaryInterceptor(context.Background(), &pb.SimpleRequest{Id: "ok"})
+		require.Nil(t, err)
+	}
+
+	var trailer metadata.MD
+	_, err := client.CallWithUnaryInterceptor(context.Background(), &pb.SimpleRequest{Id: "limited"}, grpc.Trailer(&trailer))
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"10"}, trailer.Get(ratelimit.RetryAfterMetadataKey))
+
+	// 错误中携带了RetryInfo
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	retryInfo, ok := details[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.Greater(t, retryInfo.RetryDelay.AsDuration(), 9*time.Second)
+
+	// 其它方法不受影响
+	_, err = client.CallWithUnaryInterceptor2(context.Background(), &pb.SimpleRequest{Id: "ok"})
+	require.Nil(t, err)
+}
+
+func TestRateLimitStreamInterceptor(t *testing.T) {
+	t.Parallel()
+
+	server, listener := runTestRateLimitServer(t, config.RateLimitConfig{
+		Methods: map[string]config.RateLimitRule{
+			buyMethod: {Rate: 0.1, Burst: 3, PerMessage: true},
+		},
+		MaxConcurrentStreams: 1,
+	})
+	defer server.GracefulStop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+	client := pb.NewCellphoneServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	res, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+
+	stream, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	// 等服务端确认流已经建立
+	require.Nil(t, stream.Send(&pb.BuyCellphoneRequest{Id: res.Id, Price: 1000}))
+	_, err = stream.Recv()
+	require.Nil(t, err)
+
+	// 同时只能打开一条流
+	second, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	_, err = second.Recv()
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"1"}, second.Trailer().Get(ratelimit.RetryAfterMetadataKey))
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	retryInfo, ok := details[0].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	require.Equal(t, time.Second, retryInfo.RetryDelay.AsDuration())
+
+	// 打开流和第一条消息各消耗了一个令牌，第二条消息消耗最后一个，第三条消息被限流
+	require.Nil(t, stream.Send(&pb.BuyCellphoneRequest{Id: res.Id, Price: 2000}))
+	_, err = stream.Recv()
+	require.Nil(t, err)
+	if err := stream.Send(&pb.BuyCellphoneRequest{Id: res.Id, Price: 3000}); err != nil {
+		require.Equal(t, io.EOF, err)
+	}
+	_, err = stream.Recv()
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"10"}, stream.Trailer().Get(ratelimit.RetryAfterMetadataKey))
+}
+
+func TestRateLimitExemptMethods(t *testing.T) {
+	t.Parallel()
+
+	server, listener := runTestRateLimitServer(t, config.RateLimitConfig{
+		Default:              config.RateLimitRule{Rate: 0.1, Burst: 1},
+		MaxConcurrentStreams: 1,
+	})
+	defer server.GracefulStop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+	client := pb.NewCellphoneServiceClient(conn)
+	healthClient := healthpb.NewHealthClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	created, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+
+	// 打开的流占满了上限
+	stream, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	require.Nil(t, stream.Send(&pb.BuyCellphoneRequest{Id: created.Id, Price: 1000}))
+	_, err = stream.Recv()
+	require.Nil(t, err)
+	second, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	_, err = second.Recv()
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// 健康检查不受流数量上限和默认规则的限制
+	watch, err := healthClient.Watch(ctx, &healthpb.HealthCheckRequest{})
+	require.Nil(t, err)
+	res, err := watch.Recv()
+	require.Nil(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+
+	for i := 0; i < 3; i++ {
+		res, err := healthClient.Check(ctx, &healthpb.HealthCheckRequest{})
+		require.Nil(t, err)
+		require.Equal(t, healthpb.HealthCheckResponse_SERVING, res.Status)
+	}
+}
@@ -0,0 +1,88 @@
+package rpcerr
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 客户端从错误中解析出来的详情
+type Details struct {
+	Code    codes.Code
+	Message string
+	// ErrorInfo中的reason，没有ErrorInfo时为ERROR_REASON_UNSPECIFIED
+	Reason pb.ErrorReason
+
+	ErrorInfo    *errdetails.ErrorInfo
+	BadRequest   *errdetails.BadRequest
+	ResourceInfo *errdetails.ResourceInfo
+	QuotaFailure *errdetails.QuotaFailure
+	RetryInfo    *errdetails.RetryInfo
+}
+
+// 解析gRPC错误中携带的详情，err为nil时返回nil
+func Decode(err error) *Details {
+	if err == nil {
+		return nil
+	}
+	st := status.Convert(err)
+	d := &Details{Code: st.Code(), Message: st.Message()}
+	for _, detail := range st.Details() {
+		switch v := detail.(type) {
+		case *errdetails.ErrorInfo:
+			d.ErrorInfo = v
+			if v.Domain == Domain {
+				d.Reason = pb.ErrorReason(pb.ErrorReason_value[v.Reason])
+			}
+		case *errdetails.BadRequest:
+			d.BadRequest = v
+		case *errdetails.ResourceInfo:
+			d.ResourceInfo = v
+		case *errdetails.QuotaFailure:
+			d.QuotaFailure = v
+		case *errdetails.RetryInfo:
+			d.RetryInfo = v
+		}
+	}
+	return d
+}
+
+// 返回错误的原因
+func ReasonOf(err error) pb.ErrorReason {
+	if d := Decode(err); d != nil {
+		return d.Reason
+	}
+	return pb.ErrorReason_ERROR_REASON_UNSPECIFIED
+}
+
+// 便于在命令行中打印
+func (d *Details) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "code=%s", d.Code)
+	if d.Reason != pb.ErrorReason_ERROR_REASON_UNSPECIFIED {
+		fmt.Fprintf(&sb, " reason=%s", d.Reason)
+	}
+	fmt.Fprintf(&sb, " message=%q", d.Message)
+	if d.ResourceInfo != nil {
+		fmt.Fprintf(&sb, " resource=%s/%s", d.ResourceInfo.ResourceType, d.ResourceInfo.ResourceName)
+	}
+	if d.BadRequest != nil {
+		for _, v := range d.BadRequest.FieldViolations {
+			fmt.Fprintf(&sb, "\n  field %s: %s", v.Field, v.Description)
+		}
+	}
+	if d.QuotaFailure != nil {
+		for _, v := range d.QuotaFailure.Violations {
+			fmt.Fprintf(&sb, "\n  quota %s: %s", v.Subject, v.Description)
+		}
+	}
+	if d.RetryInfo != nil {
+		fmt.Fprintf(&sb, "\n  retry after %s", d.RetryInfo.RetryDelay.AsDuration())
+	}
+	return sb.String()
+}
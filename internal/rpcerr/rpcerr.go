@@ -0,0 +1,152 @@
+package rpcerr
+
+// 构造带有详情（google.rpc.Status.details）的gRPC错误
+// 参考：https://cloud.google.com/apis/design/errors
+
+import (
+	"fmt"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// ErrorInfo中的domain
+const Domain = "cellphone.go-grpc-example"
+
+// 手机资源在ResourceInfo中的类型
+const CellphoneResourceType = "pb.Cellphone"
+
+// 创建带有ErrorInfo的错误，details会附加在ErrorInfo之后
+func New(code codes.Code, reason pb.ErrorReason, msg string, metadata map[string]string,
+	details ...protoadapt.MessageV1) error {
+
+	st := status.New(code, msg)
+	all := make([]protoadapt.MessageV1, 0, len(details)+1)
+	all = append(all, &errdetails.ErrorInfo{
+		Reason:   reason.String(),
+		Domain:   Domain,
+		Metadata: metadata,
+	})
+	all = append(all, details...)
+	if withDetails, err := st.WithDetails(all...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// 请求中的字段不合法
+func InvalidArgument(msg string, violations []*errdetails.BadRequest_FieldViolation) error {
+	return New(codes.InvalidArgument, pb.ErrorReason_INVALID_REQUEST, msg, nil,
+		&errdetails.BadRequest{FieldViolations: violations})
+}
+
+// field字段中的uuid不合法
+func InvalidUUID(field, id string) error {
+	return New(codes.InvalidArgument, pb.ErrorReason_INVALID_UUID,
+		fmt.Sprintf("invalid uuid: %q", id),
+		map[string]string{"field": field},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: field, Description: "must be a valid uuid"},
+		}})
+}
+
+// 手机不存在
+func CellphoneNotFound(id string) error {
+	return New(codes.NotFound, pb.ErrorReason_CELLPHONE_NOT_FOUND,
+		fmt.Sprintf("cellphone with %s not found", id),
+		map[string]string{"id": id},
+		&errdetails.ResourceInfo{
+			ResourceType: CellphoneResourceType,
+			ResourceName: id,
+			Description:  "cellphone does not exist",
+		})
+}
+
+// 手机已经存在
+func CellphoneAlreadyExists(id string) error {
+	return New(codes.AlreadyExists, pb.ErrorReason_CELLPHONE_ALREADY_EXISTS,
+		fmt.Sprintf("cellphone with %s already exists", id),
+		map[string]string{"id": id},
+		&errdetails.ResourceInfo{
+			ResourceType: CellphoneResourceType,
+			ResourceName: id,
+			Description:  "cellphone with the same id already exists",
+		})
+}
+
+// 封面图片超过了大小限制
+func CoverTooLarge(id string, size, limit uint32) error {
+	return New(codes.OutOfRange, pb.ErrorReason_COVER_TOO_LARGE,
+		fmt.Sprintf("provided cover image is larger than %d bytes", limit),
+		map[string]string{
+			"id":          id,
+			"size_bytes":  strconv.FormatUint(uint64(size), 10),
+			"limit_bytes": strconv.FormatUint(uint64(limit), 10),
+		},
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{
+			{
+				Subject:     "cellphone:" + id,
+				Description: fmt.Sprintf("cover image of %d bytes exceeds the limit of %d bytes", size, limit),
+			},
+		}})
+}
+
+// 服务端保存数据失败
+func StorageFailure(err error) error {
+	return New(codes.Internal, pb.ErrorReason_STORAGE_FAILURE, err.Error(), nil)
+}
+
+// 要求从已经被清理的revision开始订阅，客户端需要重新全量同步
+func RevisionCompacted(since, oldest uint64) error {
+	return New(codes.OutOfRange, pb.ErrorReason_REVISION_COMPACTED,
+		fmt.Sprintf("revision %d has been compacted, oldest available revision is %d", since, oldest),
+		map[string]string{
+			"since_revision":  strconv.FormatUint(since, 10),
+			"oldest_revision": strconv.FormatUint(oldest, 10),
+		})
+}
+
+// 订阅者处理变化的速度太慢被断开，客户端可以从revision继续订阅
+func WatcherTooSlow(revision uint64) error {
+	return New(codes.Aborted, pb.ErrorReason_WATCHER_TOO_SLOW,
+		"watcher can not keep up with changes, resume from the last received revision",
+		map[string]string{"revision": strconv.FormatUint(revision, 10)})
+}
+
+// 批量请求中的数量超过了上限
+func BatchTooLarge(limit int) error {
+	return New(codes.OutOfRange, pb.ErrorReason_BATCH_TOO_LARGE,
+		fmt.Sprintf("batch contains more than %d items", limit),
+		map[string]string{"limit": strconv.Itoa(limit)})
+}
+
+// 手机没有上传过封面图片
+func CoverNotFound(id string) error {
+	return New(codes.NotFound, pb.ErrorReason_COVER_NOT_FOUND,
+		fmt.Sprintf("cover of cellphone %s not found", id),
+		map[string]string{"id": id},
+		&errdetails.ResourceInfo{
+			ResourceType: CellphoneResourceType,
+			ResourceName: id,
+			Description:  "cellphone has no cover image",
+		})
+}
+
+// 重试的请求没有携带幂等键
+func IdempotencyKeyRequired(attempts string) error {
+	return New(codes.FailedPrecondition, pb.ErrorReason_IDEMPOTENCY_KEY_REQUIRED,
+		"retried buy requests must carry an idempotency key",
+		map[string]string{"previous_attempts": attempts})
+}
+
+// 幂等键已经被内容不同的请求使用过
+func IdempotencyKeyReused(key string) error {
+	return New(codes.FailedPrecondition, pb.ErrorReason_IDEMPOTENCY_KEY_REUSED,
+		fmt.Sprintf("idempotency key %s was used by a different request", key),
+		map[string]string{"idempotency_key": key})
+}
@@ -0,0 +1,129 @@
+package rpcerr_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const testId = "9b2f1c5e-0d4f-4f4e-9a55-3f1c9c7a0e11"
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Name   string
+		Err    error
+		Code   codes.Code
+		Reason pb.ErrorReason
+		Check  func(t *testing.T, d *rpcerr.Details)
+	}{
+		{
+			Name:   "not-found",
+			Err:    rpcerr.CellphoneNotFound(testId),
+			Code:   codes.NotFound,
+			Reason: pb.ErrorReason_CELLPHONE_NOT_FOUND,
+			Check: func(t *testing.T, d *rpcerr.Details) {
+				require.Equal(t, rpcerr.CellphoneResourceType, d.ResourceInfo.ResourceType)
+				require.Equal(t, testId, d.ResourceInfo.ResourceName)
+				require.Equal(t, testId, d.ErrorInfo.Metadata["id"])
+			},
+		},
+		{
+			Name:   "already-exists",
+			Err:    rpcerr.CellphoneAlreadyExists(testId),
+			Code:   codes.AlreadyExists,
+			Reason: pb.ErrorReason_CELLPHONE_ALREADY_EXISTS,
+			Check: func(t *testing.T, d *rpcerr.Details) {
+				require.Equal(t, testId, d.ResourceInfo.ResourceName)
+			},
+		},
+		{
+			Name:   "invalid-uuid",
+			Err:    rpcerr.InvalidUUID("id", "invalid-uuid"),
+			Code:   codes.InvalidArgument,
+			Reason: pb.ErrorReason_INVALID_UUID,
+			Check: func(t *testing.T, d *rpcerr.Details) {
+				require.Len(t, d.BadRequest.FieldViolations, 1)
+				require.Equal(t, "id", d.BadRequest.FieldViolations[0].Field)
+			},
+		},
+		{
+			Name:   "cover-too-large",
+			Err:    rpcerr.CoverTooLarge(testId, 2048, 1024),
+			Code:   codes.OutOfRange,
+			Reason: pb.ErrorReason_COVER_TOO_LARGE,
+			Check: func(t *testing.T, d *rpcerr.Details) {
+				require.Len(t, d.QuotaFailure.Violations, 1)
+				require.Equal(t, "cellphone:"+testId, d.QuotaFailure.Violations[0].Subject)
+				require.Equal(t, "2048", d.ErrorInfo.Metadata["size_bytes"])
+				require.Equal(t, "1024", d.ErrorInfo.Metadata["limit_bytes"])
+			},
+		},
+		{
+			Name:   "storage-failure",
+			Err:    rpcerr.StorageFailure(errors.New("disk full")),
+			Code:   codes.Internal,
+			Reason: pb.ErrorReason_STORAGE_FAILURE,
+			Check: func(t *testing.T, d *rpcerr.Details) {
+				require.Equal(t, "disk full", d.Message)
+			},
+		},
+		{
+			// 没有携带详情的错误也能解析
+			Name:   "plain-status",
+			Err:    status.Error(codes.Unavailable, "unavailable"),
+			Code:   codes.Unavailable,
+			Reason: pb.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			Check: func(t *testing.T, d *rpcerr.Details) {
+				require.Nil(t, d.ErrorInfo)
+			},
+		},
+		{
+			// 其它domain的ErrorInfo不解析reason
+			Name: "foreign-domain",
+			Err: func() error {
+				st, _ := status.New(codes.NotFound, "x").WithDetails(&errdetails.ErrorInfo{
+					Reason: pb.ErrorReason_CELLPHONE_NOT_FOUND.String(),
+					Domain: "example.com",
+				})
+				return st.Err()
+			}(),
+			Code:   codes.NotFound,
+			Reason: pb.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			Check: func(t *testing.T, d *rpcerr.Details) {
+				require.Equal(t, "example.com", d.ErrorInfo.Domain)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			d := rpcerr.Decode(tc.Err)
+			require.NotNil(t, d)
+			require.Equal(t, tc.Code, d.Code)
+			require.Equal(t, tc.Reason, d.Reason)
+			require.Equal(t, tc.Reason, rpcerr.ReasonOf(tc.Err))
+			tc.Check(t, d)
+			require.NotEmpty(t, d.String())
+		})
+	}
+
+	require.Nil(t, rpcerr.Decode(nil))
+}
+
+func TestDetailsString(t *testing.T) {
+	t.Parallel()
+
+	s := rpcerr.Decode(rpcerr.InvalidUUID("meta.id", "bad")).String()
+	require.Contains(t, s, "code=InvalidArgument")
+	require.Contains(t, s, "reason=INVALID_UUID")
+	require.Contains(t, s, "field meta.id: must be a valid uuid")
+}
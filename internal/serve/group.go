@@ -0,0 +1,179 @@
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// 在listener上提供服务，并且可以优雅地关闭
+type Server interface {
+	Serve(l net.Listener) error
+	// 停止接收新的连接，等待已有的请求结束；ctx结束时强制关闭
+	Shutdown(ctx context.Context) error
+}
+
+// gRPC服务，多个listener可以共用同一个grpc.Server
+func GRPC(s *grpc.Server) Server {
+	return &grpcServer{s: s}
+}
+
+type grpcServer struct {
+	s    *grpc.Server
+	once sync.Once
+	err  error
+}
+
+func (g *grpcServer) Serve(l net.Listener) error {
+	return g.s.Serve(l)
+}
+
+func (g *grpcServer) Shutdown(ctx context.Context) error {
+	g.once.Do(func() {
+		done := make(chan struct{})
+		go func() {
+			g.s.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			// 还有没有结束的流，比如watch
+			g.s.Stop()
+			<-done
+			g.err = ctx.Err()
+		}
+	})
+	return g.err
+}
+
+// HTTP服务，多个listener可以共用同一个http.Server
+func HTTP(s *http.Server) Server {
+	return &httpServer{s: s}
+}
+
+type httpServer struct {
+	s    *http.Server
+	once sync.Once
+	err  error
+}
+
+func (h *httpServer) Serve(l net.Listener) error {
+	if err := h.s.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (h *httpServer) Shutdown(ctx context.Context) error {
+	h.once.Do(func() {
+		if h.err = h.s.Shutdown(ctx); h.err != nil {
+			h.s.Close()
+		}
+	})
+	return h.err
+}
+
+type entry struct {
+	name     string
+	listener net.Listener
+	server   Server
+}
+
+// 一起启动、一起关闭的一组服务
+// 任何一个服务出错退出时，其它的服务也被关闭
+type Group struct {
+	// 关闭时等待已有请求结束的最长时间，0表示一直等待
+	ShutdownTimeout time.Duration
+
+	entries    []entry
+	onShutdown []func()
+}
+
+// 注册在关闭服务之前调用的函数，比如把健康状态设置为不可用
+func (g *Group) RegisterOnShutdown(f func()) {
+	g.onShutdown = append(g.onShutdown, f)
+}
+
+// 添加一个服务，name只用于日志和错误信息
+func (g *Group) Add(name string, l net.Listener, s Server) {
+	g.entries = append(g.entries, entry{name: name, listener: l, server: s})
+}
+
+// 按照addr监听并添加服务，出错时关闭已经添加的listener
+func (g *Group) Listen(name, addr string, s Server) error {
+	l, err := Listen(addr)
+	if err != nil {
+		g.Close()
+		return fmt.Errorf("can not listen %s on %s: %w", name, addr, err)
+	}
+	g.Add(name, l, s)
+	return nil
+}
+
+// 关闭所有的listener，用于还没有Run就出错的情况
+func (g *Group) Close() {
+	for _, e := range g.entries {
+		e.listener.Close()
+	}
+}
+
+// 启动所有的服务，直到ctx结束或者有服务出错，然后关闭所有的服务
+// 返回第一个出错的服务的错误，ctx结束时返回nil
+func (g *Group) Run(ctx context.Context) error {
+	errc := make(chan error, len(g.entries))
+	for _, e := range g.entries {
+		e := e
+		slog.Info("listening", "name", e.name,
+			"network", e.listener.Addr().Network(), "addr", e.listener.Addr().String())
+		go func() {
+			err := e.server.Serve(e.listener)
+			if err != nil {
+				err = fmt.Errorf("%s stopped: %w", e.name, err)
+			}
+			errc <- err
+		}()
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-errc:
+	}
+	g.shutdown()
+	return err
+}
+
+// 同时关闭所有的服务
+func (g *Group) shutdown() {
+	for _, f := range g.onShutdown {
+		f()
+	}
+	ctx := context.Background()
+	if g.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, g.ShutdownTimeout)
+		defer cancel()
+	}
+	var wg sync.WaitGroup
+	for _, e := range g.entries {
+		e := e
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.server.Shutdown(ctx); err != nil {
+				slog.Warn("shutdown is not graceful", "name", e.name, "error", err)
+			}
+			// grpc.Server只关闭自己Serve过的listener
+			e.listener.Close()
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,69 @@
+package serve
+
+// 监听地址和多个服务的统一启动、关闭
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strings"
+)
+
+// unix socket地址的前缀，比如unix:///run/cellphone.sock或者unix:cellphone.sock
+const unixPrefix = "unix:"
+
+// 解析监听地址，返回network和address
+// host:port为tcp地址，unix:PATH或者unix://PATH为unix socket
+func ParseAddr(addr string) (network, address string, err error) {
+	if path, ok := strings.CutPrefix(addr, unixPrefix); ok {
+		// unix:///tmp/a.sock和unix:/tmp/a.sock都表示/tmp/a.sock
+		if rest, ok := strings.CutPrefix(path, "//"); ok {
+			path = rest
+		}
+		if path == "" {
+			return "", "", fmt.Errorf("invalid unix socket address %q", addr)
+		}
+		return "unix", path, nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", "", fmt.Errorf("invalid address %q: %w", addr, err)
+	}
+	return "tcp", addr, nil
+}
+
+// 监听tcp地址或者unix socket
+// 上次没有正常退出而留下的socket文件会被删除，关闭listener时删除socket文件
+func Listen(addr string) (net.Listener, error) {
+	network, address, err := ParseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		if err := removeStaleSocket(address); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen(network, address)
+}
+
+// 只删除socket文件，其它文件说明地址配置错了
+func removeStaleSocket(path string) error {
+	stat, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if stat.Mode().Type() != fs.ModeSocket {
+		return fmt.Errorf("can not listen on %s: file exists and is not a socket", path)
+	}
+	// 还有服务在监听时不能删除
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("can not listen on %s: address already in use", path)
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,184 @@
+package serve_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/serve"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// unix socket的路径长度有限制，不使用t.TempDir()
+func socketDir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "serve")
+	require.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestParseAddr(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Name    string
+		Addr    string
+		Network string
+		Address string
+		Invalid bool
+	}{
+		{Name: "tcp", Addr: "127.0.0.1:9527", Network: "tcp", Address: "127.0.0.1:9527"},
+		{Name: "tcp any host", Addr: ":9527", Network: "tcp", Address: ":9527"},
+		{Name: "unix url", Addr: "unix:///run/cellphone.sock", Network: "unix", Address: "/run/cellphone.sock"},
+		{Name: "unix absolute", Addr: "unix:/run/cellphone.sock", Network: "unix", Address: "/run/cellphone.sock"},
+		{Name: "unix relative", Addr: "unix:cellphone.sock", Network: "unix", Address: "cellphone.sock"},
+		{Name: "unix without path", Addr: "unix://", Invalid: true},
+		{Name: "missing port", Addr: "127.0.0.1", Invalid: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			network, address, err := serve.ParseAddr(tc.Addr)
+			if tc.Invalid {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.Network, network)
+			require.Equal(t, tc.Address, address)
+		})
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	t.Parallel()
+
+	dir := socketDir(t)
+	path := filepath.Join(dir, "a.sock")
+
+	l, err := serve.Listen("unix://" + path)
+	require.Nil(t, err)
+	// 正在使用的socket不能被替换
+	_, err = serve.Listen("unix://" + path)
+	require.NotNil(t, err)
+	l.Close()
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+
+	// 上次没有正常退出留下的socket文件
+	stale, err := net.Listen("unix", path)
+	require.Nil(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+	l, err = serve.Listen("unix://" + path)
+	require.Nil(t, err)
+	l.Close()
+
+	// 不是socket的文件不会被删除
+	regular := filepath.Join(dir, "regular")
+	require.Nil(t, os.WriteFile(regular, []byte("data"), 0o644))
+	_, err = serve.Listen("unix://" + regular)
+	require.NotNil(t, err)
+	_, err = os.Stat(regular)
+	require.Nil(t, err)
+}
+
+func dialCellphone(t *testing.T, target string) pb.CellphoneServiceClient {
+	conn, err := grpc.Dial(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewCellphoneServiceClient(conn)
+}
+
+// 同一个gRPC服务监听tcp和unix socket，和HTTP服务一起关闭
+func TestGroup(t *testing.T) {
+	t.Parallel()
+
+	server := grpc.NewServer()
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(service.WithCoverPath(t.TempDir())))
+	grpcServer := serve.GRPC(server)
+	socket := filepath.Join(socketDir(t), "grpc.sock")
+
+	group := &serve.Group{ShutdownTimeout: 200 * time.Millisecond}
+	tcpListener, err := serve.Listen("127.0.0.1:0")
+	require.Nil(t, err)
+	group.Add("grpc", tcpListener, grpcServer)
+	require.Nil(t, group.Listen("grpc", "unix://"+socket, grpcServer))
+	httpListener, err := serve.Listen("127.0.0.1:0")
+	require.Nil(t, err)
+	group.Add("http", httpListener, serve.HTTP(&http.Server{Handler: http.NotFoundHandler()}))
+	shutdown := make(chan struct{})
+	group.RegisterOnShutdown(func() { close(shutdown) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- group.Run(ctx) }()
+
+	// 两个地址访问的是同一个服务
+	tcpClient := dialCellphone(t, tcpListener.Addr().String())
+	unixClient := dialCellphone(t, "unix://"+socket)
+	res, err := tcpClient.CreateCellphone(context.Background(), &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+	cellphone, err := unixClient.GetCellphone(context.Background(), &pb.GetCellphoneRequest{Id: res.Id})
+	require.Nil(t, err)
+	require.Equal(t, res.Id, cellphone.Id)
+
+	resp, err := http.Get("http://" + httpListener.Addr().String())
+	require.Nil(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// 一直不结束的流在超时之后被强制关闭
+	watch, err := unixClient.WatchCellphones(context.Background(), &pb.WatchRequest{})
+	require.Nil(t, err)
+	_, err = watch.Header()
+	require.Nil(t, err)
+
+	cancel()
+	select {
+	case err := <-done:
+		require.Nil(t, err)
+	case <-time.After(3 * time.Second):
+		t.Fatal("group did not shut down")
+	}
+	<-shutdown
+	_, err = watch.Recv()
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	_, err = os.Stat(socket)
+	require.True(t, os.IsNotExist(err))
+	_, err = http.Get("http://" + httpListener.Addr().String())
+	require.NotNil(t, err)
+}
+
+// 有服务出错退出时，其它的服务也被关闭
+func TestGroupServeError(t *testing.T) {
+	t.Parallel()
+
+	server := grpc.NewServer()
+	group := &serve.Group{}
+	require.Nil(t, group.Listen("grpc", "127.0.0.1:0", serve.GRPC(server)))
+	broken, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	broken.Close()
+	group.Add("broken", broken, serve.HTTP(&http.Server{}))
+
+	done := make(chan error, 1)
+	go func() { done <- group.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		require.ErrorContains(t, err, "broken")
+	case <-time.After(3 * time.Second):
+		t.Fatal("group did not stop")
+	}
+}
@@ -10,10 +10,23 @@ import (
 type CellphoneSaver interface {
 	// 保存一条手机信息
 	Save(context.Context, *pb.Cellphone) error
+	// 保存多条手机信息，只要有一条不能保存就一条都不保存
+	SaveAll(context.Context, []*pb.Cellphone) error
+	// 保存多条手机信息，每一条单独判断能否保存，返回和参数一一对应的错误
+	// 效果和依次调用Save相同，但是持久化的存储可以只写入一次
+	SaveEach(context.Context, []*pb.Cellphone) []error
 	// 返回已有的手机信息的数量
 	Size() int32
 	// 检查某个id的手机是否存在
 	Exists(string) bool
 	// 查找符合条件的手机
 	Search(*pb.FilterCondition) []*pb.Cellphone
+	// 获取某个id的手机，不存在时返回ErrNotFound
+	Get(string) (*pb.Cellphone, error)
+	// 更新一条已有的手机信息，不存在时返回ErrNotFound
+	Update(context.Context, *pb.Cellphone) error
+	// 删除某个id的手机，不存在时返回ErrNotFound
+	Delete(context.Context, string) error
+	// 订阅since之后的手机信息变化，since为0时只订阅之后发生的变化
+	Watch(since uint64) (*Watcher, error)
 }
@@ -5,14 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
-	"github.com/google/uuid"
 
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
+	"github.com/ryanreadbooks/go-grpc-example/internal/validate"
 	"github.com/ryanreadbooks/go-grpc-example/pb"
 )
 
@@ -21,8 +29,16 @@ var (
 )
 
 const (
-	maxCoverImageSizeMB = 1
-	MaxCoverImageBytes  = uint32(maxCoverImageSizeMB * 1024 * 1024) // bytes
+	// WatchCellphones响应header中订阅开始时的revision
+	WatchRevisionMetadataKey = "watch-revision"
+
+	// atomic模式下一个批次最多包含的手机数量
+	MaxAtomicBatchSize = 10000
+	// 非atomic模式下一次最多保存的手机数量，也是最多预先读取的请求数量
+	maxSaveBatch = 100
+
+	// 下载封面时每条响应中图片内容的大小
+	coverBlockBytes = 32 * 1024
 )
 
 // 实现pb生成的service server接口
@@ -32,18 +48,27 @@ type cellphoneServiceServer struct {
 	saver     CellphoneSaver
 	orders    OrderSaver
 	coverPath string
+	observer  observers
+	prices    *PriceTicker
+	// 购买请求的幂等键
+	idempotency *idempotencyKeys
 }
 
-func NewCellphoneServiceServer(coverPath ...string) pb.CellphoneServiceServer {
-	var coverFolder string = "../../image/server/" // 默认存放cover的路径
-	if len(coverPath) != 0 {
-		coverFolder = coverPath[0]
-	}
-	return &cellphoneServiceServer{
+func NewCellphoneServiceServer(opts ...Option) pb.CellphoneServiceServer {
+	c := &cellphoneServiceServer{
 		saver:     NewInMemoryCellphoneSaver(),
 		orders:    NewInMemoryOrderSaver(),
-		coverPath: coverFolder,
+		coverPath: "../../image/server/", // 默认存放cover的路径
+		prices:    NewPriceTicker(DefaultMaxPendingPrices),
+
+		idempotency: newIdempotencyKeys(DefaultIdempotencyKeys, DefaultIdempotencyKeyTTL),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	// 下单之后推送价格更新
+	c.observer = append(c.observer, c.prices)
+	return c
 }
 
 // 接口实现：添加一台新手机信息
@@ -59,36 +84,28 @@ func (c *cellphoneServiceServer) CreateCellphone(ctx context.Context,
 		}
 	}()
 
+	// 校验手机信息的每一个字段，包括uuid是否合法
+	if err = validate.CreateCellphoneRequest(req); err != nil {
+		response = nil
+		return
+	}
+
 	cellphone := req.Cellphone
 
 	if cellphone.Id == "" {
 		// id为空，赋予一个新的id
-		log.Printf("requested uuid is empty, now assigning a new one")
+		slog.DebugContext(ctx, "requested uuid is empty, now assigning a new one")
 		newId := uuid.NewString()
 		cellphone.Id = newId
 	}
 
-	// uuid不合法
-	if err = CheckUUIDValid(cellphone.Id); err != nil {
-		response = nil
-		err = status.Error(codes.InvalidArgument, err.Error())
-		return
-	}
-
 	if err = CheckContext(ctx); err != nil {
 		response = nil
 		return
 	}
 
 	// 保存
-	if err = c.saver.Save(ctx, cellphone); err != nil {
-		var grpcCode codes.Code
-		if errors.Is(err, ErrAlreadyExist) {
-			grpcCode = codes.AlreadyExists
-		} else {
-			grpcCode = codes.Internal
-		}
-		err = status.Error(grpcCode, err.Error())
+	if err = c.save(ctx, cellphone); err != nil {
 		response = nil
 		return
 	}
@@ -96,10 +113,90 @@ func (c *cellphoneServiceServer) CreateCellphone(ctx context.Context,
 	response = &pb.CreateCellphoneResponse{}
 	response.Id = cellphone.Id
 	err = nil
-	log.Printf("cellphone with id: %s saved", response.Id)
 	return
 }
 
+// 接口实现：获取一台手机信息
+// Unary RPC
+func (c *cellphoneServiceServer) GetCellphone(ctx context.Context, req *pb.GetCellphoneRequest) (*pb.Cellphone, error) {
+	if err := c.uuidCheck(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+	cellphone, err := c.saver.Get(req.GetId())
+	if errors.Is(err, ErrNotFound) {
+		slog.DebugContext(ctx, "cellphone not found", "id", req.GetId())
+		return nil, rpcerr.CellphoneNotFound(req.GetId())
+	}
+	if err != nil {
+		return nil, rpcerr.StorageFailure(err)
+	}
+	return cellphone, nil
+}
+
+// 接口实现：修改一台手机信息，订阅者会收到UPDATED事件
+// Unary RPC
+func (c *cellphoneServiceServer) UpdateCellphone(ctx context.Context, req *pb.UpdateCellphoneRequest) (*pb.Cellphone, error) {
+	if err := validate.UpdateCellphoneRequest(req); err != nil {
+		return nil, err
+	}
+	cellphone := req.Cellphone
+	err := c.saver.Update(ctx, cellphone)
+	if errors.Is(err, ErrNotFound) {
+		slog.DebugContext(ctx, "cellphone not found", "id", cellphone.Id)
+		return nil, rpcerr.CellphoneNotFound(cellphone.Id)
+	}
+	if err != nil {
+		return nil, rpcerr.StorageFailure(err)
+	}
+	slog.InfoContext(ctx, "cellphone updated", "id", cellphone.Id)
+	return cellphone, nil
+}
+
+// 接口实现：删除一台手机信息，订阅者会收到DELETED事件
+// Unary RPC
+func (c *cellphoneServiceServer) DeleteCellphone(ctx context.Context, req *pb.DeleteCellphoneRequest) (*pb.DeleteCellphoneResponse, error) {
+	if err := c.uuidCheck(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+	err := c.saver.Delete(ctx, req.GetId())
+	if errors.Is(err, ErrNotFound) {
+		slog.DebugContext(ctx, "cellphone not found", "id", req.GetId())
+		return nil, rpcerr.CellphoneNotFound(req.GetId())
+	}
+	if err != nil {
+		return nil, rpcerr.StorageFailure(err)
+	}
+	slog.InfoContext(ctx, "cellphone deleted", "id", req.GetId())
+	return &pb.DeleteCellphoneResponse{}, nil
+}
+
+// 保存一台已经校验过的手机，返回的错误可以直接返回给客户端
+func (c *cellphoneServiceServer) save(ctx context.Context, cellphone *pb.Cellphone) error {
+	return c.saved(ctx, cellphone, c.saver.Save(ctx, cellphone))
+}
+
+// 和save相同，但是多台手机只写入一次存储，返回和参数一一对应的错误
+func (c *cellphoneServiceServer) saveEach(ctx context.Context, cellphones []*pb.Cellphone) []error {
+	errs := c.saver.SaveEach(ctx, cellphones)
+	for i, err := range errs {
+		errs[i] = c.saved(ctx, cellphones[i], err)
+	}
+	return errs
+}
+
+// 把保存的结果转换成gRPC错误，保存成功时通知observer
+func (c *cellphoneServiceServer) saved(ctx context.Context, cellphone *pb.Cellphone, err error) error {
+	if err != nil {
+		if errors.Is(err, ErrAlreadyExist) {
+			return rpcerr.CellphoneAlreadyExists(cellphone.Id)
+		}
+		return rpcerr.StorageFailure(err)
+	}
+	slog.InfoContext(ctx, "cellphone saved", "id", cellphone.Id)
+	c.observer.CellphoneCreated(ctx, cellphone)
+	return nil
+}
+
 // 接口实现：查找符合条件的手机
 // 参数stream用来返回流式响应
 // Server streaming RPC
@@ -131,7 +228,7 @@ func (c *cellphoneServiceServer) UploadCellphoneCover(stream pb.CellphoneService
 	// 客户端第一个数据是一个meta info
 	request, err := stream.Recv()
 	if err != nil {
-		log.Printf("can not receive meta data from stream: %v\n", err)
+		slog.WarnContext(stream.Context(), "can not receive meta data from stream", "error", err)
 		return err
 	}
 
@@ -141,25 +238,29 @@ func (c *cellphoneServiceServer) UploadCellphoneCover(stream pb.CellphoneService
 	imgType := request.GetMeta().ImageType
 
 	// uuid不合法
-	if err := c.uuidCheck(cellphoneId); err != nil {
+	if err := c.uuidCheck(stream.Context(), cellphoneId); err != nil {
+		c.observer.CoverRejected(stream.Context(), cellphoneId, err)
 		return err
 	}
 
 	// 指定的cellphone id不存在
-	if err := c.cellphoneIdCheck(cellphoneId); err != nil {
+	if err := c.cellphoneIdCheck(stream.Context(), cellphoneId); err != nil {
+		c.observer.CoverRejected(stream.Context(), cellphoneId, err)
 		return err
 	}
 
 	// 文件大小太大
-	if imgSize > MaxCoverImageBytes {
-		log.Printf("server side imgSize of %d is too large\n", imgSize)
-		return status.Errorf(codes.OutOfRange, fmt.Sprintf("provided cover image is larger than %d MB", maxCoverImageSizeMB))
+	if imgSize > validate.MaxCoverImageBytes {
+		slog.WarnContext(stream.Context(), "cover image is too large", "id", cellphoneId, "size", imgSize)
+		err := rpcerr.CoverTooLarge(cellphoneId, imgSize, validate.MaxCoverImageBytes)
+		c.observer.CoverRejected(stream.Context(), cellphoneId, err)
+		return err
 	}
 	imgFileName := path.Join(c.coverPath, fmt.Sprintf("%s%s", cellphoneId, imgType))
 	imgFile, err := os.Create(imgFileName)
 	if err != nil {
-		log.Printf("can not create file when saving cover for %s: %s\n", cellphoneId, err)
-		return status.Errorf(codes.Internal, err.Error())
+		slog.ErrorContext(stream.Context(), "can not create cover file", "id", cellphoneId, "error", err)
+		return rpcerr.StorageFailure(err)
 	}
 	defer imgFile.Close()
 
@@ -174,10 +275,14 @@ func (c *cellphoneServiceServer) UploadCellphoneCover(stream pb.CellphoneService
 		// 请求数据接收完成
 		if err == io.EOF {
 			// 返回响应
-			return stream.SendAndClose(&pb.UploadCellphoneCoverResponse{
+			err := stream.SendAndClose(&pb.UploadCellphoneCoverResponse{
 				Id:   cellphoneId,
 				Size: uint32(totalSize),
 			})
+			if err == nil {
+				c.observer.CoverUploaded(stream.Context(), cellphoneId, uint32(totalSize))
+			}
+			return err
 		}
 
 		if err != nil {
@@ -187,11 +292,99 @@ func (c *cellphoneServiceServer) UploadCellphoneCover(stream pb.CellphoneService
 		block := request.GetBlock()
 		n, err := imgFile.Write(block)
 		if err != nil {
-			return status.Errorf(codes.Internal, err.Error())
+			return rpcerr.StorageFailure(err)
 		}
 		totalSize += n
-		log.Printf("written %d bytes into %s\n", n, imgFileName)
+		slog.DebugContext(stream.Context(), "cover block written", "bytes", n, "file", imgFileName)
+	}
+}
+
+// 接口实现：下载手机封面图片
+// 先发送元数据，然后分块发送图片的内容
+// Server streaming RPC
+func (c *cellphoneServiceServer) DownloadCellphoneCover(req *pb.DownloadCellphoneCoverRequest,
+	stream pb.CellphoneService_DownloadCellphoneCoverServer) error {
+
+	ctx := stream.Context()
+	cellphoneId := req.GetId()
+	if err := c.uuidCheck(ctx, cellphoneId); err != nil {
+		return err
+	}
+	if err := c.cellphoneIdCheck(ctx, cellphoneId); err != nil {
+		return err
+	}
+
+	imgFileName, err := c.findCover(cellphoneId)
+	if err != nil {
+		return rpcerr.StorageFailure(err)
+	}
+	if imgFileName == "" {
+		slog.DebugContext(ctx, "cover not found", "id", cellphoneId)
+		return rpcerr.CoverNotFound(cellphoneId)
+	}
+	imgFile, err := os.Open(imgFileName)
+	if err != nil {
+		return rpcerr.StorageFailure(err)
+	}
+	defer imgFile.Close()
+	stat, err := imgFile.Stat()
+	if err != nil {
+		return rpcerr.StorageFailure(err)
+	}
+
+	err = stream.Send(&pb.DownloadCellphoneCoverResponse{
+		Data: &pb.DownloadCellphoneCoverResponse_Meta{
+			Meta: &pb.CoverMetaInfo{
+				Id:        cellphoneId,
+				Size:      uint32(stat.Size()),
+				ImageType: filepath.Ext(imgFileName),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, coverBlockBytes)
+	for {
+		if err := CheckContext(ctx); err != nil {
+			return err
+		}
+		n, err := imgFile.Read(buf)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return rpcerr.StorageFailure(err)
+		}
+		err = stream.Send(&pb.DownloadCellphoneCoverResponse{
+			Data: &pb.DownloadCellphoneCoverResponse_Block{Block: buf[:n]},
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// 封面文件名为id加上上传时的图片类型，多次上传了不同类型的图片时使用最新的一张
+// 没有封面时返回空字符串
+func (c *cellphoneServiceServer) findCover(cellphoneId string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(c.coverPath, cellphoneId+".*"))
+	if err != nil {
+		return "", err
+	}
+	var latest string
+	var latestMod time.Time
+	for _, match := range matches {
+		stat, err := os.Stat(match)
+		if err != nil || !stat.Mode().IsRegular() {
+			continue
+		}
+		if latest == "" || stat.ModTime().After(latestMod) {
+			latest, latestMod = match, stat.ModTime()
+		}
 	}
+	return latest, nil
 }
 
 // 接口实现：购买手机的接口
@@ -205,7 +398,7 @@ func (c *cellphoneServiceServer) BuyCellphone(stream pb.CellphoneService_BuyCell
 
 		req, err := stream.Recv()
 		if err == io.EOF {
-			log.Printf("stream closed")
+			slog.DebugContext(stream.Context(), "buy cellphone stream closed")
 			break
 		}
 		if err != nil {
@@ -213,48 +406,367 @@ func (c *cellphoneServiceServer) BuyCellphone(stream pb.CellphoneService_BuyCell
 		}
 		cellphoneId := req.GetId()
 		price := req.GetPrice()
+		key := req.GetIdempotencyKey()
+
+		// 重试时可能重复下单，只接受带有幂等键的重试
+		if attempts := previousAttempts(stream.Context()); attempts != "" && key == "" {
+			return rpcerr.IdempotencyKeyRequired(attempts)
+		}
 
 		// uuid不合法
-		if err := c.uuidCheck(cellphoneId); err != nil {
+		if err := c.uuidCheck(stream.Context(), cellphoneId); err != nil {
 			return err
 		}
 
 		// 指定的cellphone id不存在
-		if err := c.cellphoneIdCheck(cellphoneId); err != nil {
+		if err := c.cellphoneIdCheck(stream.Context(), cellphoneId); err != nil {
 			return err
 		}
 
-		err = c.orders.Save(cellphoneId, price)
+		var orders *Orders
+		place := func() (*pb.BuyCellphoneResponse, error) {
+			if err := c.orders.Save(cellphoneId, price); err != nil {
+				return nil, rpcerr.StorageFailure(fmt.Errorf("can not save order for %s: %w", cellphoneId, err))
+			}
+			orders = c.orders.Get(cellphoneId)
+			return &pb.BuyCellphoneResponse{
+				Id:  cellphoneId,
+				Avg: orders.Total / float64(orders.Count),
+			}, nil
+		}
+
+		var res *pb.BuyCellphoneResponse
+		if key == "" {
+			res, err = place()
+		} else {
+			var replayed bool
+			res, replayed, err = c.idempotency.do(stream.Context(), key, cellphoneId, price, place)
+			if replayed {
+				slog.DebugContext(stream.Context(), "buy request replayed", "id", cellphoneId, "idempotency_key", key)
+			}
+		}
 		if err != nil {
-			return status.Errorf(codes.Internal, "can not save order for %s: %v\n", cellphoneId, err)
+			return err
+		}
+		// 重复的请求没有下单
+		if orders != nil {
+			c.observer.OrderPlaced(stream.Context(), cellphoneId, price, orders)
 		}
 
 		// 发送响应
-		orders := c.orders.Get(cellphoneId)
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		err = stream.Send(&pb.BuyCellphoneResponse{
-			Id:  cellphoneId,
-			Avg: orders.Total / float64(orders.Count),
-		})
+// 接口实现：订阅手机信息的变化
+// 先补发since_revision之后的变化，然后持续推送新的变化，直到客户端取消
+// 更新前或者更新后符合过滤条件的变化都会推送，这样客户端才能知道某台手机不再符合条件
+// Server streaming RPC
+func (c *cellphoneServiceServer) WatchCellphones(req *pb.WatchRequest,
+	stream pb.CellphoneService_WatchCellphonesServer) error {
+
+	ctx := stream.Context()
+	watcher, err := c.saver.Watch(req.GetSinceRevision())
+	if err != nil {
+		var compacted *CompactedError
+		if errors.As(err, &compacted) {
+			slog.DebugContext(ctx, "watch revision compacted", "since", compacted.Since, "oldest", compacted.Oldest)
+			return rpcerr.RevisionCompacted(compacted.Since, compacted.Oldest)
+		}
+		return rpcerr.StorageFailure(err)
+	}
+	defer watcher.Close()
+
+	// 通过header告诉客户端订阅开始时的revision
+	err = stream.SendHeader(metadata.Pairs(WatchRevisionMetadataKey,
+		strconv.FormatUint(watcher.Revision(), 10)))
+	if err != nil {
+		return err
+	}
+
+	lastRevision := req.GetSinceRevision()
+	for {
+		select {
+		case <-ctx.Done():
+			return CheckContext(ctx)
+		case change, ok := <-watcher.Changes():
+			if !ok {
+				slog.WarnContext(ctx, "watcher closed", "revision", lastRevision, "error", watcher.Err())
+				return rpcerr.WatcherTooSlow(lastRevision)
+			}
+			lastRevision = change.Event.Revision
+			if !conditionSatisfied(req.GetFilter(), change.Event.Cellphone) &&
+				(change.Prev == nil || !conditionSatisfied(req.GetFilter(), change.Prev)) {
+				continue
+			}
+			if err := stream.Send(change.Event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// 接口实现：订阅手机的价格变化
+// 推送不会阻塞下单，处理太慢时同一台手机的多次更新会被合并成一条
+// Server streaming RPC
+func (c *cellphoneServiceServer) WatchPrices(req *pb.WatchPricesRequest,
+	stream pb.CellphoneService_WatchPricesServer) error {
+
+	ctx := stream.Context()
+	for _, id := range req.GetIds() {
+		if err := c.uuidCheck(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	sub := c.prices.Subscribe(req.GetIds())
+	defer sub.Close()
+	// 订阅生效之后立刻发送header，客户端收到header之后的下单都会被推送
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		updates, err := sub.Next(ctx)
 		if err != nil {
+			return CheckContext(ctx)
+		}
+		for _, update := range updates {
+			if err := stream.Send(update); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// 接口实现：批量添加手机
+// 尽快返回每一台手机的结果，客户端不读取结果时服务端最多再读取maxSaveBatch条请求，
+// 由gRPC的流量控制限制客户端发送的速度
+// 非atomic模式下把已经收到的手机一起保存，持久化的存储只需要写入一次
+// atomic模式下先校验所有手机，最后一次性保存
+// Bidirectional RPC
+func (c *cellphoneServiceServer) BatchCreateCellphones(stream pb.CellphoneService_BatchCreateCellphonesServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	// 返回之后让接收请求的goroutine退出
+	defer cancel()
+
+	type received struct {
+		req *pb.BatchCreateCellphonesRequest
+		err error
+	}
+	requests := make(chan received, maxSaveBatch)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			select {
+			case requests <- received{req: req, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var atomic, optionsSeen bool
+	// atomic模式下等待保存的手机
+	var staged []*pb.Cellphone
+	stagedIds := make(map[string]bool)
+	summary := &pb.BatchSummary{}
+
+	// 还没有返回的结果，以及非atomic模式下等待保存的手机和它们的结果
+	var results []*pb.BatchItemResult
+	var pending []*pb.Cellphone
+	var pendingResults []*pb.BatchItemResult
+	flush := func() error {
+		for i, err := range c.saveEach(ctx, pending) {
+			setBatchItemError(pendingResults[i], err)
+		}
+		for _, result := range results {
+			if result.Code != int32(codes.OK) {
+				summary.Failed++
+			} else if !atomic {
+				summary.Created++
+			}
+			if err := stream.Send(&pb.BatchCreateCellphonesResponse{
+				Result: &pb.BatchCreateCellphonesResponse_Item{Item: result},
+			}); err != nil {
+				return err
+			}
+		}
+		results, pending, pendingResults = results[:0], pending[:0], pendingResults[:0]
+		return nil
+	}
+
+	for {
+		if err := CheckContext(ctx); err != nil {
 			return err
 		}
+		var r received
+		select {
+		case r = <-requests:
+		default:
+			// 暂时没有更多的请求，先保存并返回已经收到的手机
+			if err := flush(); err != nil {
+				return err
+			}
+			select {
+			case r = <-requests:
+			case <-ctx.Done():
+				return CheckContext(ctx)
+			}
+		}
+		if r.err == io.EOF {
+			break
+		}
+		if r.err != nil {
+			return r.err
+		}
+		req := r.req
+
+		if options := req.GetOptions(); options != nil {
+			// 选项只能是第一条消息
+			if summary.Total != 0 || optionsSeen {
+				if err := flush(); err != nil {
+					return err
+				}
+				return rpcerr.InvalidArgument("options must be the first message of the batch",
+					[]*errdetails.BadRequest_FieldViolation{
+						{Field: "options", Description: "must be sent before any cellphone"},
+					})
+			}
+			atomic = options.Atomic
+			optionsSeen = true
+			continue
+		}
+
+		cellphone := req.GetCellphone()
+		result := &pb.BatchItemResult{Index: summary.Total}
+		summary.Total++
+		results = append(results, result)
+
+		err := validate.Cellphone("cellphone", cellphone).Err()
+		if err == nil {
+			if cellphone.Id == "" {
+				cellphone.Id = uuid.NewString()
+			}
+			result.Id = cellphone.Id
+			if atomic {
+				switch {
+				case len(staged) >= MaxAtomicBatchSize:
+					// 整个批次都不会保存，没有必要继续接收
+					if err := flush(); err != nil {
+						return err
+					}
+					return rpcerr.BatchTooLarge(MaxAtomicBatchSize)
+				case stagedIds[cellphone.Id] || c.saver.Exists(cellphone.Id):
+					err = rpcerr.CellphoneAlreadyExists(cellphone.Id)
+				default:
+					staged = append(staged, cellphone)
+					stagedIds[cellphone.Id] = true
+				}
+			} else {
+				pending = append(pending, cellphone)
+				pendingResults = append(pendingResults, result)
+			}
+		}
+		setBatchItemError(result, err)
+
+		if len(results) >= maxSaveBatch {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
 	}
-	return nil
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if atomic && summary.Failed == 0 {
+		if err := c.saver.SaveAll(ctx, staged); err != nil {
+			// 校验之后有其它请求保存了相同id的手机
+			if errors.Is(err, ErrAlreadyExist) {
+				return rpcerr.New(codes.Aborted, pb.ErrorReason_CELLPHONE_ALREADY_EXISTS, err.Error(), nil)
+			}
+			return rpcerr.StorageFailure(err)
+		}
+		for _, cellphone := range staged {
+			c.observer.CellphoneCreated(ctx, cellphone)
+		}
+		summary.Created = uint32(len(staged))
+	}
+	summary.Committed = summary.Failed == 0
+	slog.InfoContext(ctx, "cellphone batch finished", "atomic", atomic,
+		"total", summary.Total, "created", summary.Created, "failed", summary.Failed)
+
+	return stream.Send(&pb.BatchCreateCellphonesResponse{
+		Result: &pb.BatchCreateCellphonesResponse_Summary{Summary: summary},
+	})
+}
+
+func setBatchItemError(result *pb.BatchItemResult, err error) {
+	if err == nil {
+		return
+	}
+	st := status.Convert(err)
+	result.Code = int32(st.Code())
+	result.Message = st.Message()
+	result.Reason = rpcerr.ReasonOf(err)
+}
+
+// 接口实现：查询手机的订单统计
+// Unary RPC
+func (c *cellphoneServiceServer) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	var orders []*Orders
+	if len(req.GetIds()) == 0 {
+		orders = c.orders.List()
+	} else {
+		for _, id := range req.GetIds() {
+			if err := c.uuidCheck(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+		ids := append([]string(nil), req.GetIds()...)
+		sort.Strings(ids)
+		for i, id := range ids {
+			if i > 0 && id == ids[i-1] {
+				continue
+			}
+			if o := c.orders.Get(id); o != nil {
+				o.Id = id
+				orders = append(orders, o)
+			}
+		}
+	}
+
+	res := &pb.ListOrdersResponse{Orders: make([]*pb.Order, 0, len(orders))}
+	for _, o := range orders {
+		res.Orders = append(res.Orders, &pb.Order{
+			Id:    o.Id,
+			Count: o.Count,
+			Total: o.Total,
+			Avg:   o.Total / float64(o.Count),
+		})
+	}
+	return res, nil
 }
 
-func (c *cellphoneServiceServer) uuidCheck(cellphoneId string) error {
+func (c *cellphoneServiceServer) uuidCheck(ctx context.Context, cellphoneId string) error {
 	if err := CheckUUIDValid(cellphoneId); err != nil {
-		log.Printf("cellphone with invalid uuid: %s\n", cellphoneId)
-		return status.Errorf(codes.InvalidArgument, err.Error())
+		slog.DebugContext(ctx, "cellphone with invalid uuid", "id", cellphoneId)
+		return rpcerr.InvalidUUID("id", cellphoneId)
 	}
 	return nil
 }
 
-func (c *cellphoneServiceServer) cellphoneIdCheck(cellphoneId string) error {
+func (c *cellphoneServiceServer) cellphoneIdCheck(ctx context.Context, cellphoneId string) error {
 	if !c.saver.Exists(cellphoneId) {
-		log.Printf("cellphone with id: %s not found\n", cellphoneId)
-		return status.Errorf(codes.NotFound, fmt.Sprintf("cellphone with %s not found", cellphoneId))
+		slog.DebugContext(ctx, "cellphone not found", "id", cellphoneId)
+		return rpcerr.CellphoneNotFound(cellphoneId)
 	}
 	return nil
 }
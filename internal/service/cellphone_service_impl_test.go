@@ -14,10 +14,17 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
 	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
 	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/internal/validate"
 	"github.com/ryanreadbooks/go-grpc-example/pb"
 )
 
@@ -156,70 +163,70 @@ func TestCellphoneServiceImplSearchCellphone(t *testing.T) {
 
 	cellphones := []*pb.Cellphone{
 		{
-			Cpu:     &pb.CPU{MinGhz: 2.0, Cores: 2},
+			Cpu:     &pb.CPU{MinGhz: 2.0, MaxGhz: 5.5, Cores: 2},
 			Ram:     &pb.RAM{Value: 6, Unit: pb.Unit_UnitGB},
 			Storage: &pb.Storage{Value: 1024, Unit: pb.Unit_UnitGB},
 			Battery: &pb.Battery{Capacity: 4500},
 			Brand:   "Apple",
 		},
 		{
-			Cpu:     &pb.CPU{MinGhz: 2.5, Cores: 1},
+			Cpu:     &pb.CPU{MinGhz: 2.5, MaxGhz: 5.5, Cores: 1},
 			Ram:     &pb.RAM{Value: 1, Unit: pb.Unit_UnitGB},
 			Storage: &pb.Storage{Value: 250, Unit: pb.Unit_UnitGB},
 			Battery: &pb.Battery{Capacity: 3000},
 			Brand:   "Samsung",
 		},
 		{
-			Cpu:     &pb.CPU{MinGhz: 3.2, Cores: 3},
+			Cpu:     &pb.CPU{MinGhz: 3.2, MaxGhz: 5.5, Cores: 3},
 			Ram:     &pb.RAM{Value: 4, Unit: pb.Unit_UnitGB},
 			Storage: &pb.Storage{Value: 250, Unit: pb.Unit_UnitGB},
 			Battery: &pb.Battery{Capacity: 3300},
 			Brand:   "Huawei",
 		},
 		{
-			Cpu:     &pb.CPU{MinGhz: 4.8, Cores: 4},
+			Cpu:     &pb.CPU{MinGhz: 4.8, MaxGhz: 5.5, Cores: 4},
 			Ram:     &pb.RAM{Value: 8, Unit: pb.Unit_UnitGB},
 			Storage: &pb.Storage{Value: 500, Unit: pb.Unit_UnitGB},
 			Battery: &pb.Battery{Capacity: 5467},
 			Brand:   "OPPO",
 		},
 		{
-			Cpu:     &pb.CPU{MinGhz: 5.0, Cores: 8},
+			Cpu:     &pb.CPU{MinGhz: 5.0, MaxGhz: 5.5, Cores: 8},
 			Ram:     &pb.RAM{Value: 16, Unit: pb.Unit_UnitGB},
 			Storage: &pb.Storage{Value: 128, Unit: pb.Unit_UnitGB},
 			Battery: &pb.Battery{Capacity: 4333},
 			Brand:   "Xiaomi",
 		},
 		{
-			Cpu:     &pb.CPU{MinGhz: 2.2, Cores: 2},
+			Cpu:     &pb.CPU{MinGhz: 2.2, MaxGhz: 5.5, Cores: 2},
 			Ram:     &pb.RAM{Value: 6, Unit: pb.Unit_UnitGB},
 			Storage: &pb.Storage{Value: 256, Unit: pb.Unit_UnitGB},
 			Battery: &pb.Battery{Capacity: 2500},
 			Brand:   "VIVO",
 		},
 		{
-			Cpu:     &pb.CPU{MinGhz: 3.2, Cores: 1},
+			Cpu:     &pb.CPU{MinGhz: 3.2, MaxGhz: 5.5, Cores: 1},
 			Ram:     &pb.RAM{Value: 2, Unit: pb.Unit_UnitGB},
 			Storage: &pb.Storage{Value: 128, Unit: pb.Unit_UnitGB},
 			Battery: &pb.Battery{Capacity: 1455},
 			Brand:   "Honor",
 		},
 		{
-			Cpu:     &pb.CPU{MinGhz: 1.8, Cores: 8},
+			Cpu:     &pb.CPU{MinGhz: 1.8, MaxGhz: 5.5, Cores: 8},
 			Ram:     &pb.RAM{Value: 8, Unit: pb.Unit_UnitGB},
 			Storage: &pb.Storage{Value: 512, Unit: pb.Unit_UnitGB},
 			Battery: &pb.Battery{Capacity: 5633},
 			Brand:   "Pixel",
 		},
 		{
-			Cpu:     &pb.CPU{MinGhz: 3.6, Cores: 16},
+			Cpu:     &pb.CPU{MinGhz: 3.6, MaxGhz: 5.5, Cores: 16},
 			Ram:     &pb.RAM{Value: 8, Unit: pb.Unit_UnitGB},
 			Storage: &pb.Storage{Value: 256, Unit: pb.Unit_UnitGB},
 			Battery: &pb.Battery{Capacity: 4600},
 			Brand:   "Huawei",
 		},
 		{
-			Cpu:     &pb.CPU{MinGhz: 2.8, Cores: 4},
+			Cpu:     &pb.CPU{MinGhz: 2.8, MaxGhz: 5.5, Cores: 4},
 			Ram:     &pb.RAM{Value: 16, Unit: pb.Unit_UnitGB},
 			Storage: &pb.Storage{Value: 512, Unit: pb.Unit_UnitGB},
 			Battery: &pb.Battery{Capacity: 5999},
@@ -508,3 +515,864 @@ func TestCellphoneServiceImplBuyCellphone(t *testing.T) {
 		})
 	}
 }
+
+// 测试购买请求的幂等键
+func TestCellphoneServiceImplBuyCellphoneIdempotency(t *testing.T) {
+	t.Parallel()
+
+	server, listener := runTestCellphoneServiceServer(t)
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	created, err := client.CreateCellphone(context.Background(), &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+	id := created.Id
+
+	// 在一个流中依次发送请求，返回每个请求的结果
+	buy := func(ctx context.Context, reqs ...*pb.BuyCellphoneRequest) ([]*pb.BuyCellphoneResponse, error) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		stream, err := client.BuyCellphone(ctx)
+		require.Nil(t, err)
+		var res []*pb.BuyCellphoneResponse
+		for _, req := range reqs {
+			require.Nil(t, stream.Send(req))
+			r, err := stream.Recv()
+			if err != nil {
+				return res, err
+			}
+			res = append(res, r)
+		}
+		stream.CloseSend()
+		_, err = stream.Recv()
+		require.Equal(t, io.EOF, err)
+		return res, nil
+	}
+	orderCount := func() uint32 {
+		res, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{Ids: []string{id}})
+		require.Nil(t, err)
+		require.Len(t, res.Orders, 1)
+		return res.Orders[0].Count
+	}
+
+	// 相同的幂等键只下单一次，重复的请求返回第一次的结果
+	res, err := buy(context.Background(),
+		&pb.BuyCellphoneRequest{Id: id, Price: 1000, IdempotencyKey: "key-1"},
+		&pb.BuyCellphoneRequest{Id: id, Price: 3000, IdempotencyKey: "key-2"},
+		&pb.BuyCellphoneRequest{Id: id, Price: 1000, IdempotencyKey: "key-1"})
+	require.Nil(t, err)
+	require.Len(t, res, 3)
+	require.Equal(t, 1000.0, res[0].Avg)
+	require.Equal(t, 2000.0, res[1].Avg)
+	require.True(t, proto.Equal(res[0], res[2]))
+	require.EqualValues(t, 2, orderCount())
+
+	// 在另一个流中重复也一样
+	res, err = buy(context.Background(), &pb.BuyCellphoneRequest{Id: id, Price: 3000, IdempotencyKey: "key-2"})
+	require.Nil(t, err)
+	require.Equal(t, 2000.0, res[0].Avg)
+	require.EqualValues(t, 2, orderCount())
+
+	// 幂等键不能被不同的请求使用
+	_, err = buy(context.Background(), &pb.BuyCellphoneRequest{Id: id, Price: 5000, IdempotencyKey: "key-1"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Equal(t, pb.ErrorReason_IDEMPOTENCY_KEY_REUSED, rpcerr.ReasonOf(err))
+
+	// 重试时必须携带幂等键
+	retried := metadata.AppendToOutgoingContext(context.Background(), service.PreviousAttemptsMetadataKey, "1")
+	_, err = buy(retried, &pb.BuyCellphoneRequest{Id: id, Price: 5000})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.Equal(t, pb.ErrorReason_IDEMPOTENCY_KEY_REQUIRED, rpcerr.ReasonOf(err))
+	_, err = buy(retried, &pb.BuyCellphoneRequest{Id: id, Price: 5000, IdempotencyKey: "key-3"})
+	require.Nil(t, err)
+	require.EqualValues(t, 3, orderCount())
+}
+
+// 保存价格为blockPrice的订单时阻塞，直到release被关闭
+type blockingOrderSaver struct {
+	*service.InMemoryOrderSaver
+	blockPrice float64
+	entered    chan struct{}
+	release    chan struct{}
+}
+
+func (s *blockingOrderSaver) Save(id string, price float64) error {
+	if price == s.blockPrice {
+		s.entered <- struct{}{}
+		<-s.release
+	}
+	return s.InMemoryOrderSaver.Save(id, price)
+}
+
+// 测试幂等键只阻塞相同key的请求
+func TestCellphoneServiceImplBuyCellphoneIdempotencyConcurrent(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	cellphones := service.NewInMemoryCellphoneSaver()
+	orders := &blockingOrderSaver{
+		InMemoryOrderSaver: service.NewInMemoryOrderSaver(),
+		blockPrice:         1000,
+		entered:            make(chan struct{}),
+		release:            make(chan struct{}),
+	}
+	server := grpc.NewServer()
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(
+		service.WithCellphoneSaver(cellphones), service.WithOrderSaver(orders)))
+	go server.Serve(listener)
+	defer server.Stop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	cellphone := sample.NewCellphone()
+	require.Nil(t, cellphones.Save(context.Background(), cellphone))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	buy := func(req *pb.BuyCellphoneRequest) (*pb.BuyCellphoneResponse, error) {
+		stream, err := client.BuyCellphone(ctx)
+		require.Nil(t, err)
+		require.Nil(t, stream.Send(req))
+		return stream.Recv()
+	}
+	type result struct {
+		res *pb.BuyCellphoneResponse
+		err error
+	}
+	// 第一次请求和它的重试
+	first, retried := make(chan result, 1), make(chan result, 1)
+	go func() {
+		res, err := buy(&pb.BuyCellphoneRequest{Id: cellphone.Id, Price: 1000, IdempotencyKey: "key-1"})
+		first <- result{res, err}
+	}()
+	<-orders.entered
+	go func() {
+		res, err := buy(&pb.BuyCellphoneRequest{Id: cellphone.Id, Price: 1000, IdempotencyKey: "key-1"})
+		retried <- result{res, err}
+	}()
+
+	// 其它的幂等键不需要等待
+	res, err := buy(&pb.BuyCellphoneRequest{Id: cellphone.Id, Price: 3000, IdempotencyKey: "key-2"})
+	require.Nil(t, err)
+	require.Equal(t, 3000.0, res.Avg)
+	// 不同的请求使用正在执行的幂等键
+	_, err = buy(&pb.BuyCellphoneRequest{Id: cellphone.Id, Price: 5000, IdempotencyKey: "key-1"})
+	require.Equal(t, pb.ErrorReason_IDEMPOTENCY_KEY_REUSED, rpcerr.ReasonOf(err))
+	select {
+	case <-retried:
+		t.Fatal("retried request finished before the first one")
+	default:
+	}
+
+	close(orders.release)
+	r1, r2 := <-first, <-retried
+	require.Nil(t, r1.err)
+	require.Nil(t, r2.err)
+	require.Equal(t, 2000.0, r1.res.Avg)
+	require.True(t, proto.Equal(r1.res, r2.res))
+	require.EqualValues(t, 2, orders.Get(cellphone.Id).Count)
+}
+
+// 测试开启认证时不同调用方的幂等键互不影响
+func TestCellphoneServiceImplBuyCellphoneIdempotencyScoped(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	cellphones := service.NewInMemoryCellphoneSaver()
+	orders := service.NewInMemoryOrderSaver()
+	authn := auth.NewStaticKeys([]auth.APIKey{
+		{Key: "alice-key", Subject: "alice", Roles: []string{"buyer"}},
+		{Key: "bob-key", Subject: "bob", Roles: []string{"buyer"}},
+		{Key: "team-key", Subject: "team", Roles: []string{"buyer"}},
+		{Key: "team-alice-key", Subject: "team/alice", Roles: []string{"buyer"}},
+	})
+	server := grpc.NewServer(grpc.StreamInterceptor(auth.StreamServerInterceptor(authn, auth.DefaultPolicy(), auth.Options{})))
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(
+		service.WithCellphoneSaver(cellphones), service.WithOrderSaver(orders)))
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	cellphone := sample.NewCellphone()
+	require.Nil(t, cellphones.Save(context.Background(), cellphone))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	buy := func(token, key string, price float64) (*pb.BuyCellphoneResponse, error) {
+		stream, err := client.BuyCellphone(ctx, grpc.PerRPCCredentials(auth.NewTokenCredentials(token)))
+		require.Nil(t, err)
+		require.Nil(t, stream.Send(&pb.BuyCellphoneRequest{Id: cellphone.Id, Price: price, IdempotencyKey: key}))
+		return stream.Recv()
+	}
+
+	_, err = buy("alice-key", "key-1", 1000)
+	require.Nil(t, err)
+	// 另一个调用方使用相同的幂等键
+	res, err := buy("bob-key", "key-1", 3000)
+	require.Nil(t, err)
+	require.Equal(t, 2000.0, res.Avg)
+	// 同一个调用方重复的请求
+	res, err = buy("alice-key", "key-1", 1000)
+	require.Nil(t, err)
+	require.Equal(t, 1000.0, res.Avg)
+	_, err = buy("bob-key", "key-1", 1000)
+	require.Equal(t, pb.ErrorReason_IDEMPOTENCY_KEY_REUSED, rpcerr.ReasonOf(err))
+	require.EqualValues(t, 2, orders.Get(cellphone.Id).Count)
+
+	// 调用方和幂等键拼接起来相同时也互不影响
+	_, err = buy("team-alice-key", "key-2", 5000)
+	require.Nil(t, err)
+	_, err = buy("team-key", "alice/key-2", 1000)
+	require.Nil(t, err)
+	require.EqualValues(t, 4, orders.Get(cellphone.Id).Count)
+}
+
+// 测试错误中携带的详情能被客户端解析出来
+func TestCellphoneServiceImplErrorDetails(t *testing.T) {
+	t.Parallel()
+
+	server, listener := runTestCellphoneServiceServer(t)
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cellphone := sample.NewCellphone()
+	_, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: cellphone})
+	require.Nil(t, err)
+
+	// 重复添加
+	_, err = client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: cellphone})
+	details := rpcerr.Decode(err)
+	require.Equal(t, codes.AlreadyExists, details.Code)
+	require.Equal(t, pb.ErrorReason_CELLPHONE_ALREADY_EXISTS, details.Reason)
+	require.Equal(t, cellphone.Id, details.ResourceInfo.ResourceName)
+
+	// 不合法的字段
+	invalid := sample.NewCellphone()
+	invalid.Brand = ""
+	_, err = client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: invalid})
+	details = rpcerr.Decode(err)
+	require.Equal(t, pb.ErrorReason_INVALID_REQUEST, details.Reason)
+	require.Equal(t, "cellphone.brand", details.BadRequest.FieldViolations[0].Field)
+
+	buy := func(id string) *rpcerr.Details {
+		stream, err := client.BuyCellphone(ctx)
+		require.Nil(t, err)
+		require.Nil(t, stream.Send(&pb.BuyCellphoneRequest{Id: id, Price: 1999}))
+		_, err = stream.Recv()
+		return rpcerr.Decode(err)
+	}
+
+	// 不存在的手机
+	notFoundId := "dce5fc07-d7d1-49fe-aaef-183aa779fce2"
+	details = buy(notFoundId)
+	require.Equal(t, codes.NotFound, details.Code)
+	require.Equal(t, pb.ErrorReason_CELLPHONE_NOT_FOUND, details.Reason)
+	require.Equal(t, rpcerr.CellphoneResourceType, details.ResourceInfo.ResourceType)
+	require.Equal(t, notFoundId, details.ResourceInfo.ResourceName)
+
+	// 不合法的uuid
+	details = buy("invalid-uuid")
+	require.Equal(t, codes.InvalidArgument, details.Code)
+	require.Equal(t, pb.ErrorReason_INVALID_UUID, details.Reason)
+	require.Len(t, details.BadRequest.FieldViolations, 1)
+
+	// 封面图片太大
+	stream, err := client.UploadCellphoneCover(ctx)
+	require.Nil(t, err)
+	err = stream.Send(&pb.UploadCellphoneCoverRequest{
+		Data: &pb.UploadCellphoneCoverRequest_Meta{
+			Meta: &pb.CoverMetaInfo{Id: cellphone.Id, Size: validate.MaxCoverImageBytes + 1, ImageType: ".jpeg"},
+		},
+	})
+	require.Nil(t, err)
+	_, err = stream.CloseAndRecv()
+	details = rpcerr.Decode(err)
+	require.Equal(t, codes.OutOfRange, details.Code)
+	require.Equal(t, pb.ErrorReason_COVER_TOO_LARGE, details.Reason)
+	require.Len(t, details.QuotaFailure.Violations, 1)
+}
+
+func TestCellphoneServiceImplUpdateDeleteCellphone(t *testing.T) {
+	t.Parallel()
+
+	server, listener := runTestCellphoneServiceServer(t)
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cellphone := sample.NewCellphone()
+	_, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: cellphone})
+	require.Nil(t, err)
+
+	updated := proto.Clone(cellphone).(*pb.Cellphone)
+	updated.Brand = cellphone.Brand + "-updated"
+	res, err := client.UpdateCellphone(ctx, &pb.UpdateCellphoneRequest{Cellphone: updated})
+	require.Nil(t, err)
+	require.True(t, proto.Equal(updated, res))
+	got, err := client.GetCellphone(ctx, &pb.GetCellphoneRequest{Id: cellphone.Id})
+	require.Nil(t, err)
+	require.True(t, proto.Equal(updated, got))
+
+	// 修改时id不能为空
+	withoutId := proto.Clone(updated).(*pb.Cellphone)
+	withoutId.Id = ""
+	_, err = client.UpdateCellphone(ctx, &pb.UpdateCellphoneRequest{Cellphone: withoutId})
+	require.Equal(t, pb.ErrorReason_INVALID_REQUEST, rpcerr.ReasonOf(err))
+	_, err = client.UpdateCellphone(ctx, &pb.UpdateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Equal(t, pb.ErrorReason_CELLPHONE_NOT_FOUND, rpcerr.ReasonOf(err))
+
+	_, err = client.DeleteCellphone(ctx, &pb.DeleteCellphoneRequest{Id: "invalid-uuid"})
+	require.Equal(t, pb.ErrorReason_INVALID_UUID, rpcerr.ReasonOf(err))
+	_, err = client.DeleteCellphone(ctx, &pb.DeleteCellphoneRequest{Id: cellphone.Id})
+	require.Nil(t, err)
+	_, err = client.GetCellphone(ctx, &pb.GetCellphoneRequest{Id: cellphone.Id})
+	require.Equal(t, pb.ErrorReason_CELLPHONE_NOT_FOUND, rpcerr.ReasonOf(err))
+	_, err = client.DeleteCellphone(ctx, &pb.DeleteCellphoneRequest{Id: cellphone.Id})
+	require.Equal(t, pb.ErrorReason_CELLPHONE_NOT_FOUND, rpcerr.ReasonOf(err))
+}
+
+func TestCellphoneServiceImplWatchCellphones(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	saver := service.NewInMemoryCellphoneSaver()
+	server := grpc.NewServer()
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(service.WithCellphoneSaver(saver)))
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	newCellphone := func(brand string) *pb.Cellphone {
+		cellphone := sample.NewCellphone()
+		cellphone.Brand = brand
+		return cellphone
+	}
+	create := func(cellphone *pb.Cellphone) {
+		_, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: cellphone})
+		require.Nil(t, err)
+	}
+	recv := func(stream pb.CellphoneService_WatchCellphonesClient) *pb.CellphoneEvent {
+		event, err := stream.Recv()
+		require.Nil(t, err)
+		return event
+	}
+
+	// 订阅之前的变化不会推送
+	create(newCellphone("Apple"))
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	stream, err := client.WatchCellphones(watchCtx, &pb.WatchRequest{
+		Filter: &pb.FilterCondition{Brands: []string{"Apple"}},
+	})
+	require.Nil(t, err)
+	header, err := stream.Header()
+	require.Nil(t, err)
+	require.Equal(t, []string{"1"}, header.Get(service.WatchRevisionMetadataKey))
+
+	apple := newCellphone("Apple")
+	create(newCellphone("Samsung")) // 不符合过滤条件
+	create(apple)
+
+	event := recv(stream)
+	require.Equal(t, pb.CellphoneEvent_CREATED, event.Type)
+	require.Equal(t, uint64(3), event.Revision)
+	require.Equal(t, apple.Id, event.Cellphone.Id)
+
+	// 更新后不再符合条件也需要推送，客户端才能把它移除
+	apple.Brand = "Huawei"
+	_, err = client.UpdateCellphone(ctx, &pb.UpdateCellphoneRequest{Cellphone: apple})
+	require.Nil(t, err)
+	event = recv(stream)
+	require.Equal(t, pb.CellphoneEvent_UPDATED, event.Type)
+	require.Equal(t, uint64(4), event.Revision)
+	require.Equal(t, "Huawei", event.Cellphone.Brand)
+
+	// 之后的变化都不符合条件
+	_, err = client.DeleteCellphone(ctx, &pb.DeleteCellphoneRequest{Id: apple.Id})
+	require.Nil(t, err)
+	other := newCellphone("Apple")
+	create(other)
+	event = recv(stream)
+	require.Equal(t, pb.CellphoneEvent_CREATED, event.Type)
+	require.Equal(t, uint64(6), event.Revision)
+	require.Equal(t, other.Id, event.Cellphone.Id)
+	stopWatch()
+
+	// 从revision 3继续订阅，补发之后的全部变化
+	stream, err = client.WatchCellphones(ctx, &pb.WatchRequest{SinceRevision: 3})
+	require.Nil(t, err)
+	var types []pb.CellphoneEvent_Type
+	for revision := uint64(4); revision <= 6; revision++ {
+		event := recv(stream)
+		require.Equal(t, revision, event.Revision)
+		types = append(types, event.Type)
+	}
+	require.Equal(t, []pb.CellphoneEvent_Type{
+		pb.CellphoneEvent_UPDATED, pb.CellphoneEvent_DELETED, pb.CellphoneEvent_CREATED,
+	}, types)
+
+	// 不存在的revision
+	stream, err = client.WatchCellphones(ctx, &pb.WatchRequest{SinceRevision: 100})
+	require.Nil(t, err)
+	_, err = stream.Recv()
+	details := rpcerr.Decode(err)
+	require.Equal(t, codes.OutOfRange, details.Code)
+	require.Equal(t, pb.ErrorReason_REVISION_COMPACTED, details.Reason)
+}
+
+func TestCellphoneServiceImplWatchPrices(t *testing.T) {
+	t.Parallel()
+
+	server, listener := runTestCellphoneServiceServer(t)
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		res, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+		require.Nil(t, err)
+		ids = append(ids, res.Id)
+	}
+
+	// 不合法的id
+	prices, err := client.WatchPrices(ctx, &pb.WatchPricesRequest{Ids: []string{"invalid-uuid"}})
+	require.Nil(t, err)
+	_, err = prices.Recv()
+	require.Equal(t, pb.ErrorReason_INVALID_UUID, rpcerr.ReasonOf(err))
+
+	// 只订阅第二台手机的价格
+	prices, err = client.WatchPrices(ctx, &pb.WatchPricesRequest{Ids: ids[1:]})
+	require.Nil(t, err)
+	// 收到header说明订阅已经在服务端生效
+	_, err = prices.Header()
+	require.Nil(t, err)
+
+	buy, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	for _, req := range []*pb.BuyCellphoneRequest{
+		{Id: ids[0], Price: 1000},
+		{Id: ids[1], Price: 2000},
+		{Id: ids[1], Price: 3000},
+	} {
+		require.Nil(t, buy.Send(req))
+		_, err := buy.Recv()
+		require.Nil(t, err)
+	}
+	require.Nil(t, buy.CloseSend())
+
+	// 两次更新可能被合并，最终一定能收到第二次下单之后的价格
+	var update *pb.PriceUpdate
+	for update == nil || update.Count < 2 {
+		update, err = prices.Recv()
+		require.Nil(t, err)
+		require.Equal(t, ids[1], update.Id)
+	}
+	require.Equal(t, uint32(2), update.Count)
+	require.Equal(t, float64(2500), update.Avg)
+	require.Equal(t, float64(3000), update.LastPrice)
+}
+
+func TestCellphoneServiceImplBatchCreateCellphones(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	saver := service.NewInMemoryCellphoneSaver()
+	server := grpc.NewServer()
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(service.WithCellphoneSaver(saver)))
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	existing := sample.NewCellphone()
+	require.Nil(t, saver.Save(context.Background(), existing))
+
+	options := func(atomic bool) *pb.BatchCreateCellphonesRequest {
+		return &pb.BatchCreateCellphonesRequest{
+			Data: &pb.BatchCreateCellphonesRequest_Options{Options: &pb.BatchOptions{Atomic: atomic}},
+		}
+	}
+	item := func(c *pb.Cellphone) *pb.BatchCreateCellphonesRequest {
+		return &pb.BatchCreateCellphonesRequest{Data: &pb.BatchCreateCellphonesRequest_Cellphone{Cellphone: c}}
+	}
+	invalid := func() *pb.Cellphone {
+		c := sample.NewCellphone()
+		c.Brand = ""
+		return c
+	}
+	withoutId := func() *pb.Cellphone {
+		c := sample.NewCellphone()
+		c.Id = ""
+		return c
+	}
+
+	var testCases = []struct {
+		Name     string
+		Requests []*pb.BatchCreateCellphonesRequest
+		// 每台手机的结果
+		Codes   []codes.Code
+		Summary *pb.BatchSummary
+		// 保存之后增加的手机数量
+		Saved int32
+		Err   codes.Code
+	}{
+		{
+			Name:     "no-options",
+			Requests: []*pb.BatchCreateCellphonesRequest{item(sample.NewCellphone()), item(withoutId())},
+			Codes:    []codes.Code{codes.OK, codes.OK},
+			Summary:  &pb.BatchSummary{Total: 2, Created: 2, Committed: true},
+			Saved:    2,
+		},
+		{
+			Name: "partial",
+			Requests: []*pb.BatchCreateCellphonesRequest{
+				options(false), item(sample.NewCellphone()), item(invalid()), item(existing), item(sample.NewCellphone()),
+			},
+			Codes:   []codes.Code{codes.OK, codes.InvalidArgument, codes.AlreadyExists, codes.OK},
+			Summary: &pb.BatchSummary{Total: 4, Created: 2, Failed: 2},
+			Saved:   2,
+		},
+		{
+			Name: "duplicated-in-batch",
+			Requests: func() []*pb.BatchCreateCellphonesRequest {
+				c := sample.NewCellphone()
+				return []*pb.BatchCreateCellphonesRequest{item(c), item(c)}
+			}(),
+			Codes:   []codes.Code{codes.OK, codes.AlreadyExists},
+			Summary: &pb.BatchSummary{Total: 2, Created: 1, Failed: 1},
+			Saved:   1,
+		},
+		{
+			// 超过一次保存的数量时分多次保存
+			Name: "many",
+			Requests: func() []*pb.BatchCreateCellphonesRequest {
+				var reqs []*pb.BatchCreateCellphonesRequest
+				for i := 0; i < 250; i++ {
+					reqs = append(reqs, item(withoutId()))
+				}
+				return reqs
+			}(),
+			Codes:   make([]codes.Code, 250),
+			Summary: &pb.BatchSummary{Total: 250, Created: 250, Committed: true},
+			Saved:   250,
+		},
+		{
+			Name: "atomic-rollback",
+			Requests: []*pb.BatchCreateCellphonesRequest{
+				options(true), item(sample.NewCellphone()), item(withoutId()), item(invalid()),
+			},
+			Codes:   []codes.Code{codes.OK, codes.OK, codes.InvalidArgument},
+			Summary: &pb.BatchSummary{Total: 3, Failed: 1},
+			Saved:   0,
+		},
+		{
+			Name: "atomic-duplicated-in-batch",
+			Requests: func() []*pb.BatchCreateCellphonesRequest {
+				c := sample.NewCellphone()
+				return []*pb.BatchCreateCellphonesRequest{options(true), item(c), item(c)}
+			}(),
+			Codes:   []codes.Code{codes.OK, codes.AlreadyExists},
+			Summary: &pb.BatchSummary{Total: 2, Failed: 1},
+			Saved:   0,
+		},
+		{
+			Name: "atomic-commit",
+			Requests: []*pb.BatchCreateCellphonesRequest{
+				options(true), item(sample.NewCellphone()), item(withoutId()), item(sample.NewCellphone()),
+			},
+			Codes:   []codes.Code{codes.OK, codes.OK, codes.OK},
+			Summary: &pb.BatchSummary{Total: 3, Created: 3, Committed: true},
+			Saved:   3,
+		},
+		{
+			Name:     "options-not-first",
+			Requests: []*pb.BatchCreateCellphonesRequest{item(sample.NewCellphone()), options(true)},
+			Codes:    []codes.Code{codes.OK},
+			Saved:    1,
+			Err:      codes.InvalidArgument,
+		},
+	}
+
+	// 共用同一个saver，不能并行
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(it *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			before := saver.Size()
+
+			stream, err := client.BatchCreateCellphones(ctx)
+			require.Nil(it, err)
+			for _, req := range tc.Requests {
+				require.Nil(it, stream.Send(req))
+			}
+			require.Nil(it, stream.CloseSend())
+
+			var results []*pb.BatchItemResult
+			var summary *pb.BatchSummary
+			code := codes.OK
+			for {
+				res, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					code = status.Code(err)
+					break
+				}
+				if item := res.GetItem(); item != nil {
+					results = append(results, item)
+				} else {
+					summary = res.GetSummary()
+				}
+			}
+
+			require.Equal(it, tc.Err, code)
+			require.Len(it, results, len(tc.Codes))
+			for i, result := range results {
+				require.Equal(it, uint32(i), result.Index)
+				require.Equal(it, tc.Codes[i], codes.Code(result.Code), result.Message)
+				if tc.Codes[i] == codes.OK {
+					require.NotEmpty(it, result.Id)
+				}
+			}
+			if tc.Summary != nil {
+				require.NotNil(it, summary)
+				require.True(it, proto.Equal(tc.Summary, summary), summary.String())
+			}
+			require.Equal(it, before+tc.Saved, saver.Size())
+		})
+	}
+
+	// 发送一台手机之后等待它的结果再发送下一台
+	t.Run("interactive", func(it *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		stream, err := client.BatchCreateCellphones(ctx)
+		require.Nil(it, err)
+		for i := 0; i < 3; i++ {
+			require.Nil(it, stream.Send(item(sample.NewCellphone())))
+			res, err := stream.Recv()
+			require.Nil(it, err)
+			require.Equal(it, uint32(i), res.GetItem().GetIndex())
+			require.Equal(it, int32(codes.OK), res.GetItem().GetCode())
+		}
+		require.Nil(it, stream.CloseSend())
+		res, err := stream.Recv()
+		require.Nil(it, err)
+		require.Equal(it, uint32(3), res.GetSummary().GetCreated())
+	})
+}
+
+func TestCellphoneServiceImplGetCellphone(t *testing.T) {
+	t.Parallel()
+
+	server, listener := runTestCellphoneServiceServer(t)
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cellphone := sample.NewCellphone()
+	_, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: cellphone})
+	require.Nil(t, err)
+
+	testCases := []struct {
+		Name   string
+		Id     string
+		Reason pb.ErrorReason
+	}{
+		{Name: "found", Id: cellphone.Id},
+		{Name: "invalid-uuid", Id: "invalid-uuid", Reason: pb.ErrorReason_INVALID_UUID},
+		{Name: "not-found", Id: "dce5fc07-d7d1-49fe-aaef-183aa779fce2", Reason: pb.ErrorReason_CELLPHONE_NOT_FOUND},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(tt *testing.T) {
+			got, err := client.GetCellphone(ctx, &pb.GetCellphoneRequest{Id: tc.Id})
+			if tc.Reason != pb.ErrorReason_ERROR_REASON_UNSPECIFIED {
+				require.Equal(tt, tc.Reason, rpcerr.ReasonOf(err))
+				return
+			}
+			require.Nil(tt, err)
+			require.True(tt, proto.Equal(cellphone, got))
+		})
+	}
+}
+
+func TestCellphoneServiceImplDownloadCellphoneCover(t *testing.T) {
+	t.Parallel()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	coverPath := t.TempDir()
+	server := grpc.NewServer()
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(service.WithCoverPath(coverPath)))
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	withCover, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+	withoutCover, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+
+	// 上传封面
+	image, err := os.ReadFile("../../image/client/apple.jpeg")
+	require.Nil(t, err)
+	upload, err := client.UploadCellphoneCover(ctx)
+	require.Nil(t, err)
+	require.Nil(t, upload.Send(&pb.UploadCellphoneCoverRequest{
+		Data: &pb.UploadCellphoneCoverRequest_Meta{
+			Meta: &pb.CoverMetaInfo{Id: withCover.Id, Size: uint32(len(image)), ImageType: ".jpeg"},
+		},
+	}))
+	for block := image; len(block) > 0; block = block[min(len(block), 4096):] {
+		require.Nil(t, upload.Send(&pb.UploadCellphoneCoverRequest{
+			Data: &pb.UploadCellphoneCoverRequest_Block{Block: block[:min(len(block), 4096)]},
+		}))
+	}
+	_, err = upload.CloseAndRecv()
+	require.Nil(t, err)
+
+	download := func(id string) (*pb.CoverMetaInfo, []byte, error) {
+		stream, err := client.DownloadCellphoneCover(ctx, &pb.DownloadCellphoneCoverRequest{Id: id})
+		require.Nil(t, err)
+		res, err := stream.Recv()
+		if err != nil {
+			return nil, nil, err
+		}
+		meta := res.GetMeta()
+		var data []byte
+		for {
+			res, err := stream.Recv()
+			if err == io.EOF {
+				return meta, data, nil
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			data = append(data, res.GetBlock()...)
+		}
+	}
+
+	meta, data, err := download(withCover.Id)
+	require.Nil(t, err)
+	require.Equal(t, withCover.Id, meta.Id)
+	require.Equal(t, ".jpeg", meta.ImageType)
+	require.EqualValues(t, len(image), meta.Size)
+	require.Equal(t, image, data)
+
+	// 手机存在但是没有封面
+	_, _, err = download(withoutCover.Id)
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, pb.ErrorReason_COVER_NOT_FOUND, rpcerr.ReasonOf(err))
+
+	// 手机不存在
+	_, _, err = download("dce5fc07-d7d1-49fe-aaef-183aa779fce2")
+	require.Equal(t, pb.ErrorReason_CELLPHONE_NOT_FOUND, rpcerr.ReasonOf(err))
+}
+
+func TestCellphoneServiceImplListOrders(t *testing.T) {
+	t.Parallel()
+
+	server, listener := runTestCellphoneServiceServer(t)
+	go server.Serve(listener)
+	defer server.GracefulStop()
+
+	client, conn := makeTestCellphoneServiceClient(t, listener.Addr().String())
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		res, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+		require.Nil(t, err)
+		ids = append(ids, res.Id)
+	}
+
+	// 第三台手机没有订单
+	buy, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	for _, req := range []*pb.BuyCellphoneRequest{
+		{Id: ids[0], Price: 1000},
+		{Id: ids[1], Price: 2000},
+		{Id: ids[0], Price: 3000},
+	} {
+		require.Nil(t, buy.Send(req))
+		_, err := buy.Recv()
+		require.Nil(t, err)
+	}
+	require.Nil(t, buy.CloseSend())
+
+	byId := func(orders []*pb.Order) map[string]*pb.Order {
+		m := make(map[string]*pb.Order)
+		for i, o := range orders {
+			// 按照id排序
+			if i > 0 {
+				require.Less(t, orders[i-1].Id, o.Id)
+			}
+			m[o.Id] = o
+		}
+		return m
+	}
+
+	res, err := client.ListOrders(ctx, &pb.ListOrdersRequest{})
+	require.Nil(t, err)
+	orders := byId(res.Orders)
+	require.Len(t, orders, 2)
+	require.True(t, proto.Equal(&pb.Order{Id: ids[0], Count: 2, Total: 4000, Avg: 2000}, orders[ids[0]]))
+	require.True(t, proto.Equal(&pb.Order{Id: ids[1], Count: 1, Total: 2000, Avg: 2000}, orders[ids[1]]))
+
+	// 只查询指定的手机，重复的id只返回一次
+	res, err = client.ListOrders(ctx, &pb.ListOrdersRequest{Ids: []string{ids[1], ids[2], ids[1]}})
+	require.Nil(t, err)
+	require.Len(t, res.Orders, 1)
+	require.Equal(t, ids[1], res.Orders[0].Id)
+
+	_, err = client.ListOrders(ctx, &pb.ListOrdersRequest{Ids: []string{"invalid-uuid"}})
+	require.Equal(t, pb.ErrorReason_INVALID_UUID, rpcerr.ReasonOf(err))
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const (
+	// 默认保留最近多少条变化，用于断线重连后的补发
+	DefaultChangeLogRetention = 1024
+	// 每个订阅者最多积压多少条还没有处理的变化
+	watcherBufferSize = 128
+)
+
+var (
+	ErrRevisionCompacted = errors.New("requested revision has been compacted")
+	ErrWatcherTooSlow    = errors.New("watcher can not keep up with changes")
+)
+
+// since之后的变化已经被清理，errors.Is(err, ErrRevisionCompacted)为true
+type CompactedError struct {
+	Since uint64
+	// 还保留着的最早的revision
+	Oldest uint64
+}
+
+func (e *CompactedError) Error() string {
+	return fmt.Sprintf("revision %d has been compacted, oldest available revision is %d", e.Since, e.Oldest)
+}
+
+func (e *CompactedError) Is(target error) bool {
+	return target == ErrRevisionCompacted
+}
+
+// 一次变化
+type Change struct {
+	Event *pb.CellphoneEvent
+	// 变化之前的手机信息，CREATED时为nil
+	Prev *pb.Cellphone
+}
+
+// 记录手机信息的每一次变化，并推送给订阅者
+// 每次变化都有一个单调递增的revision，只保留最近的若干条变化
+type ChangeLog struct {
+	mu        sync.Mutex
+	revision  uint64
+	retention int
+	// 按照revision从小到大排列
+	history  []*Change
+	watchers map[*Watcher]struct{}
+}
+
+func NewChangeLog(retention int) *ChangeLog {
+	if retention <= 0 {
+		retention = DefaultChangeLogRetention
+	}
+	return &ChangeLog{
+		retention: retention,
+		watchers:  make(map[*Watcher]struct{}),
+	}
+}
+
+// 当前的revision，也就是最近一次变化的revision
+func (l *ChangeLog) Revision() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.revision
+}
+
+// 记录一次变化并返回它的revision
+// cellphone和prev会被保存下来，调用方之后不能再修改它们
+func (l *ChangeLog) Append(typ pb.CellphoneEvent_Type, cellphone, prev *pb.Cellphone) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.revision++
+	change := &Change{
+		Event: &pb.CellphoneEvent{Type: typ, Revision: l.revision, Cellphone: cellphone},
+		Prev:  prev,
+	}
+	l.history = append(l.history, change)
+	if len(l.history) > l.retention {
+		l.history = l.history[len(l.history)-l.retention:]
+	}
+
+	for w := range l.watchers {
+		select {
+		case w.ch <- change:
+		default:
+			// 订阅者积压了太多变化，断开它，由客户端重新订阅
+			w.err = ErrWatcherTooSlow
+			close(w.ch)
+			delete(l.watchers, w)
+		}
+	}
+	return l.revision
+}
+
+// 订阅since之后的变化，since为0时只订阅之后发生的变化
+// since之后的变化已经被清理时返回*CompactedError
+func (l *ChangeLog) Watch(since uint64) (*Watcher, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var replay []*Change
+	if since != 0 {
+		oldest := l.revision + 1
+		if len(l.history) != 0 {
+			oldest = l.history[0].Event.Revision
+		}
+		// since比当前revision还大说明服务端重启过，之前的revision已经没有意义
+		if since+1 < oldest || since > l.revision {
+			return nil, &CompactedError{Since: since, Oldest: oldest}
+		}
+		replay = l.history[len(l.history)-int(l.revision-since):]
+	}
+
+	w := &Watcher{
+		log:      l,
+		revision: l.revision,
+		ch:       make(chan *Change, len(replay)+watcherBufferSize),
+	}
+	for _, change := range replay {
+		w.ch <- change
+	}
+	l.watchers[w] = struct{}{}
+	return w, nil
+}
+
+// 订阅者
+type Watcher struct {
+	log *ChangeLog
+	// 开始订阅时的revision
+	revision uint64
+	ch       chan *Change
+	// 被动断开的原因，ch关闭之后才可以读取
+	err error
+}
+
+// 开始订阅时的revision，之后收到的变化的revision都比它大（补发的除外）
+func (w *Watcher) Revision() uint64 {
+	return w.revision
+}
+
+// 按照revision从小到大的顺序收到的变化
+// 被断开时channel会被关闭，此时可以通过Err获取原因
+func (w *Watcher) Changes() <-chan *Change {
+	return w.ch
+}
+
+// 被断开的原因，主动调用Close时为nil
+func (w *Watcher) Err() error {
+	w.log.mu.Lock()
+	defer w.log.mu.Unlock()
+	return w.err
+}
+
+// 取消订阅
+func (w *Watcher) Close() {
+	w.log.mu.Lock()
+	defer w.log.mu.Unlock()
+	if _, ok := w.log.watchers[w]; ok {
+		delete(w.log.watchers, w)
+		close(w.ch)
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 不再使用的封面文件的原因
+const (
+	// 封面对应的手机已经不存在
+	OrphanNoCellphone = "no_cellphone"
+	// 同一台手机有更新的封面，findCover不会再使用这个文件
+	OrphanSuperseded = "superseded"
+)
+
+// 不再使用的封面文件
+type OrphanCover struct {
+	Path   string
+	Size   int64
+	Reason string
+}
+
+// 找出coverPath中不再使用的封面文件，按照文件名排序
+// 封面文件名为手机id加上图片类型，文件名不是uuid的文件不是封面，不会被列出
+func OrphanCovers(coverPath string, saver CellphoneSaver) ([]*OrphanCover, error) {
+	entries, err := os.ReadDir(coverPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 每台手机正在使用的封面和它的修改时间
+	type inUse struct {
+		cover   *OrphanCover
+		modTime time.Time
+	}
+	latest := make(map[string]inUse)
+	var res []*OrphanCover
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		id := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if _, err := uuid.Parse(id); err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// 刚被删除的文件
+			continue
+		}
+		cover := &OrphanCover{Path: filepath.Join(coverPath, entry.Name()), Size: info.Size()}
+		if !saver.Exists(id) {
+			cover.Reason = OrphanNoCellphone
+			res = append(res, cover)
+			continue
+		}
+		// 和findCover一样按照修改时间选出最新的一张
+		prev, ok := latest[id]
+		if !ok {
+			latest[id] = inUse{cover, info.ModTime()}
+			continue
+		}
+		if info.ModTime().After(prev.modTime) {
+			latest[id], cover = inUse{cover, info.ModTime()}, prev.cover
+		}
+		cover.Reason = OrphanSuperseded
+		res = append(res, cover)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res, nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/catalog"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 把cellphone信息保存在文件中，文件的格式由扩展名决定（.ndjson, .csv, .binpb）
+// 数据同时保存在内存中，每次修改之后把全部数据重新写入文件，适合数据量不大的场景，
+// 比如准备测试环境或者离线导入导出；批量保存时使用SaveAll或者SaveEach，只写入一次
+// 写文件失败时内存中的修改会被撤销，也不会通知Watch
+type FileCellphoneSaver struct {
+	*InMemoryCellphoneSaver
+	filename string
+	format   catalog.Format
+}
+
+// 文件不存在时从空的数据开始，第一次修改时创建文件
+func NewFileCellphoneSaver(filename string) (*FileCellphoneSaver, error) {
+	format, err := catalog.FormatFromFilename(filename)
+	if err != nil {
+		return nil, err
+	}
+	s := &FileCellphoneSaver{
+		InMemoryCellphoneSaver: NewInMemoryCellphoneSaver(),
+		filename:               filename,
+		format:                 format,
+	}
+	// 在InMemoryCellphoneSaver的写锁中调用
+	s.persist = s.flush
+
+	f, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := catalog.NewReader(f, format)
+	if err != nil {
+		return nil, err
+	}
+	cellphones, err := catalog.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("can not load cellphones from %s: %w", filename, err)
+	}
+	// 加载已有的数据不算作变化
+	for _, cellphone := range cellphones {
+		if _, ok := s.storage[cellphone.Id]; ok {
+			return nil, fmt.Errorf("can not load cellphones from %s: %w: %s", filename, ErrAlreadyExist, cellphone.Id)
+		}
+		s.storage[cellphone.Id] = cellphone
+	}
+	return s, nil
+}
+
+func (s *FileCellphoneSaver) Filename() string {
+	return s.filename
+}
+
+// 按照内存中的数据重新写入文件，并删除写文件时崩溃而残留的临时文件
+func (s *FileCellphoneSaver) Compact() error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+	stale, err := filepath.Glob(s.filename + ".*.tmp")
+	if err != nil {
+		return err
+	}
+	for _, tmp := range stale {
+		if err := os.Remove(tmp); err != nil {
+			return fmt.Errorf("can not remove temporary file: %w", err)
+		}
+	}
+	return nil
+}
+
+// 按照id的顺序把全部数据写入临时文件，再替换原来的文件
+// 调用方需要持有写锁，所以最后一次写入的一定是最新的数据
+func (s *FileCellphoneSaver) flush() error {
+	cellphones := make([]*pb.Cellphone, 0, len(s.storage))
+	for _, cellphone := range s.storage {
+		cellphones = append(cellphones, cellphone)
+	}
+	sort.Slice(cellphones, func(i, j int) bool { return cellphones[i].Id < cellphones[j].Id })
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.filename), filepath.Base(s.filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("can not save cellphones into file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w, err := catalog.NewWriter(tmp, s.format)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := catalog.WriteAll(w, cellphones); err != nil {
+		tmp.Close()
+		return fmt.Errorf("can not save cellphones into file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("can not save cellphones into file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.filename); err != nil {
+		return fmt.Errorf("can not save cellphones into file: %w", err)
+	}
+	return nil
+}
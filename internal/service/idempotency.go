@@ -0,0 +1,155 @@
+package service
+
+// 购买请求的幂等键
+// 客户端重试BuyCellphone时会重新发送相同的请求，相同的幂等键只下单一次，之后直接返回第一次的结果
+// 开启认证时每个调用方的幂等键互不影响
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const (
+	// 默认最多记录的幂等键数量，超过之后淘汰最早的
+	DefaultIdempotencyKeys = 10000
+	// 默认的幂等键有效时间
+	DefaultIdempotencyKeyTTL = 24 * time.Hour
+)
+
+// gRPC客户端重试时携带的header，值为之前尝试过的次数
+const PreviousAttemptsMetadataKey = "grpc-previous-rpc-attempts"
+
+// 返回之前尝试过的次数，不是重试时返回空字符串
+func previousAttempts(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(PreviousAttemptsMetadataKey); len(values) > 0 && values[0] != "0" {
+		return values[0]
+	}
+	return ""
+}
+
+// 开启认证时subject为调用方，否则为空
+type idempotencyKey struct {
+	subject string
+	key     string
+}
+
+type idempotentBuy struct {
+	key      idempotencyKey
+	id       string
+	price    float64
+	res      *pb.BuyCellphoneResponse
+	expireAt time.Time
+}
+
+// 正在执行的购买，相同key的请求等待它完成
+type inflightBuy struct {
+	id    string
+	price float64
+	done  chan struct{}
+	// done关闭之后才可以读取
+	res *pb.BuyCellphoneResponse
+	err error
+}
+
+type idempotencyKeys struct {
+	capacity int
+	ttl      time.Duration
+
+	// 只保护下面的map和queue，执行购买时不持有
+	mu       sync.Mutex
+	entries  map[idempotencyKey]*idempotentBuy
+	inflight map[idempotencyKey]*inflightBuy
+	// 按照记录的时间排列，有效时间都相同，所以越靠前越早过期
+	queue []*idempotentBuy
+}
+
+func newIdempotencyKeys(capacity int, ttl time.Duration) *idempotencyKeys {
+	return &idempotencyKeys{
+		capacity: max(1, capacity),
+		ttl:      ttl,
+		entries:  make(map[idempotencyKey]*idempotentBuy),
+		inflight: make(map[idempotencyKey]*inflightBuy),
+	}
+}
+
+// 相同的key只执行一次buy，之后返回第一次成功的结果，replayed表示结果是之前记录的
+// 并发的重试等待正在执行的buy，如果它失败了再重新执行，不同的key互不阻塞
+func (k *idempotencyKeys) do(ctx context.Context, key, id string, price float64,
+	buy func() (*pb.BuyCellphoneResponse, error)) (res *pb.BuyCellphoneResponse, replayed bool, err error) {
+
+	scoped := idempotencyKey{key: key}
+	if p, ok := auth.FromContext(ctx); ok {
+		scoped.subject = p.Subject
+	}
+
+	for {
+		k.mu.Lock()
+		k.evict(time.Now())
+		if entry, ok := k.entries[scoped]; ok {
+			k.mu.Unlock()
+			if entry.id != id || entry.price != price {
+				return nil, false, rpcerr.IdempotencyKeyReused(key)
+			}
+			return entry.res, true, nil
+		}
+		call, ok := k.inflight[scoped]
+		if !ok {
+			break
+		}
+		k.mu.Unlock()
+
+		if call.id != id || call.price != price {
+			return nil, false, rpcerr.IdempotencyKeyReused(key)
+		}
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, false, CheckContext(ctx)
+		}
+		if call.err == nil {
+			return call.res, true, nil
+		}
+	}
+
+	call := &inflightBuy{id: id, price: price, done: make(chan struct{})}
+	k.inflight[scoped] = call
+	k.mu.Unlock()
+
+	call.res, call.err = buy()
+
+	k.mu.Lock()
+	delete(k.inflight, scoped)
+	// 失败的请求不记录，重试时可以再次执行
+	if call.err == nil {
+		entry := &idempotentBuy{key: scoped, id: id, price: price, res: call.res, expireAt: time.Now().Add(k.ttl)}
+		k.entries[scoped] = entry
+		k.queue = append(k.queue, entry)
+	}
+	k.mu.Unlock()
+	close(call.done)
+
+	if call.err != nil {
+		return nil, false, call.err
+	}
+	return call.res, false, nil
+}
+
+// 淘汰过期的和超出数量的记录
+func (k *idempotencyKeys) evict(now time.Time) {
+	n := 0
+	for n < len(k.queue) && (len(k.queue)-n >= k.capacity || !now.Before(k.queue[n].expireAt)) {
+		delete(k.entries, k.queue[n].key)
+		n++
+	}
+	if n > 0 {
+		k.queue = append(k.queue[:0:0], k.queue[n:]...)
+	}
+}
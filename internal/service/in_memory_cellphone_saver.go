@@ -6,23 +6,37 @@ import (
 	"sync"
 
 	"github.com/jinzhu/copier"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/ryanreadbooks/go-grpc-example/pb"
 )
 
 var (
 	ErrAlreadyExist = fmt.Errorf("cellphone uuid exists")
+	ErrNotFound     = fmt.Errorf("cellphone not found")
 )
 
 // 将cellphone信息保存在内存中
 type InMemoryCellphoneSaver struct {
 	sync.RWMutex
 	storage map[string]*pb.Cellphone
+	changes *ChangeLog
+	// 在写锁中修改storage之后、记录变化之前调用，返回错误时撤销这次修改
+	// FileCellphoneSaver用它把数据写入文件
+	persist func() error
+}
+
+func (s *InMemoryCellphoneSaver) commit() error {
+	if s.persist == nil {
+		return nil
+	}
+	return s.persist()
 }
 
 func NewInMemoryCellphoneSaver() *InMemoryCellphoneSaver {
 	return &InMemoryCellphoneSaver{
 		storage: make(map[string]*pb.Cellphone),
+		changes: NewChangeLog(DefaultChangeLogRetention),
 	}
 }
 
@@ -46,10 +60,139 @@ func (s *InMemoryCellphoneSaver) Save(ctx context.Context, cellphone *pb.Cellpho
 	}
 
 	s.storage[cellphone.Id] = &copiedCellphone
+	if err := s.commit(); err != nil {
+		delete(s.storage, cellphone.Id)
+		return err
+	}
+	// 在锁内记录变化，保证revision的顺序和修改的顺序一致
+	s.changes.Append(pb.CellphoneEvent_CREATED, proto.Clone(&copiedCellphone).(*pb.Cellphone), nil)
 
 	return nil
 }
 
+func (s *InMemoryCellphoneSaver) SaveAll(ctx context.Context, cellphones []*pb.Cellphone) error {
+	s.Lock()
+	defer s.Unlock()
+
+	// 先检查所有的id，有冲突时不做任何修改
+	ids := make(map[string]bool, len(cellphones))
+	for _, cellphone := range cellphones {
+		if _, ok := s.storage[cellphone.Id]; ok || ids[cellphone.Id] {
+			return fmt.Errorf("%w: %s", ErrAlreadyExist, cellphone.Id)
+		}
+		ids[cellphone.Id] = true
+	}
+
+	copies := make([]*pb.Cellphone, len(cellphones))
+	for i, cellphone := range cellphones {
+		copies[i] = proto.Clone(cellphone).(*pb.Cellphone)
+		s.storage[cellphone.Id] = copies[i]
+	}
+	if err := s.commit(); err != nil {
+		for _, cellphone := range cellphones {
+			delete(s.storage, cellphone.Id)
+		}
+		return err
+	}
+	for _, copied := range copies {
+		s.changes.Append(pb.CellphoneEvent_CREATED, proto.Clone(copied).(*pb.Cellphone), nil)
+	}
+	return nil
+}
+
+func (s *InMemoryCellphoneSaver) SaveEach(ctx context.Context, cellphones []*pb.Cellphone) []error {
+	s.Lock()
+	defer s.Unlock()
+
+	errs := make([]error, len(cellphones))
+	var saved []*pb.Cellphone
+	for i, cellphone := range cellphones {
+		// 和前面的手机id相同时，前面的已经在storage中了
+		if _, ok := s.storage[cellphone.Id]; ok {
+			errs[i] = ErrAlreadyExist
+			continue
+		}
+		copied := proto.Clone(cellphone).(*pb.Cellphone)
+		s.storage[cellphone.Id] = copied
+		saved = append(saved, copied)
+	}
+	if len(saved) == 0 {
+		return errs
+	}
+	if err := s.commit(); err != nil {
+		for i, cellphone := range cellphones {
+			if errs[i] == nil {
+				delete(s.storage, cellphone.Id)
+				errs[i] = err
+			}
+		}
+		return errs
+	}
+	for _, copied := range saved {
+		s.changes.Append(pb.CellphoneEvent_CREATED, proto.Clone(copied).(*pb.Cellphone), nil)
+	}
+	return errs
+}
+
+func (s *InMemoryCellphoneSaver) Get(id string) (*pb.Cellphone, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	cellphone, ok := s.storage[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return proto.Clone(cellphone).(*pb.Cellphone), nil
+}
+
+func (s *InMemoryCellphoneSaver) Update(ctx context.Context, cellphone *pb.Cellphone) error {
+	s.Lock()
+	defer s.Unlock()
+
+	prev, ok := s.storage[cellphone.Id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	updated := proto.Clone(cellphone).(*pb.Cellphone)
+	s.storage[cellphone.Id] = updated
+	if err := s.commit(); err != nil {
+		s.storage[cellphone.Id] = prev
+		return err
+	}
+	s.changes.Append(pb.CellphoneEvent_UPDATED, proto.Clone(updated).(*pb.Cellphone), prev)
+
+	return nil
+}
+
+func (s *InMemoryCellphoneSaver) Delete(ctx context.Context, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	prev, ok := s.storage[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(s.storage, id)
+	if err := s.commit(); err != nil {
+		s.storage[id] = prev
+		return err
+	}
+	// 删除事件中携带删除前的手机信息
+	s.changes.Append(pb.CellphoneEvent_DELETED, proto.Clone(prev).(*pb.Cellphone), prev)
+
+	return nil
+}
+
+func (s *InMemoryCellphoneSaver) Watch(since uint64) (*Watcher, error) {
+	return s.changes.Watch(since)
+}
+
+func (s *InMemoryCellphoneSaver) Revision() uint64 {
+	return s.changes.Revision()
+}
+
 func (s *InMemoryCellphoneSaver) Size() int32 {
 	s.RLock()
 	defer s.RUnlock()
@@ -85,17 +228,22 @@ func (s *InMemoryCellphoneSaver) Search(condition *pb.FilterCondition) []*pb.Cel
 }
 
 // 检查cellphone是否符合条件condition
+// 使用getter访问嵌套字段，即使字段为nil也不会panic
+// condition为nil时不做任何过滤
 func conditionSatisfied(condition *pb.FilterCondition, cellphone *pb.Cellphone) bool {
-	if condition.MinCpuCore > cellphone.Cpu.Cores {
+	if condition == nil {
+		return true
+	}
+	if condition.MinCpuCore > cellphone.GetCpu().GetCores() {
 		return false
 	}
-	if condition.MinBatteryCapacity > cellphone.Battery.Capacity {
+	if condition.MinBatteryCapacity > cellphone.GetBattery().GetCapacity() {
 		return false
 	}
-	if condition.MinRamSize > cellphone.Ram.Value {
+	if condition.MinRamSize > cellphone.GetRam().GetValue() {
 		return false
 	}
-	if condition.MinStorageSize > cellphone.Storage.Value {
+	if condition.MinStorageSize > cellphone.GetStorage().GetValue() {
 		return false
 	}
 
@@ -2,10 +2,13 @@ package service_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
 	"github.com/ryanreadbooks/go-grpc-example/internal/service"
@@ -211,3 +214,227 @@ func TestInMemoryCellphoneSaverSearch(t *testing.T) {
 		})
 	}
 }
+
+func TestInMemoryCellphoneSaverUpdateDelete(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	saver := service.NewInMemoryCellphoneSaver()
+	cellphone := sample.NewCellphone()
+
+	// 不存在时不能更新和删除
+	require.ErrorIs(t, saver.Update(ctx, cellphone), service.ErrNotFound)
+	require.ErrorIs(t, saver.Delete(ctx, cellphone.Id), service.ErrNotFound)
+	_, err := saver.Get(cellphone.Id)
+	require.ErrorIs(t, err, service.ErrNotFound)
+
+	require.Nil(t, saver.Save(ctx, cellphone))
+	updated := proto.Clone(cellphone).(*pb.Cellphone)
+	updated.Brand = "updated-brand"
+	require.Nil(t, saver.Update(ctx, updated))
+
+	// 修改传入的参数不会影响保存的内容
+	updated.Brand = "modified-after-update"
+	got, err := saver.Get(cellphone.Id)
+	require.Nil(t, err)
+	require.Equal(t, "updated-brand", got.Brand)
+
+	require.Nil(t, saver.Delete(ctx, cellphone.Id))
+	require.False(t, saver.Exists(cellphone.Id))
+	require.Equal(t, uint64(3), saver.Revision())
+}
+
+func TestChangeLogWatch(t *testing.T) {
+	t.Parallel()
+
+	cellphone := sample.NewCellphone()
+	changes := service.NewChangeLog(3)
+	for i := 0; i < 5; i++ {
+		changes.Append(pb.CellphoneEvent_UPDATED, cellphone, cellphone)
+	}
+	require.Equal(t, uint64(5), changes.Revision())
+
+	var testCases = []struct {
+		Name      string
+		Since     uint64
+		Revisions []uint64
+		Err       error
+	}{
+		{Name: "only-new-changes", Since: 0, Revisions: nil},
+		{Name: "up-to-date", Since: 5, Revisions: nil},
+		{Name: "replay-all-retained", Since: 2, Revisions: []uint64{3, 4, 5}},
+		{Name: "replay-some", Since: 4, Revisions: []uint64{5}},
+		{Name: "compacted", Since: 1, Err: service.ErrRevisionCompacted},
+		{Name: "from-future", Since: 6, Err: service.ErrRevisionCompacted},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(it *testing.T) {
+			watcher, err := changes.Watch(tc.Since)
+			require.ErrorIs(it, err, tc.Err)
+			if tc.Err != nil {
+				var compacted *service.CompactedError
+				require.ErrorAs(it, err, &compacted)
+				require.Equal(it, uint64(3), compacted.Oldest)
+				return
+			}
+			defer watcher.Close()
+			require.Equal(it, uint64(5), watcher.Revision())
+
+			var revisions []uint64
+			for len(revisions) < len(tc.Revisions) {
+				revisions = append(revisions, (<-watcher.Changes()).Event.Revision)
+			}
+			require.Equal(it, tc.Revisions, revisions)
+			require.Len(it, watcher.Changes(), 0)
+		})
+	}
+}
+
+func TestChangeLogSlowWatcher(t *testing.T) {
+	t.Parallel()
+
+	changes := service.NewChangeLog(0)
+	slow, err := changes.Watch(0)
+	require.Nil(t, err)
+	fast, err := changes.Watch(0)
+	require.Nil(t, err)
+	defer fast.Close()
+
+	// 慢的订阅者积压太多之后被断开，不会阻塞其它订阅者
+	cellphone := sample.NewCellphone()
+	for i := 0; i < 1000; i++ {
+		changes.Append(pb.CellphoneEvent_CREATED, cellphone, nil)
+		<-fast.Changes()
+	}
+	for range slow.Changes() {
+	}
+	require.ErrorIs(t, slow.Err(), service.ErrWatcherTooSlow)
+	// 重复关闭不会panic
+	slow.Close()
+
+	fast.Close()
+	_, ok := <-fast.Changes()
+	require.False(t, ok)
+	require.Nil(t, fast.Err())
+}
+
+func TestInMemoryCellphoneSaverSaveAll(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	saver := service.NewInMemoryCellphoneSaver()
+	existing := sample.NewCellphone()
+	require.Nil(t, saver.Save(ctx, existing))
+
+	// 有一台冲突时一台都不保存
+	fresh := sample.NewCellphone()
+	err := saver.SaveAll(ctx, []*pb.Cellphone{fresh, existing})
+	require.ErrorIs(t, err, service.ErrAlreadyExist)
+	require.False(t, saver.Exists(fresh.Id))
+	err = saver.SaveAll(ctx, []*pb.Cellphone{fresh, fresh})
+	require.ErrorIs(t, err, service.ErrAlreadyExist)
+	require.Equal(t, int32(1), saver.Size())
+	require.Equal(t, uint64(1), saver.Revision())
+
+	require.Nil(t, saver.SaveAll(ctx, []*pb.Cellphone{fresh, sample.NewCellphone()}))
+	require.Equal(t, int32(3), saver.Size())
+	require.Equal(t, uint64(3), saver.Revision())
+}
+
+func TestInMemoryCellphoneSaverSaveEach(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	saver := service.NewInMemoryCellphoneSaver()
+	existing := sample.NewCellphone()
+	require.Nil(t, saver.Save(ctx, existing))
+
+	// 每一台单独判断，冲突的不影响其它的
+	c1, c2 := sample.NewCellphone(), sample.NewCellphone()
+	errs := saver.SaveEach(ctx, []*pb.Cellphone{c1, existing, c2, c1})
+	require.Len(t, errs, 4)
+	require.Nil(t, errs[0])
+	require.ErrorIs(t, errs[1], service.ErrAlreadyExist)
+	require.Nil(t, errs[2])
+	require.ErrorIs(t, errs[3], service.ErrAlreadyExist)
+	require.Equal(t, int32(3), saver.Size())
+	require.Equal(t, uint64(3), saver.Revision())
+
+	require.Empty(t, saver.SaveEach(ctx, nil))
+}
+
+func TestFileCellphoneSaver(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for _, ext := range []string{"ndjson", "csv", "binpb"} {
+		ext := ext
+		t.Run(ext, func(t *testing.T) {
+			t.Parallel()
+
+			filename := filepath.Join(t.TempDir(), "catalog."+ext)
+			saver, err := service.NewFileCellphoneSaver(filename)
+			require.NoError(t, err)
+			require.Equal(t, int32(0), saver.Size())
+
+			c1, c2, c3 := sample.NewCellphone(), sample.NewCellphone(), sample.NewCellphone()
+			require.NoError(t, saver.Save(ctx, c1))
+			require.NoError(t, saver.SaveAll(ctx, []*pb.Cellphone{c2, c3}))
+			require.NoError(t, saver.Delete(ctx, c3.Id))
+			updated := proto.Clone(c1).(*pb.Cellphone)
+			updated.Brand = c1.Brand + "-updated"
+			require.NoError(t, saver.Update(ctx, updated))
+
+			// 重新打开文件，内容和修改之后的一致
+			reopened, err := service.NewFileCellphoneSaver(filename)
+			require.NoError(t, err)
+			require.Equal(t, int32(2), reopened.Size())
+			got, err := reopened.Get(c1.Id)
+			require.NoError(t, err)
+			require.True(t, proto.Equal(updated, got))
+			got, err = reopened.Get(c2.Id)
+			require.NoError(t, err)
+			require.True(t, proto.Equal(c2, got))
+			require.False(t, reopened.Exists(c3.Id))
+			// 加载已有的数据不产生变化事件
+			require.Equal(t, uint64(0), reopened.Revision())
+		})
+	}
+}
+
+func TestFileCellphoneSaverRollback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	// 目录不存在时写文件失败
+	dir := filepath.Join(t.TempDir(), "catalog")
+	saver, err := service.NewFileCellphoneSaver(filepath.Join(dir, "catalog.ndjson"))
+	require.NoError(t, err)
+
+	c1, c2 := sample.NewCellphone(), sample.NewCellphone()
+	require.Error(t, saver.Save(ctx, c1))
+	require.Error(t, saver.SaveAll(ctx, []*pb.Cellphone{c1, c2}))
+	errs := saver.SaveEach(ctx, []*pb.Cellphone{c1, c2})
+	require.Error(t, errs[0])
+	require.Error(t, errs[1])
+	require.NotErrorIs(t, errs[0], service.ErrAlreadyExist)
+	// 内存中的修改被撤销，也没有产生变化事件
+	require.Equal(t, int32(0), saver.Size())
+	require.Equal(t, uint64(0), saver.Revision())
+
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	require.NoError(t, saver.Save(ctx, c1))
+	require.NoError(t, os.RemoveAll(dir))
+
+	updated := proto.Clone(c1).(*pb.Cellphone)
+	updated.Brand = c1.Brand + "-updated"
+	require.Error(t, saver.Update(ctx, updated))
+	got, err := saver.Get(c1.Id)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(c1, got))
+	require.Error(t, saver.Delete(ctx, c1.Id))
+	require.True(t, saver.Exists(c1.Id))
+	require.Equal(t, uint64(1), saver.Revision())
+}
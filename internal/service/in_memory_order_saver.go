@@ -1,6 +1,7 @@
 package service
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/jinzhu/copier"
@@ -25,7 +26,7 @@ func (s *InMemoryOrderSaver) Save(id string, price float64) error {
 		o.Count += 1
 		o.Total += price
 	} else {
-		s.data[id] = &Orders{Count: 1, Total: price}
+		s.data[id] = &Orders{Id: id, Count: 1, Total: price}
 	}
 	return nil
 }
@@ -42,3 +43,17 @@ func (s *InMemoryOrderSaver) Get(id string) *Orders {
 	}
 	return nil
 }
+
+func (s *InMemoryOrderSaver) List() []*Orders {
+	s.RLock()
+	defer s.RUnlock()
+
+	res := make([]*Orders, 0, len(s.data))
+	for _, o := range s.data {
+		var co Orders
+		copier.Copy(&co, o)
+		res = append(res, &co)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Id < res[j].Id })
+	return res
+}
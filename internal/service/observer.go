@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 观察cellphone服务中发生的业务事件
+// 比如用来统计指标或者通知其它系统
+type Observer interface {
+	// 新的手机信息保存成功
+	CellphoneCreated(ctx context.Context, cellphone *pb.Cellphone)
+	// 封面图片上传成功
+	CoverUploaded(ctx context.Context, id string, size uint32)
+	// 封面图片上传被拒绝，err为返回给客户端的错误
+	CoverRejected(ctx context.Context, id string, err error)
+	// 下单成功，orders为该手机下单后的汇总信息
+	OrderPlaced(ctx context.Context, id string, price float64, orders *Orders)
+}
+
+// 什么都不做的Observer，嵌入它之后只需要实现关心的方法
+type NopObserver struct{}
+
+func (NopObserver) CellphoneCreated(context.Context, *pb.Cellphone)       {}
+func (NopObserver) CoverUploaded(context.Context, string, uint32)         {}
+func (NopObserver) CoverRejected(context.Context, string, error)          {}
+func (NopObserver) OrderPlaced(context.Context, string, float64, *Orders) {}
+
+// 把事件分发给多个Observer
+type observers []Observer
+
+func (o observers) CellphoneCreated(ctx context.Context, cellphone *pb.Cellphone) {
+	for _, ob := range o {
+		ob.CellphoneCreated(ctx, cellphone)
+	}
+}
+
+func (o observers) CoverUploaded(ctx context.Context, id string, size uint32) {
+	for _, ob := range o {
+		ob.CoverUploaded(ctx, id, size)
+	}
+}
+
+func (o observers) CoverRejected(ctx context.Context, id string, err error) {
+	for _, ob := range o {
+		ob.CoverRejected(ctx, id, err)
+	}
+}
+
+func (o observers) OrderPlaced(ctx context.Context, id string, price float64, orders *Orders) {
+	for _, ob := range o {
+		ob.OrderPlaced(ctx, id, price, orders)
+	}
+}
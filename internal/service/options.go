@@ -0,0 +1,48 @@
+package service
+
+import "time"
+
+// 创建cellphone服务时的可选项
+type Option func(*cellphoneServiceServer)
+
+// 指定存放封面图片的目录
+func WithCoverPath(coverPath string) Option {
+	return func(c *cellphoneServiceServer) {
+		c.coverPath = coverPath
+	}
+}
+
+// 指定保存手机信息的方式
+func WithCellphoneSaver(saver CellphoneSaver) Option {
+	return func(c *cellphoneServiceServer) {
+		c.saver = saver
+	}
+}
+
+// 指定保存订单信息的方式
+func WithOrderSaver(orders OrderSaver) Option {
+	return func(c *cellphoneServiceServer) {
+		c.orders = orders
+	}
+}
+
+// 添加一个业务事件的观察者，可以添加多个
+func WithObserver(observer Observer) Option {
+	return func(c *cellphoneServiceServer) {
+		c.observer = append(c.observer, observer)
+	}
+}
+
+// 指定推送价格更新的方式，可以在多个服务之间共享
+func WithPriceTicker(prices *PriceTicker) Option {
+	return func(c *cellphoneServiceServer) {
+		c.prices = prices
+	}
+}
+
+// 指定最多记录多少个购买请求的幂等键，以及幂等键的有效时间
+func WithIdempotencyKeys(capacity int, ttl time.Duration) Option {
+	return func(c *cellphoneServiceServer) {
+		c.idempotency = newIdempotencyKeys(capacity, ttl)
+	}
+}
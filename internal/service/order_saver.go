@@ -3,6 +3,8 @@ package service
 type OrderSaver interface {
 	Save(string, float64) error
 	Get(string) *Orders
+	// 返回所有手机的订单统计，按照id排序
+	List() []*Orders
 }
 
 type Orders struct {
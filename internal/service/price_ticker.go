@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 每个订阅者最多积压多少台手机的价格更新，超过之后新手机的更新会被丢弃
+const DefaultMaxPendingPrices = 1024
+
+// 把下单事件转换成价格更新推送给订阅者
+// 推送不会阻塞下单：订阅者处理太慢时，同一台手机的更新只保留最新的一条，
+// 积压的手机数量太多时直接丢弃新的更新
+type PriceTicker struct {
+	NopObserver
+
+	mu         sync.Mutex
+	maxPending int
+	subs       map[*PriceSubscription]struct{}
+}
+
+var _ Observer = (*PriceTicker)(nil)
+
+func NewPriceTicker(maxPending int) *PriceTicker {
+	if maxPending <= 0 {
+		maxPending = DefaultMaxPendingPrices
+	}
+	return &PriceTicker{
+		maxPending: maxPending,
+		subs:       make(map[*PriceSubscription]struct{}),
+	}
+}
+
+// 实现Observer接口
+func (t *PriceTicker) OrderPlaced(ctx context.Context, id string, price float64, orders *Orders) {
+	if orders == nil || orders.Count == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for sub := range t.subs {
+		sub.offer(&pb.PriceUpdate{
+			Id:        id,
+			Count:     orders.Count,
+			Avg:       orders.Total / float64(orders.Count),
+			LastPrice: price,
+		})
+	}
+}
+
+// 订阅ids这些手机的价格更新，ids为空时订阅所有手机
+func (t *PriceTicker) Subscribe(ids []string) *PriceSubscription {
+	sub := &PriceSubscription{
+		ticker:     t,
+		maxPending: t.maxPending,
+		pending:    make(map[string]*pb.PriceUpdate),
+		notify:     make(chan struct{}, 1),
+	}
+	if len(ids) != 0 {
+		sub.ids = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			sub.ids[id] = true
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.subs[sub] = struct{}{}
+	return sub
+}
+
+// 一个价格订阅
+type PriceSubscription struct {
+	ticker     *PriceTicker
+	ids        map[string]bool
+	maxPending int
+	// 有新的更新时通知Next
+	notify chan struct{}
+
+	mu sync.Mutex
+	// 还没有被取走的更新，按照第一次到达的顺序排列
+	order   []string
+	pending map[string]*pb.PriceUpdate
+	dropped uint32
+}
+
+func (s *PriceSubscription) offer(update *pb.PriceUpdate) {
+	if s.ids != nil && !s.ids[update.Id] {
+		return
+	}
+
+	s.mu.Lock()
+	if prev, ok := s.pending[update.Id]; ok {
+		coalesced := prev.Coalesced + 1
+		// 并发下单时后到达的更新不一定更新，以订单数量为准
+		if prev.Count > update.Count {
+			update = prev
+		}
+		update.Coalesced = coalesced
+		s.pending[update.Id] = update
+	} else if len(s.pending) >= s.maxPending {
+		s.dropped++
+	} else {
+		s.order = append(s.order, update.Id)
+		s.pending[update.Id] = update
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// 取走所有积压的更新，没有更新时阻塞直到有新的更新或者ctx结束
+func (s *PriceSubscription) Next(ctx context.Context) ([]*pb.PriceUpdate, error) {
+	for {
+		if updates := s.take(); len(updates) != 0 {
+			return updates, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-s.notify:
+		}
+	}
+}
+
+func (s *PriceSubscription) take() []*pb.PriceUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.order) == 0 {
+		return nil
+	}
+	updates := make([]*pb.PriceUpdate, 0, len(s.order))
+	for _, id := range s.order {
+		update := s.pending[id]
+		update.Dropped = s.dropped
+		updates = append(updates, update)
+		delete(s.pending, id)
+	}
+	s.order = s.order[:0]
+	return updates
+}
+
+// 取消订阅
+func (s *PriceSubscription) Close() {
+	s.ticker.mu.Lock()
+	defer s.ticker.mu.Unlock()
+	delete(s.ticker.subs, s)
+}
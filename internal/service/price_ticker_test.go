@@ -0,0 +1,70 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+)
+
+func TestPriceTickerCoalesce(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	ticker := service.NewPriceTicker(2)
+	all := ticker.Subscribe(nil)
+	defer all.Close()
+	id1, id2, id3 := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	only := ticker.Subscribe([]string{id2})
+	defer only.Close()
+
+	// 订阅者没有及时处理，同一台手机的更新被合并，超过上限的手机被丢弃
+	ticker.OrderPlaced(ctx, id1, 100, &service.Orders{Count: 1, Total: 100})
+	ticker.OrderPlaced(ctx, id2, 200, &service.Orders{Count: 1, Total: 200})
+	ticker.OrderPlaced(ctx, id1, 300, &service.Orders{Count: 2, Total: 400})
+	ticker.OrderPlaced(ctx, id3, 500, &service.Orders{Count: 1, Total: 500})
+	// 并发下单时旧的汇总信息后到达，不会覆盖新的
+	ticker.OrderPlaced(ctx, id2, 220, &service.Orders{Count: 3, Total: 660})
+	ticker.OrderPlaced(ctx, id2, 240, &service.Orders{Count: 2, Total: 440})
+
+	updates, err := all.Next(ctx)
+	require.Nil(t, err)
+	require.Len(t, updates, 2)
+
+	require.Equal(t, id1, updates[0].Id)
+	require.Equal(t, uint32(2), updates[0].Count)
+	require.Equal(t, float64(200), updates[0].Avg)
+	require.Equal(t, float64(300), updates[0].LastPrice)
+	require.Equal(t, uint32(1), updates[0].Coalesced)
+	require.Equal(t, uint32(1), updates[0].Dropped)
+
+	require.Equal(t, id2, updates[1].Id)
+	require.Equal(t, uint32(3), updates[1].Count)
+	require.Equal(t, float64(220), updates[1].Avg)
+	require.Equal(t, uint32(2), updates[1].Coalesced)
+
+	// 只订阅了id2
+	updates, err = only.Next(ctx)
+	require.Nil(t, err)
+	require.Len(t, updates, 1)
+	require.Equal(t, id2, updates[0].Id)
+	require.Equal(t, uint32(0), updates[0].Dropped)
+
+	// 没有新的更新时阻塞直到ctx结束
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = all.Next(timeoutCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// 取消订阅之后不再收到更新
+	all.Close()
+	ticker.OrderPlaced(ctx, id1, 100, &service.Orders{Count: 3, Total: 500})
+	timeoutCtx, cancel = context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = all.Next(timeoutCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
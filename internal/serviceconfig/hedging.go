@@ -0,0 +1,381 @@
+package serviceconfig
+
+// 对冲：同时发起多次相同的调用，采用最先成功的结果，其余的调用被取消
+// 第一次调用之后每隔hedgingDelay发起一次新的调用，直到maxAttempts次；
+// 调用返回nonFatalStatusCodes中的错误时立即发起下一次调用，返回其它错误时放弃整个调用
+// 只支持unary和server streaming，server streaming以收到第一条响应或者正常结束作为成功
+// 和gRPC的流一样，server streaming的调用方需要取消ctx，或者一直接收到出错或io.EOF为止
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// 和gRPC的重试一样，告诉服务端之前已经发起过几次调用
+const previousAttemptsMetadataKey = "grpc-previous-rpc-attempts"
+
+// 一次尝试
+type attempt[T any] struct {
+	value  T
+	err    error
+	cancel context.CancelFunc
+}
+
+// 按照对冲策略执行run，返回被采用的尝试，其余的尝试都已经被取消
+// 被采用的尝试成功时，由调用方在用完之后调用它的cancel
+func hedge[T any](ctx context.Context, p *HedgingPolicy, run func(ctx context.Context) (T, error)) *attempt[T] {
+	// 足够容纳所有的结果，被取消的尝试结束时不会阻塞
+	results := make(chan *attempt[T], p.MaxAttempts)
+	var attempts []*attempt[T]
+	start := func() {
+		ctx, cancel := context.WithCancel(ctx)
+		if n := len(attempts); n > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, previousAttemptsMetadataKey, strconv.Itoa(n))
+		}
+		a := &attempt[T]{cancel: cancel}
+		attempts = append(attempts, a)
+		go func() {
+			a.value, a.err = run(ctx)
+			results <- a
+		}()
+	}
+
+	delay := time.Duration(p.HedgingDelay)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	resetTimer := func() {
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(delay)
+	}
+
+	start()
+	var last *attempt[T]
+	for pending := 1; pending > 0; {
+		select {
+		case <-timer.C:
+			if len(attempts) < p.MaxAttempts {
+				start()
+				pending++
+				timer.Reset(delay)
+			}
+		case a := <-results:
+			pending--
+			last = a
+			if a.err == nil || !p.nonFatal(status.Code(a.err)) {
+				for _, other := range attempts {
+					if other != a {
+						other.cancel()
+					}
+				}
+				return a
+			}
+			a.cancel()
+			if len(attempts) < p.MaxAttempts {
+				start()
+				pending++
+				resetTimer()
+			}
+		}
+	}
+	// 所有的尝试都返回了非致命的错误
+	return last
+}
+
+// Header、Trailer和Peer选项会在调用结束时写入调用方的变量，
+// 每次尝试写入自己的变量，最后只把被采用的尝试的结果写给调用方
+type callOutputs struct {
+	opts    []grpc.CallOption
+	header  *metadata.MD
+	trailer *metadata.MD
+	peer    *peer.Peer
+}
+
+func newCallOutputs(opts []grpc.CallOption) *callOutputs {
+	o := &callOutputs{}
+	for _, opt := range opts {
+		switch v := opt.(type) {
+		case grpc.HeaderCallOption:
+			o.header = v.HeaderAddr
+		case grpc.TrailerCallOption:
+			o.trailer = v.TrailerAddr
+		case grpc.PeerCallOption:
+			o.peer = v.PeerAddr
+		default:
+			o.opts = append(o.opts, opt)
+		}
+	}
+	return o
+}
+
+// 一次尝试使用的选项和写入的变量
+func (o *callOutputs) attempt() ([]grpc.CallOption, *callOutputs) {
+	out := &callOutputs{}
+	opts := append([]grpc.CallOption(nil), o.opts...)
+	if o.header != nil {
+		out.header = new(metadata.MD)
+		opts = append(opts, grpc.Header(out.header))
+	}
+	if o.trailer != nil {
+		out.trailer = new(metadata.MD)
+		opts = append(opts, grpc.Trailer(out.trailer))
+	}
+	if o.peer != nil {
+		out.peer = new(peer.Peer)
+		opts = append(opts, grpc.Peer(out.peer))
+	}
+	return opts, out
+}
+
+func (o *callOutputs) copyFrom(out *callOutputs) {
+	if out == nil {
+		return
+	}
+	if o.header != nil {
+		*o.header = *out.header
+	}
+	if o.trailer != nil {
+		*o.trailer = *out.trailer
+	}
+	if o.peer != nil {
+		*o.peer = *out.peer
+	}
+}
+
+// 对冲的调用使用方法配置中的超时时间
+func (mc *MethodConfig) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if mc.Timeout == nil || *mc.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(*mc.Timeout))
+}
+
+func (c *Config) unaryInterceptor(ctx context.Context, method string, req, reply any,
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	mc := c.methodConfig(method)
+	msg, ok := reply.(proto.Message)
+	if mc == nil || mc.HedgingPolicy == nil || !ok {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+
+	ctx, cancel := mc.withTimeout(ctx)
+	defer cancel()
+	outputs := newCallOutputs(opts)
+	type result struct {
+		reply proto.Message
+		out   *callOutputs
+	}
+	winner := hedge(ctx, mc.HedgingPolicy, func(ctx context.Context) (result, error) {
+		opts, out := outputs.attempt()
+		r := result{reply: msg.ProtoReflect().New().Interface(), out: out}
+		return r, invoker(ctx, method, req, r.reply, cc, opts...)
+	})
+	winner.cancel()
+	outputs.copyFrom(winner.value.out)
+	if winner.err != nil {
+		return winner.err
+	}
+	proto.Reset(msg)
+	proto.Merge(msg, winner.value.reply)
+	return nil
+}
+
+// 超时时间的ctx和被采用的尝试在流结束时释放，调用方不再接收时需要取消传入的ctx，
+// 否则它们会一直保留到传入的ctx结束，和grpc.ClientConn.NewStream的要求相同
+func (c *Config) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	mc := c.methodConfig(method)
+	if mc == nil || mc.HedgingPolicy == nil || desc.ClientStreams || !desc.ServerStreams {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	// 对冲时需要在调用方接收之前收到第一条响应，所以要知道响应的类型
+	response := responseType(method)
+	if response == nil {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+
+	ctx, cancel := mc.withTimeout(ctx)
+	return &hedgedStream{
+		ctx:       ctx,
+		cancel:    cancel,
+		policy:    mc.HedgingPolicy,
+		response:  response,
+		outputs:   newCallOutputs(opts),
+		committed: make(chan struct{}),
+		newStream: func(ctx context.Context, opts []grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(ctx, desc, cc, method, opts...)
+		},
+	}, nil
+}
+
+// 从注册的proto描述中找到方法的响应类型
+func responseType(fullMethod string) protoreflect.MessageType {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(md.Output().FullName())
+	if err != nil {
+		return nil
+	}
+	return mt
+}
+
+// server streaming的一次尝试，已经收到了第一条响应
+type streamAttempt struct {
+	stream grpc.ClientStream
+	first  proto.Message
+	// 流没有任何响应就正常结束了
+	eof bool
+	out *callOutputs
+}
+
+// 对冲的server streaming调用
+// 调用方发送请求之后开始对冲，第一次接收时等待被采用的尝试，之后的响应都来自它
+// ctx是调用方ctx的子ctx，调用方取消ctx时所有的尝试都会被取消
+type hedgedStream struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	policy    *HedgingPolicy
+	response  protoreflect.MessageType
+	outputs   *callOutputs
+	newStream func(ctx context.Context, opts []grpc.CallOption) (grpc.ClientStream, error)
+
+	req       any
+	startOnce sync.Once
+	// 选出被采用的尝试之后关闭
+	committed chan struct{}
+	winner    *attempt[*streamAttempt]
+
+	delivered bool
+	finished  error
+}
+
+func (h *hedgedStream) start() {
+	h.startOnce.Do(func() {
+		go func() {
+			defer close(h.committed)
+			h.winner = hedge(h.ctx, h.policy, h.run)
+		}()
+	})
+}
+
+func (h *hedgedStream) run(ctx context.Context) (*streamAttempt, error) {
+	if h.req == nil {
+		return &streamAttempt{}, status.Error(codes.Internal, "hedged stream started without a request")
+	}
+	opts, out := h.outputs.attempt()
+	stream, err := h.newStream(ctx, opts)
+	if err != nil {
+		return &streamAttempt{out: out}, err
+	}
+	a := &streamAttempt{stream: stream, first: h.response.New().Interface(), out: out}
+	// Send失败时具体的错误由RecvMsg返回
+	stream.SendMsg(h.req)
+	stream.CloseSend()
+	err = stream.RecvMsg(a.first)
+	if err == io.EOF {
+		a.eof, err = true, nil
+	}
+	return a, err
+}
+
+func (h *hedgedStream) SendMsg(m any) error {
+	if h.req != nil {
+		return status.Error(codes.Internal, "hedged streams accept only one request")
+	}
+	h.req = m
+	return nil
+}
+
+func (h *hedgedStream) CloseSend() error {
+	h.start()
+	return nil
+}
+
+func (h *hedgedStream) Header() (metadata.MD, error) {
+	h.start()
+	<-h.committed
+	if h.winner.value.stream == nil {
+		return nil, h.winner.err
+	}
+	return h.winner.value.stream.Header()
+}
+
+func (h *hedgedStream) Trailer() metadata.MD {
+	select {
+	case <-h.committed:
+	default:
+		return nil
+	}
+	if h.winner.value.stream == nil {
+		return nil
+	}
+	return h.winner.value.stream.Trailer()
+}
+
+func (h *hedgedStream) Context() context.Context {
+	return h.ctx
+}
+
+func (h *hedgedStream) RecvMsg(m any) error {
+	if h.finished != nil {
+		return h.finished
+	}
+	h.start()
+	<-h.committed
+	w := h.winner
+	var err error
+	switch {
+	case h.delivered:
+		err = w.value.stream.RecvMsg(m)
+	case w.err != nil:
+		err = w.err
+	case w.value.eof:
+		err = io.EOF
+	default:
+		h.delivered = true
+		msg := m.(proto.Message)
+		proto.Reset(msg)
+		proto.Merge(msg, w.value.first)
+		return nil
+	}
+	if err != nil {
+		h.finish(err)
+	}
+	return err
+}
+
+// 流结束时把结果写给调用方，并释放被采用的尝试
+func (h *hedgedStream) finish(err error) {
+	h.finished = err
+	h.winner.cancel()
+	h.cancel()
+	h.outputs.copyFrom(h.winner.value.out)
+}
@@ -0,0 +1,220 @@
+package serviceconfig
+
+// 客户端的service config，格式和gRPC的service config相同
+// 超时和retryPolicy由grpc-go实现，hedgingPolicy由这里的拦截器实现
+// 参考：https://github.com/grpc/grpc/blob/master/doc/service_config.md
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/codes"
+)
+
+// 和grpc-go一样，重试和对冲最多尝试5次
+const maxAttemptsLimit = 5
+
+// 内置的默认配置：
+// 幂等的查询在Unavailable时重试，BuyCellphone的请求都带有幂等键，所以也可以重试，
+// SearchCellphone在响应慢时发起对冲
+//
+//go:embed default.json
+var defaultConfig []byte
+
+// json中的时长，和protobuf的Duration一样是以s结尾的秒数，比如"0.5s"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"1.5s\": %w", err)
+	}
+	seconds, ok := strings.CutSuffix(s, "s")
+	if !ok || strings.ContainsAny(seconds, "eE+") {
+		return fmt.Errorf("invalid duration %q, expected seconds with the s suffix like \"1.5s\"", s)
+	}
+	v, err := strconv.ParseFloat(seconds, 64)
+	if err != nil || v < 0 {
+		return fmt.Errorf("invalid duration %q, expected seconds with the s suffix like \"1.5s\"", s)
+	}
+	*d = Duration(v * float64(time.Second))
+	return nil
+}
+
+// 方法名，method为空表示服务中的所有方法，都为空表示所有服务的默认配置
+type Name struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+}
+
+type RetryPolicy struct {
+	MaxAttempts          int          `json:"maxAttempts"`
+	InitialBackoff       Duration     `json:"initialBackoff"`
+	MaxBackoff           Duration     `json:"maxBackoff"`
+	BackoffMultiplier    float64      `json:"backoffMultiplier"`
+	RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+}
+
+type HedgingPolicy struct {
+	// 最多同时发起多少次调用，包括第一次
+	MaxAttempts int `json:"maxAttempts"`
+	// 每次发起新调用之前等待的时间
+	HedgingDelay Duration `json:"hedgingDelay"`
+	// 返回这些错误时立即发起下一次调用，返回其它错误时放弃整个调用
+	NonFatalStatusCodes []codes.Code `json:"nonFatalStatusCodes"`
+}
+
+func (p *HedgingPolicy) nonFatal(code codes.Code) bool {
+	for _, c := range p.NonFatalStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+type MethodConfig struct {
+	Name []Name `json:"name"`
+	// 整个调用的超时时间，包括重试和对冲
+	Timeout       *Duration      `json:"timeout"`
+	RetryPolicy   *RetryPolicy   `json:"retryPolicy"`
+	HedgingPolicy *HedgingPolicy `json:"hedgingPolicy"`
+}
+
+type Config struct {
+	MethodConfig []*MethodConfig `json:"methodConfig"`
+
+	// 原始的json交给grpc-go，其它字段比如retryThrottling也会生效
+	raw string
+	// service/method、service/或者空字符串 -> 配置
+	methods map[string]*MethodConfig
+}
+
+// 解析并校验json格式的service config
+func Parse(data []byte) (*Config, error) {
+	c := &Config{raw: string(data), methods: make(map[string]*MethodConfig)}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("can not parse service config: %w", err)
+	}
+	for i, mc := range c.MethodConfig {
+		if err := mc.validate(); err != nil {
+			return nil, fmt.Errorf("invalid methodConfig[%d]: %w", i, err)
+		}
+		for _, name := range mc.Name {
+			if name.Service == "" && name.Method != "" {
+				return nil, fmt.Errorf("invalid methodConfig[%d]: method %s has no service", i, name.Method)
+			}
+			key := name.Service + "/" + name.Method
+			if name.Service == "" {
+				key = ""
+			}
+			if _, ok := c.methods[key]; ok {
+				return nil, fmt.Errorf("invalid methodConfig[%d]: duplicate name %q", i, key)
+			}
+			c.methods[key] = mc
+		}
+	}
+	return c, nil
+}
+
+// 从文件中加载
+func Load(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// 内置的默认配置
+func Default() *Config {
+	c, err := Parse(defaultConfig)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (mc *MethodConfig) validate() error {
+	if len(mc.Name) == 0 {
+		return errors.New("name is required")
+	}
+	if mc.RetryPolicy != nil && mc.HedgingPolicy != nil {
+		return errors.New("retryPolicy and hedgingPolicy are mutually exclusive")
+	}
+	if p := mc.RetryPolicy; p != nil {
+		switch {
+		case p.MaxAttempts < 2:
+			return errors.New("retryPolicy.maxAttempts must be at least 2")
+		case p.InitialBackoff <= 0 || p.MaxBackoff <= 0:
+			return errors.New("retryPolicy backoffs must be positive")
+		case p.BackoffMultiplier <= 0:
+			return errors.New("retryPolicy.backoffMultiplier must be positive")
+		case len(p.RetryableStatusCodes) == 0:
+			return errors.New("retryPolicy.retryableStatusCodes must not be empty")
+		}
+	}
+	if p := mc.HedgingPolicy; p != nil {
+		if p.MaxAttempts < 2 {
+			return errors.New("hedgingPolicy.maxAttempts must be at least 2")
+		}
+		p.MaxAttempts = min(p.MaxAttempts, maxAttemptsLimit)
+	}
+	return nil
+}
+
+// 和grpc-go一样，依次查找方法、服务和默认的配置
+func (c *Config) methodConfig(fullMethod string) *MethodConfig {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	for _, key := range []string{service + "/" + method, service + "/", ""} {
+		if mc, ok := c.methods[key]; ok {
+			return mc
+		}
+	}
+	return nil
+}
+
+// 返回使用policy负载均衡的配置，比如round_robin，
+// healthCheck为true时只把请求发给健康检查通过的后端，pick_first不支持健康检查
+func (c *Config) WithLoadBalancing(policy string, healthCheck bool) (*Config, error) {
+	if balancer.Get(policy) == nil {
+		return nil, fmt.Errorf("unknown load balancing policy %q", policy)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(c.raw), &raw); err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		raw = make(map[string]json.RawMessage)
+	}
+	lb, _ := json.Marshal([]map[string]struct{}{{policy: {}}})
+	raw["loadBalancingConfig"] = lb
+	delete(raw, "loadBalancingPolicy")
+	delete(raw, "healthCheckConfig")
+	if healthCheck {
+		// 服务名为空表示检查整个服务端
+		raw["healthCheckConfig"] = json.RawMessage(`{"serviceName": ""}`)
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// 连接时使用的选项
+func (c *Config) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(c.raw),
+		grpc.WithChainUnaryInterceptor(c.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(c.streamInterceptor),
+	}
+}
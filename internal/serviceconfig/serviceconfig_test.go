@@ -0,0 +1,285 @@
+package serviceconfig_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/flaky"
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/internal/serviceconfig"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const (
+	getMethod    = "/pb.CellphoneService/GetCellphone"
+	searchMethod = "/pb.CellphoneService/SearchCellphone"
+	buyMethod    = "/pb.CellphoneService/BuyCellphone"
+)
+
+// 测试中使用的配置，重试的等待时间很短
+const testConfig = `{
+  "methodConfig": [
+    {
+      "name": [{"service": "pb.CellphoneService", "method": "GetCellphone"}],
+      "retryPolicy": {
+        "maxAttempts": 4,
+        "initialBackoff": "0.01s",
+        "maxBackoff": "0.05s",
+        "backoffMultiplier": 2,
+        "retryableStatusCodes": ["UNAVAILABLE"]
+      }
+    },
+    {
+      "name": [{"service": "pb.CellphoneService", "method": "ListOrders"}],
+      "timeout": "0.2s"
+    },
+    {
+      "name": [
+        {"service": "pb.CellphoneService", "method": "SearchCellphone"},
+        {"service": "pb.CustomService", "method": "MetadataCarryTest"}
+      ],
+      "hedgingPolicy": {
+        "maxAttempts": 3,
+        "hedgingDelay": "0.1s",
+        "nonFatalStatusCodes": ["UNAVAILABLE"]
+      }
+    },
+    {
+      "name": [{"service": "pb.CellphoneService", "method": "BuyCellphone"}],
+      "retryPolicy": {
+        "maxAttempts": 3,
+        "initialBackoff": "0.01s",
+        "maxBackoff": "0.05s",
+        "backoffMultiplier": 2,
+        "retryableStatusCodes": ["UNAVAILABLE"]
+      }
+    }
+  ]
+}`
+
+// 运行按照rules出错的服务端，返回使用testConfig的客户端和服务端中已经创建的手机id
+func runFlakyServer(t *testing.T, rules ...flaky.Rule) (pb.CellphoneServiceClient, *flaky.Injector, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口监听
+	require.Nil(t, err)
+
+	injector := flaky.New(rules...)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(injector.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(injector.StreamServerInterceptor()),
+	)
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(service.WithCoverPath(t.TempDir())))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	cfg, err := serviceconfig.Parse([]byte(testConfig))
+	require.Nil(t, err)
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, cfg.DialOptions()...)
+	conn, err := grpc.Dial(listener.Addr().String(), opts...)
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	client := pb.NewCellphoneServiceClient(conn)
+	res, err := client.CreateCellphone(context.Background(), &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+	return client, injector, res.Id
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	// 内置的配置
+	require.NotNil(t, serviceconfig.Default())
+
+	testCases := []struct {
+		Name   string
+		Config string
+	}{
+		{Name: "invalid json", Config: `{"methodConfig": [}`},
+		{Name: "missing name", Config: `{"methodConfig": [{"timeout": "1s"}]}`},
+		{Name: "method without service", Config: `{"methodConfig": [{"name": [{"method": "Get"}]}]}`},
+		{Name: "duplicate name", Config: `{"methodConfig": [{"name": [{"service": "a"}]}, {"name": [{"service": "a"}]}]}`},
+		{Name: "duration without unit", Config: `{"methodConfig": [{"name": [{"service": "a"}], "timeout": "1"}]}`},
+		{Name: "duration in ms", Config: `{"methodConfig": [{"name": [{"service": "a"}], "timeout": "100ms"}]}`},
+		{Name: "retry and hedging", Config: `{"methodConfig": [{"name": [{"service": "a"}],
+			"retryPolicy": {"maxAttempts": 2, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": ["UNAVAILABLE"]},
+			"hedgingPolicy": {"maxAttempts": 2}}]}`},
+		{Name: "single retry attempt", Config: `{"methodConfig": [{"name": [{"service": "a"}],
+			"retryPolicy": {"maxAttempts": 1, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 1, "retryableStatusCodes": ["UNAVAILABLE"]}}]}`},
+		{Name: "no retryable codes", Config: `{"methodConfig": [{"name": [{"service": "a"}],
+			"retryPolicy": {"maxAttempts": 2, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 1}}]}`},
+		{Name: "unknown code", Config: `{"methodConfig": [{"name": [{"service": "a"}],
+			"hedgingPolicy": {"maxAttempts": 2, "nonFatalStatusCodes": ["FLAKY"]}}]}`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := serviceconfig.Parse([]byte(tc.Config))
+			require.NotNil(t, err)
+		})
+	}
+}
+
+// 幂等的查询在Unavailable时重试
+func TestRetry(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Name  string
+		Rule  flaky.Rule
+		Code  codes.Code
+		Calls int
+	}{
+		// 第三次尝试成功
+		{Name: "recovered", Rule: flaky.Rule{FailFirst: 2, Code: codes.Unavailable}, Code: codes.OK, Calls: 3},
+		// 最多尝试4次
+		{Name: "exhausted", Rule: flaky.Rule{FailFirst: 10, Code: codes.Unavailable}, Code: codes.Unavailable, Calls: 4},
+		// 不在retryableStatusCodes中的错误不重试
+		{Name: "not retryable", Rule: flaky.Rule{FailFirst: 10, Code: codes.Internal}, Code: codes.Internal, Calls: 1},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			tc.Rule.Method = getMethod
+			client, injector, id := runFlakyServer(t, tc.Rule)
+
+			res, err := client.GetCellphone(context.Background(), &pb.GetCellphoneRequest{Id: id})
+			require.Equal(t, tc.Code, status.Code(err))
+			if tc.Code == codes.OK {
+				require.Equal(t, id, res.Id)
+			}
+			require.Equal(t, tc.Calls, injector.Calls(getMethod))
+		})
+	}
+}
+
+// 方法配置中的超时时间
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	method := "/pb.CellphoneService/ListOrders"
+	client, _, _ := runFlakyServer(t, flaky.Rule{Method: method, SlowFirst: 1, Delay: 5 * time.Second})
+
+	start := time.Now()
+	_, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{})
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	require.Less(t, time.Since(start), 2*time.Second)
+}
+
+func search(ctx context.Context, client pb.CellphoneServiceClient) ([]*pb.Cellphone, metadata.MD, error) {
+	var header metadata.MD
+	stream, err := client.SearchCellphone(ctx, &pb.FilterCondition{}, grpc.Header(&header))
+	if err != nil {
+		return nil, nil, err
+	}
+	var res []*pb.Cellphone
+	for {
+		cellphone, err := stream.Recv()
+		if err == io.EOF {
+			return res, header, nil
+		}
+		if err != nil {
+			return nil, header, err
+		}
+		res = append(res, cellphone)
+	}
+}
+
+// SearchCellphone变慢或者失败时发起对冲
+func TestHedging(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Name  string
+		Rule  flaky.Rule
+		Code  codes.Code
+		Calls int
+	}{
+		// 第一次调用很慢，第二次调用先返回
+		{Name: "slow", Rule: flaky.Rule{SlowFirst: 1, Delay: 5 * time.Second}, Code: codes.OK, Calls: 2},
+		// 非致命的错误立即发起下一次调用
+		{Name: "non fatal", Rule: flaky.Rule{FailFirst: 2, Code: codes.Unavailable}, Code: codes.OK, Calls: 3},
+		// 所有的调用都失败
+		{Name: "exhausted", Rule: flaky.Rule{FailFirst: 10, Code: codes.Unavailable}, Code: codes.Unavailable, Calls: 3},
+		// 致命的错误放弃整个调用
+		{Name: "fatal", Rule: flaky.Rule{FailFirst: 10, Code: codes.PermissionDenied}, Code: codes.PermissionDenied, Calls: 1},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			tc.Rule.Method = searchMethod
+			client, injector, id := runFlakyServer(t, tc.Rule)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+			res, header, err := search(ctx, client)
+			require.Equal(t, tc.Code, status.Code(err))
+			if tc.Code == codes.OK {
+				require.Len(t, res, 1)
+				require.Equal(t, id, res[0].Id)
+				// Header选项得到的是被采用的调用的header
+				require.NotEmpty(t, header.Get("content-type"))
+			}
+			require.Equal(t, tc.Calls, injector.Calls(searchMethod))
+		})
+	}
+}
+
+// BuyCellphone的响应丢失后重试，相同的幂等键不会重复下单
+func TestRetryBuyCellphone(t *testing.T) {
+	t.Parallel()
+
+	buy := func(client pb.CellphoneServiceClient, req *pb.BuyCellphoneRequest) (*pb.BuyCellphoneResponse, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		stream, err := client.BuyCellphone(ctx)
+		require.Nil(t, err)
+		require.Nil(t, stream.Send(req))
+		require.Nil(t, stream.CloseSend())
+		return stream.Recv()
+	}
+	orderCount := func(client pb.CellphoneServiceClient, id string) uint32 {
+		res, err := client.ListOrders(context.Background(), &pb.ListOrdersRequest{Ids: []string{id}})
+		require.Nil(t, err)
+		if len(res.Orders) == 0 {
+			return 0
+		}
+		return res.Orders[0].Count
+	}
+	rule := flaky.Rule{Method: buyMethod, DropFirst: 1, Code: codes.Unavailable}
+
+	t.Run("with idempotency key", func(t *testing.T) {
+		t.Parallel()
+		client, injector, id := runFlakyServer(t, rule)
+
+		res, err := buy(client, &pb.BuyCellphoneRequest{Id: id, Price: 1000, IdempotencyKey: "key"})
+		require.Nil(t, err)
+		require.Equal(t, 1000.0, res.Avg)
+		require.Equal(t, 2, injector.Calls(buyMethod))
+		require.EqualValues(t, 1, orderCount(client, id))
+	})
+
+	t.Run("without idempotency key", func(t *testing.T) {
+		t.Parallel()
+		client, injector, id := runFlakyServer(t, rule)
+
+		_, err := buy(client, &pb.BuyCellphoneRequest{Id: id, Price: 1000})
+		require.Equal(t, codes.FailedPrecondition, status.Code(err))
+		require.Equal(t, pb.ErrorReason_IDEMPOTENCY_KEY_REQUIRED, rpcerr.ReasonOf(err))
+		require.Equal(t, 2, injector.Calls(buyMethod))
+		// 只有第一次的请求下了单
+		require.EqualValues(t, 1, orderCount(client, id))
+	})
+}
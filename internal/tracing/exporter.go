@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// span结束时会被交给Exporter，Export必须可以并发调用
+type Exporter interface {
+	Export(span *SpanData)
+}
+
+// 丢弃所有的span
+type NopExporter struct{}
+
+func (NopExporter) Export(*SpanData) {}
+
+// 每个span以一行json的形式写到w中
+type StdoutExporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewStdoutExporter(w io.Writer) *StdoutExporter {
+	return &StdoutExporter{enc: json.NewEncoder(w)}
+}
+
+func (e *StdoutExporter) Export(span *SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.enc.Encode(span)
+}
+
+// 把span保存在内存中，主要用于测试
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) Export(span *SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, *span)
+}
+
+// 按照结束的顺序返回所有的span
+func (e *InMemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	res := make([]SpanData, len(e.spans))
+	copy(res, e.spans)
+	return res
+}
+
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
@@ -0,0 +1,296 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 流中每条消息对应的span的名字
+const (
+	SpanNameSend = "send"
+	SpanNameRecv = "recv"
+)
+
+// 服务端unary rpc的拦截器
+// 从metadata中读取traceparent，为每次调用创建一个server span
+func UnaryServerInterceptor(tracer *Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		ctx, span := startServerSpan(ctx, tracer, info.FullMethod)
+		annotateCellphoneID(span, req)
+
+		res, err := handler(ctx, req)
+
+		if err == nil {
+			annotateCellphoneID(span, res)
+		}
+		endSpan(span, err)
+		return res, err
+	}
+}
+
+// 服务端streaming rpc的拦截器
+// 除了整个调用的span以外，流中的每条消息也会有一个span
+func StreamServerInterceptor(tracer *Tracer) grpc.StreamServerInterceptor {
+	return func(srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+
+		ctx, span := startServerSpan(ss.Context(), tracer, info.FullMethod)
+		err := handler(srv, &tracedServerStream{
+			ServerStream: ss,
+			ctx:          ctx,
+			messages:     messageTracer{tracer: tracer, parent: span},
+		})
+		endSpan(span, err)
+		return err
+	}
+}
+
+// 客户端unary rpc的拦截器
+// 以ctx中的span为parent创建client span，并把traceparent写进metadata
+func UnaryClientInterceptor(tracer *Tracer) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context,
+		method string,
+		req, reply interface{},
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption) error {
+
+		ctx, span := startClientSpan(ctx, tracer, method, cc.Target())
+		annotateCellphoneID(span, req)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		if err == nil {
+			annotateCellphoneID(span, reply)
+		}
+		endSpan(span, err)
+		return err
+	}
+}
+
+// 客户端streaming rpc的拦截器
+// 服务端返回错误、流结束、发送失败或者调用方取消ctx时，整个调用的span才结束
+func StreamClientInterceptor(tracer *Tracer) grpc.StreamClientInterceptor {
+	return func(ctx context.Context,
+		desc *grpc.StreamDesc,
+		cc *grpc.ClientConn,
+		method string,
+		streamer grpc.Streamer,
+		opts ...grpc.CallOption) (grpc.ClientStream, error) {
+
+		ctx, span := startClientSpan(ctx, tracer, method, cc.Target())
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			endSpan(span, err)
+			return nil, err
+		}
+		s := &tracedClientStream{
+			ClientStream:  cs,
+			ctx:           ctx,
+			span:          span,
+			serverStreams: desc.ServerStreams,
+			messages:      messageTracer{tracer: tracer, parent: span},
+			done:          make(chan struct{}),
+		}
+		// 调用方没有读完就放弃的流，取消ctx之后结束span
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.end(status.FromContextError(ctx.Err()).Err())
+			case <-s.done:
+			}
+		}()
+		return s, nil
+	}
+}
+
+func startServerSpan(ctx context.Context, tracer *Tracer, fullMethod string) (context.Context, *Span) {
+	var parent SpanContext
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(TraceparentMetadataKey); len(values) != 0 {
+			// 不合法的traceparent会被忽略，开始一条新的trace
+			parent, _ = ParseTraceparent(values[0])
+		}
+		if values := md.Get(TracestateMetadataKey); len(values) != 0 && parent.IsValid() {
+			parent.TraceState = strings.Join(values, ",")
+		}
+	}
+
+	ctx, span := tracer.StartWithParent(ctx, parent, strings.TrimPrefix(fullMethod, "/"), SpanKindServer)
+	setMethodAttributes(span, fullMethod)
+	if p, ok := peer.FromContext(ctx); ok {
+		span.SetAttribute(AttrPeer, p.Addr.String())
+	}
+	return ctx, span
+}
+
+func startClientSpan(ctx context.Context, tracer *Tracer, fullMethod, target string) (context.Context, *Span) {
+	ctx, span := tracer.Start(ctx, strings.TrimPrefix(fullMethod, "/"), SpanKindClient)
+	setMethodAttributes(span, fullMethod)
+	span.SetAttribute(AttrPeer, target)
+
+	sc := span.SpanContext()
+	ctx = metadata.AppendToOutgoingContext(ctx, TraceparentMetadataKey, sc.Traceparent())
+	if sc.TraceState != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, TracestateMetadataKey, sc.TraceState)
+	}
+	return ctx, span
+}
+
+// 方法全名的格式为/package.service/method
+func setMethodAttributes(span *Span, fullMethod string) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		span.SetAttribute(AttrRPCService, name[:i])
+		span.SetAttribute(AttrRPCMethod, name[i+1:])
+	} else {
+		span.SetAttribute(AttrRPCMethod, name)
+	}
+}
+
+func endSpan(span *Span, err error) {
+	st := status.Convert(err)
+	span.SetAttribute(AttrStatusCode, st.Code().String())
+	span.SetStatus(st.Code(), st.Message())
+	span.End()
+}
+
+// 为流中的每一条消息创建span
+type messageTracer struct {
+	tracer *Tracer
+	// 整个调用的span，会记录流中第一次出现的手机id
+	parent    *Span
+	sentCount atomic.Int64
+	recvCount atomic.Int64
+}
+
+func (m *messageTracer) send(ctx context.Context, msg interface{}, send func() error) error {
+	_, span := m.tracer.Start(ctx, SpanNameSend, SpanKindInternal)
+	span.SetAttribute(AttrMessageID, strconv.FormatInt(m.sentCount.Add(1), 10))
+	m.annotate(span, msg)
+	err := send()
+	endSpan(span, err)
+	return err
+}
+
+func (m *messageTracer) recv(ctx context.Context, msg interface{}, recv func() error) error {
+	_, span := m.tracer.Start(ctx, SpanNameRecv, SpanKindInternal)
+	err := recv()
+	// 流正常结束时的那一次recv不是一条消息，不导出
+	if errors.Is(err, io.EOF) {
+		return err
+	}
+	span.SetAttribute(AttrMessageID, strconv.FormatInt(m.recvCount.Add(1), 10))
+	if err == nil {
+		m.annotate(span, msg)
+	}
+	endSpan(span, err)
+	return err
+}
+
+type tracedServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	messages messageTracer
+}
+
+// handler中拿到的context带有server span
+func (s *tracedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *tracedServerStream) SendMsg(m interface{}) error {
+	return s.messages.send(s.ctx, m, func() error { return s.ServerStream.SendMsg(m) })
+}
+
+func (s *tracedServerStream) RecvMsg(m interface{}) error {
+	return s.messages.recv(s.ctx, m, func() error { return s.ServerStream.RecvMsg(m) })
+}
+
+type tracedClientStream struct {
+	grpc.ClientStream
+	ctx           context.Context
+	span          *Span
+	serverStreams bool
+	messages      messageTracer
+
+	// span只结束一次，结束之后关闭done
+	once sync.Once
+	done chan struct{}
+}
+
+func (s *tracedClientStream) SendMsg(m interface{}) error {
+	err := s.messages.send(s.ctx, m, func() error { return s.ClientStream.SendMsg(m) })
+	// io.EOF表示流已经结束，具体的状态由RecvMsg返回
+	if err != nil && !errors.Is(err, io.EOF) {
+		s.end(err)
+	}
+	return err
+}
+
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.messages.recv(s.ctx, m, func() error { return s.ClientStream.RecvMsg(m) })
+	if errors.Is(err, io.EOF) {
+		s.end(nil)
+	} else if err != nil || !s.serverStreams {
+		// 服务端只返回一条消息时，收到响应就代表调用结束
+		s.end(err)
+	}
+	return err
+}
+
+func (s *tracedClientStream) end(err error) {
+	s.once.Do(func() {
+		endSpan(s.span, err)
+		close(s.done)
+	})
+}
+
+func (m *messageTracer) annotate(span *Span, msg interface{}) {
+	if pm, ok := msg.(proto.Message); ok {
+		span.SetAttribute(AttrMessageType, string(pm.ProtoReflect().Descriptor().FullName()))
+	}
+	if id := cellphoneID(msg); id != "" {
+		span.SetAttribute(AttrCellphoneID, id)
+		m.parent.setAttributeIfAbsent(AttrCellphoneID, id)
+	}
+}
+
+// 把消息中的手机id记录到span上
+func annotateCellphoneID(span *Span, msg interface{}) {
+	if id := cellphoneID(msg); id != "" {
+		span.SetAttribute(AttrCellphoneID, id)
+	}
+}
+
+func cellphoneID(msg interface{}) string {
+	switch m := msg.(type) {
+	case *pb.CreateCellphoneRequest:
+		return m.GetCellphone().GetId()
+	case *pb.UploadCellphoneCoverRequest:
+		return m.GetMeta().GetId()
+	case interface{ GetId() string }:
+		// pb.Cellphone以及各种带有id字段的请求和响应
+		return m.GetId()
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+)
+
+// span的类型
+const (
+	SpanKindServer   = "server"
+	SpanKindClient   = "client"
+	SpanKindInternal = "internal"
+)
+
+// span上常用的属性名
+const (
+	AttrRPCService  = "rpc.service"
+	AttrRPCMethod   = "rpc.method"
+	AttrStatusCode  = "rpc.grpc.status_code"
+	AttrPeer        = "net.peer"
+	AttrMessageType = "message.type"
+	AttrMessageID   = "message.id"
+	AttrCellphoneID = "cellphone.id"
+)
+
+// 事件
+type Event struct {
+	Name       string            `json:"name"`
+	Time       time.Time         `json:"time"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// 已经结束的span，交给Exporter导出
+type SpanData struct {
+	Name         string            `json:"name"`
+	Kind         string            `json:"kind"`
+	TraceID      string            `json:"trace_id"`
+	SpanID       string            `json:"span_id"`
+	ParentSpanID string            `json:"parent_span_id,omitempty"`
+	Start        time.Time         `json:"start"`
+	End          time.Time         `json:"end"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+	Events       []Event           `json:"events,omitempty"`
+	Code         codes.Code        `json:"code"`
+	Message      string            `json:"message,omitempty"`
+}
+
+func (d *SpanData) Duration() time.Duration {
+	return d.End.Sub(d.Start)
+}
+
+// 一个正在进行的操作
+// span的方法都可以并发调用，对nil的span调用也是安全的
+// span结束之后的修改会被忽略
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]string)
+	}
+	s.data.Attributes[key] = value
+}
+
+func (s *Span) setAttributeIfAbsent(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if _, ok := s.data.Attributes[key]; ok {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]string)
+	}
+	s.data.Attributes[key] = value
+}
+
+// attrs为key, value交替出现的列表
+func (s *Span) AddEvent(name string, attrs ...string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 远端传过来的span没有tracer，并且已经结束
+	if s.ended || s.tracer == nil {
+		return
+	}
+	e := Event{Name: name, Time: s.tracer.now()}
+	if len(attrs) >= 2 {
+		e.Attributes = make(map[string]string, len(attrs)/2)
+		for i := 0; i+1 < len(attrs); i += 2 {
+			e.Attributes[attrs[i]] = attrs[i+1]
+		}
+	}
+	s.data.Events = append(s.data.Events, e)
+}
+
+// 设置span的结果
+func (s *Span) SetStatus(code codes.Code, msg string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Code = code
+	s.data.Message = msg
+}
+
+// 结束span，只有第一次调用有效
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = s.tracer.now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.sc.Sampled {
+		s.tracer.exporter.Export(&data)
+	}
+}
+
+type spanKey struct{}
+
+// 从context中取出当前的span，没有时返回nil
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// 把远端传过来的span作为parent放进context中，后续创建的span都属于这条trace
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	// 已经结束的span不会被导出，也不能被修改
+	return ContextWithSpan(ctx, &Span{sc: sc, ended: true})
+}
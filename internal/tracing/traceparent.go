@@ -0,0 +1,119 @@
+package tracing
+
+// W3C Trace Context的traceparent格式：
+// https://www.w3.org/TR/trace-context/#traceparent-header
+//   version-traceid-parentid-flags，比如
+//   00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// 携带trace上下文的metadata key
+const (
+	TraceparentMetadataKey = "traceparent"
+	TracestateMetadataKey  = "tracestate"
+)
+
+const (
+	traceparentVersion = "00"
+	flagSampled        = 0x01
+)
+
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+type TraceID [16]byte
+
+func (t TraceID) IsValid() bool { return t != TraceID{} }
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+type SpanID [8]byte
+
+func (s SpanID) IsValid() bool { return s != SpanID{} }
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// 跨进程传递的span信息
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+	// 原样透传的tracestate
+	TraceState string
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// 编码成traceparent
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return traceparentVersion + "-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// 解析traceparent
+// 未来的版本可能在末尾追加字段，所以版本号不是00时只解析前面4个字段
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 {
+		return sc, ErrInvalidTraceparent
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if len(version) != 2 || version == "ff" || !isLowerHex(version) {
+		return sc, ErrInvalidTraceparent
+	}
+	if version == traceparentVersion && len(parts) != 4 {
+		return sc, ErrInvalidTraceparent
+	}
+	if len(traceID) != 32 || len(spanID) != 16 || len(flags) != 2 ||
+		!isLowerHex(traceID) || !isLowerHex(spanID) || !isLowerHex(flags) {
+		return sc, ErrInvalidTraceparent
+	}
+
+	hex.Decode(sc.TraceID[:], []byte(traceID))
+	hex.Decode(sc.SpanID[:], []byte(spanID))
+	var f [1]byte
+	hex.Decode(f[:], []byte(flags))
+	sc.Sampled = f[0]&flagSampled != 0
+
+	if !sc.IsValid() {
+		return SpanContext{}, ErrInvalidTraceparent
+	}
+	return sc, nil
+}
+
+// 规范要求只能是小写的十六进制
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func newTraceID() TraceID {
+	var t TraceID
+	for !t.IsValid() {
+		rand.Read(t[:])
+	}
+	return t
+}
+
+func newSpanID() SpanID {
+	var s SpanID
+	for !s.IsValid() {
+		rand.Read(s[:])
+	}
+	return s
+}
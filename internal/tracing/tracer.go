@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"time"
+)
+
+// 负责创建span
+type Tracer struct {
+	exporter Exporter
+	now      func() time.Time
+}
+
+// exporter为nil时span不会被导出，但trace上下文依然会传递
+func NewTracer(exporter Exporter) *Tracer {
+	if exporter == nil {
+		exporter = NopExporter{}
+	}
+	return &Tracer{exporter: exporter, now: time.Now}
+}
+
+// 以ctx中的span为parent创建一个新的span
+func (t *Tracer) Start(ctx context.Context, name, kind string) (context.Context, *Span) {
+	return t.StartWithParent(ctx, SpanFromContext(ctx).SpanContext(), name, kind)
+}
+
+// 以parent为父span创建一个新的span，parent无效时开始一条新的trace
+// 新的trace总是会被采样，否则沿用parent的采样结果
+func (t *Tracer) StartWithParent(ctx context.Context, parent SpanContext,
+	name, kind string) (context.Context, *Span) {
+
+	sc := SpanContext{SpanID: newSpanID(), Sampled: true}
+	span := &Span{tracer: t}
+	if parent.IsValid() {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+		sc.TraceState = parent.TraceState
+		span.data.ParentSpanID = parent.SpanID.String()
+	} else {
+		sc.TraceID = newTraceID()
+	}
+	span.sc = sc
+	span.data.Name = name
+	span.data.Kind = kind
+	span.data.TraceID = sc.TraceID.String()
+	span.data.SpanID = sc.SpanID.String()
+	span.data.Start = t.now()
+	return ContextWithSpan(ctx, span), span
+}
@@ -0,0 +1,309 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/internal/tracing"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Name        string
+		Traceparent string
+		Ok          bool
+		Sampled     bool
+	}{
+		{"sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true, true},
+		{"not-sampled", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", true, false},
+		{"future-version", "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what-the-future", true, true},
+		{"version-00-extra-field", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false, false},
+		{"version-ff", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false, false},
+		{"upper-case", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false, false},
+		{"zero-trace-id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", false, false},
+		{"zero-span-id", "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false, false},
+		{"short-trace-id", "00-4bf92f3577b34da6-00f067aa0ba902b7-01", false, false},
+		{"empty", "", false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			sc, err := tracing.ParseTraceparent(tc.Traceparent)
+			if !tc.Ok {
+				require.ErrorIs(t, err, tracing.ErrInvalidTraceparent)
+				return
+			}
+			require.Nil(t, err)
+			require.True(t, sc.IsValid())
+			require.Equal(t, tc.Sampled, sc.Sampled)
+			require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+			require.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+		})
+	}
+
+	// 编码后再解析得到相同的结果
+	sc, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.Nil(t, err)
+	require.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", sc.Traceparent())
+}
+
+func TestTracerStart(t *testing.T) {
+	t.Parallel()
+
+	exporter := tracing.NewInMemoryExporter()
+	tracer := tracing.NewTracer(exporter)
+
+	ctx, root := tracer.Start(context.Background(), "root", tracing.SpanKindInternal)
+	_, child := tracer.Start(ctx, "child", tracing.SpanKindInternal)
+	child.SetAttribute("k", "v")
+	child.AddEvent("something-happened", "key", "value")
+	child.End()
+	root.End()
+	// 重复End不会重复导出，结束后的修改也会被忽略
+	root.End()
+	root.SetAttribute("ignored", "true")
+
+	spans := exporter.Spans()
+	require.Len(t, spans, 2)
+	require.Equal(t, "child", spans[0].Name)
+	require.Equal(t, "root", spans[1].Name)
+	require.Equal(t, spans[1].TraceID, spans[0].TraceID)
+	require.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	require.Empty(t, spans[1].ParentSpanID)
+	require.Equal(t, "v", spans[0].Attributes["k"])
+	require.Equal(t, "value", spans[0].Events[0].Attributes["key"])
+	require.NotContains(t, spans[1].Attributes, "ignored")
+
+	// 没有被采样的trace不导出
+	exporter.Reset()
+	parent, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.Nil(t, err)
+	_, span := tracer.Start(tracing.ContextWithRemoteSpanContext(context.Background(), parent),
+		"not-sampled", tracing.SpanKindInternal)
+	require.Equal(t, parent.TraceID, span.SpanContext().TraceID)
+	span.End()
+	require.Empty(t, exporter.Spans())
+}
+
+func TestRemoteSpan(t *testing.T) {
+	t.Parallel()
+
+	sc, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.Nil(t, err)
+	ctx := tracing.ContextWithRemoteSpanContext(context.Background(), sc)
+
+	// 远端的span只用来传递trace，修改会被忽略
+	span := tracing.SpanFromContext(ctx)
+	require.NotPanics(t, func() {
+		span.AddEvent("ignored", "key", "value")
+		span.SetAttribute("ignored", "true")
+		span.SetStatus(codes.Internal, "ignored")
+		span.End()
+	})
+	require.Equal(t, sc, span.SpanContext())
+}
+
+func TestStdoutExporter(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	tracer := tracing.NewTracer(tracing.NewStdoutExporter(&buf))
+	_, span := tracer.Start(context.Background(), "json", tracing.SpanKindClient)
+	span.SetAttribute(tracing.AttrCellphoneID, "id")
+	span.End()
+
+	var data tracing.SpanData
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &data))
+	require.Equal(t, "json", data.Name)
+	require.Equal(t, "id", data.Attributes[tracing.AttrCellphoneID])
+}
+
+// 启动带有追踪拦截器的服务端，返回带有追踪拦截器的客户端
+func runTracedServer(t *testing.T) (pb.CellphoneServiceClient, *tracing.InMemoryExporter, *tracing.InMemoryExporter) {
+	serverSpans := tracing.NewInMemoryExporter()
+	serverTracer := tracing.NewTracer(serverSpans)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(tracing.UnaryServerInterceptor(serverTracer)),
+		grpc.StreamInterceptor(tracing.StreamServerInterceptor(serverTracer)),
+	)
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	clientSpans := tracing.NewInMemoryExporter()
+	clientTracer := tracing.NewTracer(clientSpans)
+	conn, err := grpc.Dial(listener.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(tracing.UnaryClientInterceptor(clientTracer)),
+		grpc.WithStreamInterceptor(tracing.StreamClientInterceptor(clientTracer)),
+	)
+	require.Nil(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewCellphoneServiceClient(conn), clientSpans, serverSpans
+}
+
+// 服务端的span在handler返回之后才结束，需要等待一下
+func waitSpans(t *testing.T, exporter *tracing.InMemoryExporter, n int) []tracing.SpanData {
+	require.Eventually(t, func() bool { return len(exporter.Spans()) >= n }, 2*time.Second, 5*time.Millisecond)
+	return exporter.Spans()
+}
+
+func findSpan(spans []tracing.SpanData, name, kind string) []tracing.SpanData {
+	var res []tracing.SpanData
+	for _, s := range spans {
+		if s.Name == name && s.Kind == kind {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+func TestUnaryInterceptors(t *testing.T) {
+	t.Parallel()
+
+	client, clientSpans, serverSpans := runTracedServer(t)
+
+	// 客户端的调用属于上游传过来的trace
+	frontend, err := tracing.ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.Nil(t, err)
+	ctx := tracing.ContextWithRemoteSpanContext(context.Background(), frontend)
+
+	res, err := client.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+	require.Nil(t, err)
+
+	clientSpan := waitSpans(t, clientSpans, 1)[0]
+	serverSpan := waitSpans(t, serverSpans, 1)[0]
+
+	require.Equal(t, tracing.SpanKindClient, clientSpan.Kind)
+	require.Equal(t, frontend.TraceID.String(), clientSpan.TraceID)
+	require.Equal(t, frontend.SpanID.String(), clientSpan.ParentSpanID)
+
+	require.Equal(t, "pb.CellphoneService/CreateCellphone", serverSpan.Name)
+	require.Equal(t, tracing.SpanKindServer, serverSpan.Kind)
+	require.Equal(t, clientSpan.TraceID, serverSpan.TraceID)
+	require.Equal(t, clientSpan.SpanID, serverSpan.ParentSpanID)
+	require.Equal(t, "pb.CellphoneService", serverSpan.Attributes[tracing.AttrRPCService])
+	require.Equal(t, "CreateCellphone", serverSpan.Attributes[tracing.AttrRPCMethod])
+	require.Equal(t, res.Id, serverSpan.Attributes[tracing.AttrCellphoneID])
+	require.Equal(t, codes.OK.String(), serverSpan.Attributes[tracing.AttrStatusCode])
+	require.NotEmpty(t, serverSpan.Attributes[tracing.AttrPeer])
+}
+
+func TestStreamInterceptors(t *testing.T) {
+	t.Parallel()
+
+	client, clientSpans, serverSpans := runTracedServer(t)
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		res, err := client.CreateCellphone(context.Background(),
+			&pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()})
+		require.Nil(t, err)
+		ids = append(ids, res.Id)
+	}
+	waitSpans(t, serverSpans, 2)
+	clientSpans.Reset()
+	serverSpans.Reset()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	for _, id := range ids {
+		require.Nil(t, stream.Send(&pb.BuyCellphoneRequest{Id: id, Price: 2999}))
+		_, err := stream.Recv()
+		require.Nil(t, err)
+	}
+	require.Nil(t, stream.CloseSend())
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+
+	// 服务端：1个rpc span，2个recv span，2个send span
+	spans := waitSpans(t, serverSpans, 5)
+	rpc := findSpan(spans, "pb.CellphoneService/BuyCellphone", tracing.SpanKindServer)
+	require.Len(t, rpc, 1)
+	require.Equal(t, ids[0], rpc[0].Attributes[tracing.AttrCellphoneID])
+	require.Equal(t, codes.OK.String(), rpc[0].Attributes[tracing.AttrStatusCode])
+
+	recv := findSpan(spans, tracing.SpanNameRecv, tracing.SpanKindInternal)
+	require.Len(t, recv, 2)
+	for i, s := range recv {
+		require.Equal(t, rpc[0].SpanID, s.ParentSpanID)
+		require.Equal(t, ids[i], s.Attributes[tracing.AttrCellphoneID])
+		require.Equal(t, "pb.BuyCellphoneRequest", s.Attributes[tracing.AttrMessageType])
+	}
+	require.Len(t, findSpan(spans, tracing.SpanNameSend, tracing.SpanKindInternal), 2)
+
+	// 客户端的rpc span在收到EOF后结束
+	spans = waitSpans(t, clientSpans, 5)
+	rpc = findSpan(spans, "pb.CellphoneService/BuyCellphone", tracing.SpanKindClient)
+	require.Len(t, rpc, 1)
+	require.Equal(t, rpc[0].TraceID, findSpan(serverSpans.Spans(), "pb.CellphoneService/BuyCellphone", tracing.SpanKindServer)[0].TraceID)
+}
+
+func TestInterceptorsRecordErrors(t *testing.T) {
+	t.Parallel()
+
+	client, _, serverSpans := runTracedServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := client.BuyCellphone(ctx)
+	require.Nil(t, err)
+	notFoundId := "dce5fc07-d7d1-49fe-aaef-183aa779fce2"
+	require.Nil(t, stream.Send(&pb.BuyCellphoneRequest{Id: notFoundId, Price: 2999}))
+	_, err = stream.Recv()
+	require.NotNil(t, err)
+
+	spans := waitSpans(t, serverSpans, 2)
+	rpc := findSpan(spans, "pb.CellphoneService/BuyCellphone", tracing.SpanKindServer)
+	require.Len(t, rpc, 1)
+	require.Equal(t, codes.NotFound, rpc[0].Code)
+	require.Equal(t, codes.NotFound.String(), rpc[0].Attributes[tracing.AttrStatusCode])
+	require.Equal(t, notFoundId, rpc[0].Attributes[tracing.AttrCellphoneID])
+}
+
+// 调用方没有读完就取消的流，客户端的rpc span也会结束
+func TestClientStreamSpanEndsOnCancel(t *testing.T) {
+	t.Parallel()
+
+	client, clientSpans, _ := runTracedServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watch, err := client.WatchCellphones(ctx, &pb.WatchRequest{})
+	require.Nil(t, err)
+	_, err = watch.Header()
+	require.Nil(t, err)
+	upload, err := client.UploadCellphoneCover(ctx)
+	require.Nil(t, err)
+	require.Nil(t, upload.Send(&pb.UploadCellphoneCoverRequest{
+		Data: &pb.UploadCellphoneCoverRequest_Meta{Meta: &pb.CoverMetaInfo{Id: uuid.NewString(), ImageType: ".jpeg", Size: 100}}}))
+	cancel()
+
+	spans := waitSpans(t, clientSpans, 3)
+	for _, name := range []string{"pb.CellphoneService/WatchCellphones", "pb.CellphoneService/UploadCellphoneCover"} {
+		rpc := findSpan(spans, name, tracing.SpanKindClient)
+		require.Len(t, rpc, 1, name)
+		require.Equal(t, codes.Canceled, rpc[0].Code)
+	}
+}
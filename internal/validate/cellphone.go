@@ -0,0 +1,194 @@
+package validate
+
+// 校验pb.Cellphone的每一个字段
+// 一次性返回所有不合法的字段，而不是遇到第一个错误就返回
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+const maxNameLength = 64
+
+// 分辨率的格式为"宽x高"，比如1920x1080
+var resolutionPattern = regexp.MustCompile(`^([1-9][0-9]{0,4})x([1-9][0-9]{0,4})$`)
+
+// 校验添加手机的请求
+func CreateCellphoneRequest(req *pb.CreateCellphoneRequest) error {
+	var v Violations
+	cellphone(&v, "cellphone", req.GetCellphone())
+	return v.Err()
+}
+
+// 校验修改手机的请求，和添加手机不同，id不能为空
+func UpdateCellphoneRequest(req *pb.UpdateCellphoneRequest) error {
+	var v Violations
+	if req.GetCellphone() != nil && req.GetCellphone().GetId() == "" {
+		v.Add("cellphone.id", "is required")
+	}
+	cellphone(&v, "cellphone", req.GetCellphone())
+	return v.Err()
+}
+
+// 校验一台手机，field为这台手机在请求中的字段路径
+func Cellphone(field string, c *pb.Cellphone) Violations {
+	var v Violations
+	cellphone(&v, field, c)
+	return v
+}
+
+func cellphone(v *Violations, field string, c *pb.Cellphone) {
+	if c == nil {
+		v.Add(field, "is required")
+		return
+	}
+
+	// id为空时由服务端分配
+	if c.Id != "" {
+		if _, err := uuid.Parse(c.Id); err != nil {
+			v.Add(field+".id", "must be a valid uuid")
+		}
+	}
+	name(v, field+".brand", c.Brand, true)
+	cpu(v, field+".cpu", c.Cpu)
+	ram(v, field+".ram", c.Ram)
+	storage(v, field+".storage", c.Storage)
+	battery(v, field+".battery", c.Battery)
+
+	// 下面这些信息是可选的，提供了才校验
+	if c.Gpu != nil {
+		gpu(v, field+".gpu", c.Gpu)
+	}
+	if c.OperatingSystem != nil {
+		name(v, field+".operating_system.name", c.OperatingSystem.Name, true)
+		name(v, field+".operating_system.version", c.OperatingSystem.Version, false)
+	}
+	if c.Screen != nil {
+		screen(v, field+".screen", c.Screen)
+	}
+	if c.Camera != nil {
+		name(v, field+".camera.brand", c.Camera.Brand, true)
+		name(v, field+".camera.spec", c.Camera.Spec, false)
+	}
+	if c.CreatedAt != nil {
+		if err := c.CreatedAt.CheckValid(); err != nil {
+			v.Add(field+".created_at", "must be a valid timestamp")
+		}
+	}
+}
+
+func cpu(v *Violations, field string, c *pb.CPU) {
+	if c == nil {
+		v.Add(field, "is required")
+		return
+	}
+	name(v, field+".manufacturer", c.Manufacturer, false)
+	if c.Cores <= 0 {
+		v.Add(field+".cores", "must be greater than 0")
+	}
+	frequency(v, field, c.MinGhz, c.MaxGhz)
+}
+
+func gpu(v *Violations, field string, g *pb.GPU) {
+	name(v, field+".manufacturer", g.Manufacturer, false)
+	if g.Memory <= 0 {
+		v.Add(field+".memory", "must be greater than 0")
+	}
+	enum(v, field+".memory_unit", g.MemoryUnit)
+	frequency(v, field, g.MinGhz, g.MaxGhz)
+}
+
+// 浮点数还需要排除NaN和Inf，NaN和任何数比较的结果都是false
+func positive(x float64) bool {
+	return x > 0 && !math.IsInf(x, 1)
+}
+
+func frequency(v *Violations, field string, min, max float64) {
+	if !positive(min) {
+		v.Add(field+".min_ghz", "must be a finite number greater than 0")
+	}
+	if !positive(max) {
+		v.Add(field+".max_ghz", "must be a finite number greater than 0")
+	} else if min > max {
+		v.Add(field+".max_ghz", "must not be less than min_ghz")
+	}
+}
+
+func ram(v *Violations, field string, r *pb.RAM) {
+	if r == nil {
+		v.Add(field, "is required")
+		return
+	}
+	if r.Value <= 0 {
+		v.Add(field+".value", "must be greater than 0")
+	}
+	enum(v, field+".unit", r.Unit)
+	enum(v, field+".ddr_type", r.DdrType)
+}
+
+func storage(v *Violations, field string, s *pb.Storage) {
+	if s == nil {
+		v.Add(field, "is required")
+		return
+	}
+	if s.Value <= 0 {
+		v.Add(field+".value", "must be greater than 0")
+	}
+	enum(v, field+".unit", s.Unit)
+	enum(v, field+".storage_type", s.StorageType)
+}
+
+func battery(v *Violations, field string, b *pb.Battery) {
+	if b == nil {
+		v.Add(field, "is required")
+		return
+	}
+	if b.Capacity <= 0 {
+		v.Add(field+".capacity", "must be greater than 0")
+	}
+}
+
+func screen(v *Violations, field string, s *pb.Screen) {
+	if !positive(s.Size) {
+		v.Add(field+".size", "must be a finite number greater than 0")
+	}
+	if _, _, ok := ParseResolution(s.Resolution); !ok {
+		v.Add(field+".resolution", "must be in the form of WIDTHxHEIGHT, e.g. 1920x1080")
+	}
+}
+
+// 解析"宽x高"形式的分辨率
+func ParseResolution(resolution string) (width, height int, ok bool) {
+	m := resolutionPattern.FindStringSubmatch(resolution)
+	if m == nil {
+		return 0, 0, false
+	}
+	width, _ = strconv.Atoi(m[1])
+	height, _ = strconv.Atoi(m[2])
+	return width, height, true
+}
+
+func name(v *Violations, field, value string, required bool) {
+	if value == "" {
+		if required {
+			v.Add(field, "is required")
+		}
+		return
+	}
+	if len(value) > maxNameLength {
+		v.Add(field, "must not be longer than "+strconv.Itoa(maxNameLength)+" characters")
+	}
+}
+
+// 枚举值必须是proto中定义过的
+func enum(v *Violations, field string, e protoreflect.Enum) {
+	if e.Descriptor().Values().ByNumber(e.Number()) == nil {
+		v.Add(field, "unknown enum value "+strconv.Itoa(int(e.Number())))
+	}
+}
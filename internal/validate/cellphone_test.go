@@ -0,0 +1,120 @@
+package validate_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/validate"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+func fields(v validate.Violations) []string {
+	var res []string
+	for _, fv := range v {
+		res = append(res, fv.Field)
+	}
+	return res
+}
+
+func TestValidateCellphone(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Name   string
+		Modify func(c *pb.Cellphone)
+		Fields []string
+	}{
+		{"valid", func(c *pb.Cellphone) {}, nil},
+		{"empty-id", func(c *pb.Cellphone) { c.Id = "" }, nil},
+		{"optional-fields-missing", func(c *pb.Cellphone) {
+			c.Gpu, c.OperatingSystem, c.Screen, c.Camera, c.CreatedAt = nil, nil, nil, nil, nil
+		}, nil},
+		{"invalid-uuid", func(c *pb.Cellphone) { c.Id = "invalid-uuid" }, []string{"cellphone.id"}},
+		{"empty-brand", func(c *pb.Cellphone) { c.Brand = "" }, []string{"cellphone.brand"}},
+		{"nil-cpu", func(c *pb.Cellphone) { c.Cpu = nil }, []string{"cellphone.cpu"}},
+		{"negative-cores", func(c *pb.Cellphone) { c.Cpu.Cores = -2 }, []string{"cellphone.cpu.cores"}},
+		{"min-greater-than-max", func(c *pb.Cellphone) { c.Cpu.MinGhz, c.Cpu.MaxGhz = 3, 2 }, []string{"cellphone.cpu.max_ghz"}},
+		{"nan-min-ghz", func(c *pb.Cellphone) { c.Cpu.MinGhz = math.NaN() }, []string{"cellphone.cpu.min_ghz"}},
+		{"nan-max-ghz", func(c *pb.Cellphone) { c.Cpu.MaxGhz = math.NaN() }, []string{"cellphone.cpu.max_ghz"}},
+		{"inf-max-ghz", func(c *pb.Cellphone) { c.Cpu.MaxGhz = math.Inf(1) }, []string{"cellphone.cpu.max_ghz"}},
+		{"negative-inf-gpu-min-ghz", func(c *pb.Cellphone) { c.Gpu.MinGhz = math.Inf(-1) }, []string{"cellphone.gpu.min_ghz"}},
+		{"zero-battery", func(c *pb.Cellphone) { c.Battery.Capacity = 0 }, []string{"cellphone.battery.capacity"}},
+		{"nil-ram", func(c *pb.Cellphone) { c.Ram = nil }, []string{"cellphone.ram"}},
+		{"unknown-unit", func(c *pb.Cellphone) { c.Storage.Unit = pb.Unit(42) }, []string{"cellphone.storage.unit"}},
+		{"unknown-storage-type", func(c *pb.Cellphone) { c.Storage.StorageType = pb.StorageType(1) }, []string{"cellphone.storage.storage_type"}},
+		{"gpu-without-memory", func(c *pb.Cellphone) { c.Gpu.Memory = 0 }, []string{"cellphone.gpu.memory"}},
+		{"bad-resolution", func(c *pb.Cellphone) { c.Screen.Resolution = "full-hd" }, []string{"cellphone.screen.resolution"}},
+		{"zero-screen-size", func(c *pb.Cellphone) { c.Screen.Size = 0 }, []string{"cellphone.screen.size"}},
+		{"nan-screen-size", func(c *pb.Cellphone) { c.Screen.Size = math.NaN() }, []string{"cellphone.screen.size"}},
+		{"inf-screen-size", func(c *pb.Cellphone) { c.Screen.Size = math.Inf(1) }, []string{"cellphone.screen.size"}},
+		{"invalid-timestamp", func(c *pb.Cellphone) { c.CreatedAt = &timestamppb.Timestamp{Nanos: -1} }, []string{"cellphone.created_at"}},
+		{"many-problems", func(c *pb.Cellphone) {
+			c.Cpu = nil
+			c.Battery.Capacity = 0
+			c.Ram.Value = -1
+			c.Screen.Resolution = "1920*1080"
+		}, []string{"cellphone.cpu", "cellphone.ram.value", "cellphone.battery.capacity", "cellphone.screen.resolution"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			c := sample.NewCellphone()
+			tc.Modify(c)
+			v := validate.Cellphone("cellphone", c)
+			require.Equal(t, tc.Fields, fields(v))
+		})
+	}
+}
+
+func TestValidateCreateCellphoneRequest(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, validate.CreateCellphoneRequest(&pb.CreateCellphoneRequest{Cellphone: sample.NewCellphone()}))
+
+	err := validate.CreateCellphoneRequest(&pb.CreateCellphoneRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// 所有问题都会放在BadRequest中一次性返回
+	err = validate.CreateCellphoneRequest(&pb.CreateCellphoneRequest{Cellphone: &pb.Cellphone{Id: "bad"}})
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 2)
+	errorInfo, ok := st.Details()[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	require.Equal(t, pb.ErrorReason_INVALID_REQUEST.String(), errorInfo.Reason)
+	badRequest, ok := st.Details()[1].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, badRequest.FieldViolations, 6)
+	require.Contains(t, st.Message(), "cellphone.id must be a valid uuid")
+}
+
+func TestParseResolution(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		Resolution    string
+		Width, Height int
+		Ok            bool
+	}{
+		{"1920x1080", 1920, 1080, true},
+		{"1334x750", 1334, 750, true},
+		{"0x1080", 0, 0, false},
+		{"1920X1080", 0, 0, false},
+		{"1920x", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tc := range testCases {
+		w, h, ok := validate.ParseResolution(tc.Resolution)
+		require.Equal(t, tc.Ok, ok, tc.Resolution)
+		require.Equal(t, tc.Width, w, tc.Resolution)
+		require.Equal(t, tc.Height, h, tc.Resolution)
+	}
+}
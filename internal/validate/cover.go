@@ -0,0 +1,6 @@
+package validate
+
+const maxCoverImageSizeMB = 1
+
+// 封面图片的最大字节数，服务端拒绝更大的图片，客户端也据此提前检查
+const MaxCoverImageBytes = uint32(maxCoverImageSizeMB * 1024 * 1024) // bytes
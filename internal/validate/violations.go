@@ -0,0 +1,34 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
+)
+
+// 不合法的字段列表
+type Violations []*errdetails.BadRequest_FieldViolation
+
+func (v *Violations) Add(field, description string) {
+	*v = append(*v, &errdetails.BadRequest_FieldViolation{Field: field, Description: description})
+}
+
+// 没有不合法的字段时返回nil
+// 否则返回InvalidArgument错误，并在详情中附带errdetails.BadRequest
+func (v Violations) Err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return rpcerr.InvalidArgument(v.message(), v)
+}
+
+func (v Violations) message() string {
+	parts := make([]string, 0, len(v))
+	for _, fv := range v {
+		parts = append(parts, fmt.Sprintf("%s %s", fv.Field, fv.Description))
+	}
+	return "invalid request: " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,412 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	mrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 推送相关的可选项，为0的字段使用默认值
+type Options struct {
+	// 最多尝试多少次，之后推送被标记为失败
+	MaxAttempts int
+	// 第一次重试之前等待的时间，之后每次翻倍
+	InitialBackoff time.Duration
+	// 重试等待时间的上限
+	MaxBackoff time.Duration
+	// 单次推送的超时时间
+	Timeout time.Duration
+	// 同时进行的推送数量
+	Workers int
+	// 发送请求使用的client，为nil时使用http.DefaultClient
+	Client *http.Client
+}
+
+func (o *Options) setDefaults() {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 8
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 5 * time.Minute
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	if o.Workers <= 0 {
+		o.Workers = 4
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+}
+
+// 把业务事件推送给订阅了它的webhook
+// 实现了service.Observer接口，通过service.WithObserver安装
+// 事件先放进Store的内存队列，由Run写入文件并推送，失败之后按照指数退避重试
+type Dispatcher struct {
+	store *Store
+	opts  Options
+	// 有新的推送需要进行
+	wake chan struct{}
+
+	mu       sync.Mutex
+	inflight map[string]bool
+}
+
+var _ service.Observer = (*Dispatcher)(nil)
+
+func NewDispatcher(store *Store, opts Options) *Dispatcher {
+	opts.setDefaults()
+	return &Dispatcher{
+		store:    store,
+		opts:     opts,
+		wake:     make(chan struct{}, 1),
+		inflight: make(map[string]bool),
+	}
+}
+
+func (d *Dispatcher) Store() *Store {
+	return d.store
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 注册一个webhook，secret为空时生成一个随机的secret
+func (d *Dispatcher) Register(rawURL string, events []string, secret string) (*Subscription, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url %q", rawURL)
+	}
+	for _, e := range events {
+		if !ValidEventType(e) {
+			return nil, fmt.Errorf("unknown webhook event type %q", e)
+		}
+	}
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secret = hex.EncodeToString(buf)
+	}
+	sub := &Subscription{
+		Id:        uuid.NewString(),
+		URL:       rawURL,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := d.store.PutSubscription(sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// 取消一个webhook
+func (d *Dispatcher) Unregister(id string) error {
+	return d.store.DeleteSubscription(id)
+}
+
+// 重新推送一条已经结束的推送
+func (d *Dispatcher) Redeliver(id string) (*Delivery, error) {
+	delivery, err := d.store.Delivery(id)
+	if err != nil {
+		return nil, err
+	}
+	if !delivery.finished() {
+		return nil, ErrDeliveryPending
+	}
+	now := time.Now().UTC()
+	delivery.Status = StatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	delivery.UpdatedAt = now
+	if err := d.store.PutDelivery(delivery); err != nil {
+		return nil, err
+	}
+	d.notify()
+	return delivery, nil
+}
+
+// 产生一个事件，为每个订阅了这种事件的webhook创建一条推送
+// 在rpc中调用，所以不写文件，推送由Run保存到队列目录中
+func (d *Dispatcher) Publish(typ string, data interface{}) (*Event, error) {
+	event, err := newEvent(typ, data)
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range d.store.Subscriptions() {
+		if !sub.Wants(typ) {
+			continue
+		}
+		delivery := &Delivery{
+			Id:             uuid.NewString(),
+			SubscriptionId: sub.Id,
+			Event:          event,
+			Status:         StatusPending,
+			NextAttemptAt:  event.CreatedAt,
+			CreatedAt:      event.CreatedAt,
+			UpdatedAt:      event.CreatedAt,
+		}
+		d.store.Enqueue(delivery)
+	}
+	d.notify()
+	return event, nil
+}
+
+func (d *Dispatcher) publish(ctx context.Context, typ string, data interface{}) {
+	if _, err := d.Publish(typ, data); err != nil {
+		slog.ErrorContext(ctx, "can not publish webhook event", "event", typ, "error", err)
+	}
+}
+
+func (d *Dispatcher) CellphoneCreated(ctx context.Context, cellphone *pb.Cellphone) {
+	d.publish(ctx, EventCellphoneCreated, cellphone)
+}
+
+func (d *Dispatcher) CoverUploaded(ctx context.Context, id string, size uint32) {
+	d.publish(ctx, EventCoverUploaded, &CoverUploadedData{Id: id, Size: size})
+}
+
+// 被拒绝的上传不推送
+func (d *Dispatcher) CoverRejected(context.Context, string, error) {}
+
+func (d *Dispatcher) OrderPlaced(ctx context.Context, id string, price float64, orders *service.Orders) {
+	data := &OrderPlacedData{Id: id, Price: price}
+	if orders != nil && orders.Count != 0 {
+		data.Count = orders.Count
+		data.Avg = orders.Total / float64(orders.Count)
+	}
+	d.publish(ctx, EventOrderPlaced, data)
+}
+
+// 不断推送到期的推送，直到ctx结束
+// 重启之后Store中没有完成的推送会继续进行
+func (d *Dispatcher) Run(ctx context.Context) {
+	sem := make(chan struct{}, d.opts.Workers)
+	var wg sync.WaitGroup
+	// 退出之前保存还在内存中的推送，重启之后继续
+	defer d.flush(ctx)
+	defer wg.Wait()
+
+	for {
+		d.flush(ctx)
+		next, ok := d.dispatchDue(ctx, sem, &wg)
+		if !ok {
+			return
+		}
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(time.Until(next))
+			expired = timer.C
+		}
+		select {
+		case <-ctx.Done():
+		case <-d.wake:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// 开始所有到期的推送，返回下一条推送到期的时间，没有需要等待的推送时返回零值
+func (d *Dispatcher) dispatchDue(ctx context.Context, sem chan struct{}, wg *sync.WaitGroup) (time.Time, bool) {
+	var next time.Time
+	for _, delivery := range d.store.Deliveries(DeliveryFilter{Status: StatusPending}) {
+		d.mu.Lock()
+		busy := d.inflight[delivery.Id]
+		d.mu.Unlock()
+		if busy {
+			continue
+		}
+		if delivery.NextAttemptAt.After(time.Now()) {
+			if next.IsZero() || delivery.NextAttemptAt.Before(next) {
+				next = delivery.NextAttemptAt
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return time.Time{}, false
+		case sem <- struct{}{}:
+		}
+		d.mu.Lock()
+		busy = d.inflight[delivery.Id]
+		d.inflight[delivery.Id] = true
+		d.mu.Unlock()
+		// 取快照之后推送可能已经结束，重新读取一次
+		current, err := d.store.Delivery(delivery.Id)
+		if busy || err != nil || current.finished() || current.NextAttemptAt.After(time.Now()) {
+			if !busy {
+				d.mu.Lock()
+				delete(d.inflight, delivery.Id)
+				d.mu.Unlock()
+			}
+			<-sem
+			continue
+		}
+		delivery = current
+
+		wg.Add(1)
+		go func(delivery *Delivery) {
+			defer func() {
+				d.mu.Lock()
+				delete(d.inflight, delivery.Id)
+				d.mu.Unlock()
+				<-sem
+				wg.Done()
+				// 推送结束之后可能需要安排重试
+				d.notify()
+			}()
+			d.attempt(ctx, delivery)
+		}(delivery)
+	}
+	return next, true
+}
+
+// 进行一次推送，并记录结果
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) {
+	sub, err := d.store.Subscription(delivery.SubscriptionId)
+	if err != nil {
+		// 订阅已经被删除
+		delivery.Status = StatusFailed
+		delivery.LastError = err.Error()
+		delivery.UpdatedAt = time.Now().UTC()
+		d.save(ctx, delivery)
+		return
+	}
+
+	statusCode, retryAfter, err := d.send(ctx, sub, delivery)
+	if ctx.Err() != nil {
+		// 服务关闭时中断的推送不计入尝试次数，重启之后继续
+		return
+	}
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+	delivery.UpdatedAt = now
+	if err != nil {
+		delivery.LastError = err.Error()
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = StatusDelivered
+	case delivery.Attempts >= d.opts.MaxAttempts:
+		delivery.Status = StatusFailed
+		slog.WarnContext(ctx, "webhook delivery failed", "delivery", delivery.Id,
+			"url", sub.URL, "attempts", delivery.Attempts, "error", err)
+	default:
+		wait := d.backoff(delivery.Attempts)
+		if retryAfter > wait {
+			wait = min(retryAfter, d.opts.MaxBackoff)
+		}
+		delivery.NextAttemptAt = now.Add(wait)
+		slog.DebugContext(ctx, "webhook delivery will be retried", "delivery", delivery.Id,
+			"url", sub.URL, "attempts", delivery.Attempts, "after", wait, "error", err)
+	}
+	d.save(ctx, delivery)
+}
+
+// 保存Publish产生的推送
+func (d *Dispatcher) flush(ctx context.Context) {
+	if err := d.store.Flush(); err != nil {
+		slog.ErrorContext(ctx, "can not save webhook deliveries", "error", err)
+	}
+}
+
+func (d *Dispatcher) save(ctx context.Context, delivery *Delivery) {
+	if err := d.store.PutDelivery(delivery); err != nil {
+		slog.ErrorContext(ctx, "can not save webhook delivery", "delivery", delivery.Id, "error", err)
+	}
+}
+
+// 第n次失败之后等待的时间，额外加上最多10%的随机抖动，避免所有重试同时进行
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.opts.InitialBackoff
+	for i := 1; i < attempts && wait < d.opts.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.opts.MaxBackoff {
+		wait = d.opts.MaxBackoff
+	}
+	if jitter := int64(wait / 10); jitter > 0 {
+		wait += time.Duration(mrand.Int63n(jitter))
+	}
+	return wait
+}
+
+// 发送推送请求，返回响应的状态码和Retry-After
+// 非2xx的响应也作为错误返回
+func (d *Dispatcher) send(ctx context.Context, sub *Subscription, delivery *Delivery) (int, time.Duration, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, 0, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-grpc-example-webhook/1.0")
+	req.Header.Set(HeaderEventId, delivery.Event.Id)
+	req.Header.Set(HeaderEventType, delivery.Event.Type)
+	req.Header.Set(HeaderDelivery, delivery.Id)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, body))
+
+	resp, err := d.opts.Client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	// 读完body才能复用连接
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, 0, nil
+	}
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	return resp.StatusCode, retryAfter, fmt.Errorf("unexpected status %s", resp.Status)
+}
@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 可以订阅的事件类型
+const (
+	EventCellphoneCreated = "cellphone.created"
+	EventCoverUploaded    = "cover.uploaded"
+	EventOrderPlaced      = "order.placed"
+)
+
+var eventTypes = map[string]bool{
+	EventCellphoneCreated: true,
+	EventCoverUploaded:    true,
+	EventOrderPlaced:      true,
+}
+
+// 检查事件类型是否合法
+func ValidEventType(typ string) bool {
+	return eventTypes[typ]
+}
+
+// 推送给webhook的事件，序列化之后作为请求的body
+type Event struct {
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// 封面图片上传成功的事件内容
+type CoverUploadedData struct {
+	Id   string `json:"id"`
+	Size uint32 `json:"size"`
+}
+
+// 下单成功的事件内容
+type OrderPlacedData struct {
+	Id    string  `json:"id"`
+	Price float64 `json:"price"`
+	Count uint32  `json:"count"`
+	Avg   float64 `json:"avg"`
+}
+
+func newEvent(typ string, data interface{}) (*Event, error) {
+	var raw []byte
+	var err error
+	if cellphone, ok := data.(*pb.Cellphone); ok {
+		// 和网关的json格式保持一致
+		raw, err = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}.Marshal(cellphone)
+	} else {
+		raw, err = json.Marshal(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can not marshal %s event: %w", typ, err)
+	}
+	return &Event{
+		Id:        uuid.NewString(),
+		Type:      typ,
+		CreatedAt: time.Now().UTC(),
+		Data:      raw,
+	}, nil
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 管理webhook的http接口，挂载在管理端口的/webhooks下
+//
+//	GET    /webhooks                                 所有订阅
+//	POST   /webhooks                                 注册，body为{"url","events","secret"}
+//	GET    /webhooks/{id}                            某个订阅
+//	DELETE /webhooks/{id}                            取消订阅
+//	GET    /webhooks/deliveries?status=&subscription_id=  推送记录
+//	GET    /webhooks/deliveries/{id}                 某条推送记录
+//	POST   /webhooks/deliveries/{id}/redeliver       重新推送
+func (d *Dispatcher) Handler() http.Handler {
+	return http.HandlerFunc(d.serveHTTP)
+}
+
+// 注册webhook的请求
+type registerRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// 返回给管理接口的订阅信息，只有注册时才返回secret
+type subscriptionView struct {
+	Id        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func newSubscriptionView(sub *Subscription, withSecret bool) *subscriptionView {
+	v := &subscriptionView{Id: sub.Id, URL: sub.URL, Events: sub.Events, CreatedAt: sub.CreatedAt}
+	if v.Events == nil {
+		v.Events = []string{}
+	}
+	if withSecret {
+		v.Secret = sub.Secret
+	}
+	return v
+}
+
+func (d *Dispatcher) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/webhooks"), "/")
+	parts := strings.Split(path, "/")
+	if path == "" {
+		parts = nil
+	}
+
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		subs := d.store.Subscriptions()
+		views := make([]*subscriptionView, 0, len(subs))
+		for _, sub := range subs {
+			views = append(views, newSubscriptionView(sub, false))
+		}
+		writeJSON(w, http.StatusOK, views)
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		var req registerRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		sub, err := d.Register(req.URL, req.Events, req.Secret)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, newSubscriptionView(sub, true))
+	case len(parts) == 1 && parts[0] == "deliveries" && r.Method == http.MethodGet:
+		deliveries := d.store.Deliveries(DeliveryFilter{
+			Status:         r.URL.Query().Get("status"),
+			SubscriptionId: r.URL.Query().Get("subscription_id"),
+		})
+		if deliveries == nil {
+			deliveries = []*Delivery{}
+		}
+		writeJSON(w, http.StatusOK, deliveries)
+	case len(parts) == 2 && parts[0] == "deliveries" && r.Method == http.MethodGet:
+		delivery, err := d.store.Delivery(parts[1])
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, delivery)
+	case len(parts) == 3 && parts[0] == "deliveries" && parts[2] == "redeliver" && r.Method == http.MethodPost:
+		delivery, err := d.Redeliver(parts[1])
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, delivery)
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		sub, err := d.store.Subscription(parts[0])
+		if err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newSubscriptionView(sub, false))
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if err := d.Unregister(parts[0]); err != nil {
+			writeError(w, statusOf(err), err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("no such webhook endpoint"))
+	}
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrSubscriptionNotFound), errors.Is(err, ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrDeliveryPending):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
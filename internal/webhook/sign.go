@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 推送请求携带的请求头
+const (
+	HeaderEventId    = "X-Webhook-Id"
+	HeaderEventType  = "X-Webhook-Event"
+	HeaderDelivery   = "X-Webhook-Delivery"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+	signatureVersion = "sha256="
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// 签名的内容为"时间戳.body"，时间戳为unix秒
+// 接收方可以通过时间戳拒绝重放的请求
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// 接收方校验签名，时间戳和当前时间相差超过tolerance时也认为不合法，tolerance为0时不检查时间戳
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		diff := time.Since(time.Unix(ts, 0))
+		if diff > tolerance || diff < -tolerance {
+			return ErrInvalidSignature
+		}
+	}
+	if !strings.HasPrefix(signature, signatureVersion) {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrDeliveryPending      = errors.New("webhook delivery is still pending")
+)
+
+// 推送的状态
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// 一个webhook订阅
+type Subscription struct {
+	Id  string `json:"id"`
+	URL string `json:"url"`
+	// 订阅的事件类型，为空时订阅所有事件
+	Events []string `json:"events"`
+	// 用来对推送内容签名的密钥
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 是否订阅了某种事件
+func (s *Subscription) Wants(typ string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// 一个事件对一个订阅的一次推送，失败之后会重试
+type Delivery struct {
+	Id             string `json:"id"`
+	SubscriptionId string `json:"subscription_id"`
+	Event          *Event `json:"event"`
+	Status         string `json:"status"`
+	// 已经尝试的次数
+	Attempts int `json:"attempts"`
+	// 下一次尝试的时间
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// 最近一次尝试的结果
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (d *Delivery) finished() bool {
+	return d.Status != StatusPending
+}
+
+// 保存订阅和推送记录
+// dir不为空时每次修改都会写入到文件中，重启之后没有完成的推送会继续进行
+// 例外是Enqueue加入的推送，由Flush批量写入，避免在rpc中同步写文件
+// 目录结构：dir/subscriptions.json，dir/deliveries/<id>.json
+type Store struct {
+	mu  sync.Mutex
+	dir string
+	// 最多保留多少条已经结束的推送记录
+	retention     int
+	subscriptions map[string]*Subscription
+	deliveries    map[string]*Delivery
+	// Enqueue之后还没有写入文件的推送
+	unsaved map[string]bool
+}
+
+// dir为空时只保存在内存中
+func NewStore(dir string, retention int) (*Store, error) {
+	s := &Store{
+		dir:           dir,
+		retention:     retention,
+		subscriptions: make(map[string]*Subscription),
+		deliveries:    make(map[string]*Delivery),
+		unsaved:       make(map[string]bool),
+	}
+	if dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "deliveries"), 0o755); err != nil {
+		return nil, fmt.Errorf("can not create webhook queue dir: %w", err)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	data, err := os.ReadFile(s.subscriptionsFile())
+	if err == nil {
+		var subs []*Subscription
+		if err := json.Unmarshal(data, &subs); err != nil {
+			return fmt.Errorf("can not parse webhook subscriptions: %w", err)
+		}
+		for _, sub := range subs {
+			s.subscriptions[sub.Id] = sub
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("can not read webhook subscriptions: %w", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.dir, "deliveries", "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("can not read webhook delivery: %w", err)
+		}
+		var d Delivery
+		if err := json.Unmarshal(data, &d); err != nil {
+			return fmt.Errorf("can not parse webhook delivery %s: %w", file, err)
+		}
+		s.deliveries[d.Id] = &d
+	}
+	return nil
+}
+
+func (s *Store) subscriptionsFile() string {
+	return filepath.Join(s.dir, "subscriptions.json")
+}
+
+func (s *Store) deliveryFile(id string) string {
+	return filepath.Join(s.dir, "deliveries", id+".json")
+}
+
+// 先写临时文件再重命名，避免写到一半时崩溃留下损坏的文件
+func writeFileAtomic(filename string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+func (s *Store) persistSubscriptions() error {
+	if s.dir == "" {
+		return nil
+	}
+	subs := make([]*Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		subs = append(subs, sub)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].Id < subs[j].Id })
+	return writeFileAtomic(s.subscriptionsFile(), subs)
+}
+
+func (s *Store) persistDelivery(d *Delivery) error {
+	if s.dir == "" {
+		return nil
+	}
+	return writeFileAtomic(s.deliveryFile(d.Id), d)
+}
+
+// 添加或者替换一个订阅
+func (s *Store) PutSubscription(sub *Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *sub
+	s.subscriptions[sub.Id] = &copied
+	return s.persistSubscriptions()
+}
+
+// 删除一个订阅，还没有完成的推送不会再进行
+func (s *Store) DeleteSubscription(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[id]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	delete(s.subscriptions, id)
+	return s.persistSubscriptions()
+}
+
+func (s *Store) Subscription(id string) (*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.subscriptions[id]
+	if !ok {
+		return nil, ErrSubscriptionNotFound
+	}
+	copied := *sub
+	return &copied, nil
+}
+
+// 按照创建时间排列的所有订阅
+func (s *Store) Subscriptions() []*Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := make([]*Subscription, 0, len(s.subscriptions))
+	for _, sub := range s.subscriptions {
+		copied := *sub
+		subs = append(subs, &copied)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].CreatedAt.Equal(subs[j].CreatedAt) {
+			return subs[i].Id < subs[j].Id
+		}
+		return subs[i].CreatedAt.Before(subs[j].CreatedAt)
+	})
+	return subs
+}
+
+// 添加或者更新一条推送记录
+func (s *Store) PutDelivery(d *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *d
+	s.deliveries[d.Id] = &copied
+	if err := s.persistDelivery(&copied); err != nil {
+		s.unsaved[d.Id] = true
+		return err
+	}
+	delete(s.unsaved, d.Id)
+	if copied.finished() {
+		s.prune()
+	}
+	return nil
+}
+
+// 添加一条新的推送，只写入内存，需要调用Flush才会写入文件
+func (s *Store) Enqueue(d *Delivery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *d
+	s.deliveries[d.Id] = &copied
+	if s.dir != "" {
+		s.unsaved[d.Id] = true
+	}
+}
+
+// 把Enqueue之后还没有保存的推送写入文件，失败的推送下次调用时会重新写入
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flush()
+}
+
+func (s *Store) flush() error {
+	var errs []error
+	for id := range s.unsaved {
+		d, ok := s.deliveries[id]
+		if !ok {
+			delete(s.unsaved, id)
+			continue
+		}
+		if err := s.persistDelivery(d); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(s.unsaved, id)
+	}
+	return errors.Join(errs...)
+}
+
+// 只保留最近结束的retention条推送记录
+func (s *Store) prune() {
+	if s.retention <= 0 {
+		return
+	}
+	var finished []*Delivery
+	for _, d := range s.deliveries {
+		if d.finished() {
+			finished = append(finished, d)
+		}
+	}
+	if len(finished) <= s.retention {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].UpdatedAt.Before(finished[j].UpdatedAt) })
+	for _, d := range finished[:len(finished)-s.retention] {
+		delete(s.deliveries, d.Id)
+		delete(s.unsaved, d.Id)
+		if s.dir != "" {
+			os.Remove(s.deliveryFile(d.Id))
+		}
+	}
+}
+
+// 清理保存的文件：重新写入订阅，删除内存中已经没有的推送记录和写到一半的临时文件
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune()
+	if s.dir == "" {
+		return nil
+	}
+	if err := s.persistSubscriptions(); err != nil {
+		return err
+	}
+	if err := s.flush(); err != nil {
+		return err
+	}
+	files, err := filepath.Glob(filepath.Join(s.dir, "deliveries", "*"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if id, ok := strings.CutSuffix(filepath.Base(file), ".json"); ok {
+			if _, exists := s.deliveries[id]; exists {
+				continue
+			}
+		}
+		if err := os.Remove(file); err != nil {
+			return fmt.Errorf("can not remove webhook delivery file: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) Delivery(id string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.deliveries[id]
+	if !ok {
+		return nil, ErrDeliveryNotFound
+	}
+	copied := *d
+	return &copied, nil
+}
+
+// 查询推送记录的条件，字段为空时不过滤
+type DeliveryFilter struct {
+	Status         string
+	SubscriptionId string
+}
+
+// 按照创建时间排列的推送记录
+func (s *Store) Deliveries(filter DeliveryFilter) []*Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []*Delivery
+	for _, d := range s.deliveries {
+		if filter.Status != "" && !strings.EqualFold(filter.Status, d.Status) {
+			continue
+		}
+		if filter.SubscriptionId != "" && filter.SubscriptionId != d.SubscriptionId {
+			continue
+		}
+		copied := *d
+		res = append(res, &copied)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].CreatedAt.Equal(res[j].CreatedAt) {
+			return res[i].Id < res[j].Id
+		}
+		return res[i].CreatedAt.Before(res[j].CreatedAt)
+	})
+	return res
+}
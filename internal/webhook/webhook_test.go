@@ -0,0 +1,332 @@
+package webhook_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/internal/webhook"
+)
+
+// 测试中接收推送的服务端，前failures次请求返回500
+type receiver struct {
+	t      *testing.T
+	secret string
+
+	mu       sync.Mutex
+	failures int
+	events   []*webhook.Event
+	requests int
+}
+
+func newReceiver(t *testing.T, secret string, failures int) (*receiver, *httptest.Server) {
+	r := &receiver{t: t, secret: secret, failures: failures}
+	ts := httptest.NewServer(http.HandlerFunc(r.serveHTTP))
+	t.Cleanup(ts.Close)
+	return r, ts
+}
+
+func (r *receiver) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	require.Nil(r.t, err)
+	// 每次推送都带有合法的签名
+	err = webhook.Verify(r.secret, req.Header.Get(webhook.HeaderTimestamp),
+		req.Header.Get(webhook.HeaderSignature), body, time.Minute)
+	require.Nil(r.t, err)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests++
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var event webhook.Event
+	require.Nil(r.t, json.Unmarshal(body, &event))
+	require.Equal(r.t, event.Id, req.Header.Get(webhook.HeaderEventId))
+	require.Equal(r.t, event.Type, req.Header.Get(webhook.HeaderEventType))
+	r.events = append(r.events, &event)
+}
+
+func (r *receiver) received() ([]*webhook.Event, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*webhook.Event(nil), r.events...), r.requests
+}
+
+func newDispatcher(t *testing.T, dir string, opts webhook.Options) *webhook.Dispatcher {
+	store, err := webhook.NewStore(dir, 0)
+	require.Nil(t, err)
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = 10 * time.Millisecond
+	}
+	return webhook.NewDispatcher(store, opts)
+}
+
+func run(t *testing.T, d *webhook.Dispatcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// 等待所有推送结束
+func waitFinished(t *testing.T, d *webhook.Dispatcher) []*webhook.Delivery {
+	require.Eventually(t, func() bool {
+		return len(d.Store().Deliveries(webhook.DeliveryFilter{Status: webhook.StatusPending})) == 0
+	}, 5*time.Second, 5*time.Millisecond)
+	return d.Store().Deliveries(webhook.DeliveryFilter{})
+}
+
+func TestSignVerify(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"id":"1"}`)
+	now := time.Now().Unix()
+	signature := webhook.Sign("secret", now, body)
+
+	var testCases = []struct {
+		Name      string
+		Secret    string
+		Timestamp int64
+		Signature string
+		Body      []byte
+		Err       error
+	}{
+		{Name: "ok", Secret: "secret", Timestamp: now, Signature: signature, Body: body},
+		{Name: "wrong-secret", Secret: "other", Timestamp: now, Signature: signature, Body: body, Err: webhook.ErrInvalidSignature},
+		{Name: "tampered-body", Secret: "secret", Timestamp: now, Signature: signature, Body: []byte(`{"id":"2"}`), Err: webhook.ErrInvalidSignature},
+		{Name: "replayed", Secret: "secret", Timestamp: now - 3600, Signature: webhook.Sign("secret", now-3600, body), Body: body, Err: webhook.ErrInvalidSignature},
+		{Name: "no-version", Secret: "secret", Timestamp: now, Signature: signature[len("sha256="):], Body: body, Err: webhook.ErrInvalidSignature},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(it *testing.T) {
+			err := webhook.Verify(tc.Secret, strconv.FormatInt(tc.Timestamp, 10), tc.Signature, tc.Body, time.Minute)
+			require.ErrorIs(it, err, tc.Err)
+		})
+	}
+}
+
+func TestDispatcherRetry(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	d := newDispatcher(t, "", webhook.Options{MaxAttempts: 5})
+	recv, ts := newReceiver(t, "s3cr3t", 2)
+	orders, err := d.Register(ts.URL, []string{webhook.EventOrderPlaced}, "s3cr3t")
+	require.Nil(t, err)
+	run(t, d)
+
+	// 只订阅了下单事件
+	d.CellphoneCreated(ctx, sample.NewCellphone())
+	d.OrderPlaced(ctx, "order-id", 1999, &service.Orders{Count: 2, Total: 3000})
+
+	deliveries := waitFinished(t, d)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, orders.Id, deliveries[0].SubscriptionId)
+	require.Equal(t, webhook.StatusDelivered, deliveries[0].Status)
+	// 前两次失败，第三次成功
+	require.Equal(t, 3, deliveries[0].Attempts)
+	require.Equal(t, http.StatusOK, deliveries[0].LastStatusCode)
+	require.Empty(t, deliveries[0].LastError)
+
+	events, requests := recv.received()
+	require.Equal(t, 3, requests)
+	require.Len(t, events, 1)
+	require.Equal(t, webhook.EventOrderPlaced, events[0].Type)
+	var data webhook.OrderPlacedData
+	require.Nil(t, json.Unmarshal(events[0].Data, &data))
+	require.Equal(t, webhook.OrderPlacedData{Id: "order-id", Price: 1999, Count: 2, Avg: 1500}, data)
+}
+
+func TestDispatcherGiveUpAndRedeliver(t *testing.T) {
+	t.Parallel()
+
+	d := newDispatcher(t, "", webhook.Options{MaxAttempts: 2})
+	recv, ts := newReceiver(t, "secret", 3)
+	_, err := d.Register(ts.URL, nil, "secret")
+	require.Nil(t, err)
+	run(t, d)
+
+	_, err = d.Publish(webhook.EventCoverUploaded, &webhook.CoverUploadedData{Id: "cover-id", Size: 1024})
+	require.Nil(t, err)
+	deliveries := waitFinished(t, d)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, webhook.StatusFailed, deliveries[0].Status)
+	require.Equal(t, 2, deliveries[0].Attempts)
+	require.Equal(t, http.StatusInternalServerError, deliveries[0].LastStatusCode)
+
+	// 重新推送，再失败一次之后成功
+	_, err = d.Redeliver(deliveries[0].Id)
+	require.Nil(t, err)
+	deliveries = waitFinished(t, d)
+	require.Equal(t, webhook.StatusDelivered, deliveries[0].Status)
+	require.Equal(t, 2, deliveries[0].Attempts)
+	events, requests := recv.received()
+	require.Equal(t, 4, requests)
+	require.Len(t, events, 1)
+
+	// 不存在的推送
+	_, err = d.Redeliver("not-exist")
+	require.ErrorIs(t, err, webhook.ErrDeliveryNotFound)
+}
+
+func TestDispatcherPersistentQueue(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	recv, ts := newReceiver(t, "secret", 0)
+
+	// 没有运行Run，推送只是放进内存队列，不会在rpc中写文件
+	first := newDispatcher(t, dir, webhook.Options{})
+	sub, err := first.Register(ts.URL, []string{webhook.EventCellphoneCreated}, "secret")
+	require.Nil(t, err)
+	cellphone := sample.NewCellphone()
+	first.CellphoneCreated(context.Background(), cellphone)
+	require.Len(t, first.Store().Deliveries(webhook.DeliveryFilter{Status: webhook.StatusPending}), 1)
+	files, err := filepath.Glob(filepath.Join(dir, "deliveries", "*.json"))
+	require.Nil(t, err)
+	require.Empty(t, files)
+	// Run在推送之前保存队列，这里直接保存，模拟保存之后推送之前进程退出
+	require.Nil(t, first.Store().Flush())
+
+	// 重启之后从目录中恢复订阅和队列，继续推送
+	second := newDispatcher(t, dir, webhook.Options{})
+	require.Len(t, second.Store().Subscriptions(), 1)
+	run(t, second)
+	deliveries := waitFinished(t, second)
+	require.Len(t, deliveries, 1)
+	require.Equal(t, sub.Id, deliveries[0].SubscriptionId)
+	require.Equal(t, webhook.StatusDelivered, deliveries[0].Status)
+
+	events, _ := recv.received()
+	require.Len(t, events, 1)
+	require.Equal(t, webhook.EventCellphoneCreated, events[0].Type)
+	var data map[string]interface{}
+	require.Nil(t, json.Unmarshal(events[0].Data, &data))
+	require.Equal(t, cellphone.Id, data["id"])
+
+	// 推送结果也被保存下来
+	third := newDispatcher(t, dir, webhook.Options{})
+	delivery, err := third.Store().Delivery(deliveries[0].Id)
+	require.Nil(t, err)
+	require.Equal(t, webhook.StatusDelivered, delivery.Status)
+}
+
+// 压缩之后只剩下内存中的推送记录对应的文件
+func TestStoreCompact(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store, err := webhook.NewStore(dir, 0)
+	require.Nil(t, err)
+	require.Nil(t, store.PutDelivery(&webhook.Delivery{Id: "kept", Status: webhook.StatusPending}))
+	// 被删除之前崩溃留下的推送记录和写到一半的临时文件
+	stale := []string{
+		filepath.Join(dir, "deliveries", "removed.json"),
+		filepath.Join(dir, "deliveries", "kept.json.tmp"),
+	}
+	for _, file := range stale {
+		require.Nil(t, os.WriteFile(file, []byte("{}"), 0o600))
+	}
+
+	require.Nil(t, store.Compact())
+	for _, file := range stale {
+		_, err := os.Stat(file)
+		require.True(t, os.IsNotExist(err))
+	}
+	_, err = os.Stat(filepath.Join(dir, "subscriptions.json"))
+	require.Nil(t, err)
+	reopened, err := webhook.NewStore(dir, 0)
+	require.Nil(t, err)
+	_, err = reopened.Delivery("kept")
+	require.Nil(t, err)
+}
+
+func TestDispatcherHandler(t *testing.T) {
+	t.Parallel()
+
+	d := newDispatcher(t, "", webhook.Options{})
+	admin := httptest.NewServer(d.Handler())
+	t.Cleanup(admin.Close)
+
+	do := func(method, path string, body interface{}, v interface{}) int {
+		var reader io.Reader
+		if body != nil {
+			data, err := json.Marshal(body)
+			require.Nil(t, err)
+			reader = bytes.NewReader(data)
+		}
+		req, err := http.NewRequest(method, admin.URL+path, reader)
+		require.Nil(t, err)
+		res, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer res.Body.Close()
+		if v != nil {
+			require.Nil(t, json.NewDecoder(res.Body).Decode(v))
+		}
+		return res.StatusCode
+	}
+
+	var testCases = []struct {
+		Name string
+		Body map[string]interface{}
+		Code int
+	}{
+		{Name: "ok", Body: map[string]interface{}{"url": "http://127.0.0.1:1/hook", "events": []string{"order.placed"}}, Code: http.StatusCreated},
+		{Name: "bad-url", Body: map[string]interface{}{"url": "ftp://127.0.0.1/hook"}, Code: http.StatusBadRequest},
+		{Name: "bad-event", Body: map[string]interface{}{"url": "http://127.0.0.1:1/hook", "events": []string{"unknown"}}, Code: http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.Code, do(http.MethodPost, "/webhooks", tc.Body, nil), tc.Name)
+	}
+
+	// 注册时返回生成的secret，列表中不返回
+	var created map[string]interface{}
+	require.Equal(t, http.StatusCreated, do(http.MethodPost, "/webhooks", map[string]interface{}{"url": "http://127.0.0.1:1/other"}, &created))
+	require.NotEmpty(t, created["secret"])
+	var subs []map[string]interface{}
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/webhooks", nil, &subs))
+	require.Len(t, subs, 2)
+	for _, sub := range subs {
+		require.NotContains(t, sub, "secret")
+	}
+
+	// 推送记录
+	_, err := d.Publish(webhook.EventOrderPlaced, &webhook.OrderPlacedData{Id: "id"})
+	require.Nil(t, err)
+	var deliveries []*webhook.Delivery
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/webhooks/deliveries?status=pending", nil, &deliveries))
+	require.Len(t, deliveries, 2)
+	id := created["id"].(string)
+	require.Equal(t, http.StatusOK, do(http.MethodGet, "/webhooks/deliveries?subscription_id="+id, nil, &deliveries))
+	require.Len(t, deliveries, 1)
+	require.Equal(t, http.StatusConflict, do(http.MethodPost, "/webhooks/deliveries/"+deliveries[0].Id+"/redeliver", nil, nil))
+
+	// 取消订阅
+	require.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/webhooks/"+id, nil, nil))
+	require.Equal(t, http.StatusNotFound, do(http.MethodGet, "/webhooks/"+id, nil, nil))
+	require.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/webhooks/"+id, nil, nil))
+}
@@ -20,6 +20,58 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type CellphoneEvent_Type int32
+
+const (
+	CellphoneEvent_TYPE_UNSPECIFIED CellphoneEvent_Type = 0
+	CellphoneEvent_CREATED          CellphoneEvent_Type = 1
+	CellphoneEvent_UPDATED          CellphoneEvent_Type = 2
+	CellphoneEvent_DELETED          CellphoneEvent_Type = 3
+)
+
+// Enum value maps for CellphoneEvent_Type.
+var (
+	CellphoneEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "CREATED",
+		2: "UPDATED",
+		3: "DELETED",
+	}
+	CellphoneEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"CREATED":          1,
+		"UPDATED":          2,
+		"DELETED":          3,
+	}
+)
+
+func (x CellphoneEvent_Type) Enum() *CellphoneEvent_Type {
+	p := new(CellphoneEvent_Type)
+	*p = x
+	return p
+}
+
+func (x CellphoneEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CellphoneEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_cellphone_service_proto_enumTypes[0].Descriptor()
+}
+
+func (CellphoneEvent_Type) Type() protoreflect.EnumType {
+	return &file_cellphone_service_proto_enumTypes[0]
+}
+
+func (x CellphoneEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CellphoneEvent_Type.Descriptor instead.
+func (CellphoneEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{18, 0}
+}
+
 // 添加一台手机信息的请求
 type CreateCellphoneRequest struct {
 	state         protoimpl.MessageState
@@ -398,17 +450,17 @@ func (x *UploadCellphoneCoverResponse) GetSize() uint32 {
 	return 0
 }
 
-type BuyCellphoneRequest struct {
+// 获取一台手机信息的请求
+type GetCellphoneRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id    string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Price float64 `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 }
 
-func (x *BuyCellphoneRequest) Reset() {
-	*x = BuyCellphoneRequest{}
+func (x *GetCellphoneRequest) Reset() {
+	*x = GetCellphoneRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_cellphone_service_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -416,13 +468,13 @@ func (x *BuyCellphoneRequest) Reset() {
 	}
 }
 
-func (x *BuyCellphoneRequest) String() string {
+func (x *GetCellphoneRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BuyCellphoneRequest) ProtoMessage() {}
+func (*GetCellphoneRequest) ProtoMessage() {}
 
-func (x *BuyCellphoneRequest) ProtoReflect() protoreflect.Message {
+func (x *GetCellphoneRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_cellphone_service_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -434,51 +486,92 @@ func (x *BuyCellphoneRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BuyCellphoneRequest.ProtoReflect.Descriptor instead.
-func (*BuyCellphoneRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use GetCellphoneRequest.ProtoReflect.Descriptor instead.
+func (*GetCellphoneRequest) Descriptor() ([]byte, []int) {
 	return file_cellphone_service_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *BuyCellphoneRequest) GetId() string {
+func (x *GetCellphoneRequest) GetId() string {
 	if x != nil {
 		return x.Id
 	}
 	return ""
 }
 
-func (x *BuyCellphoneRequest) GetPrice() float64 {
+// 修改一台手机信息的请求，用cellphone替换id相同的手机
+type UpdateCellphoneRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cellphone *Cellphone `protobuf:"bytes,1,opt,name=cellphone,proto3" json:"cellphone,omitempty"`
+}
+
+func (x *UpdateCellphoneRequest) Reset() {
+	*x = UpdateCellphoneRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *UpdateCellphoneRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateCellphoneRequest) ProtoMessage() {}
+
+func (x *UpdateCellphoneRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateCellphoneRequest.ProtoReflect.Descriptor instead.
+func (*UpdateCellphoneRequest) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateCellphoneRequest) GetCellphone() *Cellphone {
 	if x != nil {
-		return x.Price
+		return x.Cellphone
 	}
-	return 0
+	return nil
 }
 
-type BuyCellphoneResponse struct {
+// 删除一台手机信息的请求
+type DeleteCellphoneRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Id  string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Avg float64 `protobuf:"fixed64,2,opt,name=avg,proto3" json:"avg,omitempty"`
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 }
 
-func (x *BuyCellphoneResponse) Reset() {
-	*x = BuyCellphoneResponse{}
+func (x *DeleteCellphoneRequest) Reset() {
+	*x = DeleteCellphoneRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_cellphone_service_proto_msgTypes[7]
+		mi := &file_cellphone_service_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *BuyCellphoneResponse) String() string {
+func (x *DeleteCellphoneRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*BuyCellphoneResponse) ProtoMessage() {}
+func (*DeleteCellphoneRequest) ProtoMessage() {}
 
-func (x *BuyCellphoneResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_cellphone_service_proto_msgTypes[7]
+func (x *DeleteCellphoneRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -489,160 +582,1437 @@ func (x *BuyCellphoneResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use BuyCellphoneResponse.ProtoReflect.Descriptor instead.
-func (*BuyCellphoneResponse) Descriptor() ([]byte, []int) {
-	return file_cellphone_service_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use DeleteCellphoneRequest.ProtoReflect.Descriptor instead.
+func (*DeleteCellphoneRequest) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{8}
 }
 
-func (x *BuyCellphoneResponse) GetId() string {
+func (x *DeleteCellphoneRequest) GetId() string {
 	if x != nil {
 		return x.Id
 	}
 	return ""
 }
 
-func (x *BuyCellphoneResponse) GetAvg() float64 {
+// 删除一台手机信息的响应
+type DeleteCellphoneResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *DeleteCellphoneResponse) Reset() {
+	*x = DeleteCellphoneResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DeleteCellphoneResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteCellphoneResponse) ProtoMessage() {}
+
+func (x *DeleteCellphoneResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteCellphoneResponse.ProtoReflect.Descriptor instead.
+func (*DeleteCellphoneResponse) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{9}
+}
+
+// 下载封面图片的请求
+type DownloadCellphoneCoverRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+}
+
+func (x *DownloadCellphoneCoverRequest) Reset() {
+	*x = DownloadCellphoneCoverRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *DownloadCellphoneCoverRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadCellphoneCoverRequest) ProtoMessage() {}
+
+func (x *DownloadCellphoneCoverRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadCellphoneCoverRequest.ProtoReflect.Descriptor instead.
+func (*DownloadCellphoneCoverRequest) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DownloadCellphoneCoverRequest) GetId() string {
 	if x != nil {
-		return x.Avg
+		return x.Id
 	}
-	return 0
+	return ""
 }
 
-var File_cellphone_service_proto protoreflect.FileDescriptor
+// 下载封面图片的响应，第一条为元数据，之后为图片的内容
+type DownloadCellphoneCoverResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_cellphone_service_proto_rawDesc = []byte{
-	0x0a, 0x17, 0x63, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x5f, 0x73, 0x65, 0x72, 0x76,
-	0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x1a, 0x0f, 0x63,
-	0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x45,
-	0x0a, 0x16, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e,
-	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2b, 0x0a, 0x09, 0x63, 0x65, 0x6c, 0x6c,
-	0x70, 0x68, 0x6f, 0x6e, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x62,
-	0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x09, 0x63, 0x65, 0x6c, 0x6c,
-	0x70, 0x68, 0x6f, 0x6e, 0x65, 0x22, 0x29, 0x0a, 0x17, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43,
-	0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
-	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64,
-	0x22, 0xc9, 0x01, 0x0a, 0x0f, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x64, 0x69,
-	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x0c, 0x6d, 0x69, 0x6e, 0x5f, 0x63, 0x70, 0x75, 0x5f,
-	0x63, 0x6f, 0x72, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x6d, 0x69, 0x6e, 0x43,
-	0x70, 0x75, 0x43, 0x6f, 0x72, 0x65, 0x12, 0x30, 0x0a, 0x14, 0x6d, 0x69, 0x6e, 0x5f, 0x62, 0x61,
-	0x74, 0x74, 0x65, 0x72, 0x79, 0x5f, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x12, 0x6d, 0x69, 0x6e, 0x42, 0x61, 0x74, 0x74, 0x65, 0x72, 0x79,
-	0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x12, 0x20, 0x0a, 0x0c, 0x6d, 0x69, 0x6e, 0x5f,
-	0x72, 0x61, 0x6d, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a,
-	0x6d, 0x69, 0x6e, 0x52, 0x61, 0x6d, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x28, 0x0a, 0x10, 0x6d, 0x69,
-	0x6e, 0x5f, 0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x0e, 0x6d, 0x69, 0x6e, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65,
-	0x53, 0x69, 0x7a, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x72, 0x61, 0x6e, 0x64, 0x73, 0x18, 0x05,
-	0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x62, 0x72, 0x61, 0x6e, 0x64, 0x73, 0x22, 0x66, 0x0a, 0x1b,
-	0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x43,
-	0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x27, 0x0a, 0x04, 0x6d,
-	0x65, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x62, 0x2e, 0x43,
-	0x6f, 0x76, 0x65, 0x72, 0x4d, 0x65, 0x74, 0x61, 0x49, 0x6e, 0x66, 0x6f, 0x48, 0x00, 0x52, 0x04,
-	0x6d, 0x65, 0x74, 0x61, 0x12, 0x16, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x42, 0x06, 0x0a, 0x04,
-	0x64, 0x61, 0x74, 0x61, 0x22, 0x52, 0x0a, 0x0d, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x4d, 0x65, 0x74,
-	0x61, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x0d, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x6d, 0x61,
-	0x67, 0x65, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x69,
-	0x6d, 0x61, 0x67, 0x65, 0x54, 0x79, 0x70, 0x65, 0x22, 0x42, 0x0a, 0x1c, 0x55, 0x70, 0x6c, 0x6f,
-	0x61, 0x64, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x22, 0x3b, 0x0a, 0x13,
-	0x42, 0x75, 0x79, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x01, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x22, 0x38, 0x0a, 0x14, 0x42, 0x75, 0x79,
-	0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69,
-	0x64, 0x12, 0x10, 0x0a, 0x03, 0x61, 0x76, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03,
-	0x61, 0x76, 0x67, 0x32, 0xbb, 0x02, 0x0a, 0x10, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e,
-	0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x4a, 0x0a, 0x0f, 0x43, 0x72, 0x65, 0x61,
-	0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x12, 0x1a, 0x2e, 0x70, 0x62,
-	0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x72, 0x65,
-	0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x37, 0x0a, 0x0f, 0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x43, 0x65,
-	0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x12, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c,
-	0x74, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x1a, 0x0d, 0x2e, 0x70,
-	0x62, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x30, 0x01, 0x12, 0x5b, 0x0a,
-	0x14, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65,
-	0x43, 0x6f, 0x76, 0x65, 0x72, 0x12, 0x1f, 0x2e, 0x70, 0x62, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61,
-	0x64, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x70, 0x62, 0x2e, 0x55, 0x70, 0x6c, 0x6f,
-	0x61, 0x64, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x12, 0x45, 0x0a, 0x0c, 0x42, 0x75,
-	0x79, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x12, 0x17, 0x2e, 0x70, 0x62, 0x2e,
-	0x42, 0x75, 0x79, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x70, 0x62, 0x2e, 0x42, 0x75, 0x79, 0x43, 0x65, 0x6c, 0x6c,
-	0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x30,
-	0x01, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+	// Types that are assignable to Data:
+	//
+	//	*DownloadCellphoneCoverResponse_Meta
+	//	*DownloadCellphoneCoverResponse_Block
+	Data isDownloadCellphoneCoverResponse_Data `protobuf_oneof:"data"`
 }
 
-var (
-	file_cellphone_service_proto_rawDescOnce sync.Once
-	file_cellphone_service_proto_rawDescData = file_cellphone_service_proto_rawDesc
-)
+func (x *DownloadCellphoneCoverResponse) Reset() {
+	*x = DownloadCellphoneCoverResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
 
-func file_cellphone_service_proto_rawDescGZIP() []byte {
-	file_cellphone_service_proto_rawDescOnce.Do(func() {
-		file_cellphone_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_cellphone_service_proto_rawDescData)
-	})
-	return file_cellphone_service_proto_rawDescData
+func (x *DownloadCellphoneCoverResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var file_cellphone_service_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
-var file_cellphone_service_proto_goTypes = []interface{}{
-	(*CreateCellphoneRequest)(nil),       // 0: pb.CreateCellphoneRequest
-	(*CreateCellphoneResponse)(nil),      // 1: pb.CreateCellphoneResponse
-	(*FilterCondition)(nil),              // 2: pb.FilterCondition
-	(*UploadCellphoneCoverRequest)(nil),  // 3: pb.UploadCellphoneCoverRequest
-	(*CoverMetaInfo)(nil),                // 4: pb.CoverMetaInfo
-	(*UploadCellphoneCoverResponse)(nil), // 5: pb.UploadCellphoneCoverResponse
-	(*BuyCellphoneRequest)(nil),          // 6: pb.BuyCellphoneRequest
-	(*BuyCellphoneResponse)(nil),         // 7: pb.BuyCellphoneResponse
-	(*Cellphone)(nil),                    // 8: pb.Cellphone
+func (*DownloadCellphoneCoverResponse) ProtoMessage() {}
+
+func (x *DownloadCellphoneCoverResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
-var file_cellphone_service_proto_depIdxs = []int32{
-	8, // 0: pb.CreateCellphoneRequest.cellphone:type_name -> pb.Cellphone
-	4, // 1: pb.UploadCellphoneCoverRequest.meta:type_name -> pb.CoverMetaInfo
-	0, // 2: pb.CellphoneService.CreateCellphone:input_type -> pb.CreateCellphoneRequest
-	2, // 3: pb.CellphoneService.SearchCellphone:input_type -> pb.FilterCondition
-	3, // 4: pb.CellphoneService.UploadCellphoneCover:input_type -> pb.UploadCellphoneCoverRequest
-	6, // 5: pb.CellphoneService.BuyCellphone:input_type -> pb.BuyCellphoneRequest
-	1, // 6: pb.CellphoneService.CreateCellphone:output_type -> pb.CreateCellphoneResponse
-	8, // 7: pb.CellphoneService.SearchCellphone:output_type -> pb.Cellphone
-	5, // 8: pb.CellphoneService.UploadCellphoneCover:output_type -> pb.UploadCellphoneCoverResponse
-	7, // 9: pb.CellphoneService.BuyCellphone:output_type -> pb.BuyCellphoneResponse
-	6, // [6:10] is the sub-list for method output_type
-	2, // [2:6] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+
+// Deprecated: Use DownloadCellphoneCoverResponse.ProtoReflect.Descriptor instead.
+func (*DownloadCellphoneCoverResponse) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{11}
 }
 
-func init() { file_cellphone_service_proto_init() }
-func file_cellphone_service_proto_init() {
-	if File_cellphone_service_proto != nil {
-		return
+func (m *DownloadCellphoneCoverResponse) GetData() isDownloadCellphoneCoverResponse_Data {
+	if m != nil {
+		return m.Data
 	}
-	file_cellphone_proto_init()
-	if !protoimpl.UnsafeEnabled {
-		file_cellphone_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CreateCellphoneRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+	return nil
+}
+
+func (x *DownloadCellphoneCoverResponse) GetMeta() *CoverMetaInfo {
+	if x, ok := x.GetData().(*DownloadCellphoneCoverResponse_Meta); ok {
+		return x.Meta
+	}
+	return nil
+}
+
+func (x *DownloadCellphoneCoverResponse) GetBlock() []byte {
+	if x, ok := x.GetData().(*DownloadCellphoneCoverResponse_Block); ok {
+		return x.Block
+	}
+	return nil
+}
+
+type isDownloadCellphoneCoverResponse_Data interface {
+	isDownloadCellphoneCoverResponse_Data()
+}
+
+type DownloadCellphoneCoverResponse_Meta struct {
+	Meta *CoverMetaInfo `protobuf:"bytes,1,opt,name=meta,proto3,oneof"`
+}
+
+type DownloadCellphoneCoverResponse_Block struct {
+	Block []byte `protobuf:"bytes,2,opt,name=block,proto3,oneof"`
+}
+
+func (*DownloadCellphoneCoverResponse_Meta) isDownloadCellphoneCoverResponse_Data() {}
+
+func (*DownloadCellphoneCoverResponse_Block) isDownloadCellphoneCoverResponse_Data() {}
+
+type BuyCellphoneRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id    string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Price float64 `protobuf:"fixed64,2,opt,name=price,proto3" json:"price,omitempty"`
+	// 幂等键，相同的键只会下单一次，重复的请求直接返回第一次的结果
+	// 客户端重试BuyCellphone时必须携带
+	IdempotencyKey string `protobuf:"bytes,3,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+}
+
+func (x *BuyCellphoneRequest) Reset() {
+	*x = BuyCellphoneRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BuyCellphoneRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuyCellphoneRequest) ProtoMessage() {}
+
+func (x *BuyCellphoneRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_cellphone_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuyCellphoneRequest.ProtoReflect.Descriptor instead.
+func (*BuyCellphoneRequest) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BuyCellphoneRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BuyCellphoneRequest) GetPrice() float64 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *BuyCellphoneRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+type BuyCellphoneResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id  string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Avg float64 `protobuf:"fixed64,2,opt,name=avg,proto3" json:"avg,omitempty"`
+}
+
+func (x *BuyCellphoneResponse) Reset() {
+	*x = BuyCellphoneResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BuyCellphoneResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuyCellphoneResponse) ProtoMessage() {}
+
+func (x *BuyCellphoneResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuyCellphoneResponse.ProtoReflect.Descriptor instead.
+func (*BuyCellphoneResponse) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *BuyCellphoneResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BuyCellphoneResponse) GetAvg() float64 {
+	if x != nil {
+		return x.Avg
+	}
+	return 0
+}
+
+// 查询订单的请求
+type ListOrdersRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 只返回这些手机的订单，为空时返回所有手机的订单
+	Ids []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+}
+
+func (x *ListOrdersRequest) Reset() {
+	*x = ListOrdersRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListOrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOrdersRequest) ProtoMessage() {}
+
+func (x *ListOrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOrdersRequest.ProtoReflect.Descriptor instead.
+func (*ListOrdersRequest) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListOrdersRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// 一台手机的订单统计
+type Order struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id    string  `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Count uint32  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	Total float64 `protobuf:"fixed64,3,opt,name=total,proto3" json:"total,omitempty"`
+	Avg   float64 `protobuf:"fixed64,4,opt,name=avg,proto3" json:"avg,omitempty"`
+}
+
+func (x *Order) Reset() {
+	*x = Order{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Order) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Order) ProtoMessage() {}
+
+func (x *Order) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Order.ProtoReflect.Descriptor instead.
+func (*Order) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Order) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Order) GetCount() uint32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Order) GetTotal() float64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Order) GetAvg() float64 {
+	if x != nil {
+		return x.Avg
+	}
+	return 0
+}
+
+// 查询订单的响应，按照id排序，没有订单的手机不会出现
+type ListOrdersResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Orders []*Order `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+}
+
+func (x *ListOrdersResponse) Reset() {
+	*x = ListOrdersResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListOrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListOrdersResponse) ProtoMessage() {}
+
+func (x *ListOrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListOrdersResponse.ProtoReflect.Descriptor instead.
+func (*ListOrdersResponse) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ListOrdersResponse) GetOrders() []*Order {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
+// 订阅手机信息变化的请求
+type WatchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 只关心符合条件的手机，为空时关心所有手机
+	Filter *FilterCondition `protobuf:"bytes,1,opt,name=filter,proto3" json:"filter,omitempty"`
+	// 从这个revision之后的变化开始推送，0表示只推送订阅之后发生的变化
+	SinceRevision uint64 `protobuf:"varint,2,opt,name=since_revision,json=sinceRevision,proto3" json:"since_revision,omitempty"`
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *WatchRequest) GetFilter() *FilterCondition {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *WatchRequest) GetSinceRevision() uint64 {
+	if x != nil {
+		return x.SinceRevision
+	}
+	return 0
+}
+
+// 手机信息的变化
+type CellphoneEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// CREATED来自CreateCellphone和BatchCreateCellphones，
+	// UPDATED和DELETED来自UpdateCellphone和DeleteCellphone
+	Type CellphoneEvent_Type `protobuf:"varint,1,opt,name=type,proto3,enum=pb.CellphoneEvent_Type" json:"type,omitempty"`
+	// 每次变化都会让revision加1
+	Revision uint64 `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	// 变化之后的手机信息，DELETED时为删除之前的手机信息
+	Cellphone *Cellphone `protobuf:"bytes,3,opt,name=cellphone,proto3" json:"cellphone,omitempty"`
+}
+
+func (x *CellphoneEvent) Reset() {
+	*x = CellphoneEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CellphoneEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CellphoneEvent) ProtoMessage() {}
+
+func (x *CellphoneEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CellphoneEvent.ProtoReflect.Descriptor instead.
+func (*CellphoneEvent) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CellphoneEvent) GetType() CellphoneEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return CellphoneEvent_TYPE_UNSPECIFIED
+}
+
+func (x *CellphoneEvent) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *CellphoneEvent) GetCellphone() *Cellphone {
+	if x != nil {
+		return x.Cellphone
+	}
+	return nil
+}
+
+// 订阅价格变化的请求
+type WatchPricesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 只关心这些手机的价格，为空时关心所有手机
+	Ids []string `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"`
+}
+
+func (x *WatchPricesRequest) Reset() {
+	*x = WatchPricesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchPricesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchPricesRequest) ProtoMessage() {}
+
+func (x *WatchPricesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchPricesRequest.ProtoReflect.Descriptor instead.
+func (*WatchPricesRequest) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *WatchPricesRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+// 某台手机有新的订单之后的价格信息
+type PriceUpdate struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// 累计的订单数量
+	Count uint32 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	// 平均价格
+	Avg float64 `protobuf:"fixed64,3,opt,name=avg,proto3" json:"avg,omitempty"`
+	// 最近一次下单的价格
+	LastPrice float64 `protobuf:"fixed64,4,opt,name=last_price,json=lastPrice,proto3" json:"last_price,omitempty"`
+	// 订阅者处理太慢时，同一台手机还没有推送的更新会被合并，这里是被合并掉的更新数量
+	Coalesced uint32 `protobuf:"varint,5,opt,name=coalesced,proto3" json:"coalesced,omitempty"`
+	// 订阅者积压太多时会丢弃更新，这里是本次订阅累计丢弃的更新数量
+	Dropped uint32 `protobuf:"varint,6,opt,name=dropped,proto3" json:"dropped,omitempty"`
+}
+
+func (x *PriceUpdate) Reset() {
+	*x = PriceUpdate{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PriceUpdate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PriceUpdate) ProtoMessage() {}
+
+func (x *PriceUpdate) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PriceUpdate.ProtoReflect.Descriptor instead.
+func (*PriceUpdate) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *PriceUpdate) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *PriceUpdate) GetCount() uint32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *PriceUpdate) GetAvg() float64 {
+	if x != nil {
+		return x.Avg
+	}
+	return 0
+}
+
+func (x *PriceUpdate) GetLastPrice() float64 {
+	if x != nil {
+		return x.LastPrice
+	}
+	return 0
+}
+
+func (x *PriceUpdate) GetCoalesced() uint32 {
+	if x != nil {
+		return x.Coalesced
+	}
+	return 0
+}
+
+func (x *PriceUpdate) GetDropped() uint32 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+// 批量添加手机的选项
+type BatchOptions struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 为true时所有手机都合法才会保存，否则一台都不保存
+	Atomic bool `protobuf:"varint,1,opt,name=atomic,proto3" json:"atomic,omitempty"`
+}
+
+func (x *BatchOptions) Reset() {
+	*x = BatchOptions{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchOptions) ProtoMessage() {}
+
+func (x *BatchOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchOptions.ProtoReflect.Descriptor instead.
+func (*BatchOptions) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *BatchOptions) GetAtomic() bool {
+	if x != nil {
+		return x.Atomic
+	}
+	return false
+}
+
+// 批量添加手机的请求，第一条消息可以是选项，之后每条消息是一台手机
+type BatchCreateCellphonesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Data:
+	//
+	//	*BatchCreateCellphonesRequest_Options
+	//	*BatchCreateCellphonesRequest_Cellphone
+	Data isBatchCreateCellphonesRequest_Data `protobuf_oneof:"data"`
+}
+
+func (x *BatchCreateCellphonesRequest) Reset() {
+	*x = BatchCreateCellphonesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchCreateCellphonesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateCellphonesRequest) ProtoMessage() {}
+
+func (x *BatchCreateCellphonesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateCellphonesRequest.ProtoReflect.Descriptor instead.
+func (*BatchCreateCellphonesRequest) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{22}
+}
+
+func (m *BatchCreateCellphonesRequest) GetData() isBatchCreateCellphonesRequest_Data {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+func (x *BatchCreateCellphonesRequest) GetOptions() *BatchOptions {
+	if x, ok := x.GetData().(*BatchCreateCellphonesRequest_Options); ok {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *BatchCreateCellphonesRequest) GetCellphone() *Cellphone {
+	if x, ok := x.GetData().(*BatchCreateCellphonesRequest_Cellphone); ok {
+		return x.Cellphone
+	}
+	return nil
+}
+
+type isBatchCreateCellphonesRequest_Data interface {
+	isBatchCreateCellphonesRequest_Data()
+}
+
+type BatchCreateCellphonesRequest_Options struct {
+	Options *BatchOptions `protobuf:"bytes,1,opt,name=options,proto3,oneof"`
+}
+
+type BatchCreateCellphonesRequest_Cellphone struct {
+	Cellphone *Cellphone `protobuf:"bytes,2,opt,name=cellphone,proto3,oneof"`
+}
+
+func (*BatchCreateCellphonesRequest_Options) isBatchCreateCellphonesRequest_Data() {}
+
+func (*BatchCreateCellphonesRequest_Cellphone) isBatchCreateCellphonesRequest_Data() {}
+
+// 批量添加时每台手机的处理结果
+type BatchItemResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 这台手机在请求中的序号，从0开始
+	Index uint32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	// 手机的id，请求中为空时为服务端分配的id
+	Id string `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	// grpc状态码，0表示成功
+	// atomic模式下成功只表示校验通过，是否保存以最后的汇总为准
+	Code    int32       `protobuf:"varint,3,opt,name=code,proto3" json:"code,omitempty"`
+	Message string      `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	Reason  ErrorReason `protobuf:"varint,5,opt,name=reason,proto3,enum=pb.ErrorReason" json:"reason,omitempty"`
+}
+
+func (x *BatchItemResult) Reset() {
+	*x = BatchItemResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchItemResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItemResult) ProtoMessage() {}
+
+func (x *BatchItemResult) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItemResult.ProtoReflect.Descriptor instead.
+func (*BatchItemResult) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *BatchItemResult) GetIndex() uint32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BatchItemResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BatchItemResult) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *BatchItemResult) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *BatchItemResult) GetReason() ErrorReason {
+	if x != nil {
+		return x.Reason
+	}
+	return ErrorReason_ERROR_REASON_UNSPECIFIED
+}
+
+// 批量添加的汇总，在所有手机处理完之后发送
+type BatchSummary struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Total uint32 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	// 保存成功的数量
+	Created uint32 `protobuf:"varint,2,opt,name=created,proto3" json:"created,omitempty"`
+	Failed  uint32 `protobuf:"varint,3,opt,name=failed,proto3" json:"failed,omitempty"`
+	// 是否所有手机都保存成功，atomic模式下为false时一台手机都没有保存
+	Committed bool `protobuf:"varint,4,opt,name=committed,proto3" json:"committed,omitempty"`
+}
+
+func (x *BatchSummary) Reset() {
+	*x = BatchSummary{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchSummary) ProtoMessage() {}
+
+func (x *BatchSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchSummary.ProtoReflect.Descriptor instead.
+func (*BatchSummary) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *BatchSummary) GetTotal() uint32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *BatchSummary) GetCreated() uint32 {
+	if x != nil {
+		return x.Created
+	}
+	return 0
+}
+
+func (x *BatchSummary) GetFailed() uint32 {
+	if x != nil {
+		return x.Failed
+	}
+	return 0
+}
+
+func (x *BatchSummary) GetCommitted() bool {
+	if x != nil {
+		return x.Committed
+	}
+	return false
+}
+
+// 批量添加手机的响应，每台手机对应一个结果，最后是汇总
+type BatchCreateCellphonesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Result:
+	//
+	//	*BatchCreateCellphonesResponse_Item
+	//	*BatchCreateCellphonesResponse_Summary
+	Result isBatchCreateCellphonesResponse_Result `protobuf_oneof:"result"`
+}
+
+func (x *BatchCreateCellphonesResponse) Reset() {
+	*x = BatchCreateCellphonesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cellphone_service_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BatchCreateCellphonesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateCellphonesResponse) ProtoMessage() {}
+
+func (x *BatchCreateCellphonesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_cellphone_service_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateCellphonesResponse.ProtoReflect.Descriptor instead.
+func (*BatchCreateCellphonesResponse) Descriptor() ([]byte, []int) {
+	return file_cellphone_service_proto_rawDescGZIP(), []int{25}
+}
+
+func (m *BatchCreateCellphonesResponse) GetResult() isBatchCreateCellphonesResponse_Result {
+	if m != nil {
+		return m.Result
+	}
+	return nil
+}
+
+func (x *BatchCreateCellphonesResponse) GetItem() *BatchItemResult {
+	if x, ok := x.GetResult().(*BatchCreateCellphonesResponse_Item); ok {
+		return x.Item
+	}
+	return nil
+}
+
+func (x *BatchCreateCellphonesResponse) GetSummary() *BatchSummary {
+	if x, ok := x.GetResult().(*BatchCreateCellphonesResponse_Summary); ok {
+		return x.Summary
+	}
+	return nil
+}
+
+type isBatchCreateCellphonesResponse_Result interface {
+	isBatchCreateCellphonesResponse_Result()
+}
+
+type BatchCreateCellphonesResponse_Item struct {
+	Item *BatchItemResult `protobuf:"bytes,1,opt,name=item,proto3,oneof"`
+}
+
+type BatchCreateCellphonesResponse_Summary struct {
+	Summary *BatchSummary `protobuf:"bytes,2,opt,name=summary,proto3,oneof"`
+}
+
+func (*BatchCreateCellphonesResponse_Item) isBatchCreateCellphonesResponse_Result() {}
+
+func (*BatchCreateCellphonesResponse_Summary) isBatchCreateCellphonesResponse_Result() {}
+
+var File_cellphone_service_proto protoreflect.FileDescriptor
+
+var file_cellphone_service_proto_rawDesc = []byte{
+	0x0a, 0x17, 0x63, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x5f, 0x73, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x1a, 0x0f, 0x63,
+	0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x12,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x22, 0x45, 0x0a, 0x16, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c,
+	0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2b, 0x0a, 0x09,
+	0x63, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0d, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x09,
+	0x63, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x22, 0x29, 0x0a, 0x17, 0x43, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x02, 0x69, 0x64, 0x22, 0xc9, 0x01, 0x0a, 0x0f, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x43,
+	0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x0c, 0x6d, 0x69, 0x6e, 0x5f,
+	0x63, 0x70, 0x75, 0x5f, 0x63, 0x6f, 0x72, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a,
+	0x6d, 0x69, 0x6e, 0x43, 0x70, 0x75, 0x43, 0x6f, 0x72, 0x65, 0x12, 0x30, 0x0a, 0x14, 0x6d, 0x69,
+	0x6e, 0x5f, 0x62, 0x61, 0x74, 0x74, 0x65, 0x72, 0x79, 0x5f, 0x63, 0x61, 0x70, 0x61, 0x63, 0x69,
+	0x74, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x12, 0x6d, 0x69, 0x6e, 0x42, 0x61, 0x74,
+	0x74, 0x65, 0x72, 0x79, 0x43, 0x61, 0x70, 0x61, 0x63, 0x69, 0x74, 0x79, 0x12, 0x20, 0x0a, 0x0c,
+	0x6d, 0x69, 0x6e, 0x5f, 0x72, 0x61, 0x6d, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x0a, 0x6d, 0x69, 0x6e, 0x52, 0x61, 0x6d, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x28,
+	0x0a, 0x10, 0x6d, 0x69, 0x6e, 0x5f, 0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x5f, 0x73, 0x69,
+	0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0e, 0x6d, 0x69, 0x6e, 0x53, 0x74, 0x6f,
+	0x72, 0x61, 0x67, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x72, 0x61, 0x6e,
+	0x64, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x62, 0x72, 0x61, 0x6e, 0x64, 0x73,
+	0x22, 0x66, 0x0a, 0x1b, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68,
+	0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x27, 0x0a, 0x04, 0x6d, 0x65, 0x74, 0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e,
+	0x70, 0x62, 0x2e, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x4d, 0x65, 0x74, 0x61, 0x49, 0x6e, 0x66, 0x6f,
+	0x48, 0x00, 0x52, 0x04, 0x6d, 0x65, 0x74, 0x61, 0x12, 0x16, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63,
+	0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
+	0x42, 0x06, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x52, 0x0a, 0x0d, 0x43, 0x6f, 0x76, 0x65,
+	0x72, 0x4d, 0x65, 0x74, 0x61, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x1d, 0x0a,
+	0x0a, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x09, 0x69, 0x6d, 0x61, 0x67, 0x65, 0x54, 0x79, 0x70, 0x65, 0x22, 0x42, 0x0a, 0x1c,
+	0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x43,
+	0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04,
+	0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65,
+	0x22, 0x25, 0x0a, 0x13, 0x47, 0x65, 0x74, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x45, 0x0a, 0x16, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x2b, 0x0a, 0x09, 0x63, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68,
+	0x6f, 0x6e, 0x65, 0x52, 0x09, 0x63, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x22, 0x28,
+	0x0a, 0x16, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x19, 0x0a, 0x17, 0x44, 0x65, 0x6c, 0x65,
+	0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x2f, 0x0a, 0x1d, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x43,
+	0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x02, 0x69, 0x64, 0x22, 0x69, 0x0a, 0x1e, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64,
+	0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x27, 0x0a, 0x04, 0x6d, 0x65, 0x74, 0x61, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x4d,
+	0x65, 0x74, 0x61, 0x49, 0x6e, 0x66, 0x6f, 0x48, 0x00, 0x52, 0x04, 0x6d, 0x65, 0x74, 0x61, 0x12,
+	0x16, 0x0a, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00,
+	0x52, 0x05, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x42, 0x06, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22,
+	0x64, 0x0a, 0x13, 0x42, 0x75, 0x79, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x70, 0x72, 0x69, 0x63, 0x65, 0x12, 0x27, 0x0a, 0x0f,
+	0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x5f, 0x6b, 0x65, 0x79, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e,
+	0x63, 0x79, 0x4b, 0x65, 0x79, 0x22, 0x38, 0x0a, 0x14, 0x42, 0x75, 0x79, 0x43, 0x65, 0x6c, 0x6c,
+	0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a,
+	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x10, 0x0a,
+	0x03, 0x61, 0x76, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x61, 0x76, 0x67, 0x22,
+	0x25, 0x0a, 0x11, 0x4c, 0x69, 0x73, 0x74, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x09, 0x52, 0x03, 0x69, 0x64, 0x73, 0x22, 0x55, 0x0a, 0x05, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x12,
+	0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12,
+	0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05,
+	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x10, 0x0a, 0x03, 0x61,
+	0x76, 0x67, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x61, 0x76, 0x67, 0x22, 0x37, 0x0a,
+	0x12, 0x4c, 0x69, 0x73, 0x74, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x21, 0x0a, 0x06, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x09, 0x2e, 0x70, 0x62, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x52, 0x06,
+	0x6f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x22, 0x62, 0x0a, 0x0c, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2b, 0x0a, 0x06, 0x66, 0x69, 0x6c, 0x74, 0x65, 0x72,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x74,
+	0x65, 0x72, 0x43, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x66, 0x69, 0x6c,
+	0x74, 0x65, 0x72, 0x12, 0x25, 0x0a, 0x0e, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x5f, 0x72, 0x65, 0x76,
+	0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0d, 0x73, 0x69, 0x6e,
+	0x63, 0x65, 0x52, 0x65, 0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0xcb, 0x01, 0x0a, 0x0e, 0x43,
+	0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x2b, 0x0a,
+	0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x17, 0x2e, 0x70, 0x62,
+	0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x2e,
+	0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65,
+	0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x72, 0x65,
+	0x76, 0x69, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x2b, 0x0a, 0x09, 0x63, 0x65, 0x6c, 0x6c, 0x70, 0x68,
+	0x6f, 0x6e, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x62, 0x2e, 0x43,
+	0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x09, 0x63, 0x65, 0x6c, 0x6c, 0x70, 0x68,
+	0x6f, 0x6e, 0x65, 0x22, 0x43, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x10, 0x54,
+	0x59, 0x50, 0x45, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10,
+	0x00, 0x12, 0x0b, 0x0a, 0x07, 0x43, 0x52, 0x45, 0x41, 0x54, 0x45, 0x44, 0x10, 0x01, 0x12, 0x0b,
+	0x0a, 0x07, 0x55, 0x50, 0x44, 0x41, 0x54, 0x45, 0x44, 0x10, 0x02, 0x12, 0x0b, 0x0a, 0x07, 0x44,
+	0x45, 0x4c, 0x45, 0x54, 0x45, 0x44, 0x10, 0x03, 0x22, 0x26, 0x0a, 0x12, 0x57, 0x61, 0x74, 0x63,
+	0x68, 0x50, 0x72, 0x69, 0x63, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10,
+	0x0a, 0x03, 0x69, 0x64, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x69, 0x64, 0x73,
+	0x22, 0x9c, 0x01, 0x0a, 0x0b, 0x50, 0x72, 0x69, 0x63, 0x65, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64,
+	0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x61, 0x76, 0x67, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x03, 0x61, 0x76, 0x67, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x61, 0x73, 0x74,
+	0x5f, 0x70, 0x72, 0x69, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x6c, 0x61,
+	0x73, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x61, 0x6c, 0x65,
+	0x73, 0x63, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x63, 0x6f, 0x61, 0x6c,
+	0x65, 0x73, 0x63, 0x65, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x64, 0x72, 0x6f, 0x70, 0x70, 0x65, 0x64, 0x22,
+	0x26, 0x0a, 0x0c, 0x42, 0x61, 0x74, 0x63, 0x68, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12,
+	0x16, 0x0a, 0x06, 0x61, 0x74, 0x6f, 0x6d, 0x69, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x06, 0x61, 0x74, 0x6f, 0x6d, 0x69, 0x63, 0x22, 0x83, 0x01, 0x0a, 0x1c, 0x42, 0x61, 0x74, 0x63,
+	0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2c, 0x0a, 0x07, 0x6f, 0x70, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70, 0x62, 0x2e, 0x42,
+	0x61, 0x74, 0x63, 0x68, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x48, 0x00, 0x52, 0x07, 0x6f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x2d, 0x0a, 0x09, 0x63, 0x65, 0x6c, 0x6c, 0x70, 0x68,
+	0x6f, 0x6e, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x62, 0x2e, 0x43,
+	0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x48, 0x00, 0x52, 0x09, 0x63, 0x65, 0x6c, 0x6c,
+	0x70, 0x68, 0x6f, 0x6e, 0x65, 0x42, 0x06, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x8e, 0x01,
+	0x0a, 0x0f, 0x42, 0x61, 0x74, 0x63, 0x68, 0x49, 0x74, 0x65, 0x6d, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d,
+	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65,
+	0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x27, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0f, 0x2e, 0x70, 0x62, 0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72,
+	0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x74,
+	0x0a, 0x0c, 0x42, 0x61, 0x74, 0x63, 0x68, 0x53, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x12, 0x14,
+	0x0a, 0x05, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x74,
+	0x6f, 0x74, 0x61, 0x6c, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x12, 0x16,
+	0x0a, 0x06, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06,
+	0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74,
+	0x74, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x63, 0x6f, 0x6d, 0x6d, 0x69,
+	0x74, 0x74, 0x65, 0x64, 0x22, 0x82, 0x01, 0x0a, 0x1d, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72,
+	0x65, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x73, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x29, 0x0a, 0x04, 0x69, 0x74, 0x65, 0x6d, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x49,
+	0x74, 0x65, 0x6d, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x48, 0x00, 0x52, 0x04, 0x69, 0x74, 0x65,
+	0x6d, 0x12, 0x2c, 0x0a, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70, 0x62, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x53, 0x75, 0x6d,
+	0x6d, 0x61, 0x72, 0x79, 0x48, 0x00, 0x52, 0x07, 0x73, 0x75, 0x6d, 0x6d, 0x61, 0x72, 0x79, 0x42,
+	0x08, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x32, 0xf4, 0x06, 0x0a, 0x10, 0x43, 0x65,
+	0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x4a,
+	0x0a, 0x0f, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e,
+	0x65, 0x12, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c,
+	0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1b, 0x2e,
+	0x70, 0x62, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f,
+	0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x36, 0x0a, 0x0c, 0x47, 0x65,
+	0x74, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x12, 0x17, 0x2e, 0x70, 0x62, 0x2e,
+	0x47, 0x65, 0x74, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x1a, 0x0d, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f,
+	0x6e, 0x65, 0x12, 0x3c, 0x0a, 0x0f, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c,
+	0x70, 0x68, 0x6f, 0x6e, 0x65, 0x12, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x55, 0x70, 0x64, 0x61, 0x74,
+	0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x0d, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65,
+	0x12, 0x4a, 0x0a, 0x0f, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68,
+	0x6f, 0x6e, 0x65, 0x12, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x43,
+	0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1b, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70,
+	0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x37, 0x0a, 0x0f,
+	0x53, 0x65, 0x61, 0x72, 0x63, 0x68, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x12,
+	0x13, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x74, 0x65, 0x72, 0x43, 0x6f, 0x6e, 0x64, 0x69,
+	0x74, 0x69, 0x6f, 0x6e, 0x1a, 0x0d, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68,
+	0x6f, 0x6e, 0x65, 0x30, 0x01, 0x12, 0x5b, 0x0a, 0x14, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43,
+	0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x12, 0x1f, 0x2e,
+	0x70, 0x62, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f,
+	0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20,
+	0x2e, 0x70, 0x62, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68,
+	0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x28, 0x01, 0x12, 0x61, 0x0a, 0x16, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x65,
+	0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x12, 0x21, 0x2e, 0x70,
+	0x62, 0x2e, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68,
+	0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x22, 0x2e, 0x70, 0x62, 0x2e, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x43, 0x65, 0x6c,
+	0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x30, 0x01, 0x12, 0x45, 0x0a, 0x0c, 0x42, 0x75, 0x79, 0x43, 0x65, 0x6c, 0x6c,
+	0x70, 0x68, 0x6f, 0x6e, 0x65, 0x12, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x42, 0x75, 0x79, 0x43, 0x65,
+	0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18,
+	0x2e, 0x70, 0x62, 0x2e, 0x42, 0x75, 0x79, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x30, 0x01, 0x12, 0x39, 0x0a, 0x0f,
+	0x57, 0x61, 0x74, 0x63, 0x68, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x73, 0x12,
+	0x10, 0x2e, 0x70, 0x62, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x12, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x12, 0x38, 0x0a, 0x0b, 0x57, 0x61, 0x74, 0x63, 0x68,
+	0x50, 0x72, 0x69, 0x63, 0x65, 0x73, 0x12, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x57, 0x61, 0x74, 0x63,
+	0x68, 0x50, 0x72, 0x69, 0x63, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f,
+	0x2e, 0x70, 0x62, 0x2e, 0x50, 0x72, 0x69, 0x63, 0x65, 0x55, 0x70, 0x64, 0x61, 0x74, 0x65, 0x30,
+	0x01, 0x12, 0x60, 0x0a, 0x15, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65,
+	0x43, 0x65, 0x6c, 0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x73, 0x12, 0x20, 0x2e, 0x70, 0x62, 0x2e,
+	0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c, 0x6c, 0x70,
+	0x68, 0x6f, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x21, 0x2e, 0x70,
+	0x62, 0x2e, 0x42, 0x61, 0x74, 0x63, 0x68, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x43, 0x65, 0x6c,
+	0x6c, 0x70, 0x68, 0x6f, 0x6e, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28,
+	0x01, 0x30, 0x01, 0x12, 0x3b, 0x0a, 0x0a, 0x4c, 0x69, 0x73, 0x74, 0x4f, 0x72, 0x64, 0x65, 0x72,
+	0x73, 0x12, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x4f, 0x72, 0x64, 0x65, 0x72,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x4c, 0x69,
+	0x73, 0x74, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_cellphone_service_proto_rawDescOnce sync.Once
+	file_cellphone_service_proto_rawDescData = file_cellphone_service_proto_rawDesc
+)
+
+func file_cellphone_service_proto_rawDescGZIP() []byte {
+	file_cellphone_service_proto_rawDescOnce.Do(func() {
+		file_cellphone_service_proto_rawDescData = protoimpl.X.CompressGZIP(file_cellphone_service_proto_rawDescData)
+	})
+	return file_cellphone_service_proto_rawDescData
+}
+
+var file_cellphone_service_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_cellphone_service_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_cellphone_service_proto_goTypes = []interface{}{
+	(CellphoneEvent_Type)(0),               // 0: pb.CellphoneEvent.Type
+	(*CreateCellphoneRequest)(nil),         // 1: pb.CreateCellphoneRequest
+	(*CreateCellphoneResponse)(nil),        // 2: pb.CreateCellphoneResponse
+	(*FilterCondition)(nil),                // 3: pb.FilterCondition
+	(*UploadCellphoneCoverRequest)(nil),    // 4: pb.UploadCellphoneCoverRequest
+	(*CoverMetaInfo)(nil),                  // 5: pb.CoverMetaInfo
+	(*UploadCellphoneCoverResponse)(nil),   // 6: pb.UploadCellphoneCoverResponse
+	(*GetCellphoneRequest)(nil),            // 7: pb.GetCellphoneRequest
+	(*UpdateCellphoneRequest)(nil),         // 8: pb.UpdateCellphoneRequest
+	(*DeleteCellphoneRequest)(nil),         // 9: pb.DeleteCellphoneRequest
+	(*DeleteCellphoneResponse)(nil),        // 10: pb.DeleteCellphoneResponse
+	(*DownloadCellphoneCoverRequest)(nil),  // 11: pb.DownloadCellphoneCoverRequest
+	(*DownloadCellphoneCoverResponse)(nil), // 12: pb.DownloadCellphoneCoverResponse
+	(*BuyCellphoneRequest)(nil),            // 13: pb.BuyCellphoneRequest
+	(*BuyCellphoneResponse)(nil),           // 14: pb.BuyCellphoneResponse
+	(*ListOrdersRequest)(nil),              // 15: pb.ListOrdersRequest
+	(*Order)(nil),                          // 16: pb.Order
+	(*ListOrdersResponse)(nil),             // 17: pb.ListOrdersResponse
+	(*WatchRequest)(nil),                   // 18: pb.WatchRequest
+	(*CellphoneEvent)(nil),                 // 19: pb.CellphoneEvent
+	(*WatchPricesRequest)(nil),             // 20: pb.WatchPricesRequest
+	(*PriceUpdate)(nil),                    // 21: pb.PriceUpdate
+	(*BatchOptions)(nil),                   // 22: pb.BatchOptions
+	(*BatchCreateCellphonesRequest)(nil),   // 23: pb.BatchCreateCellphonesRequest
+	(*BatchItemResult)(nil),                // 24: pb.BatchItemResult
+	(*BatchSummary)(nil),                   // 25: pb.BatchSummary
+	(*BatchCreateCellphonesResponse)(nil),  // 26: pb.BatchCreateCellphonesResponse
+	(*Cellphone)(nil),                      // 27: pb.Cellphone
+	(ErrorReason)(0),                       // 28: pb.ErrorReason
+}
+var file_cellphone_service_proto_depIdxs = []int32{
+	27, // 0: pb.CreateCellphoneRequest.cellphone:type_name -> pb.Cellphone
+	5,  // 1: pb.UploadCellphoneCoverRequest.meta:type_name -> pb.CoverMetaInfo
+	27, // 2: pb.UpdateCellphoneRequest.cellphone:type_name -> pb.Cellphone
+	5,  // 3: pb.DownloadCellphoneCoverResponse.meta:type_name -> pb.CoverMetaInfo
+	16, // 4: pb.ListOrdersResponse.orders:type_name -> pb.Order
+	3,  // 5: pb.WatchRequest.filter:type_name -> pb.FilterCondition
+	0,  // 6: pb.CellphoneEvent.type:type_name -> pb.CellphoneEvent.Type
+	27, // 7: pb.CellphoneEvent.cellphone:type_name -> pb.Cellphone
+	22, // 8: pb.BatchCreateCellphonesRequest.options:type_name -> pb.BatchOptions
+	27, // 9: pb.BatchCreateCellphonesRequest.cellphone:type_name -> pb.Cellphone
+	28, // 10: pb.BatchItemResult.reason:type_name -> pb.ErrorReason
+	24, // 11: pb.BatchCreateCellphonesResponse.item:type_name -> pb.BatchItemResult
+	25, // 12: pb.BatchCreateCellphonesResponse.summary:type_name -> pb.BatchSummary
+	1,  // 13: pb.CellphoneService.CreateCellphone:input_type -> pb.CreateCellphoneRequest
+	7,  // 14: pb.CellphoneService.GetCellphone:input_type -> pb.GetCellphoneRequest
+	8,  // 15: pb.CellphoneService.UpdateCellphone:input_type -> pb.UpdateCellphoneRequest
+	9,  // 16: pb.CellphoneService.DeleteCellphone:input_type -> pb.DeleteCellphoneRequest
+	3,  // 17: pb.CellphoneService.SearchCellphone:input_type -> pb.FilterCondition
+	4,  // 18: pb.CellphoneService.UploadCellphoneCover:input_type -> pb.UploadCellphoneCoverRequest
+	11, // 19: pb.CellphoneService.DownloadCellphoneCover:input_type -> pb.DownloadCellphoneCoverRequest
+	13, // 20: pb.CellphoneService.BuyCellphone:input_type -> pb.BuyCellphoneRequest
+	18, // 21: pb.CellphoneService.WatchCellphones:input_type -> pb.WatchRequest
+	20, // 22: pb.CellphoneService.WatchPrices:input_type -> pb.WatchPricesRequest
+	23, // 23: pb.CellphoneService.BatchCreateCellphones:input_type -> pb.BatchCreateCellphonesRequest
+	15, // 24: pb.CellphoneService.ListOrders:input_type -> pb.ListOrdersRequest
+	2,  // 25: pb.CellphoneService.CreateCellphone:output_type -> pb.CreateCellphoneResponse
+	27, // 26: pb.CellphoneService.GetCellphone:output_type -> pb.Cellphone
+	27, // 27: pb.CellphoneService.UpdateCellphone:output_type -> pb.Cellphone
+	10, // 28: pb.CellphoneService.DeleteCellphone:output_type -> pb.DeleteCellphoneResponse
+	27, // 29: pb.CellphoneService.SearchCellphone:output_type -> pb.Cellphone
+	6,  // 30: pb.CellphoneService.UploadCellphoneCover:output_type -> pb.UploadCellphoneCoverResponse
+	12, // 31: pb.CellphoneService.DownloadCellphoneCover:output_type -> pb.DownloadCellphoneCoverResponse
+	14, // 32: pb.CellphoneService.BuyCellphone:output_type -> pb.BuyCellphoneResponse
+	19, // 33: pb.CellphoneService.WatchCellphones:output_type -> pb.CellphoneEvent
+	21, // 34: pb.CellphoneService.WatchPrices:output_type -> pb.PriceUpdate
+	26, // 35: pb.CellphoneService.BatchCreateCellphones:output_type -> pb.BatchCreateCellphonesResponse
+	17, // 36: pb.CellphoneService.ListOrders:output_type -> pb.ListOrdersResponse
+	25, // [25:37] is the sub-list for method output_type
+	13, // [13:25] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_cellphone_service_proto_init() }
+func file_cellphone_service_proto_init() {
+	if File_cellphone_service_proto != nil {
+		return
+	}
+	file_cellphone_proto_init()
+	file_error_reason_proto_init()
+	if !protoimpl.UnsafeEnabled {
+		file_cellphone_service_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateCellphoneRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*CreateCellphoneResponse); i {
 			case 0:
 				return &v.state
@@ -703,7 +2073,7 @@ func file_cellphone_service_proto_init() {
 			}
 		}
 		file_cellphone_service_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*BuyCellphoneRequest); i {
+			switch v := v.(*GetCellphoneRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -715,6 +2085,78 @@ func file_cellphone_service_proto_init() {
 			}
 		}
 		file_cellphone_service_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*UpdateCellphoneRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteCellphoneRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DeleteCellphoneResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DownloadCellphoneCoverRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*DownloadCellphoneCoverResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BuyCellphoneRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*BuyCellphoneResponse); i {
 			case 0:
 				return &v.state
@@ -726,23 +2168,180 @@ func file_cellphone_service_proto_init() {
 				return nil
 			}
 		}
+		file_cellphone_service_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListOrdersRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Order); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListOrdersResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CellphoneEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchPricesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PriceUpdate); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchOptions); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchCreateCellphonesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchItemResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchSummary); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_cellphone_service_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BatchCreateCellphonesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	file_cellphone_service_proto_msgTypes[3].OneofWrappers = []interface{}{
 		(*UploadCellphoneCoverRequest_Meta)(nil),
 		(*UploadCellphoneCoverRequest_Block)(nil),
 	}
+	file_cellphone_service_proto_msgTypes[11].OneofWrappers = []interface{}{
+		(*DownloadCellphoneCoverResponse_Meta)(nil),
+		(*DownloadCellphoneCoverResponse_Block)(nil),
+	}
+	file_cellphone_service_proto_msgTypes[22].OneofWrappers = []interface{}{
+		(*BatchCreateCellphonesRequest_Options)(nil),
+		(*BatchCreateCellphonesRequest_Cellphone)(nil),
+	}
+	file_cellphone_service_proto_msgTypes[25].OneofWrappers = []interface{}{
+		(*BatchCreateCellphonesResponse_Item)(nil),
+		(*BatchCreateCellphonesResponse_Summary)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_cellphone_service_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   8,
+			NumEnums:      1,
+			NumMessages:   26,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_cellphone_service_proto_goTypes,
 		DependencyIndexes: file_cellphone_service_proto_depIdxs,
+		EnumInfos:         file_cellphone_service_proto_enumTypes,
 		MessageInfos:      file_cellphone_service_proto_msgTypes,
 	}.Build()
 	File_cellphone_service_proto = out.File
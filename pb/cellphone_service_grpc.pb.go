@@ -25,15 +25,39 @@ type CellphoneServiceClient interface {
 	// Unary RPC
 	// 添加一条手机信息
 	CreateCellphone(ctx context.Context, in *CreateCellphoneRequest, opts ...grpc.CallOption) (*CreateCellphoneResponse, error)
+	// Unary RPC
+	// 获取一台手机信息
+	GetCellphone(ctx context.Context, in *GetCellphoneRequest, opts ...grpc.CallOption) (*Cellphone, error)
+	// Unary RPC
+	// 修改一台手机信息，返回修改之后的手机信息
+	UpdateCellphone(ctx context.Context, in *UpdateCellphoneRequest, opts ...grpc.CallOption) (*Cellphone, error)
+	// Unary RPC
+	// 删除一台手机信息，封面图片由AdminService.PurgeOrphanCovers清理
+	DeleteCellphone(ctx context.Context, in *DeleteCellphoneRequest, opts ...grpc.CallOption) (*DeleteCellphoneResponse, error)
 	// Server streaming RPC
 	// 查找符合条件的手机
 	SearchCellphone(ctx context.Context, in *FilterCondition, opts ...grpc.CallOption) (CellphoneService_SearchCellphoneClient, error)
 	// Client streaming RPC
 	// 客户端上传字节流数据（上传手机封面图片）
 	UploadCellphoneCover(ctx context.Context, opts ...grpc.CallOption) (CellphoneService_UploadCellphoneCoverClient, error)
+	// Server streaming RPC
+	// 下载手机封面图片
+	DownloadCellphoneCover(ctx context.Context, in *DownloadCellphoneCoverRequest, opts ...grpc.CallOption) (CellphoneService_DownloadCellphoneCoverClient, error)
 	// Bidirectional stream RPC
 	// 客户端购买手机，服务端返回购买手机的平均价格
 	BuyCellphone(ctx context.Context, opts ...grpc.CallOption) (CellphoneService_BuyCellphoneClient, error)
+	// Server streaming RPC
+	// 订阅手机信息的变化，断开后可以从最后收到的revision继续订阅
+	WatchCellphones(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CellphoneService_WatchCellphonesClient, error)
+	// Server streaming RPC
+	// 订阅手机的价格变化，任何客户端购买手机之后都会推送
+	WatchPrices(ctx context.Context, in *WatchPricesRequest, opts ...grpc.CallOption) (CellphoneService_WatchPricesClient, error)
+	// Bidirectional stream RPC
+	// 批量添加手机，每处理完一台手机就返回它的结果
+	BatchCreateCellphones(ctx context.Context, opts ...grpc.CallOption) (CellphoneService_BatchCreateCellphonesClient, error)
+	// Unary RPC
+	// 查询手机的订单统计
+	ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error)
 }
 
 type cellphoneServiceClient struct {
@@ -53,6 +77,33 @@ func (c *cellphoneServiceClient) CreateCellphone(ctx context.Context, in *Create
 	return out, nil
 }
 
+func (c *cellphoneServiceClient) GetCellphone(ctx context.Context, in *GetCellphoneRequest, opts ...grpc.CallOption) (*Cellphone, error) {
+	out := new(Cellphone)
+	err := c.cc.Invoke(ctx, "/pb.CellphoneService/GetCellphone", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cellphoneServiceClient) UpdateCellphone(ctx context.Context, in *UpdateCellphoneRequest, opts ...grpc.CallOption) (*Cellphone, error) {
+	out := new(Cellphone)
+	err := c.cc.Invoke(ctx, "/pb.CellphoneService/UpdateCellphone", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cellphoneServiceClient) DeleteCellphone(ctx context.Context, in *DeleteCellphoneRequest, opts ...grpc.CallOption) (*DeleteCellphoneResponse, error) {
+	out := new(DeleteCellphoneResponse)
+	err := c.cc.Invoke(ctx, "/pb.CellphoneService/DeleteCellphone", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *cellphoneServiceClient) SearchCellphone(ctx context.Context, in *FilterCondition, opts ...grpc.CallOption) (CellphoneService_SearchCellphoneClient, error) {
 	stream, err := c.cc.NewStream(ctx, &CellphoneService_ServiceDesc.Streams[0], "/pb.CellphoneService/SearchCellphone", opts...)
 	if err != nil {
@@ -119,8 +170,40 @@ func (x *cellphoneServiceUploadCellphoneCoverClient) CloseAndRecv() (*UploadCell
 	return m, nil
 }
 
+func (c *cellphoneServiceClient) DownloadCellphoneCover(ctx context.Context, in *DownloadCellphoneCoverRequest, opts ...grpc.CallOption) (CellphoneService_DownloadCellphoneCoverClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CellphoneService_ServiceDesc.Streams[2], "/pb.CellphoneService/DownloadCellphoneCover", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cellphoneServiceDownloadCellphoneCoverClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CellphoneService_DownloadCellphoneCoverClient interface {
+	Recv() (*DownloadCellphoneCoverResponse, error)
+	grpc.ClientStream
+}
+
+type cellphoneServiceDownloadCellphoneCoverClient struct {
+	grpc.ClientStream
+}
+
+func (x *cellphoneServiceDownloadCellphoneCoverClient) Recv() (*DownloadCellphoneCoverResponse, error) {
+	m := new(DownloadCellphoneCoverResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *cellphoneServiceClient) BuyCellphone(ctx context.Context, opts ...grpc.CallOption) (CellphoneService_BuyCellphoneClient, error) {
-	stream, err := c.cc.NewStream(ctx, &CellphoneService_ServiceDesc.Streams[2], "/pb.CellphoneService/BuyCellphone", opts...)
+	stream, err := c.cc.NewStream(ctx, &CellphoneService_ServiceDesc.Streams[3], "/pb.CellphoneService/BuyCellphone", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -150,6 +233,110 @@ func (x *cellphoneServiceBuyCellphoneClient) Recv() (*BuyCellphoneResponse, erro
 	return m, nil
 }
 
+func (c *cellphoneServiceClient) WatchCellphones(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (CellphoneService_WatchCellphonesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CellphoneService_ServiceDesc.Streams[4], "/pb.CellphoneService/WatchCellphones", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cellphoneServiceWatchCellphonesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CellphoneService_WatchCellphonesClient interface {
+	Recv() (*CellphoneEvent, error)
+	grpc.ClientStream
+}
+
+type cellphoneServiceWatchCellphonesClient struct {
+	grpc.ClientStream
+}
+
+func (x *cellphoneServiceWatchCellphonesClient) Recv() (*CellphoneEvent, error) {
+	m := new(CellphoneEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cellphoneServiceClient) WatchPrices(ctx context.Context, in *WatchPricesRequest, opts ...grpc.CallOption) (CellphoneService_WatchPricesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CellphoneService_ServiceDesc.Streams[5], "/pb.CellphoneService/WatchPrices", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cellphoneServiceWatchPricesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type CellphoneService_WatchPricesClient interface {
+	Recv() (*PriceUpdate, error)
+	grpc.ClientStream
+}
+
+type cellphoneServiceWatchPricesClient struct {
+	grpc.ClientStream
+}
+
+func (x *cellphoneServiceWatchPricesClient) Recv() (*PriceUpdate, error) {
+	m := new(PriceUpdate)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cellphoneServiceClient) BatchCreateCellphones(ctx context.Context, opts ...grpc.CallOption) (CellphoneService_BatchCreateCellphonesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &CellphoneService_ServiceDesc.Streams[6], "/pb.CellphoneService/BatchCreateCellphones", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &cellphoneServiceBatchCreateCellphonesClient{stream}
+	return x, nil
+}
+
+type CellphoneService_BatchCreateCellphonesClient interface {
+	Send(*BatchCreateCellphonesRequest) error
+	Recv() (*BatchCreateCellphonesResponse, error)
+	grpc.ClientStream
+}
+
+type cellphoneServiceBatchCreateCellphonesClient struct {
+	grpc.ClientStream
+}
+
+func (x *cellphoneServiceBatchCreateCellphonesClient) Send(m *BatchCreateCellphonesRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *cellphoneServiceBatchCreateCellphonesClient) Recv() (*BatchCreateCellphonesResponse, error) {
+	m := new(BatchCreateCellphonesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *cellphoneServiceClient) ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error) {
+	out := new(ListOrdersResponse)
+	err := c.cc.Invoke(ctx, "/pb.CellphoneService/ListOrders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CellphoneServiceServer is the server API for CellphoneService service.
 // All implementations must embed UnimplementedCellphoneServiceServer
 // for forward compatibility
@@ -157,15 +344,39 @@ type CellphoneServiceServer interface {
 	// Unary RPC
 	// 添加一条手机信息
 	CreateCellphone(context.Context, *CreateCellphoneRequest) (*CreateCellphoneResponse, error)
+	// Unary RPC
+	// 获取一台手机信息
+	GetCellphone(context.Context, *GetCellphoneRequest) (*Cellphone, error)
+	// Unary RPC
+	// 修改一台手机信息，返回修改之后的手机信息
+	UpdateCellphone(context.Context, *UpdateCellphoneRequest) (*Cellphone, error)
+	// Unary RPC
+	// 删除一台手机信息，封面图片由AdminService.PurgeOrphanCovers清理
+	DeleteCellphone(context.Context, *DeleteCellphoneRequest) (*DeleteCellphoneResponse, error)
 	// Server streaming RPC
 	// 查找符合条件的手机
 	SearchCellphone(*FilterCondition, CellphoneService_SearchCellphoneServer) error
 	// Client streaming RPC
 	// 客户端上传字节流数据（上传手机封面图片）
 	UploadCellphoneCover(CellphoneService_UploadCellphoneCoverServer) error
+	// Server streaming RPC
+	// 下载手机封面图片
+	DownloadCellphoneCover(*DownloadCellphoneCoverRequest, CellphoneService_DownloadCellphoneCoverServer) error
 	// Bidirectional stream RPC
 	// 客户端购买手机，服务端返回购买手机的平均价格
 	BuyCellphone(CellphoneService_BuyCellphoneServer) error
+	// Server streaming RPC
+	// 订阅手机信息的变化，断开后可以从最后收到的revision继续订阅
+	WatchCellphones(*WatchRequest, CellphoneService_WatchCellphonesServer) error
+	// Server streaming RPC
+	// 订阅手机的价格变化，任何客户端购买手机之后都会推送
+	WatchPrices(*WatchPricesRequest, CellphoneService_WatchPricesServer) error
+	// Bidirectional stream RPC
+	// 批量添加手机，每处理完一台手机就返回它的结果
+	BatchCreateCellphones(CellphoneService_BatchCreateCellphonesServer) error
+	// Unary RPC
+	// 查询手机的订单统计
+	ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error)
 	mustEmbedUnimplementedCellphoneServiceServer()
 }
 
@@ -176,15 +387,39 @@ type UnimplementedCellphoneServiceServer struct {
 func (UnimplementedCellphoneServiceServer) CreateCellphone(context.Context, *CreateCellphoneRequest) (*CreateCellphoneResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateCellphone not implemented")
 }
+func (UnimplementedCellphoneServiceServer) GetCellphone(context.Context, *GetCellphoneRequest) (*Cellphone, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCellphone not implemented")
+}
+func (UnimplementedCellphoneServiceServer) UpdateCellphone(context.Context, *UpdateCellphoneRequest) (*Cellphone, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCellphone not implemented")
+}
+func (UnimplementedCellphoneServiceServer) DeleteCellphone(context.Context, *DeleteCellphoneRequest) (*DeleteCellphoneResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCellphone not implemented")
+}
 func (UnimplementedCellphoneServiceServer) SearchCellphone(*FilterCondition, CellphoneService_SearchCellphoneServer) error {
 	return status.Errorf(codes.Unimplemented, "method SearchCellphone not implemented")
 }
 func (UnimplementedCellphoneServiceServer) UploadCellphoneCover(CellphoneService_UploadCellphoneCoverServer) error {
 	return status.Errorf(codes.Unimplemented, "method UploadCellphoneCover not implemented")
 }
+func (UnimplementedCellphoneServiceServer) DownloadCellphoneCover(*DownloadCellphoneCoverRequest, CellphoneService_DownloadCellphoneCoverServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadCellphoneCover not implemented")
+}
 func (UnimplementedCellphoneServiceServer) BuyCellphone(CellphoneService_BuyCellphoneServer) error {
 	return status.Errorf(codes.Unimplemented, "method BuyCellphone not implemented")
 }
+func (UnimplementedCellphoneServiceServer) WatchCellphones(*WatchRequest, CellphoneService_WatchCellphonesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchCellphones not implemented")
+}
+func (UnimplementedCellphoneServiceServer) WatchPrices(*WatchPricesRequest, CellphoneService_WatchPricesServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchPrices not implemented")
+}
+func (UnimplementedCellphoneServiceServer) BatchCreateCellphones(CellphoneService_BatchCreateCellphonesServer) error {
+	return status.Errorf(codes.Unimplemented, "method BatchCreateCellphones not implemented")
+}
+func (UnimplementedCellphoneServiceServer) ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListOrders not implemented")
+}
 func (UnimplementedCellphoneServiceServer) mustEmbedUnimplementedCellphoneServiceServer() {}
 
 // UnsafeCellphoneServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -216,6 +451,60 @@ func _CellphoneService_CreateCellphone_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CellphoneService_GetCellphone_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCellphoneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CellphoneServiceServer).GetCellphone(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CellphoneService/GetCellphone",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CellphoneServiceServer).GetCellphone(ctx, req.(*GetCellphoneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CellphoneService_UpdateCellphone_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCellphoneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CellphoneServiceServer).UpdateCellphone(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CellphoneService/UpdateCellphone",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CellphoneServiceServer).UpdateCellphone(ctx, req.(*UpdateCellphoneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CellphoneService_DeleteCellphone_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCellphoneRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CellphoneServiceServer).DeleteCellphone(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CellphoneService/DeleteCellphone",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CellphoneServiceServer).DeleteCellphone(ctx, req.(*DeleteCellphoneRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _CellphoneService_SearchCellphone_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(FilterCondition)
 	if err := stream.RecvMsg(m); err != nil {
@@ -263,6 +552,27 @@ func (x *cellphoneServiceUploadCellphoneCoverServer) Recv() (*UploadCellphoneCov
 	return m, nil
 }
 
+func _CellphoneService_DownloadCellphoneCover_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DownloadCellphoneCoverRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CellphoneServiceServer).DownloadCellphoneCover(m, &cellphoneServiceDownloadCellphoneCoverServer{stream})
+}
+
+type CellphoneService_DownloadCellphoneCoverServer interface {
+	Send(*DownloadCellphoneCoverResponse) error
+	grpc.ServerStream
+}
+
+type cellphoneServiceDownloadCellphoneCoverServer struct {
+	grpc.ServerStream
+}
+
+func (x *cellphoneServiceDownloadCellphoneCoverServer) Send(m *DownloadCellphoneCoverResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _CellphoneService_BuyCellphone_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(CellphoneServiceServer).BuyCellphone(&cellphoneServiceBuyCellphoneServer{stream})
 }
@@ -289,6 +599,92 @@ func (x *cellphoneServiceBuyCellphoneServer) Recv() (*BuyCellphoneRequest, error
 	return m, nil
 }
 
+func _CellphoneService_WatchCellphones_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CellphoneServiceServer).WatchCellphones(m, &cellphoneServiceWatchCellphonesServer{stream})
+}
+
+type CellphoneService_WatchCellphonesServer interface {
+	Send(*CellphoneEvent) error
+	grpc.ServerStream
+}
+
+type cellphoneServiceWatchCellphonesServer struct {
+	grpc.ServerStream
+}
+
+func (x *cellphoneServiceWatchCellphonesServer) Send(m *CellphoneEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CellphoneService_WatchPrices_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchPricesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CellphoneServiceServer).WatchPrices(m, &cellphoneServiceWatchPricesServer{stream})
+}
+
+type CellphoneService_WatchPricesServer interface {
+	Send(*PriceUpdate) error
+	grpc.ServerStream
+}
+
+type cellphoneServiceWatchPricesServer struct {
+	grpc.ServerStream
+}
+
+func (x *cellphoneServiceWatchPricesServer) Send(m *PriceUpdate) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _CellphoneService_BatchCreateCellphones_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(CellphoneServiceServer).BatchCreateCellphones(&cellphoneServiceBatchCreateCellphonesServer{stream})
+}
+
+type CellphoneService_BatchCreateCellphonesServer interface {
+	Send(*BatchCreateCellphonesResponse) error
+	Recv() (*BatchCreateCellphonesRequest, error)
+	grpc.ServerStream
+}
+
+type cellphoneServiceBatchCreateCellphonesServer struct {
+	grpc.ServerStream
+}
+
+func (x *cellphoneServiceBatchCreateCellphonesServer) Send(m *BatchCreateCellphonesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *cellphoneServiceBatchCreateCellphonesServer) Recv() (*BatchCreateCellphonesRequest, error) {
+	m := new(BatchCreateCellphonesRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _CellphoneService_ListOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CellphoneServiceServer).ListOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pb.CellphoneService/ListOrders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CellphoneServiceServer).ListOrders(ctx, req.(*ListOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CellphoneService_ServiceDesc is the grpc.ServiceDesc for CellphoneService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -300,6 +696,22 @@ var CellphoneService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateCellphone",
 			Handler:    _CellphoneService_CreateCellphone_Handler,
 		},
+		{
+			MethodName: "GetCellphone",
+			Handler:    _CellphoneService_GetCellphone_Handler,
+		},
+		{
+			MethodName: "UpdateCellphone",
+			Handler:    _CellphoneService_UpdateCellphone_Handler,
+		},
+		{
+			MethodName: "DeleteCellphone",
+			Handler:    _CellphoneService_DeleteCellphone_Handler,
+		},
+		{
+			MethodName: "ListOrders",
+			Handler:    _CellphoneService_ListOrders_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -312,12 +724,33 @@ var CellphoneService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _CellphoneService_UploadCellphoneCover_Handler,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "DownloadCellphoneCover",
+			Handler:       _CellphoneService_DownloadCellphoneCover_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "BuyCellphone",
 			Handler:       _CellphoneService_BuyCellphone_Handler,
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "WatchCellphones",
+			Handler:       _CellphoneService_WatchCellphones_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchPrices",
+			Handler:       _CellphoneService_WatchPrices_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "BatchCreateCellphones",
+			Handler:       _CellphoneService_BatchCreateCellphones_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "cellphone_service.proto",
 }
@@ -0,0 +1,192 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.28.1
+// 	protoc        v4.22.3
+// source: error_reason.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// 错误原因，放在google.rpc.ErrorInfo的reason字段中
+// 客户端可以根据它来判断错误的具体原因，而不需要解析错误信息的文本
+// 已有的取值不能修改，只能新增
+type ErrorReason int32
+
+const (
+	ErrorReason_ERROR_REASON_UNSPECIFIED ErrorReason = 0
+	// 请求中的字段不合法
+	ErrorReason_INVALID_REQUEST ErrorReason = 1
+	// uuid格式不合法
+	ErrorReason_INVALID_UUID ErrorReason = 2
+	// 指定的手机不存在
+	ErrorReason_CELLPHONE_NOT_FOUND ErrorReason = 3
+	// 相同id的手机已经存在
+	ErrorReason_CELLPHONE_ALREADY_EXISTS ErrorReason = 4
+	// 封面图片超过了大小限制
+	ErrorReason_COVER_TOO_LARGE ErrorReason = 5
+	// 服务端保存数据失败
+	ErrorReason_STORAGE_FAILURE ErrorReason = 6
+	// 订阅时指定的revision已经被清理，需要重新查询后再订阅
+	ErrorReason_REVISION_COMPACTED ErrorReason = 7
+	// 订阅者处理事件太慢，可以从最后收到的revision重新订阅
+	ErrorReason_WATCHER_TOO_SLOW ErrorReason = 8
+	// 批量请求中的数量超过了上限
+	ErrorReason_BATCH_TOO_LARGE ErrorReason = 9
+	// 指定的手机没有上传过封面图片
+	ErrorReason_COVER_NOT_FOUND ErrorReason = 10
+	// 重试的购买请求没有携带幂等键，无法保证不会重复下单
+	ErrorReason_IDEMPOTENCY_KEY_REQUIRED ErrorReason = 11
+	// 幂等键已经被内容不同的请求使用过
+	ErrorReason_IDEMPOTENCY_KEY_REUSED ErrorReason = 12
+)
+
+// Enum value maps for ErrorReason.
+var (
+	ErrorReason_name = map[int32]string{
+		0:  "ERROR_REASON_UNSPECIFIED",
+		1:  "INVALID_REQUEST",
+		2:  "INVALID_UUID",
+		3:  "CELLPHONE_NOT_FOUND",
+		4:  "CELLPHONE_ALREADY_EXISTS",
+		5:  "COVER_TOO_LARGE",
+		6:  "STORAGE_FAILURE",
+		7:  "REVISION_COMPACTED",
+		8:  "WATCHER_TOO_SLOW",
+		9:  "BATCH_TOO_LARGE",
+		10: "COVER_NOT_FOUND",
+		11: "IDEMPOTENCY_KEY_REQUIRED",
+		12: "IDEMPOTENCY_KEY_REUSED",
+	}
+	ErrorReason_value = map[string]int32{
+		"ERROR_REASON_UNSPECIFIED": 0,
+		"INVALID_REQUEST":          1,
+		"INVALID_UUID":             2,
+		"CELLPHONE_NOT_FOUND":      3,
+		"CELLPHONE_ALREADY_EXISTS": 4,
+		"COVER_TOO_LARGE":          5,
+		"STORAGE_FAILURE":          6,
+		"REVISION_COMPACTED":       7,
+		"WATCHER_TOO_SLOW":         8,
+		"BATCH_TOO_LARGE":          9,
+		"COVER_NOT_FOUND":          10,
+		"IDEMPOTENCY_KEY_REQUIRED": 11,
+		"IDEMPOTENCY_KEY_REUSED":   12,
+	}
+)
+
+func (x ErrorReason) Enum() *ErrorReason {
+	p := new(ErrorReason)
+	*p = x
+	return p
+}
+
+func (x ErrorReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_error_reason_proto_enumTypes[0].Descriptor()
+}
+
+func (ErrorReason) Type() protoreflect.EnumType {
+	return &file_error_reason_proto_enumTypes[0]
+}
+
+func (x ErrorReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorReason.Descriptor instead.
+func (ErrorReason) EnumDescriptor() ([]byte, []int) {
+	return file_error_reason_proto_rawDescGZIP(), []int{0}
+}
+
+var File_error_reason_proto protoreflect.FileDescriptor
+
+var file_error_reason_proto_rawDesc = []byte{
+	0x0a, 0x12, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x5f, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x2e, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x12, 0x02, 0x70, 0x62, 0x2a, 0xc5, 0x02, 0x0a, 0x0b, 0x45, 0x72, 0x72,
+	0x6f, 0x72, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x18, 0x45, 0x52, 0x52, 0x4f,
+	0x52, 0x5f, 0x52, 0x45, 0x41, 0x53, 0x4f, 0x4e, 0x5f, 0x55, 0x4e, 0x53, 0x50, 0x45, 0x43, 0x49,
+	0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x13, 0x0a, 0x0f, 0x49, 0x4e, 0x56, 0x41, 0x4c, 0x49,
+	0x44, 0x5f, 0x52, 0x45, 0x51, 0x55, 0x45, 0x53, 0x54, 0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x49,
+	0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x5f, 0x55, 0x55, 0x49, 0x44, 0x10, 0x02, 0x12, 0x17, 0x0a,
+	0x13, 0x43, 0x45, 0x4c, 0x4c, 0x50, 0x48, 0x4f, 0x4e, 0x45, 0x5f, 0x4e, 0x4f, 0x54, 0x5f, 0x46,
+	0x4f, 0x55, 0x4e, 0x44, 0x10, 0x03, 0x12, 0x1c, 0x0a, 0x18, 0x43, 0x45, 0x4c, 0x4c, 0x50, 0x48,
+	0x4f, 0x4e, 0x45, 0x5f, 0x41, 0x4c, 0x52, 0x45, 0x41, 0x44, 0x59, 0x5f, 0x45, 0x58, 0x49, 0x53,
+	0x54, 0x53, 0x10, 0x04, 0x12, 0x13, 0x0a, 0x0f, 0x43, 0x4f, 0x56, 0x45, 0x52, 0x5f, 0x54, 0x4f,
+	0x4f, 0x5f, 0x4c, 0x41, 0x52, 0x47, 0x45, 0x10, 0x05, 0x12, 0x13, 0x0a, 0x0f, 0x53, 0x54, 0x4f,
+	0x52, 0x41, 0x47, 0x45, 0x5f, 0x46, 0x41, 0x49, 0x4c, 0x55, 0x52, 0x45, 0x10, 0x06, 0x12, 0x16,
+	0x0a, 0x12, 0x52, 0x45, 0x56, 0x49, 0x53, 0x49, 0x4f, 0x4e, 0x5f, 0x43, 0x4f, 0x4d, 0x50, 0x41,
+	0x43, 0x54, 0x45, 0x44, 0x10, 0x07, 0x12, 0x14, 0x0a, 0x10, 0x57, 0x41, 0x54, 0x43, 0x48, 0x45,
+	0x52, 0x5f, 0x54, 0x4f, 0x4f, 0x5f, 0x53, 0x4c, 0x4f, 0x57, 0x10, 0x08, 0x12, 0x13, 0x0a, 0x0f,
+	0x42, 0x41, 0x54, 0x43, 0x48, 0x5f, 0x54, 0x4f, 0x4f, 0x5f, 0x4c, 0x41, 0x52, 0x47, 0x45, 0x10,
+	0x09, 0x12, 0x13, 0x0a, 0x0f, 0x43, 0x4f, 0x56, 0x45, 0x52, 0x5f, 0x4e, 0x4f, 0x54, 0x5f, 0x46,
+	0x4f, 0x55, 0x4e, 0x44, 0x10, 0x0a, 0x12, 0x1c, 0x0a, 0x18, 0x49, 0x44, 0x45, 0x4d, 0x50, 0x4f,
+	0x54, 0x45, 0x4e, 0x43, 0x59, 0x5f, 0x4b, 0x45, 0x59, 0x5f, 0x52, 0x45, 0x51, 0x55, 0x49, 0x52,
+	0x45, 0x44, 0x10, 0x0b, 0x12, 0x1a, 0x0a, 0x16, 0x49, 0x44, 0x45, 0x4d, 0x50, 0x4f, 0x54, 0x45,
+	0x4e, 0x43, 0x59, 0x5f, 0x4b, 0x45, 0x59, 0x5f, 0x52, 0x45, 0x55, 0x53, 0x45, 0x44, 0x10, 0x0c,
+	0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_error_reason_proto_rawDescOnce sync.Once
+	file_error_reason_proto_rawDescData = file_error_reason_proto_rawDesc
+)
+
+func file_error_reason_proto_rawDescGZIP() []byte {
+	file_error_reason_proto_rawDescOnce.Do(func() {
+		file_error_reason_proto_rawDescData = protoimpl.X.CompressGZIP(file_error_reason_proto_rawDescData)
+	})
+	return file_error_reason_proto_rawDescData
+}
+
+var file_error_reason_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_error_reason_proto_goTypes = []interface{}{
+	(ErrorReason)(0), // 0: pb.ErrorReason
+}
+var file_error_reason_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_error_reason_proto_init() }
+func file_error_reason_proto_init() {
+	if File_error_reason_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_error_reason_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   0,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_error_reason_proto_goTypes,
+		DependencyIndexes: file_error_reason_proto_depIdxs,
+		EnumInfos:         file_error_reason_proto_enumTypes,
+	}.Build()
+	File_error_reason_proto = out.File
+	file_error_reason_proto_rawDesc = nil
+	file_error_reason_proto_goTypes = nil
+	file_error_reason_proto_depIdxs = nil
+}
@@ -0,0 +1,118 @@
+package cellphoneclient
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 在一个BuyCellphone流中连续购买手机
+// 购买请求写入Requests，写完之后关闭Requests；响应从Responses中读取，
+// 流结束之后Responses被关闭，Err返回流的错误
+// 流出错或者调用Close之后写入Requests的请求会被丢弃，不会阻塞调用方；
+// 无论流是否结束，调用方都需要关闭Requests，用完之后调用Close释放资源
+type Buyer struct {
+	requests  chan *pb.BuyCellphoneRequest
+	responses chan *pb.BuyCellphoneResponse
+	cancel    context.CancelFunc
+
+	mu  sync.Mutex
+	err error
+}
+
+// 打开购买的流，流持续到Requests被关闭或者调用Close，不受默认超时时间的限制
+func (c *Client) NewBuyer(ctx context.Context) (*Buyer, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.raw.BuyCellphone(ctx, c.callOpts...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	b := &Buyer{
+		requests:  make(chan *pb.BuyCellphoneRequest),
+		responses: make(chan *pb.BuyCellphoneResponse),
+		cancel:    cancel,
+	}
+	go b.send(ctx, stream)
+	go b.receive(ctx, stream)
+	return b, nil
+}
+
+// 写入购买请求，幂等键为空时使用随机的幂等键
+func (b *Buyer) Requests() chan<- *pb.BuyCellphoneRequest {
+	return b.requests
+}
+
+// 按照请求的顺序返回的响应
+func (b *Buyer) Responses() <-chan *pb.BuyCellphoneResponse {
+	return b.responses
+}
+
+// Responses被关闭之后返回流的错误，正常结束时为nil
+func (b *Buyer) Err() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// 取消流，没有收到响应的请求可能已经下单，也可能没有；流已经结束时只释放资源
+// 之后写入Requests的请求被丢弃，直到Requests被关闭
+func (b *Buyer) Close() {
+	b.cancel()
+}
+
+// 一直读取到Requests被关闭，流出错或者被取消之后丢弃读到的请求
+func (b *Buyer) send(ctx context.Context, stream pb.CellphoneService_BuyCellphoneClient) {
+	failed := false
+	done := ctx.Done()
+	for {
+		select {
+		case <-done:
+			failed = true
+			// 不再关心ctx，只等待Requests
+			done = nil
+		case req, ok := <-b.requests:
+			if !ok {
+				stream.CloseSend()
+				return
+			}
+			if failed {
+				continue
+			}
+			if req.IdempotencyKey == "" {
+				req = &pb.BuyCellphoneRequest{Id: req.Id, Price: req.Price, IdempotencyKey: uuid.NewString()}
+			}
+			// Send失败时具体的错误由Recv返回
+			if err := stream.Send(req); err != nil {
+				failed = true
+			}
+		}
+	}
+}
+
+func (b *Buyer) receive(ctx context.Context, stream pb.CellphoneService_BuyCellphoneClient) {
+	defer close(b.responses)
+	for {
+		res, err := stream.Recv()
+		if err != nil {
+			if err != io.EOF {
+				b.mu.Lock()
+				b.err = err
+				b.mu.Unlock()
+			}
+			return
+		}
+		select {
+		case b.responses <- res:
+		case <-ctx.Done():
+			b.mu.Lock()
+			b.err = ctx.Err()
+			b.mu.Unlock()
+			return
+		}
+	}
+}
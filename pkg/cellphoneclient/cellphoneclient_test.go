@@ -0,0 +1,307 @@
+package cellphoneclient_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/flaky"
+	"github.com/ryanreadbooks/go-grpc-example/internal/sample"
+	"github.com/ryanreadbooks/go-grpc-example/internal/service"
+	"github.com/ryanreadbooks/go-grpc-example/internal/validate"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+	"github.com/ryanreadbooks/go-grpc-example/pkg/cellphoneclient"
+)
+
+const coverFile = "../../image/client/apple.jpeg"
+
+// 运行服务端，返回监听的地址
+func runTestServer(t *testing.T, opts ...grpc.ServerOption) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0") // 随机端口监听
+	require.Nil(t, err)
+	server := grpc.NewServer(opts...)
+	pb.RegisterCellphoneServiceServer(server, service.NewCellphoneServiceServer(service.WithCoverPath(t.TempDir())))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return listener.Addr().String()
+}
+
+func newTestClient(t *testing.T, addr string, opts ...cellphoneclient.Option) *cellphoneclient.Client {
+	client, err := cellphoneclient.Dial(addr, opts...)
+	require.Nil(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestClientUnary(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, runTestServer(t))
+	ctx := context.Background()
+
+	cellphone := sample.NewCellphone()
+	id, err := client.Create(ctx, cellphone)
+	require.Nil(t, err)
+	require.Equal(t, cellphone.Id, id)
+
+	got, err := client.Get(ctx, id)
+	require.Nil(t, err)
+	require.Equal(t, id, got.Id)
+
+	// 错误保留了gRPC的状态和原因
+	_, err = client.Get(ctx, "00000000-0000-0000-0000-000000000000")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, cellphoneclient.ReasonCellphoneNotFound, cellphoneclient.ReasonOf(err))
+	details := cellphoneclient.DecodeError(err)
+	require.Equal(t, "00000000-0000-0000-0000-000000000000", details.ResourceInfo.ResourceName)
+
+	// 相同的幂等键只下一次单
+	res, err := client.BuyWithKey(ctx, id, 1000, "key")
+	require.Nil(t, err)
+	require.Equal(t, 1000.0, res.Avg)
+	_, err = client.BuyWithKey(ctx, id, 1000, "key")
+	require.Nil(t, err)
+	res, err = client.Buy(ctx, id, 3000)
+	require.Nil(t, err)
+	require.Equal(t, 2000.0, res.Avg)
+
+	orders, err := client.ListOrders(ctx, id)
+	require.Nil(t, err)
+	require.Len(t, orders, 1)
+	require.EqualValues(t, 2, orders[0].Count)
+}
+
+func TestClientSearch(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, runTestServer(t))
+	ctx := context.Background()
+
+	ids := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		id, err := client.Create(ctx, sample.NewCellphone())
+		require.Nil(t, err)
+		ids[id] = true
+	}
+
+	cellphones, err := client.SearchAll(ctx, nil)
+	require.Nil(t, err)
+	require.Len(t, cellphones, len(ids))
+	for _, cellphone := range cellphones {
+		require.True(t, ids[cellphone.Id])
+	}
+
+	// 提前结束迭代
+	it := client.Search(ctx, &pb.FilterCondition{})
+	require.True(t, it.Next())
+	require.NotNil(t, it.Cellphone())
+	it.Close()
+	require.False(t, it.Next())
+	require.Nil(t, it.Err())
+
+	// 出错时Next返回false，Err返回错误
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	it = client.Search(canceled, nil)
+	defer it.Close()
+	require.False(t, it.Next())
+	require.Equal(t, codes.Canceled, status.Code(it.Err()))
+}
+
+func TestClientCover(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, runTestServer(t))
+	ctx := context.Background()
+	image, err := os.ReadFile(coverFile)
+	require.Nil(t, err)
+
+	testCases := []struct {
+		Name   string
+		Reader func() io.Reader
+		Type   string
+	}{
+		// 使用文件的扩展名和大小
+		{Name: "file", Reader: func() io.Reader {
+			f, err := os.Open(coverFile)
+			require.Nil(t, err)
+			t.Cleanup(func() { f.Close() })
+			return f
+		}, Type: filepath.Ext(coverFile)},
+		// 从内容中识别图片类型
+		{Name: "bytes", Reader: func() io.Reader { return bytes.NewReader(image) }, Type: ".jpeg"},
+		// 大小未知
+		{Name: "unknown size", Reader: func() io.Reader { return io.MultiReader(bytes.NewReader(image)) }, Type: ".jpeg"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			id, err := client.Create(ctx, sample.NewCellphone())
+			require.Nil(t, err)
+
+			res, err := client.UploadCover(ctx, id, tc.Reader())
+			require.Nil(t, err)
+			require.EqualValues(t, len(image), res.Size)
+
+			var buf bytes.Buffer
+			meta, err := client.DownloadCover(ctx, id, &buf)
+			require.Nil(t, err)
+			require.Equal(t, tc.Type, meta.ImageType)
+			require.Equal(t, image, buf.Bytes())
+		})
+	}
+
+	// 封面太大
+	id, err := client.Create(ctx, sample.NewCellphone())
+	require.Nil(t, err)
+	large := io.LimitReader(zeros{}, int64(validate.MaxCoverImageBytes)+10)
+	_, err = client.UploadCover(ctx, id, large)
+	require.Equal(t, cellphoneclient.ReasonCoverTooLarge, cellphoneclient.ReasonOf(err))
+
+	// 没有封面
+	_, err = client.DownloadCover(ctx, id, io.Discard)
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestBuyer(t *testing.T) {
+	t.Parallel()
+
+	client := newTestClient(t, runTestServer(t))
+	ctx := context.Background()
+	id, err := client.Create(ctx, sample.NewCellphone())
+	require.Nil(t, err)
+
+	buyer, err := client.NewBuyer(ctx)
+	require.Nil(t, err)
+	defer buyer.Close()
+	go func() {
+		for _, price := range []float64{1000, 2000, 3000} {
+			buyer.Requests() <- &pb.BuyCellphoneRequest{Id: id, Price: price}
+		}
+		close(buyer.Requests())
+	}()
+	var avgs []float64
+	for res := range buyer.Responses() {
+		avgs = append(avgs, res.Avg)
+	}
+	require.Nil(t, buyer.Err())
+	require.Equal(t, []float64{1000, 1500, 2000}, avgs)
+
+	// 流出错之后的请求被丢弃，不会阻塞
+	buyer, err = client.NewBuyer(ctx)
+	require.Nil(t, err)
+	defer buyer.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buyer.Requests() <- &pb.BuyCellphoneRequest{Id: "00000000-0000-0000-0000-000000000000", Price: 1000}
+		for i := 0; i < 3; i++ {
+			buyer.Requests() <- &pb.BuyCellphoneRequest{Id: id, Price: 1000}
+		}
+		close(buyer.Requests())
+	}()
+	for range buyer.Responses() {
+	}
+	require.Equal(t, codes.NotFound, status.Code(buyer.Err()))
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("requests blocked after the stream failed")
+	}
+
+	// 调用Close之后的请求也被丢弃
+	buyer, err = client.NewBuyer(ctx)
+	require.Nil(t, err)
+	buyer.Close()
+	for range buyer.Responses() {
+	}
+	require.Error(t, buyer.Err())
+	done = make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 3; i++ {
+			buyer.Requests() <- &pb.BuyCellphoneRequest{Id: id, Price: 1000}
+		}
+		close(buyer.Requests())
+	}()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("requests blocked after the buyer was closed")
+	}
+	orders, err := client.ListOrders(ctx, id)
+	require.Nil(t, err)
+	require.EqualValues(t, 3, orders[0].Count)
+}
+
+// 默认的超时时间只限制没有deadline的调用
+func TestClientTimeout(t *testing.T) {
+	t.Parallel()
+
+	injector := flaky.New(flaky.Rule{Method: "/pb.CellphoneService/ListOrders", SlowFirst: 2, Delay: time.Second})
+	addr := runTestServer(t, grpc.UnaryInterceptor(injector.UnaryServerInterceptor()))
+	client := newTestClient(t, addr, cellphoneclient.WithTimeout(100*time.Millisecond))
+
+	_, err := client.ListOrders(context.Background())
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err = client.ListOrders(ctx)
+	require.Nil(t, err)
+}
+
+func TestClientToken(t *testing.T) {
+	t.Parallel()
+
+	keys := auth.NewStaticKeys([]auth.APIKey{{Key: "secret", Subject: "tester", Roles: []string{"admin"}}})
+	addr := runTestServer(t,
+		grpc.UnaryInterceptor(auth.UnaryServerInterceptor(keys, auth.DefaultPolicy(), auth.Options{})))
+
+	_, err := newTestClient(t, addr).ListOrders(context.Background())
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = newTestClient(t, addr, cellphoneclient.WithToken("secret")).ListOrders(context.Background())
+	require.Nil(t, err)
+}
+
+func TestDialInvalidServiceConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := cellphoneclient.Dial("127.0.0.1:0", cellphoneclient.WithServiceConfig([]byte(`{"methodConfig": [{}]}`)))
+	require.NotNil(t, err)
+
+	// 未知的负载均衡策略
+	_, err = cellphoneclient.Dial("static:///127.0.0.1:0", cellphoneclient.WithLoadBalancing("random"))
+	require.NotNil(t, err)
+}
+
+func TestClientMaxMessageSize(t *testing.T) {
+	t.Parallel()
+
+	addr := runTestServer(t)
+	id, err := newTestClient(t, addr).Create(context.Background(), sample.NewCellphone())
+	require.Nil(t, err)
+
+	// 响应超过了客户端允许的大小
+	client := newTestClient(t, addr, cellphoneclient.WithMaxMessageSize(64, 64))
+	_, err = client.Get(context.Background(), id)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
@@ -0,0 +1,164 @@
+package cellphoneclient
+
+// CellphoneService的客户端，封装了流式调用的收发细节
+// 调用返回的错误保留了gRPC的状态，可以用status.Code和ReasonOf判断，DecodeError返回更多的详情
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/auth"
+	"github.com/ryanreadbooks/go-grpc-example/internal/compression"
+	"github.com/ryanreadbooks/go-grpc-example/internal/connopts"
+	_ "github.com/ryanreadbooks/go-grpc-example/internal/loadbalance"
+	"github.com/ryanreadbooks/go-grpc-example/internal/serviceconfig"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+type Client struct {
+	raw  pb.CellphoneServiceClient
+	opts options
+	// 每次调用都要带上的选项
+	callOpts []grpc.CallOption
+	// Dial创建的连接由Close关闭
+	conn *grpc.ClientConn
+}
+
+// 连接到target，默认使用内置的service config
+func Dial(target string, opts ...Option) (*Client, error) {
+	o := newOptions(opts)
+	if err := compression.Validate(o.compressor); err != nil {
+		return nil, err
+	}
+	creds := o.creds
+	if creds == nil {
+		creds = insecure.NewCredentials()
+	}
+	cfg := serviceconfig.Default()
+	var err error
+	if o.serviceConfig != nil {
+		if cfg, err = serviceconfig.Parse(o.serviceConfig); err != nil {
+			return nil, err
+		}
+	}
+	if o.loadBalancing != "" {
+		if cfg, err = cfg.WithLoadBalancing(o.loadBalancing, true); err != nil {
+			return nil, err
+		}
+	}
+	dialOpts := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, o.conn.DialOptions()...)
+	dialOpts = append(dialOpts, cfg.DialOptions()...)
+	dialOpts = append(dialOpts, o.dialOpts...)
+	conn, err := grpc.Dial(target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("can not dial to %s: %w", target, err)
+	}
+	c := New(conn, opts...)
+	c.conn = conn
+	return c, nil
+}
+
+// 使用已有的连接，连接相关的选项不生效，由调用方关闭连接
+func New(conn grpc.ClientConnInterface, opts ...Option) *Client {
+	c := &Client{raw: pb.NewCellphoneServiceClient(conn), opts: newOptions(opts)}
+	if c.opts.token != "" {
+		c.callOpts = append(c.callOpts, grpc.PerRPCCredentials(auth.NewTokenCredentials(c.opts.token)))
+	}
+	c.callOpts = append(c.callOpts, compression.CallOptions(c.opts.compressor)...)
+	return c
+}
+
+func newOptions(opts []Option) options {
+	o := options{conn: connopts.DefaultClient()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// 生成的客户端，用于这里没有封装的调用
+func (c *Client) Raw() pb.CellphoneServiceClient {
+	return c.raw
+}
+
+// 关闭Dial创建的连接
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// ctx没有deadline时加上默认的超时时间
+func (c *Client) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.opts.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.opts.timeout)
+}
+
+// 创建手机，id为空时由服务端生成，返回手机的id
+func (c *Client) Create(ctx context.Context, cellphone *pb.Cellphone) (string, error) {
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+	res, err := c.raw.CreateCellphone(ctx, &pb.CreateCellphoneRequest{Cellphone: cellphone}, c.callOpts...)
+	if err != nil {
+		return "", err
+	}
+	return res.Id, nil
+}
+
+func (c *Client) Get(ctx context.Context, id string) (*pb.Cellphone, error) {
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+	return c.raw.GetCellphone(ctx, &pb.GetCellphoneRequest{Id: id}, c.callOpts...)
+}
+
+// 用cellphone替换id相同的手机，返回修改之后的手机
+func (c *Client) Update(ctx context.Context, cellphone *pb.Cellphone) (*pb.Cellphone, error) {
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+	return c.raw.UpdateCellphone(ctx, &pb.UpdateCellphoneRequest{Cellphone: cellphone}, c.callOpts...)
+}
+
+func (c *Client) Delete(ctx context.Context, id string) error {
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+	_, err := c.raw.DeleteCellphone(ctx, &pb.DeleteCellphoneRequest{Id: id}, c.callOpts...)
+	return err
+}
+
+// 手机的订单，不指定id时返回所有订单
+func (c *Client) ListOrders(ctx context.Context, ids ...string) ([]*pb.Order, error) {
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+	res, err := c.raw.ListOrders(ctx, &pb.ListOrdersRequest{Ids: ids}, c.callOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return res.Orders, nil
+}
+
+// 购买一次手机，使用随机的幂等键，重试时不会重复下单
+// 连续购买多次时使用Buyer
+func (c *Client) Buy(ctx context.Context, id string, price float64) (*pb.BuyCellphoneResponse, error) {
+	return c.BuyWithKey(ctx, id, price, uuid.NewString())
+}
+
+// 使用指定的幂等键购买一次手机，相同的键只会下一次单
+func (c *Client) BuyWithKey(ctx context.Context, id string, price float64, key string) (*pb.BuyCellphoneResponse, error) {
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+	stream, err := c.raw.BuyCellphone(ctx, c.callOpts...)
+	if err != nil {
+		return nil, err
+	}
+	// Send失败时具体的错误由Recv返回
+	stream.Send(&pb.BuyCellphoneRequest{Id: id, Price: price, IdempotencyKey: key})
+	stream.CloseSend()
+	return stream.Recv()
+}
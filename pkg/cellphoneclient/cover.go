@@ -0,0 +1,171 @@
+package cellphoneclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/validate"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 上传封面时每个block的大小
+const blockSize = 4096
+
+// 从内容中识别出的图片类型和扩展名
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpeg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/bmp":  ".bmp",
+}
+
+// 上传手机的封面
+// r为文件时使用文件的扩展名和大小，否则从内容中识别图片类型，
+// 大小未知的内容会先读到内存中，超过服务端的限制时由服务端拒绝
+func (c *Client) UploadCover(ctx context.Context, id string, r io.Reader) (*pb.UploadCellphoneCoverResponse, error) {
+	size, r, err := coverSize(r)
+	if err != nil {
+		return nil, fmt.Errorf("can not read cover: %w", err)
+	}
+	var imageType string
+	if named, ok := r.(interface{ Name() string }); ok {
+		imageType = filepath.Ext(named.Name())
+	} else {
+		br := bufio.NewReaderSize(r, 512)
+		// 内容不足512字节时Peek返回能读到的部分
+		head, err := br.Peek(512)
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("can not read cover: %w", err)
+		}
+		imageType = imageTypes[http.DetectContentType(head)]
+		r = br
+	}
+	return c.uploadCover(ctx, id, imageType, size, r)
+}
+
+// 上传手机的封面，imageType为图片的扩展名，比如".jpeg"
+func (c *Client) UploadCoverWithType(ctx context.Context, id, imageType string, r io.Reader) (*pb.UploadCellphoneCoverResponse, error) {
+	size, r, err := coverSize(r)
+	if err != nil {
+		return nil, fmt.Errorf("can not read cover: %w", err)
+	}
+	return c.uploadCover(ctx, id, imageType, size, r)
+}
+
+func (c *Client) uploadCover(ctx context.Context, id, imageType string, size uint32, r io.Reader) (*pb.UploadCellphoneCoverResponse, error) {
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+	stream, err := c.raw.UploadCellphoneCover(ctx, c.callOpts...)
+	if err != nil {
+		return nil, err
+	}
+	// 先发送图片的元数据，然后不断发送block
+	// Send失败时具体的错误由CloseAndRecv返回
+	err = stream.Send(&pb.UploadCellphoneCoverRequest{
+		Data: &pb.UploadCellphoneCoverRequest_Meta{
+			Meta: &pb.CoverMetaInfo{Id: id, ImageType: imageType, Size: size},
+		},
+	})
+	buf := make([]byte, blockSize)
+	for err == nil {
+		var n int
+		n, err = r.Read(buf)
+		if n > 0 {
+			if sendErr := stream.Send(&pb.UploadCellphoneCoverRequest{
+				Data: &pb.UploadCellphoneCoverRequest_Block{Block: buf[:n]},
+			}); sendErr != nil {
+				break
+			}
+		}
+		if err == io.EOF {
+			err = nil
+			break
+		}
+		if err != nil {
+			// 取消调用，服务端不会保存不完整的封面
+			cancel()
+			return nil, fmt.Errorf("can not read cover: %w", err)
+		}
+	}
+	return stream.CloseAndRecv()
+}
+
+// 上传文件作为手机的封面
+func (c *Client) UploadCoverFile(ctx context.Context, id, filename string) (*pb.UploadCellphoneCoverResponse, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return c.UploadCover(ctx, id, f)
+}
+
+// 封面的大小，以及之后用来读取内容的reader
+func coverSize(r io.Reader) (uint32, io.Reader, error) {
+	switch v := r.(type) {
+	case interface{ Stat() (fs.FileInfo, error) }:
+		stat, err := v.Stat()
+		if err != nil {
+			return 0, nil, err
+		}
+		if stat.Mode().IsRegular() {
+			return uint32(min(stat.Size(), int64(validate.MaxCoverImageBytes)+1)), r, nil
+		}
+	case interface{ Len() int }:
+		return uint32(min(v.Len(), int(validate.MaxCoverImageBytes)+1)), r, nil
+	}
+	// 多读一个字节，超过限制时服务端返回COVER_TOO_LARGE
+	data, err := io.ReadAll(io.LimitReader(r, int64(validate.MaxCoverImageBytes)+1))
+	if err != nil {
+		return 0, nil, err
+	}
+	return uint32(len(data)), bytes.NewReader(data), nil
+}
+
+// 下载手机的封面写入w，返回封面的元数据
+// 收到的内容和元数据中的大小不一致时返回错误
+func (c *Client) DownloadCover(ctx context.Context, id string, w io.Writer) (*pb.CoverMetaInfo, error) {
+	ctx, cancel := c.context(ctx)
+	defer cancel()
+	stream, err := c.raw.DownloadCellphoneCover(ctx, &pb.DownloadCellphoneCoverRequest{Id: id}, c.callOpts...)
+	if err != nil {
+		return nil, err
+	}
+	// 第一条响应为元数据
+	res, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	meta := res.GetMeta()
+	if meta == nil {
+		return nil, errors.New("can not download cover: metadata is missing")
+	}
+	var written int64
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return meta, err
+		}
+		n, err := w.Write(res.GetBlock())
+		written += int64(n)
+		if err != nil {
+			return meta, err
+		}
+	}
+	if written != int64(meta.Size) {
+		return meta, fmt.Errorf("can not download cover: received %d of %d bytes", written, meta.Size)
+	}
+	return meta, nil
+}
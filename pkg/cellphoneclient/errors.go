@@ -0,0 +1,38 @@
+package cellphoneclient
+
+import (
+	"github.com/ryanreadbooks/go-grpc-example/internal/rpcerr"
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 服务端在错误的ErrorInfo中携带的原因，和pb.ErrorReason相同
+type ErrorReason = pb.ErrorReason
+
+const (
+	ReasonUnspecified            = pb.ErrorReason_ERROR_REASON_UNSPECIFIED
+	ReasonInvalidRequest         = pb.ErrorReason_INVALID_REQUEST
+	ReasonInvalidUUID            = pb.ErrorReason_INVALID_UUID
+	ReasonCellphoneNotFound      = pb.ErrorReason_CELLPHONE_NOT_FOUND
+	ReasonCellphoneAlreadyExists = pb.ErrorReason_CELLPHONE_ALREADY_EXISTS
+	ReasonCoverTooLarge          = pb.ErrorReason_COVER_TOO_LARGE
+	ReasonStorageFailure         = pb.ErrorReason_STORAGE_FAILURE
+	ReasonRevisionCompacted      = pb.ErrorReason_REVISION_COMPACTED
+	ReasonWatcherTooSlow         = pb.ErrorReason_WATCHER_TOO_SLOW
+	ReasonBatchTooLarge          = pb.ErrorReason_BATCH_TOO_LARGE
+	ReasonCoverNotFound          = pb.ErrorReason_COVER_NOT_FOUND
+	ReasonIdempotencyKeyRequired = pb.ErrorReason_IDEMPOTENCY_KEY_REQUIRED
+	ReasonIdempotencyKeyReused   = pb.ErrorReason_IDEMPOTENCY_KEY_REUSED
+)
+
+// 从错误中解析出来的状态码、原因以及BadRequest、ResourceInfo等详情
+type ErrorDetails = rpcerr.Details
+
+// 解析调用返回的错误中携带的详情，err为nil时返回nil
+func DecodeError(err error) *ErrorDetails {
+	return rpcerr.Decode(err)
+}
+
+// 返回调用出错的原因，错误中没有原因时返回ReasonUnspecified
+func ReasonOf(err error) ErrorReason {
+	return rpcerr.ReasonOf(err)
+}
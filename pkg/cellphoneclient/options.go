@@ -0,0 +1,103 @@
+package cellphoneclient
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/ryanreadbooks/go-grpc-example/internal/compression"
+	"github.com/ryanreadbooks/go-grpc-example/internal/connopts"
+)
+
+// 创建客户端时的可选项
+type Option func(*options)
+
+type options struct {
+	timeout       time.Duration
+	token         string
+	creds         credentials.TransportCredentials
+	serviceConfig []byte
+	loadBalancing string
+	conn          connopts.Client
+	compressor    string
+	dialOpts      []grpc.DialOption
+}
+
+// 没有设置deadline的调用使用的超时时间，0表示不限制
+// 持续到被关闭的Buyer不受限制
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// 每次调用都在authorization中携带token，可以是api key或者jwt
+func WithToken(token string) Option {
+	return func(o *options) {
+		o.token = token
+	}
+}
+
+// 连接服务端使用的传输层凭证，默认不加密
+func WithTransportCredentials(creds credentials.TransportCredentials) Option {
+	return func(o *options) {
+		o.creds = creds
+	}
+}
+
+// json格式的service config，用来替换内置的重试和对冲策略
+func WithServiceConfig(data []byte) Option {
+	return func(o *options) {
+		o.serviceConfig = data
+	}
+}
+
+// target解析出多个地址时使用的负载均衡策略：round_robin或者least_request，
+// 只把请求发给健康检查通过的后端；默认只使用第一个可用的地址
+// target可以是static:///host1:9527,host2:9527或者dns:///host:9527
+func WithLoadBalancing(policy string) Option {
+	return func(o *options) {
+		o.loadBalancing = policy
+	}
+}
+
+// 单条消息的大小上限，默认都是4MiB
+func WithMaxMessageSize(recv, send int) Option {
+	return func(o *options) {
+		o.conn.MaxRecvMsgBytes = recv
+		o.conn.MaxSendMsgBytes = send
+	}
+}
+
+// 有调用时连接空闲多久之后发送ping，以及等待回应的时间，默认为1分钟和20秒
+// interval为0表示不发送，gRPC不允许小于10秒，也不能小于服务端允许的最小间隔
+func WithKeepalive(interval, timeout time.Duration) Option {
+	return func(o *options) {
+		o.conn.KeepaliveTime = interval
+		o.conn.KeepaliveTimeout = timeout
+	}
+}
+
+// WithCompressor可以使用的压缩算法
+const (
+	// 不压缩，和不设置WithCompressor相同
+	CompressorIdentity = compression.Identity
+	CompressorGzip     = compression.Gzip
+	CompressorZstd     = compression.Zstd
+)
+
+// 使用CompressorGzip或者CompressorZstd压缩请求，服务端用同样的算法压缩响应
+// 默认不压缩；New创建的客户端只在调用时检查算法是否存在
+func WithCompressor(name string) Option {
+	return func(o *options) {
+		o.compressor = name
+	}
+}
+
+// 连接时额外使用的选项，比如拦截器
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOpts = append(o.dialOpts, opts...)
+	}
+}
@@ -0,0 +1,89 @@
+package cellphoneclient
+
+import (
+	"context"
+	"io"
+
+	"github.com/ryanreadbooks/go-grpc-example/pb"
+)
+
+// 逐个接收搜索结果，用法和bufio.Scanner一样：
+//
+//	it := client.Search(ctx, condition)
+//	defer it.Close()
+//	for it.Next() {
+//		cellphone := it.Cellphone()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type Iterator struct {
+	stream pb.CellphoneService_SearchCellphoneClient
+	cancel context.CancelFunc
+	cur    *pb.Cellphone
+	err    error
+	done   bool
+}
+
+// 搜索满足条件的手机，condition为nil时返回所有手机
+// 调用方要在用完之后调用Close
+func (c *Client) Search(ctx context.Context, condition *pb.FilterCondition) *Iterator {
+	if condition == nil {
+		condition = &pb.FilterCondition{}
+	}
+	ctx, cancel := c.context(ctx)
+	it := &Iterator{cancel: cancel}
+	it.stream, it.err = c.raw.SearchCellphone(ctx, condition, c.callOpts...)
+	if it.err != nil {
+		it.Close()
+	}
+	return it
+}
+
+// 接收下一部手机，没有更多结果或者出错时返回false
+func (it *Iterator) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+	cellphone, err := it.stream.Recv()
+	if err != nil {
+		// 正常结束时Err返回nil
+		if err != io.EOF {
+			it.err = err
+		}
+		it.Close()
+		return false
+	}
+	it.cur = cellphone
+	return true
+}
+
+// Next返回true之后得到的手机
+func (it *Iterator) Cellphone() *pb.Cellphone {
+	return it.cur
+}
+
+// 搜索过程中出现的错误
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+// 放弃剩下的结果，可以多次调用
+func (it *Iterator) Close() {
+	it.done = true
+	it.cur = nil
+	it.cancel()
+}
+
+// 搜索满足条件的所有手机
+func (c *Client) SearchAll(ctx context.Context, condition *pb.FilterCondition) ([]*pb.Cellphone, error) {
+	it := c.Search(ctx, condition)
+	defer it.Close()
+	var cellphones []*pb.Cellphone
+	for it.Next() {
+		cellphones = append(cellphones, it.Cellphone())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	return cellphones, nil
+}